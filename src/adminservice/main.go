@@ -6,16 +6,20 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/config"
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/dblayer"
+	"github.com/dsbezerra/amenic/src/lib/shared"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
+)
+
+const (
+	ServiceName = "Admin"
 )
 
 var (
-	log = logrus.WithFields(logrus.Fields{"App": "Admin"})
+	log = logrus.WithFields(logrus.Fields{"App": ServiceName})
 )
 
 // Context ...
@@ -30,27 +34,39 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	// Catch events that don't match their contract during development.
+	messagequeue.ValidatePayloads = !settings.IsProduction
 
-	conn, err := amqp.Dial(settings.AMQPMessageBroker)
+	broker, err := shared.OpenBroker(settings)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer broker.Close()
 
-	eventEmitter, err := messagequeue.NewAMQPEventEmitter(conn, "events")
+	eventEmitter, err := broker.Emitter(ServiceName)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// eventListener, err := messagequeue.NewAMQPEventListener(conn, "events", "api")
+	// Commands are dispatched as requests so their results are replied.
+	requester, err := broker.Requester(ServiceName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// eventListener, err := broker.Listener("api")
 	// if err != nil {
 	// 	log.Fatal(err)
 	// }
 
-	db, err := mongolayer.NewMongoDAL(settings.DBConnection)
+	db, err := dblayer.NewPersistenceLayer(settings.DBType, settings.DBConnection)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = db.Setup()
 	if err != nil {
 		log.Fatal(err)
 	}
-	db.Setup()
 	defer db.Close()
 
 	log.Info("Database setup completed!")
@@ -64,7 +80,7 @@ func main() {
 	router := ctx.buildRouter()
 
 	// Serve API
-	rest.ServeAPI(router, db, eventEmitter)
+	rest.ServeAPI(router, db, requester, settings.DatasetDir, log)
 	router.Run(settings.RESTEndpoint)
 }
 
@@ -1,17 +1,22 @@
 package rest
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/dsbezerra/amenic/src/lib/dataset"
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"github.com/dsbezerra/amenic/src/lib/util/apiutil"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -25,15 +30,15 @@ var DefaultEventArgs = map[string]interface{}{
 	"execution_timeout": DefaultExecutionTimeout,
 }
 
-// RunningCommands defines a custom type to a map[string]command just to make possible to use methods in maps.
+// RunningCommands defines a custom type to a map[string]*commandCall just to make possible to use methods in maps.
 type RunningCommands struct {
 	sync.RWMutex
-	m map[string]models.Command
+	m map[string]*commandCall
 }
 
 // running command list
 var running = RunningCommands{
-	m: make(map[string]models.Command),
+	m: make(map[string]*commandCall),
 }
 
 // CommandList is the list of all commands supported by this route.
@@ -108,21 +113,72 @@ var commandList = []models.CommandInfo{
 		Description:  "Sync scores (Rotten Tomatoes and IMDb) of now playing movies",
 		PossibleArgs: []models.CommandArg{},
 	},
+	models.CommandInfo{
+		Name:        models.CommandExportDataset,
+		Description: "Exports all collections of the catalog or the one(s) specified in args to a NDJSON file.",
+		PossibleArgs: []models.CommandArg{
+			models.CommandArg{
+				Name:        "-collections",
+				Description: "comma separated collections to export (can be any of cities, theaters, scrapers, tasks, movies, prices)",
+				Required:    false,
+			},
+			models.CommandArg{
+				Name:        "-file",
+				Description: "name of the file in the dataset directory, compressed with gzip if it ends with .gz (defaults to dataset_<date>.ndjson.gz)",
+				Required:    false,
+			},
+			models.CommandArg{
+				Name:        "-overwrite",
+				Description: "whether an existing file should be replaced or not (defaults to false)",
+				Required:    false,
+			},
+		},
+	},
+	models.CommandInfo{
+		Name:        models.CommandImportDataset,
+		Description: "Imports all collections or the one(s) specified in args from a NDJSON file. Importing the same file twice doesn't duplicate data.",
+		PossibleArgs: []models.CommandArg{
+			models.CommandArg{
+				Name:        "-collections",
+				Description: "comma separated collections to import (can be any of cities, theaters, scrapers, tasks, movies, prices)",
+				Required:    false,
+			},
+			models.CommandArg{
+				Name:        "-file",
+				Description: "name of the file exported by export_dataset in the dataset directory",
+				Required:    true,
+			},
+		},
+	},
+	models.CommandInfo{
+		Name:        models.CommandCheckIntegrity,
+		Description: "Reports references to missing movies and theaters and sessions without movie.",
+		PossibleArgs: []models.CommandArg{
+			models.CommandArg{
+				Name:        "-repair",
+				Description: "whether sessions should be matched again by movie slug, or hidden, after reviewing the last report (defaults to false)",
+				Required:    false,
+			},
+		},
+	},
 }
 
 // CommandService ...
 type CommandService struct {
-	data    persistence.DataAccessLayer
-	emitter messagequeue.EventEmitter
+	data       persistence.DataAccessLayer
+	requester  messagequeue.Requester
+	datasetDir string
+	log        *logrus.Entry
 }
 
 // ServeCommands ...
 func (rs *Service) ServeCommands(r *gin.Engine) {
-	s := &CommandService{rs.data, rs.emitter}
+	s := &CommandService{rs.data, rs.requester, rs.datasetDir, rs.log}
 
 	commands := r.Group("/commands")
 	commands.GET("/", s.GetAll)
 	commands.POST("/", s.RunCommand)
+	commands.GET("/:id", s.GetStatus)
 }
 
 // GetAll ...
@@ -143,43 +199,72 @@ func (s *CommandService) RunCommand(c *gin.Context) {
 		return
 	}
 
+	// The wait query parameter, e.g.: 30s, makes the response wait until the
+	// command finishes, up to MaxCommandWait. Otherwise its status can be
+	// polled with GetStatus.
+	var wait time.Duration
+	if value := c.Query("wait"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			apiutil.SendBadRequest(c)
+			return
+		}
+		if d > MaxCommandWait {
+			d = MaxCommandWait
+		}
+		wait = d
+	}
+
 	if running.isRunning(cmd) {
 		apiutil.SendSuccessOrError(c, "command already running", nil)
 		return
 	}
 
-	go run(s, cmd)
+	call := run(s, cmd)
+	if wait > 0 {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+		call.wait(ctx)
+		cancel()
+	}
+
+	apiutil.SendSuccess(c, call.status())
+}
 
-	apiutil.SendSuccess(c, "command started")
+// GetStatus returns the status and the replies of a command started in the
+// last CommandTimeout.
+func (s *CommandService) GetStatus(c *gin.Context) {
+	call, ok := calls.get(c.Param("id"))
+	if !ok {
+		apiutil.SendNotFound(c)
+		return
+	}
+	apiutil.SendSuccess(c, call.status())
 }
 
-// Run runs all given commands sequentially.
+// Run runs all given commands sequentially, waiting for each one to finish.
 func (s *CommandService) Run(commands ...models.Command) {
-	size := len(commands)
-
-	if size == 0 {
-		// Do nothing.
-	} else if size == 1 {
-		run(s, commands[0])
-	} else {
-		for _, cmd := range commands {
-			run(s, cmd)
-		}
+	for _, cmd := range commands {
+		ctx, cancel := context.WithTimeout(context.Background(), CommandTimeout)
+		run(s, cmd).wait(ctx)
+		cancel()
 	}
 }
 
-// RunSingle ...
-func run(s *CommandService, cmd models.Command) {
-	running.add(cmd)
+// run starts a command. Commands run by other services are dispatched as
+// requests, so their call gets the replies of the service.
+func run(s *CommandService, cmd models.Command) *commandCall {
+	call := newCommandCall(cmd)
+	running.add(call)
 
 	printRunningList()
 
 	name := cmd.Name
 	args := models.ParseArgs(cmd.Args)
 
+	var event messagequeue.Event
 	switch name {
 	case models.CommandCreateStatic, models.CommandClearStatic:
-		event := &contracts.EventStaticDispatched{
+		e := &contracts.EventStaticDispatched{
 			Name:             cmd.Name,
 			Type:             args["type"],
 			CinemaID:         args["theater"],
@@ -189,13 +274,12 @@ func run(s *CommandService, cmd models.Command) {
 
 		ok, ID := models.GenerateTaskID(cmd.Name, cmd.Args)
 		if ok {
-			event.TaskID = ID
+			e.TaskID = ID
 		}
-
-		s.emitter.Emit(event)
+		event = e
 
 	case models.CommandCheckOpeningMovies, models.CommandSyncScores:
-		event := &contracts.EventCommandDispatched{
+		e := &contracts.EventCommandDispatched{
 			Name:             cmd.Name,
 			Type:             cmd.Name, // Command name == event type here
 			DispatchTime:     time.Now().UTC(),
@@ -204,48 +288,150 @@ func run(s *CommandService, cmd models.Command) {
 
 		ok, ID := models.GenerateTaskID(cmd.Name, cmd.Args)
 		if ok {
-			event.TaskID = ID
+			e.TaskID = ID
 		}
+		event = e
 
-		s.emitter.Emit(event)
-
-	case models.CommandStartScraper:
-		s.emitter.Emit(&contracts.EventCommandDispatched{
+	case models.CommandStartScraper, models.CommandCheckIntegrity:
+		event = &contracts.EventCommandDispatched{
 			Name:             cmd.Name,
 			Type:             cmd.Name, // Command name == event type here
 			Args:             cmd.Args,
 			DispatchTime:     time.Now().UTC(),
 			ExecutionTimeout: DefaultExecutionTimeout,
-		})
+		}
+
+	case models.CommandExportDataset, models.CommandImportDataset:
+		calls.add(call)
+		go func() {
+			call.finish(runDataset(s.data, s.log, s.datasetDir, cmd.Name, args))
+			running.remove(call)
+		}()
+		return call
+
+	default:
+		call.finish(nil, fmt.Errorf("command %s can't be run", name))
+		calls.add(call)
+		running.remove(call)
+		return call
 	}
 
-	running.remove(cmd)
+	ctx, cancel := context.WithTimeout(context.Background(), CommandTimeout)
+	c, err := s.requester.Request(ctx, event)
+	if err != nil {
+		cancel()
+		call.finish(nil, err)
+		calls.add(call)
+		running.remove(call)
+		return call
+	}
+	call.dispatched(c)
+	calls.add(call)
+
+	go func() {
+		call.wait(ctx)
+		cancel()
+		running.remove(call)
+	}()
+	return call
 }
 
+// runDataset exports or imports a dataset file and returns the counts of
+// each collection. These run here instead of being dispatched since they
+// only need the database.
+//
+// The file is named by whoever sends the command, so it's always resolved in
+// dir and exports never replace an existing file unless -overwrite is true.
+func runDataset(data persistence.DataAccessLayer, log *logrus.Entry, dir, name string, args map[string]string) (interface{}, error) {
+	collections, err := dataset.ParseCollections(args["collections"])
+	if err != nil {
+		return nil, err
+	}
+
+	file := args["file"]
+	if name == models.CommandExportDataset {
+		if file == "" {
+			file = fmt.Sprintf("dataset_%s.ndjson.gz", time.Now().Format("20060102_150405"))
+		}
+		path, err := datasetPath(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		counts, err := dataset.ExportFile(data, path, args["overwrite"] == "true", collections...)
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("file %s already exists, use -overwrite true to replace it", file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("%s: exported %v to %s", name, counts, path)
+		return map[string]interface{}{"file": file, "counts": counts}, nil
+	}
+
+	if file == "" {
+		return nil, fmt.Errorf("missing -file")
+	}
+	path, err := datasetPath(dir, file)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := dataset.ImportFile(data, path, collections...)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range collections {
+		if count, ok := counts[c]; ok {
+			log.Infof("%s: %s %+v", name, c, *count)
+		}
+	}
+	return map[string]interface{}{"file": file, "counts": counts}, nil
+}
+
+// datasetPath returns the path of the dataset file in dir. Absolute names
+// and names with .. elements are rejected so no file outside dir is used.
+func datasetPath(dir, file string) (string, error) {
+	if filepath.IsAbs(file) || strings.HasPrefix(file, "/") || strings.HasPrefix(file, "\\") {
+		return "", fmt.Errorf("invalid -file %s: must be relative to the dataset directory", file)
+	}
+	for _, elem := range strings.FieldsFunc(file, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if elem == ".." {
+			return "", fmt.Errorf("invalid -file %s: must not contain ..", file)
+		}
+	}
+	return filepath.Join(dir, file), nil
+}
+
+// isRunning reports whether c was started and didn't finish yet.
 func (r *RunningCommands) isRunning(c models.Command) bool {
 	r.RLock()
-	_, ok := r.m[c.Hash()]
+	call, ok := r.m[c.Hash()]
 	r.RUnlock()
-	return ok
+	return ok && !call.finished()
 }
 
-func (r *RunningCommands) add(c models.Command) {
-	if c.Name == "" {
+func (r *RunningCommands) add(c *commandCall) {
+	if c.cmd.Name == "" {
 		return
 	}
 
 	r.Lock()
-	r.m[c.Hash()] = c
+	r.m[c.cmd.Hash()] = c
 	r.Unlock()
 }
 
-func (r *RunningCommands) remove(c models.Command) {
-	if c.Name == "" {
+func (r *RunningCommands) remove(c *commandCall) {
+	if c.cmd.Name == "" {
 		return
 	}
 
 	r.Lock()
-	delete(r.m, c.Hash())
+	hash := c.cmd.Hash()
+	if r.m[hash] == c {
+		delete(r.m, hash)
+	}
 	r.Unlock()
 }
 
@@ -254,7 +440,8 @@ func printRunningList() {
 
 	fmt.Printf("\n------------------>   RUNNING COMMANDS   <------------------]\n\n")
 	fmt.Printf("command_name\t\t-\tcommand_args\n")
-	for _, c := range running.m {
+	for _, call := range running.m {
+		c := call.cmd
 		args := strings.Join(c.Args, " ")
 		if args == "" {
 			args = "(no args)"
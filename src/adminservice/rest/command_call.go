@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// CommandTimeout is how long replies to a command are waited for. Its
+	// status is kept for the same time.
+	CommandTimeout = time.Hour
+
+	// MaxCommandWait is the longest RunCommand waits for a command to finish
+	// before responding.
+	MaxCommandWait = time.Minute
+
+	// CommandRunning is the status of commands without a final reply.
+	CommandRunning = "running"
+)
+
+// CommandStatus is the status of a command run through RunCommand.
+type CommandStatus struct {
+	ID        string               `json:"id"`
+	Command   models.Command       `json:"command"`
+	StartedAt time.Time            `json:"started_at"`
+	Status    string               `json:"status"` // CommandRunning or the status of the final reply
+	Replies   []messagequeue.Reply `json:"replies"`
+}
+
+// commandCall tracks a command. Commands dispatched to other services get
+// their replies from call, the ones run here have a single local reply.
+type commandCall struct {
+	id        string
+	cmd       models.Command
+	startedAt time.Time
+	call      *messagequeue.Call
+
+	mu    sync.Mutex
+	local []messagequeue.Reply
+	done  chan struct{}
+}
+
+// commandCalls holds the calls of recent commands by ID.
+type commandCalls struct {
+	sync.RWMutex
+	m map[string]*commandCall
+}
+
+var calls = commandCalls{
+	m: make(map[string]*commandCall),
+}
+
+func newCommandCall(cmd models.Command) *commandCall {
+	return &commandCall{
+		id:        primitive.NewObjectID().Hex(),
+		cmd:       cmd,
+		startedAt: time.Now().UTC(),
+		done:      make(chan struct{}),
+	}
+}
+
+// dispatched sets the call of a command dispatched to another service.
+func (c *commandCall) dispatched(call *messagequeue.Call) {
+	c.mu.Lock()
+	c.id = call.ID
+	c.call = call
+	c.mu.Unlock()
+}
+
+// finish sets the final reply of a command run here, or that couldn't be
+// dispatched.
+func (c *commandCall) finish(result interface{}, err error) {
+	reply := messagequeue.Reply{
+		RequestID: c.id,
+		Source:    "Admin",
+		Status:    messagequeue.ReplySucceeded,
+		Timestamp: time.Now().UTC(),
+	}
+	if err != nil {
+		reply.Status = messagequeue.ReplyFailed
+		reply.Error = err.Error()
+	} else if result != nil {
+		reply.Result, _ = json.Marshal(result)
+	}
+
+	c.mu.Lock()
+	c.local = append(c.local, reply)
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// wait blocks until the command finishes or ctx is done.
+func (c *commandCall) wait(ctx context.Context) {
+	c.mu.Lock()
+	var done <-chan struct{} = c.done
+	if c.call != nil {
+		done = c.call.Done()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// finished reports whether the final reply was received.
+func (c *commandCall) finished() bool {
+	return c.status().Status != CommandRunning
+}
+
+func (c *commandCall) status() CommandStatus {
+	c.mu.Lock()
+	replies := append([]messagequeue.Reply{}, c.local...)
+	if c.call != nil {
+		replies = c.call.Replies()
+	}
+	c.mu.Unlock()
+
+	status := CommandRunning
+	if n := len(replies); n > 0 && replies[n-1].Final() {
+		status = replies[n-1].Status
+	}
+	return CommandStatus{
+		ID:        c.id,
+		Command:   c.cmd,
+		StartedAt: c.startedAt,
+		Status:    status,
+		Replies:   replies,
+	}
+}
+
+// add stores c, removing the calls older than CommandTimeout.
+func (r *commandCalls) add(c *commandCall) {
+	r.Lock()
+	defer r.Unlock()
+	for id, old := range r.m {
+		if time.Since(old.startedAt) > CommandTimeout {
+			delete(r.m, id)
+		}
+	}
+	r.m[c.id] = c
+}
+
+func (r *commandCalls) get(id string) (*commandCall, bool) {
+	r.RLock()
+	c, ok := r.m[id]
+	r.RUnlock()
+	return c, ok
+}
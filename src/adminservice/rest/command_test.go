@@ -0,0 +1,150 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/dsbezerra/amenic/src/lib/dataset"
+	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// statusResponse is the response of RunCommand and GetStatus.
+type statusResponse struct {
+	Data CommandStatus `json:"data"`
+}
+
+func request(t *testing.T, r *gin.Engine, method, url, body string) CommandStatus {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Skip the prefix added by SecureJSON.
+	var res statusResponse
+	b := w.Body.Bytes()
+	if i := strings.Index(string(b), "{"); i >= 0 {
+		assert.NoError(t, json.Unmarshal(b[i:], &res))
+	}
+	return res.Data
+}
+
+func TestRunCommand(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	data, err := memlayer.NewMemDAL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := messagequeue.NewMemoryBroker()
+	defer broker.Close()
+
+	scores := make(chan struct{})
+	errs, err := broker.Listener("Score").Consume(func(ctx context.Context, event messagequeue.Event) error {
+		e := event.(*contracts.EventCommandDispatched)
+		if e.Type == models.CommandCheckIntegrity {
+			return messagequeue.SetResult(ctx, e.Args)
+		}
+		if e.Type != models.CommandSyncScores {
+			messagequeue.Ignore(ctx)
+			return nil
+		}
+		messagequeue.ReportProgress(ctx, 0.5, "syncing scores")
+		<-scores
+		return messagequeue.SetResult(ctx, map[string]int{"synced": 2})
+	}, "commandDispatched")
+	if !assert.NoError(t, err) {
+		return
+	}
+	go func() {
+		for range errs {
+		}
+	}()
+
+	r := gin.New()
+	s := &Service{data, broker.Requester("Admin"), t.TempDir(), logrus.NewEntry(logrus.New())}
+	s.ServeCommands(r)
+
+	// Started commands are polled.
+	status := request(t, r, "POST", "/commands/", `{"command_name":"sync_scores","command_args":[]}`)
+	assert.NotEmpty(t, status.ID)
+	assert.Equal(t, CommandRunning, status.Status)
+	close(scores)
+	broker.Wait()
+
+	status = request(t, r, "GET", "/commands/"+status.ID, "")
+	assert.Equal(t, messagequeue.ReplySucceeded, status.Status)
+	if assert.Len(t, status.Replies, 2) {
+		assert.Equal(t, "syncing scores", status.Replies[0].Message)
+		assert.JSONEq(t, `{"synced":2}`, string(status.Replies[1].Result))
+	}
+
+	// Or waited for.
+	status = request(t, r, "POST", "/commands/?wait=5s", `{"command_name":"sync_scores","command_args":[]}`)
+	assert.Equal(t, messagequeue.ReplySucceeded, status.Status)
+
+	// Repairs are only made when asked to.
+	status = request(t, r, "POST", "/commands/?wait=5s", `{"command_name":"check_integrity","command_args":["-repair","true"]}`)
+	assert.Equal(t, messagequeue.ReplySucceeded, status.Status)
+	if assert.Len(t, status.Replies, 1) {
+		assert.JSONEq(t, `["-repair","true"]`, string(status.Replies[0].Result))
+	}
+
+	status = request(t, r, "POST", "/commands/?wait=5s", `{"command_name":"import_dataset","command_args":[]}`)
+	assert.Equal(t, messagequeue.ReplyFailed, status.Status)
+	if assert.Len(t, status.Replies, 1) {
+		assert.Equal(t, "missing -file", status.Replies[0].Error)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/commands/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRunDataset(t *testing.T) {
+	data, err := memlayer.NewMemDAL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logrus.NewEntry(logrus.New())
+	dir := t.TempDir()
+	export := func(args ...string) error {
+		_, err := runDataset(data, log, dir, models.CommandExportDataset, models.ParseArgs(args))
+		return err
+	}
+
+	assert.NoError(t, export("-file", "catalog.ndjson"))
+	_, err = os.Stat(filepath.Join(dir, "catalog.ndjson"))
+	assert.NoError(t, err)
+	assert.NoError(t, export("-file", "backups/catalog.ndjson"))
+	_, err = os.Stat(filepath.Join(dir, "backups", "catalog.ndjson"))
+	assert.NoError(t, err)
+
+	// Existing files are only replaced when asked to.
+	assert.EqualError(t, export("-file", "catalog.ndjson"),
+		"file catalog.ndjson already exists, use -overwrite true to replace it")
+	assert.NoError(t, export("-file", "catalog.ndjson", "-overwrite", "true"))
+
+	// Files outside the dataset directory can't be used.
+	for _, file := range []string{"/etc/passwd", "../catalog.ndjson", "backups/../../catalog.ndjson"} {
+		assert.Error(t, export("-file", file), file)
+		_, err := runDataset(data, log, dir, models.CommandImportDataset, map[string]string{"file": file})
+		assert.Error(t, err, file)
+	}
+
+	result, err := runDataset(data, log, dir, models.CommandImportDataset, map[string]string{"file": "catalog.ndjson"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"file": "catalog.ndjson", "counts": dataset.Result{}}, result)
+}
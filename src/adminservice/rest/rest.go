@@ -5,17 +5,20 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/middlewares/rest"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 // Service TODO
 type Service struct {
-	data    persistence.DataAccessLayer
-	emitter messagequeue.EventEmitter
+	data       persistence.DataAccessLayer
+	requester  messagequeue.Requester
+	datasetDir string
+	log        *logrus.Entry
 }
 
 // ServeAPI ...
-func ServeAPI(r *gin.Engine, data persistence.DataAccessLayer, emitter messagequeue.EventEmitter) {
-	s := &Service{data, emitter}
+func ServeAPI(r *gin.Engine, data persistence.DataAccessLayer, requester messagequeue.Requester, datasetDir string, log *logrus.Entry) {
+	s := &Service{data, requester, datasetDir, log}
 
 	// Apply default middlewares
 	r.Use(rest.Init(), rest.AdminAuth(data))
@@ -1,12 +1,14 @@
 package listener
 
 import (
+	"context"
 	"time"
 
 	"github.com/dsbezerra/amenic/src/apiservice/v1"
 	"github.com/dsbezerra/amenic/src/contracts"
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/cachelayer"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"github.com/dsbezerra/amenic/src/lib/shared"
 	"github.com/dsbezerra/amenic/src/lib/util/scraperutil"
@@ -17,6 +19,7 @@ import (
 type EventProcessor struct {
 	EventListener messagequeue.EventListener
 	Data          persistence.DataAccessLayer
+	Cache         *cachelayer.CacheDAL // Optional, invalidated by events changing cached data
 	Log           *logrus.Entry
 }
 
@@ -24,56 +27,93 @@ type EventProcessor struct {
 func (p *EventProcessor) ProcessEvents() error {
 	p.Log.Println("Listening to events...")
 
-	var eventsList = []string{
-		"staticDispatched", // Used to handle manual static stuff
-		"scraperFinished",  // We need to recreate static files whenever a scraper runs to ensure it's updated
-	}
-
-	received, errors, err := p.EventListener.Listen(eventsList...)
+	errors, err := p.routes().Listen(p.EventListener)
 	if err != nil {
 		return err
 	}
 
-	for {
-		select {
-		case event := <-received:
-			p.handle(event)
-		case err = <-errors:
-			p.Log.Printf("received error while processing message: %s", err)
-		}
+	for err = range errors {
+		p.Log.Printf("received error while processing message: %s", err)
 	}
+	return nil
 }
 
-func (p *EventProcessor) handle(event messagequeue.Event) {
-	switch event.(type) {
-	case *contracts.EventStaticDispatched:
-		p.handleStaticDispatched(event.(*contracts.EventStaticDispatched))
-
-	case *contracts.EventScraperFinished:
-		e := event.(*contracts.EventScraperFinished)
-		if e.Type != scraperutil.TypePrices {
-			// Temporaly create and handle a fake staticDispatched event
-			name := models.CommandCreateStatic
-			_, ID := models.GenerateTaskID(name, []string{"-type", "home"})
-			p.handleStaticDispatched(&contracts.EventStaticDispatched{
-				TaskID:           ID,
-				Name:             name,
-				Type:             e.Type,
-				DispatchTime:     time.Now().UTC(),
-				ExecutionTimeout: time.Second * 5,
-			})
-		}
+// routes returns a router calling the handler of each event.
+func (p *EventProcessor) routes() *messagequeue.Router {
+	router := messagequeue.NewRouter()
+	// Static files are written to the same paths, so they must not be
+	// created at the same time.
+	router.Concurrency = 1
 
-	default:
-		p.Log.Infof("unknown event: %t", event)
+	messagequeue.Handle(router, p.handleStaticDispatched) // Used to handle manual static stuff
+	messagequeue.Handle(router, p.handleScraperFinished)  // We need to recreate static files whenever a scraper runs to ensure it's updated
+	messagequeue.Handle(router, p.handleMovieCreated)     // Used to invalidate cached reads
+	messagequeue.Handle(router, p.handleMovieDeleted)     // Used to invalidate cached reads
+
+	if p.Cache != nil {
+		router.Use(func(next messagequeue.EventHandler) messagequeue.EventHandler {
+			return func(ctx context.Context, event messagequeue.Event) error {
+				if p.Cache.HandleEvent(event) {
+					p.scoped(ctx).Log.Infof("cache invalidated by event %s", event.EventName())
+				}
+				return next(ctx, event)
+			}
+		})
+	}
+	return router
+}
+
+// scoped returns a copy of p tagging every log entry with the event and the
+// correlation it belongs to.
+func (p *EventProcessor) scoped(ctx context.Context) *EventProcessor {
+	scoped := *p
+	scoped.Log = p.Log.WithFields(messagequeue.LogFields(ctx))
+	return &scoped
+}
+
+func (p *EventProcessor) handleScraperFinished(ctx context.Context, e *contracts.EventScraperFinished) error {
+	if e.Type == scraperutil.TypePrices {
+		return nil
 	}
+
+	// Temporaly create and handle a fake staticDispatched event
+	name := models.CommandCreateStatic
+	_, ID := models.GenerateTaskID(name, []string{"-type", "home"})
+	return p.handleStaticDispatched(ctx, &contracts.EventStaticDispatched{
+		TaskID:           ID,
+		Name:             name,
+		Type:             e.Type,
+		DispatchTime:     time.Now().UTC(),
+		ExecutionTimeout: time.Second * 5,
+	})
+}
+
+// handleMovieCreated does nothing, the event is only used to invalidate the
+// cache.
+func (p *EventProcessor) handleMovieCreated(ctx context.Context, e *contracts.EventMovieCreated) error {
+	return nil
 }
 
-func (p *EventProcessor) handleStaticDispatched(e *contracts.EventStaticDispatched) {
-	abort := messagequeue.CheckAbort(e.DispatchTime, e.ExecutionTimeout)
+// handleMovieDeleted does nothing, the event is only used to invalidate the
+// cache.
+func (p *EventProcessor) handleMovieDeleted(ctx context.Context, e *contracts.EventMovieDeleted) error {
+	return nil
+}
+
+func (p *EventProcessor) handleStaticDispatched(ctx context.Context, e *contracts.EventStaticDispatched) error {
+	p = p.scoped(ctx)
+
+	// Retried commands were received in time, only the first delivery may be
+	// too late.
+	abort := messagequeue.RetryCount(ctx) == 0 && messagequeue.CheckAbort(e.DispatchTime, e.ExecutionTimeout)
 	if abort {
+		if e.Name != models.CommandCreateStatic && e.Name != models.CommandClearStatic {
+			// Meant for another service, which replies the failure.
+			messagequeue.Ignore(ctx)
+			return nil
+		}
 		p.Log.Infof("event %s aborted. reason: timeout reached", e.Name)
-		return
+		return messagequeue.Permanent(messagequeue.ErrTimeout)
 	}
 
 	p.Log.Infof("handling event %s. dispatched at: %s", e.Name, e.DispatchTime)
@@ -89,6 +129,8 @@ func (p *EventProcessor) handleStaticDispatched(e *contracts.EventStaticDispatch
 	case models.CommandClearStatic:
 		_, runError = v1.ClearStatic(t)
 	default:
+		// Meant for another service.
+		messagequeue.Ignore(ctx)
 		p.Log.Infof("handler for event %s was not found", e.Name)
 		ran = false
 	}
@@ -96,4 +138,5 @@ func (p *EventProcessor) handleStaticDispatched(e *contracts.EventStaticDispatch
 	if ran {
 		shared.UpdateTaskStatus(e.TaskID, p.Data, p.Log, runError)
 	}
+	return runError
 }
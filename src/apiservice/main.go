@@ -14,16 +14,18 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
 	"github.com/dsbezerra/amenic/src/lib/middlewares"
 	"github.com/dsbezerra/amenic/src/lib/middlewares/rest"
+	"github.com/dsbezerra/amenic/src/lib/outbox"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/cachelayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/dblayer"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
+	"github.com/dsbezerra/amenic/src/lib/shared"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-contrib/static"
 	"github.com/gin-gonic/gin"
 	"github.com/robfig/cron"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
 )
 
 const (
@@ -49,6 +51,7 @@ type Context struct {
 	Stats   *Stats
 	Data    persistence.DataAccessLayer
 	Emitter messagequeue.EventEmitter
+	Outbox  messagequeue.EventEmitter // Emits events with database writes
 	Log     *logrus.Entry
 }
 
@@ -58,24 +61,27 @@ func main() {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Config = settings
+	// Catch events that don't match their contract during development.
+	messagequeue.ValidatePayloads = !settings.IsProduction
 
 	tasks, err := config.LoadTasks()
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
 
-	conn, err := amqp.Dial(settings.AMQPMessageBroker)
+	broker, err := shared.OpenBroker(settings)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
+	defer broker.Close()
 
-	eventEmitter, err := messagequeue.NewAMQPEventEmitter(conn, "events")
+	eventEmitter, err := broker.Emitter(ServiceName)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Emitter = eventEmitter
 
-	eventListener, err := messagequeue.NewAMQPEventListener(conn, "events", ServiceName)
+	eventListener, err := broker.Listener(ServiceName)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
@@ -86,18 +92,37 @@ func main() {
 	// Let's setup our main database
 	ctx.Log.Info("Setting up database...")
 
-	data, err := mongolayer.NewMongoDAL(settings.DBConnection)
+	data, err := dblayer.NewPersistenceLayer(settings.DBType, settings.DBConnection)
+	if err != nil {
+		ctx.Log.Fatal(err)
+	}
+	err = data.Setup()
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
-	data.Setup()
 	defer data.Close()
 
+	// Ensure our tasks are saved in database.
+	data.EnsureTasksExists(tasks)
+
+	var cache *cachelayer.CacheDAL
+	if settings.CacheTTL > 0 {
+		cache = cachelayer.NewCacheDAL(data, cachelayer.Options{TTL: settings.CacheTTL})
+		data = cache
+		ctx.Log.Infof("Caching database reads for %s", settings.CacheTTL)
+	}
+
 	ctx.Data = data
 	ctx.Log.Info("Database setup completed!")
 
-	// Ensure our tasks are saved in database.
-	(data.(*mongolayer.MongoDAL)).EnsureTasksExists(tasks)
+	// Events emitted with database writes are stored in the outbox and
+	// published by the relay.
+	publisher, err := broker.Publisher()
+	if err != nil {
+		ctx.Log.Fatal(err)
+	}
+	ctx.Outbox = outbox.NewEmitter(data, ServiceName)
+	go outbox.NewRelay(data, publisher, ServiceName, ctx.Log).Run()
 
 	// Initialize app context
 	ctx.Stats = initStats()
@@ -105,6 +130,7 @@ func main() {
 	// Start event processor.
 	p := listener.EventProcessor{
 		Data:          data,
+		Cache:         cache,
 		Log:           ctx.Log,
 		EventListener: eventListener,
 	}
@@ -207,7 +233,7 @@ func (ctx *Context) buildRouter() *gin.Engine {
 	})
 
 	v1.AddRoutes(router, ctx.Data, ctx.Emitter)
-	v2.AddRoutes(router, ctx.Data, ctx.Emitter)
+	v2.AddRoutes(router, ctx.Data, ctx.Outbox)
 	return router
 }
 
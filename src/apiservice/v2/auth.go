@@ -4,8 +4,6 @@ import (
 	"strings"
 	"time"
 
-	"go.mongodb.org/mongo-driver/mongo"
-
 	"golang.org/x/crypto/bcrypt"
 
 	jwt_lib "github.com/dgrijalva/jwt-go"
@@ -48,7 +46,7 @@ func (r *AuthService) Login(c *gin.Context) {
 
 	admin, err := r.data.FindAdmin(r.data.DefaultQuery().AddCondition("username", body.Username))
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == persistence.ErrNotFound {
 			err = apiutil.ErrInvalidCredentials
 		}
 		apiutil.SendSuccessOrError(c, admin, err)
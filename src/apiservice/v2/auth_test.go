@@ -44,7 +44,7 @@ func TestAuth(t *testing.T) {
 	testAPIKey := models.APIKey{
 		Key:      "test-api-key",
 		UserType: "admin",
-		Owner:    adm.Username,
+		Owner:    testAdmin.Username,
 	}
 	apikey, err := data.FindAPIKey(data.DefaultQuery().AddCondition("key", testAPIKey.Key))
 	if apikey != nil {
@@ -0,0 +1,38 @@
+package v2
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/middlewares/rest"
+	"github.com/dsbezerra/amenic/src/lib/persistence/cachelayer"
+	"github.com/dsbezerra/amenic/src/lib/util/apiutil"
+	"github.com/gin-gonic/gin"
+)
+
+// CacheService ...
+type CacheService struct {
+	cache *cachelayer.CacheDAL
+}
+
+// ServeCache serves the cache routes. They're only available when database
+// reads are cached.
+func (r *RESTService) ServeCache(rg *gin.RouterGroup) {
+	cache, ok := r.data.(*cachelayer.CacheDAL)
+	if !ok {
+		return
+	}
+	s := &CacheService{cache}
+
+	admin := rg.Group("/cache", rest.JWTAuth(&rest.Endpoint{AdminOnly: true}))
+	admin.GET("/stats", s.GetStats)
+	admin.DELETE("", s.Invalidate)
+}
+
+// GetStats gets the cache hit and miss statistics.
+func (s *CacheService) GetStats(c *gin.Context) {
+	apiutil.SendSuccess(c, s.cache.Stats())
+}
+
+// Invalidate drops every cached result.
+func (s *CacheService) Invalidate(c *gin.Context) {
+	s.cache.Invalidate()
+	apiutil.SendSuccess(c, 1)
+}
@@ -0,0 +1,75 @@
+package v2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/lib/middlewares"
+	"github.com/dsbezerra/amenic/src/lib/middlewares/rest"
+	"github.com/dsbezerra/amenic/src/lib/persistence/cachelayer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	data := cachelayer.NewCacheDAL(NewMockDataAccessLayer(), cachelayer.Options{})
+	assert.NoError(t, data.Setup())
+
+	r := NewMockRouter(data)
+	r.Use(rest.Init(), middlewares.BaseParseQuery())
+
+	s := RESTService{data: data}
+	s.ServeStates(&r.RouterGroup)
+	s.ServeCache(&r.RouterGroup)
+
+	clientAuthToken := getClientAuthToken(t)
+	adminAuthToken := getAdminAuthToken(t)
+
+	cases := []apiTestCase{
+		apiTestCase{
+			name:      "It should return Unauthorized because client token is not allowed to access",
+			method:    "GET",
+			url:       "/cache/stats",
+			status:    http.StatusUnauthorized,
+			authToken: clientAuthToken,
+		},
+		apiTestCase{
+			name:      "It should return OK",
+			method:    "GET",
+			url:       "/states/state/MG",
+			status:    http.StatusOK,
+			authToken: adminAuthToken,
+		},
+		apiTestCase{
+			name:      "It should return OK",
+			method:    "GET",
+			url:       "/states/state/MG",
+			status:    http.StatusOK,
+			authToken: adminAuthToken,
+		},
+		apiTestCase{
+			name:      "It should return OK with one hit and one miss",
+			method:    "GET",
+			url:       "/cache/stats",
+			status:    http.StatusOK,
+			authToken: adminAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				var stats cachelayer.Stats
+				ConvertAPIResponse(r, &stats)
+				assert.Equal(t, uint64(1), stats.Hits)
+				assert.Equal(t, uint64(1), stats.Misses)
+				assert.Equal(t, 1, stats.Entries)
+			},
+		},
+		apiTestCase{
+			name:      "It should return OK",
+			method:    "DELETE",
+			url:       "/cache",
+			status:    http.StatusOK,
+			authToken: adminAuthToken,
+		},
+	}
+
+	r.RunTests(t, cases)
+	assert.Equal(t, 0, data.Stats().Entries)
+}
@@ -1,14 +1,21 @@
 package v2
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 
+	"github.com/dsbezerra/amenic/src/contracts"
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
 	"github.com/dsbezerra/amenic/src/lib/middlewares/rest"
+	"github.com/dsbezerra/amenic/src/lib/outbox"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"github.com/dsbezerra/amenic/src/lib/util/apiutil"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // MovieService ...
@@ -36,16 +43,81 @@ func (r *RESTService) ServeMovies(rg *gin.RouterGroup) {
 	admin := rg.Group("/movies", rest.JWTAuth(&rest.Endpoint{AdminOnly: true}))
 	admin.GET("", s.GetAll)
 	admin.GET("/count", s.Count)
+	admin.PATCH("/movie/:id", s.Patch)
 }
 
-// GetNowPlaying gets all now playing movies
+// GetNowPlaying gets all now playing movies. If near is given, see
+// BuildTheaterQuery, only theaters in the radius are considered and
+// sort=distance sorts movies by their nearest theater.
 func (s *MovieService) GetNowPlaying(c *gin.Context) {
+	query := c.MustGet("query_options").(map[string]string)
+	if _, ok := query["near"]; ok {
+		movies, err := s.getNowPlayingNear(query)
+		apiutil.SendSuccessOrError(c, movies, err)
+		return
+	}
+
 	// NOTE: We use SessionQuery because in order to retrieve now playing movies
 	// we need to perform an aggregation on Sessions collection
 	movies, err := s.data.GetNowPlayingMovies(BuildSessionQuery(s.data, c))
 	apiutil.SendSuccessOrError(c, movies, err)
 }
 
+// getNowPlayingNear gets now playing movies in theaters near the point given
+// in the query string.
+func (s *MovieService) getNowPlayingNear(q map[string]string) ([]models.Movie, error) {
+	theaters, err := s.data.GetTheaters(s.data.
+		BuildTheaterQuery(map[string]string{"near": q["near"], "radius": q["radius"]}).
+		SetFields("_id").
+		SetLimit(-1))
+	if err != nil {
+		return nil, err
+	}
+	distances := make(map[primitive.ObjectID]float64, len(theaters))
+	ids := make([]interface{}, len(theaters))
+	for i, t := range theaters {
+		distances[t.ID] = t.Distance
+		ids[i] = t.ID
+	}
+
+	// Movies are sorted here, the session query can't sort by distance.
+	byDistance := q["sort"] == "distance"
+	sessionQuery := make(map[string]string, len(q))
+	for k, v := range q {
+		if k != "sort" || !byDistance {
+			sessionQuery[k] = v
+		}
+	}
+
+	movies, err := s.data.GetNowPlayingMovies(s.data.
+		BuildSessionQuery(sessionQuery).
+		Where(persistence.In("theaterId", ids...)))
+	if err != nil || !byDistance || len(movies) == 0 {
+		return movies, err
+	}
+
+	sessions, err := s.data.GetSessions(s.data.
+		BuildSessionQuery(sessionQuery).
+		Where(persistence.In("theaterId", ids...)).
+		SetFields("movieId", "theaterId").
+		SetSkip(0).
+		SetLimit(-1))
+	if err != nil {
+		return nil, err
+	}
+	nearest := make(map[primitive.ObjectID]float64, len(movies))
+	for _, session := range sessions {
+		d := distances[session.TheaterID]
+		if current, ok := nearest[session.MovieID]; !ok || d < current {
+			nearest[session.MovieID] = d
+		}
+	}
+	sort.SliceStable(movies, func(i, j int) bool {
+		return nearest[movies[i].ID] < nearest[movies[j].ID]
+	})
+	return movies, nil
+}
+
 // GetUpcoming gets all upcoming movies
 func (s *MovieService) GetUpcoming(c *gin.Context) {
 	query := c.MustGet("query_options").(map[string]string)
@@ -56,6 +128,9 @@ func (s *MovieService) GetUpcoming(c *gin.Context) {
 // Get gets the movie corresponding the requested ID.
 func (s *MovieService) Get(c *gin.Context) {
 	movie, err := s.data.GetMovie(c.Param("id"), BuildMovieQuery(s.data, c))
+	if err == nil {
+		apiutil.SetETag(c, movie.Version)
+	}
 	apiutil.SendSuccessOrError(c, movie, err)
 }
 
@@ -69,9 +144,6 @@ func (s *MovieService) GetAll(c *gin.Context) {
 func (s *MovieService) GetSessions(c *gin.Context) {
 	query := BuildSessionQuery(s.data, c).
 		AddCondition("movieId", c.Param("id"))
-	if cinema := c.Query("cinema"); cinema != "" {
-		query.AddCondition("cinemaId", cinema)
-	}
 	showtimes, err := s.data.GetSessions(query)
 	apiutil.SendSuccessOrError(c, showtimes, err)
 }
@@ -82,7 +154,8 @@ func (s *MovieService) Count(c *gin.Context) {
 	apiutil.SendSuccessOrError(c, count, err)
 }
 
-// Update apply to movie with the given ID the given body data
+// Update apply to movie with the given ID the given body data. It responds
+// with Conflict if the movie was modified since the given version.
 func (s *MovieService) Update(c *gin.Context) {
 	movie := models.Movie{}
 	err := c.ShouldBindJSON(&movie)
@@ -90,14 +163,180 @@ func (s *MovieService) Update(c *gin.Context) {
 		apiutil.SendBadRequest(c)
 		return
 	}
+	// The version being updated is given by If-Match or the body. Without
+	// one the update is unconditional.
+	version, ok := apiutil.IfMatch(c)
+	if !ok {
+		apiutil.SendBadRequest(c)
+		return
+	}
+	if version != 0 {
+		movie.Version = version
+	}
 	_, err = s.data.UpdateMovie(c.Param("id"), movie)
-	apiutil.SendSuccessOrError(c, movie, err)
+	if err != nil {
+		apiutil.HandleError(c, err)
+		return
+	}
+	updated, err := s.data.GetMovie(c.Param("id"), s.data.DefaultQuery())
+	if err == nil {
+		apiutil.SetETag(c, updated.Version)
+	}
+	apiutil.SendSuccessOrError(c, updated, err)
+}
+
+// MoviePatch is the body of a movie patch. Fields holds the new values of the
+// fields being edited, see models.MovieLocks, and Unlock the fields that
+// scrapers are allowed to update again.
+type MoviePatch struct {
+	Fields  map[string]json.RawMessage
+	Unlock  []string
+	Version int64
+}
+
+// Patch sets only the fields in the body of the movie with the given ID and
+// locks them, so scrapers never overwrite an admin edit. Fields with empty
+// values are removed. It responds with Conflict if the movie was modified
+// since the given version.
+func (s *MovieService) Patch(c *gin.Context) {
+	var patch MoviePatch
+	err := c.ShouldBindJSON(&patch.Fields)
+	if err != nil || !patch.parse() {
+		apiutil.SendBadRequest(c)
+		return
+	}
+	version, ok := apiutil.IfMatch(c)
+	if !ok {
+		apiutil.SendBadRequest(c)
+		return
+	}
+	if version == 0 {
+		version = patch.Version
+	}
+	update, lock, unlock, err := patch.update()
+	if err != nil {
+		apiutil.SendBadRequest(c)
+		return
+	}
+
+	current, err := s.data.GetMovie(c.Param("id"), s.data.DefaultQuery().
+		SetFields("lockFlags", "version"))
+	if err != nil {
+		apiutil.HandleError(c, err)
+		return
+	}
+	// Without a version the patch still must not race with other updates
+	// since the lock flags are based on the current ones.
+	if version == 0 {
+		version = current.Version
+	}
+	flags := current.LockFlags&^unlock | lock
+	if flags != 0 {
+		update.Set["lockFlags"] = int64(flags)
+	} else {
+		update.Unset = append(update.Unset, "lockFlags")
+	}
+
+	_, err = s.data.PatchMovie(c.Param("id"), version, update)
+	if err != nil {
+		apiutil.HandleError(c, err)
+		return
+	}
+	updated, err := s.data.GetMovie(c.Param("id"), s.data.DefaultQuery())
+	if err == nil {
+		apiutil.SetETag(c, updated.Version)
+	}
+	apiutil.SendSuccessOrError(c, updated, err)
+}
+
+// parse moves the unlock and version members of the body out of the fields.
+// It's not ok if they're invalid or if nothing is being changed.
+func (p *MoviePatch) parse() bool {
+	if raw, ok := p.Fields["unlock"]; ok {
+		if err := json.Unmarshal(raw, &p.Unlock); err != nil {
+			return false
+		}
+		delete(p.Fields, "unlock")
+	}
+	if raw, ok := p.Fields["version"]; ok {
+		if err := json.Unmarshal(raw, &p.Version); err != nil {
+			return false
+		}
+		delete(p.Fields, "version")
+	}
+	return len(p.Fields) > 0 || len(p.Unlock) > 0
+}
+
+// update converts the patch to a persistence.Update and the flags of the fields
+// being locked and unlocked. Values are decoded into the models.Movie field of
+// the same name, so they're stored exactly like in a full update.
+func (p *MoviePatch) update() (persistence.Update, uint64, uint64, error) {
+	update := persistence.Update{Set: map[string]interface{}{}}
+	var lock, unlock uint64
+	for field := range p.Fields {
+		flag, ok := models.MovieLocks[field]
+		if !ok {
+			return update, 0, 0, fmt.Errorf("field %q can't be edited", field)
+		}
+		lock |= flag
+	}
+	for _, field := range p.Unlock {
+		flag, ok := models.MovieLocks[field]
+		if !ok {
+			return update, 0, 0, fmt.Errorf("field %q can't be unlocked", field)
+		}
+		unlock |= flag
+	}
+
+	for field, raw := range p.Fields {
+		f, ok := movieFields[field]
+		if !ok {
+			return update, 0, 0, fmt.Errorf("field %q can't be edited", field)
+		}
+		value := reflect.New(f.Type)
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return update, 0, 0, err
+		}
+		// Empty values are omitted when encoded, those are removed.
+		if isEmptyValue(value.Elem()) && strings.HasSuffix(f.Tag.Get("bson"), ",omitempty") {
+			update.Unset = append(update.Unset, field)
+		} else {
+			update.Set[field] = value.Elem().Interface()
+		}
+	}
+	return update, lock, unlock, nil
+}
+
+// movieFields maps the BSON names of the models.Movie fields to them.
+var movieFields = func() map[string]reflect.StructField {
+	t := reflect.TypeOf(models.Movie{})
+	result := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		result[strings.Split(f.Tag.Get("bson"), ",")[0]] = f
+	}
+	return result
+}()
+
+// isEmptyValue reports whether v is omitted by omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
 }
 
 // Delete the movie with the given ID
 func (s *MovieService) Delete(c *gin.Context) {
-	err := s.data.DeleteMovie(c.Param("id"))
-	// TODO: Emit movie deleted event if successful
+	id := c.Param("id")
+	err := s.data.WithTransaction(func(tx persistence.DataAccessLayer) error {
+		err := tx.DeleteMovie(id)
+		if err != nil || s.emitter == nil {
+			return err
+		}
+		return outbox.InTx(s.emitter, tx).Emit(&contracts.EventMovieDeleted{MovieID: id})
+	})
 	apiutil.SendSuccessOrError(c, 1, err)
 }
 
@@ -1,11 +1,15 @@
 package v2
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/dsbezerra/amenic/src/lib/middlewares"
 	"github.com/dsbezerra/amenic/src/lib/middlewares/rest"
+	"github.com/dsbezerra/amenic/src/lib/outbox"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -81,3 +85,236 @@ func TestMovie(t *testing.T) {
 	err = data.DeleteMovie(testMovie.ID.Hex())
 	assert.NoError(t, err)
 }
+
+func TestMoviePatch(t *testing.T) {
+	data := NewMockDataAccessLayer()
+
+	r := NewMockRouter(data)
+	r.Use(rest.Init(), middlewares.ValidObjectIDHex(), middlewares.BaseParseQuery())
+
+	testMovie := models.Movie{
+		ID:       primitive.NewObjectID(),
+		Title:    "Test Movie",
+		Synopsis: "Old synopsis",
+		Trailer:  "trailer-key",
+	}
+	err := data.InsertMovie(testMovie)
+	assert.NoError(t, err)
+
+	s := RESTService{data: data}
+	s.ServeMovies(&r.RouterGroup)
+
+	URL := "/movies/movie/" + testMovie.ID.Hex()
+
+	clientAuthToken := getClientAuthToken(t)
+	adminAuthToken := getAdminAuthToken(t)
+
+	lockFlags := func() uint64 {
+		movie, err := data.GetMovie(testMovie.ID.Hex(), data.DefaultQuery())
+		assert.NoError(t, err)
+		return movie.LockFlags
+	}
+
+	cases := []apiTestCase{
+		apiTestCase{
+			name:      "It should return Unauthorized because endpoint can be accessed only by admins",
+			method:    "PATCH",
+			url:       URL,
+			body:      `{"synopsis": "New synopsis"}`,
+			status:    http.StatusUnauthorized,
+			authToken: clientAuthToken,
+		},
+		apiTestCase{
+			name:      "It should return BadRequest since cinemas is not an editable field",
+			method:    "PATCH",
+			url:       URL,
+			body:      `{"cinemas": []}`,
+			status:    http.StatusBadRequest,
+			authToken: adminAuthToken,
+		},
+		apiTestCase{
+			name:      "It should return BadRequest since nothing is being changed",
+			method:    "PATCH",
+			url:       URL,
+			body:      `{}`,
+			status:    http.StatusBadRequest,
+			authToken: adminAuthToken,
+		},
+		apiTestCase{
+			name:      "It should update and lock only the given fields",
+			method:    "PATCH",
+			url:       URL,
+			body:      `{"synopsis": "New synopsis", "trailer": null}`,
+			status:    http.StatusOK,
+			authToken: adminAuthToken,
+			header:    http.Header{"If-Match": {`"1"`}},
+			onResponse: func(r *httptest.ResponseRecorder) {
+				var movie models.Movie
+				ConvertAPIResponse(r, &movie)
+				assert.Equal(t, "Test Movie", movie.Title)
+				assert.Equal(t, "New synopsis", movie.Synopsis)
+				assert.Empty(t, movie.Trailer)
+				assert.Equal(t, `"2"`, r.Header().Get("ETag"))
+				assert.Equal(t, models.MovieLockSynopsis|models.MovieLockTrailer, lockFlags())
+			},
+		},
+		apiTestCase{
+			name:      "It should return Conflict since the Movie was modified since version 1",
+			method:    "PATCH",
+			url:       URL,
+			body:      `{"title": "Stale Title"}`,
+			status:    http.StatusConflict,
+			authToken: adminAuthToken,
+			header:    http.Header{"If-Match": {`"1"`}},
+		},
+		apiTestCase{
+			name:      "It should unlock the given fields",
+			method:    "PATCH",
+			url:       URL,
+			body:      `{"unlock": ["trailer"]}`,
+			status:    http.StatusOK,
+			authToken: adminAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				assert.Equal(t, models.MovieLockSynopsis, lockFlags())
+			},
+		},
+	}
+
+	r.RunTests(t, cases)
+}
+
+func TestMoviePatchUpdate(t *testing.T) {
+	patch := MoviePatch{Fields: map[string]json.RawMessage{
+		"hidden":      json.RawMessage(`false`),
+		"genres":      json.RawMessage(`[]`),
+		"runtime":     json.RawMessage(`120`),
+		"releaseDate": json.RawMessage(`"2019-08-29T00:00:00Z"`),
+	}}
+	update, lock, _, err := patch.update()
+	assert.NoError(t, err)
+	assert.Equal(t, models.MovieLockHidden|models.MovieLockGenres|models.MovieLockRuntime|models.MovieLockReleaseDate, lock)
+	assert.Equal(t, false, update.Set["hidden"])
+	assert.Equal(t, 120, update.Set["runtime"])
+	release := time.Date(2019, 8, 29, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, &release, update.Set["releaseDate"])
+	assert.Equal(t, []string{"genres"}, update.Unset)
+
+	patch = MoviePatch{Fields: map[string]json.RawMessage{"runtime": json.RawMessage(`"long"`)}}
+	_, _, _, err = patch.update()
+	assert.Error(t, err)
+}
+
+func TestNowPlayingNear(t *testing.T) {
+	data := NewMockDataAccessLayer()
+
+	r := NewMockRouter(data)
+	r.Use(rest.Init(), middlewares.BaseParseQuery())
+
+	s := RESTService{data: data}
+	s.ServeMovies(&r.RouterGroup)
+
+	// Add test data
+	point := func(lat, lng float64) *models.GeoPoint {
+		p := models.NewGeoPoint(lat, lng)
+		return &p
+	}
+	theaters := []models.Theater{
+		{ID: primitive.NewObjectID(), Name: "Near", Location: point(-19.9191, -43.9386)},
+		{ID: primitive.NewObjectID(), Name: "Nearer", Location: point(-19.9201, -43.9381)},
+		{ID: primitive.NewObjectID(), Name: "Far", Location: point(-23.5505, -46.6333)},
+	}
+	movies := []models.Movie{
+		{ID: primitive.NewObjectID(), Title: "Near Movie"},
+		{ID: primitive.NewObjectID(), Title: "Nearer Movie"},
+		{ID: primitive.NewObjectID(), Title: "Far Movie"},
+	}
+	start := time.Now().Add(time.Hour)
+	for i := range theaters {
+		assert.NoError(t, data.InsertTheater(theaters[i]))
+		assert.NoError(t, data.InsertMovie(movies[i]))
+		assert.NoError(t, data.InsertSession(models.Session{
+			ID:        primitive.NewObjectID(),
+			MovieID:   movies[i].ID,
+			TheaterID: theaters[i].ID,
+			StartTime: &start,
+		}))
+	}
+
+	clientAuthToken := getClientAuthToken(t)
+
+	titles := func(r *httptest.ResponseRecorder) []string {
+		var result []models.Movie
+		ConvertAPIResponse(r, &result)
+		titles := make([]string, len(result))
+		for i, m := range result {
+			titles[i] = m.Title
+		}
+		return titles
+	}
+
+	cases := []apiTestCase{
+		apiTestCase{
+			name:      "It should return movies of theaters in the radius",
+			method:    "GET",
+			url:       "/movies/now_playing?near=-19.9200,-43.9380&radius=1000",
+			status:    http.StatusOK,
+			authToken: clientAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				assert.ElementsMatch(t, []string{"Near Movie", "Nearer Movie"}, titles(r))
+			},
+		},
+		apiTestCase{
+			name:      "It should return movies sorted by their nearest theater",
+			method:    "GET",
+			url:       "/movies/now_playing?near=-19.9200,-43.9380&radius=1000000&sort=distance",
+			status:    http.StatusOK,
+			authToken: clientAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				assert.Equal(t, []string{"Nearer Movie", "Near Movie", "Far Movie"}, titles(r))
+			},
+		},
+	}
+
+	r.RunTests(t, cases)
+}
+
+func TestMovieDelete(t *testing.T) {
+	data := NewMockDataAccessLayer()
+
+	r := NewMockRouter(data)
+	r.Use(rest.Init(), middlewares.ValidObjectIDHex(), middlewares.BaseParseQuery())
+
+	testMovie := models.Movie{
+		ID:    primitive.NewObjectID(),
+		Title: "Test Movie",
+	}
+	err := data.InsertMovie(testMovie)
+	assert.NoError(t, err)
+
+	s := RESTService{data: data, emitter: outbox.NewEmitter(data, "API")}
+	s.ServeMovies(&r.RouterGroup)
+
+	HexID := testMovie.ID.Hex()
+
+	cases := []apiTestCase{
+		apiTestCase{
+			name:      "It should return OK and store the movieDeleted event in the outbox",
+			method:    "DELETE",
+			url:       "/movies/movie/" + HexID,
+			status:    http.StatusOK,
+			authToken: getAdminAuthToken(t),
+		},
+	}
+
+	r.RunTests(t, cases)
+
+	_, err = data.GetMovie(HexID, data.DefaultQuery())
+	assert.Error(t, err)
+	events, err := data.GetPendingOutboxEvents("API", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "movieDeleted", events[0].Name)
+		assert.Equal(t, "API", events[0].Source)
+		assert.Contains(t, events[0].Payload, HexID)
+	}
+}
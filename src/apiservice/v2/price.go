@@ -37,18 +37,7 @@ func (s *PriceService) GetAll(c *gin.Context) {
 	apiutil.SendSuccessOrError(c, prices, err)
 }
 
-// ParseQuery builds the conditional Mongo query
+// ParseQuery builds the query for the Price model from the request query string
 func (s *PriceService) ParseQuery(c *gin.Context) persistence.Query {
-	query := c.MustGet("query_options").(persistence.Query)
-
-	// This was added for back compat with app versions belo 1.0.22
-	if cinemaID := c.Query("cinema"); cinemaID != "" {
-		query.AddCondition("cinema_id", cinemaID)
-	}
-
-	if cinemaID := c.Query("cinema_id"); cinemaID != "" {
-		query.AddCondition("cinema_id", cinemaID)
-	}
-
-	return query
+	return s.data.BuildPriceQuery(c.MustGet("query_options").(map[string]string))
 }
@@ -12,7 +12,6 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/util/apiutil"
 	"github.com/dsbezerra/amenic/src/lib/util/timeutil"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -98,10 +97,7 @@ func BuildScheduleQuery(data persistence.DataAccessLayer, c *gin.Context) persis
 		t = timeutil.StartOfDay()
 	}
 
-	query.AddCondition("startTime", bson.D{
-		{Key: "$gte", Value: t},
-		{Key: "$lte", Value: t.Add((time.Hour * 24) - time.Nanosecond)},
-	})
+	query.Where(persistence.Range("startTime", t, t.Add((time.Hour*24)-time.Nanosecond)))
 	query.AddInclude("movie")
 	query.SetSort("movieSlug", "room", "version", "format", "startTime")
 	query.SetLimit(-1)
@@ -0,0 +1,160 @@
+package v2
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dsbezerra/amenic/src/lib/middlewares/rest"
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/apiutil"
+	"github.com/dsbezerra/amenic/src/lib/util/searchutil"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultSearchLimit is the number of results of each type returned when
+	// no limit is given.
+	DefaultSearchLimit = 10
+
+	// MaxSearchLimit is the maximum number of results of each type.
+	MaxSearchLimit = 50
+)
+
+// SearchService ...
+type SearchService struct {
+	data persistence.DataAccessLayer
+}
+
+// SearchResult holds the results of a search by type.
+type SearchResult struct {
+	Movies   []models.Movie   `json:"movies"`
+	Theaters []models.Theater `json:"theaters"`
+	Cities   []models.City    `json:"cities"`
+}
+
+// ServeSearch ...
+func (r *RESTService) ServeSearch(rg *gin.RouterGroup) {
+	s := &SearchService{r.data}
+
+	search := rg.Group("/search", rest.JWTAuth(nil))
+	search.GET("", s.Search)
+	search.GET("/autocomplete", s.Autocomplete)
+}
+
+// Search searches movies, theaters and cities matching the words in q, the
+// most relevant first. Accents and case are ignored, e.g.: "acao" matches
+// "Ação".
+func (s *SearchService) Search(c *gin.Context) {
+	q, limit, ok := parseSearch(c)
+	if !ok {
+		apiutil.SendBadRequest(c)
+		return
+	}
+
+	var result SearchResult
+	var err error
+	result.Movies, err = s.data.GetMovies(s.data.DefaultQuery().
+		AddCondition("hidden", false).
+		Where(persistence.Text(q)).
+		SetSort(persistence.SortTextScore).
+		SetLimit(limit))
+	if err != nil {
+		apiutil.HandleError(c, err)
+		return
+	}
+	result.Theaters, err = s.data.GetTheaters(s.data.DefaultQuery().
+		AddCondition("hidden", false).
+		Where(persistence.Text(q)).
+		SetSort(persistence.SortTextScore).
+		SetLimit(limit))
+	if err != nil {
+		apiutil.HandleError(c, err)
+		return
+	}
+	// Cities have no text index, their names are short enough to be found by
+	// prefix.
+	result.Cities, err = s.data.GetCities(s.data.DefaultQuery().
+		Where(searchutil.Conditions(q)...).
+		SetSort("name").
+		SetLimit(limit))
+	apiutil.SendSuccessOrError(c, result.withEmptyLists(), err)
+}
+
+// Autocomplete searches movies, theaters and cities with words starting with
+// the words in q, e.g.: "vinga ult" matches "Vingadores: Ultimato". Only the
+// fields needed to show suggestions are returned.
+func (s *SearchService) Autocomplete(c *gin.Context) {
+	q, limit, ok := parseSearch(c)
+	if !ok {
+		apiutil.SendBadRequest(c)
+		return
+	}
+
+	var result SearchResult
+	var err error
+	result.Movies, err = s.data.GetMovies(s.data.DefaultQuery().
+		AddCondition("hidden", false).
+		Where(searchutil.Conditions(q)...).
+		SetFields("slug", "title", "originalTitle", "poster").
+		SetSort("title").
+		SetLimit(limit))
+	if err != nil {
+		apiutil.HandleError(c, err)
+		return
+	}
+	result.Theaters, err = s.data.GetTheaters(s.data.DefaultQuery().
+		AddCondition("hidden", false).
+		Where(searchutil.Conditions(q)...).
+		SetFields("cityId", "name", "shortName").
+		SetSort("name").
+		SetLimit(limit))
+	if err != nil {
+		apiutil.HandleError(c, err)
+		return
+	}
+	result.Cities, err = s.data.GetCities(s.data.DefaultQuery().
+		Where(searchutil.Conditions(q)...).
+		SetFields("stateId", "name").
+		SetSort("name").
+		SetLimit(limit))
+	apiutil.SendSuccessOrError(c, result.withEmptyLists(), err)
+}
+
+// parseSearch returns the search and the limit of results of each type of
+// the request. It's not ok if the search has no words or the limit is
+// invalid.
+func parseSearch(c *gin.Context) (string, int64, bool) {
+	q := strings.TrimSpace(c.Query("q"))
+	if len(searchutil.Conditions(q)) == 0 {
+		return "", 0, false
+	}
+
+	limit := int64(DefaultSearchLimit)
+	if value := c.Query("limit"); value != "" {
+		var err error
+		limit, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || limit <= 0 {
+			return "", 0, false
+		}
+		if limit > MaxSearchLimit {
+			limit = MaxSearchLimit
+		}
+	}
+	return q, limit, true
+}
+
+// withEmptyLists replaces missing results with empty lists so every type is
+// always present in the response.
+func (r SearchResult) withEmptyLists() SearchResult {
+	if r.Movies == nil {
+		r.Movies = []models.Movie{}
+	}
+	if r.Theaters == nil {
+		r.Theaters = []models.Theater{}
+	}
+	if r.Cities == nil {
+		r.Cities = []models.City{}
+	}
+	return r
+}
@@ -0,0 +1,135 @@
+package v2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/lib/middlewares"
+	"github.com/dsbezerra/amenic/src/lib/middlewares/rest"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSearch(t *testing.T) {
+	data := NewMockDataAccessLayer()
+
+	r := NewMockRouter(data)
+	r.Use(rest.Init(), middlewares.BaseParseQuery())
+
+	s := RESTService{data: data}
+	s.ServeSearch(&r.RouterGroup)
+
+	// Add test data
+	movies := []models.Movie{
+		{ID: primitive.NewObjectID(), Title: "Ação e Reação"},
+		{ID: primitive.NewObjectID(), Title: "Ação", OriginalTitle: "Action"},
+		{ID: primitive.NewObjectID(), Title: "Vingadores: Ultimato", OriginalTitle: "Avengers: Endgame"},
+		{ID: primitive.NewObjectID(), Title: "Ação Escondida", Hidden: true},
+	}
+	for _, m := range movies {
+		assert.NoError(t, data.InsertMovie(m))
+	}
+	assert.NoError(t, data.InsertTheater(models.Theater{ID: primitive.NewObjectID(), Name: "Cinemark Paulínia", ShortName: "Paulínia"}))
+	assert.NoError(t, data.InsertCity(models.City{ID: primitive.NewObjectID(), Name: "São Paulo", StateID: models.SP}))
+	assert.NoError(t, data.InsertCity(models.City{ID: primitive.NewObjectID(), Name: "Paulínia", StateID: models.SP}))
+
+	clientAuthToken := getClientAuthToken(t)
+
+	search := func(path, q string) string {
+		return path + "?q=" + url.QueryEscape(q)
+	}
+
+	cases := []apiTestCase{
+		apiTestCase{
+			name:   "It should return Unauthorized",
+			method: "GET",
+			url:    search("/search", "acao"),
+			status: http.StatusUnauthorized,
+		},
+		apiTestCase{
+			name:      "It should return BadRequest because search has no words",
+			method:    "GET",
+			url:       search("/search", " - "),
+			status:    http.StatusBadRequest,
+			authToken: clientAuthToken,
+		},
+		apiTestCase{
+			name:      "It should return BadRequest because limit is invalid",
+			method:    "GET",
+			url:       search("/search", "acao") + "&limit=0",
+			status:    http.StatusBadRequest,
+			authToken: clientAuthToken,
+		},
+		apiTestCase{
+			name:      "It should return visible movies ignoring accents, the most relevant first",
+			method:    "GET",
+			url:       search("/search", "ACAO"),
+			status:    http.StatusOK,
+			authToken: clientAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				var result SearchResult
+				ConvertAPIResponse(r, &result)
+				if assert.Len(t, result.Movies, 2) {
+					assert.Equal(t, "Ação", result.Movies[0].Title)
+					assert.Equal(t, "Ação e Reação", result.Movies[1].Title)
+				}
+				assert.Len(t, result.Theaters, 0)
+				assert.Len(t, result.Cities, 0)
+			},
+		},
+		apiTestCase{
+			name:      "It should return theaters and cities",
+			method:    "GET",
+			url:       search("/search", "paulinia"),
+			status:    http.StatusOK,
+			authToken: clientAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				var result SearchResult
+				ConvertAPIResponse(r, &result)
+				assert.Len(t, result.Movies, 0)
+				assert.Len(t, result.Theaters, 1)
+				if assert.Len(t, result.Cities, 1) {
+					assert.Equal(t, "Paulínia", result.Cities[0].Name)
+					assert.Equal(t, models.SP, result.Cities[0].StateID)
+				}
+				// Clients read the state code from state.
+				assert.Contains(t, r.Body.String(), `"state":"SP"`)
+			},
+		},
+		apiTestCase{
+			name:      "It should return matches by prefix of every word",
+			method:    "GET",
+			url:       search("/search/autocomplete", "vinga ULT"),
+			status:    http.StatusOK,
+			authToken: clientAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				var result SearchResult
+				ConvertAPIResponse(r, &result)
+				if assert.Len(t, result.Movies, 1) {
+					assert.Equal(t, "Vingadores: Ultimato", result.Movies[0].Title)
+				}
+			},
+		},
+		apiTestCase{
+			name:      "It should return movies, theaters and cities by prefix",
+			method:    "GET",
+			url:       search("/search/autocomplete", "pa") + "&limit=1",
+			status:    http.StatusOK,
+			authToken: clientAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				var result SearchResult
+				ConvertAPIResponse(r, &result)
+				assert.Len(t, result.Movies, 0)
+				assert.Len(t, result.Theaters, 1)
+				if assert.Len(t, result.Cities, 1) {
+					assert.Equal(t, "Paulínia", result.Cities[0].Name)
+				}
+			},
+		},
+	}
+
+	r.RunTests(t, cases)
+}
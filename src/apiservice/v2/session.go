@@ -9,7 +9,6 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/util/apiutil"
 	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 // SessionService ...
@@ -41,14 +40,13 @@ func (s *SessionService) GetAll(c *gin.Context) {
 
 	query := s.data.BuildSessionQuery(q)
 
-	var hasStart, hasEnd bool
+	var from, to interface{}
 	start, ok := q["start"]
 	if ok {
 		loc, _ := time.LoadLocation("America/Sao_Paulo")
 		t, err := time.ParseInLocation("2006-01-02", start, loc)
 		if err == nil {
-			query.AddCondition("startTime", bson.M{"$gte": t})
-			hasStart = true
+			from = t
 		}
 	}
 
@@ -57,19 +55,17 @@ func (s *SessionService) GetAll(c *gin.Context) {
 		loc, _ := time.LoadLocation("America/Sao_Paulo")
 		t, err := time.ParseInLocation("2006-01-02", end, loc)
 		if err == nil {
-			t.Add((time.Hour * 24) - time.Nanosecond)
-			query.AddCondition("startTime", bson.M{"$lte": t})
-			hasEnd = true
+			to = t.Add((time.Hour * 24) - time.Nanosecond)
 		}
 	}
 
-	if hasStart && hasEnd {
-		query.SetLimit(-1)
-	} else if !hasStart {
-		period := scheduleutil.GetWeekPeriod(nil)
-		query.AddCondition("startTime", bson.M{"$gte": period.Start})
+	if from == nil || to != nil {
 		query.SetLimit(-1)
 	}
+	if from == nil {
+		from = scheduleutil.GetWeekPeriod(nil).Start
+	}
+	query.Where(persistence.Range("startTime", from, to))
 
 	// If we are not sorting let's set the default sort
 	if !query.Sorting() {
@@ -4,17 +4,10 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
 	"github.com/dsbezerra/amenic/src/lib/middlewares/rest"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
-	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"github.com/dsbezerra/amenic/src/lib/util/apiutil"
 	"github.com/gin-gonic/gin"
 )
 
-// StateResponse ...
-type StateResponse struct {
-	ID   models.State `json:"_id"`
-	Name string       `json:"name"`
-}
-
 // StateService ...
 type StateService struct {
 	data    persistence.DataAccessLayer
@@ -34,32 +27,31 @@ func (r *RESTService) ServeStates(rg *gin.RouterGroup) {
 
 // Get gets the State corresponding the requested ID.
 func (s *StateService) Get(c *gin.Context) {
-	state, ok := models.GetState(c.Param("id"))
-	if ok {
-		apiutil.SendSuccessOrError(c, StateResponse{ID: state, Name: state.Name()}, nil)
-		return
-	}
-	apiutil.SendBadRequest(c)
+	state, err := s.data.GetState(c.Param("id"), BuildStateQuery(s.data, c))
+	apiutil.SendSuccessOrError(c, state, err)
 }
 
 // GetAll gets all States.
 func (s *StateService) GetAll(c *gin.Context) {
-	states := []StateResponse{}
-	for _, state := range models.GetStateList() {
-		states = append(states, StateResponse{ID: state, Name: state.Name()})
-	}
-	apiutil.SendSuccessOrError(c, states, nil)
+	states, err := s.data.GetStates(BuildStateQuery(s.data, c))
+	apiutil.SendSuccessOrError(c, states, err)
 }
 
 // GetCities gets all cities from the given State.
 func (s *StateService) GetCities(c *gin.Context) {
-	state, ok := models.GetState(c.Param("id"))
-	if !ok {
-		apiutil.SendBadRequest(c)
+	state, err := s.data.GetState(c.Param("id"), s.data.DefaultQuery())
+	if err != nil {
+		apiutil.HandleError(c, err)
 		return
 	}
 	query := c.MustGet("query_options").(map[string]string)
-	query["state"] = string(state)
+	query["state"] = state.ID
 	cities, err := s.data.GetCities(s.data.BuildCityQuery(query))
 	apiutil.SendSuccessOrError(c, cities, err)
 }
+
+// BuildStateQuery builds State query from request query string
+func BuildStateQuery(data persistence.DataAccessLayer, c *gin.Context) persistence.Query {
+	query := c.MustGet("query_options").(map[string]string)
+	return data.BuildStateQuery(query)
+}
@@ -7,10 +7,13 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/middlewares"
 
 	"github.com/dsbezerra/amenic/src/lib/middlewares/rest"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestState(t *testing.T) {
 	data := NewMockDataAccessLayer()
+	// Setup inserts the default states
+	assert.NoError(t, data.Setup())
 
 	r := NewMockRouter(data)
 	r.Use(rest.Init(), middlewares.BaseParseQuery())
@@ -43,6 +46,13 @@ func TestState(t *testing.T) {
 			status:    http.StatusNotFound,
 			authToken: adminAuthToken,
 		},
+		apiTestCase{
+			name:      "It should return NotFound because state isn't stored",
+			method:    "GET",
+			url:       "/states/state/XX",
+			status:    http.StatusNotFound,
+			authToken: adminAuthToken,
+		},
 		apiTestCase{
 			name:      "It should return OK",
 			method:    "GET",
@@ -34,6 +34,9 @@ func (r *RESTService) ServeTheaters(rg *gin.RouterGroup) {
 // Get gets the theater corresponding the requested ID.
 func (s *TheaterService) Get(c *gin.Context) {
 	theater, err := s.data.GetTheater(c.Param("id"), BuildTheaterQuery(s.data, c))
+	if err == nil {
+		apiutil.SetETag(c, theater.Version)
+	}
 	apiutil.SendSuccessOrError(c, theater, err)
 }
 
@@ -59,7 +62,8 @@ func (s *TheaterService) GetSessions(c *gin.Context) {
 	apiutil.SendSuccessOrError(c, sessions, err)
 }
 
-// Update apply to Theater with the given ID the given body data
+// Update apply to Theater with the given ID the given body data. It responds
+// with Conflict if the Theater was modified since the given version.
 func (s *TheaterService) Update(c *gin.Context) {
 	theater := models.Theater{}
 	err := c.ShouldBindJSON(&theater)
@@ -67,8 +71,26 @@ func (s *TheaterService) Update(c *gin.Context) {
 		apiutil.SendBadRequest(c)
 		return
 	}
+	// The version being updated is given by If-Match or the body. Without
+	// one the update is unconditional.
+	version, ok := apiutil.IfMatch(c)
+	if !ok {
+		apiutil.SendBadRequest(c)
+		return
+	}
+	if version != 0 {
+		theater.Version = version
+	}
 	_, err = s.data.UpdateTheater(c.Param("id"), theater)
-	apiutil.SendSuccessOrError(c, theater, err)
+	if err != nil {
+		apiutil.HandleError(c, err)
+		return
+	}
+	updated, err := s.data.GetTheater(c.Param("id"), s.data.DefaultQuery())
+	if err == nil {
+		apiutil.SetETag(c, updated.Version)
+	}
+	apiutil.SendSuccessOrError(c, updated, err)
 }
 
 // Delete the Theater with the given ID
@@ -2,6 +2,7 @@ package v2
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/dsbezerra/amenic/src/lib/middlewares"
@@ -21,10 +22,12 @@ func TestTheater(t *testing.T) {
 	s.ServeTheaters(&r.RouterGroup)
 
 	// Add test data
+	location := models.NewGeoPoint(-19.9191, -43.9386)
 	testTheater := models.Theater{
 		ID:        primitive.NewObjectID(),
 		Name:      "Fake Theater",
 		ShortName: "Fake",
+		Location:  &location,
 	}
 	err := data.InsertTheater(testTheater)
 	assert.NoError(t, err)
@@ -54,6 +57,9 @@ func TestTheater(t *testing.T) {
 			url:       "/theaters/theater/" + HexID,
 			status:    http.StatusOK,
 			authToken: clientAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				assert.Equal(t, `"1"`, r.Header().Get("ETag"))
+			},
 		},
 		apiTestCase{
 			name:      "It should return all prices of Theater with ID " + HexID,
@@ -62,6 +68,83 @@ func TestTheater(t *testing.T) {
 			status:    http.StatusOK,
 			authToken: clientAuthToken,
 		},
+		apiTestCase{
+			name:      "It should return theaters in the radius with their distance",
+			method:    "GET",
+			url:       "/theaters?near=-19.9200,-43.9380&radius=1000",
+			status:    http.StatusOK,
+			authToken: adminAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				var theaters []models.Theater
+				ConvertAPIResponse(r, &theaters)
+				if assert.Len(t, theaters, 1) {
+					assert.InDelta(t, 120, theaters[0].Distance, 10)
+				}
+			},
+		},
+		apiTestCase{
+			name:      "It should return no theaters since none is in the radius",
+			method:    "GET",
+			url:       "/theaters?near=-23.5505,-46.6333&radius=1000",
+			status:    http.StatusOK,
+			authToken: adminAuthToken,
+			onResponse: func(r *httptest.ResponseRecorder) {
+				var theaters []models.Theater
+				ConvertAPIResponse(r, &theaters)
+				assert.Len(t, theaters, 0)
+			},
+		},
+		apiTestCase{
+			name:      "It should return BadRequest since If-Match is not a valid version",
+			method:    "PUT",
+			url:       "/theaters/theater/" + HexID,
+			body:      `{"name": "Fake Theater Updated", "shortName": "Fake"}`,
+			status:    http.StatusBadRequest,
+			authToken: adminAuthToken,
+			header:    http.Header{"If-Match": {`W/"abc"`}},
+		},
+		apiTestCase{
+			name:      "It should update the Theater and return its new version",
+			method:    "PUT",
+			url:       "/theaters/theater/" + HexID,
+			body:      `{"name": "Fake Theater Updated", "shortName": "Fake"}`,
+			status:    http.StatusOK,
+			authToken: adminAuthToken,
+			header:    http.Header{"If-Match": {`"1"`}},
+			onResponse: func(r *httptest.ResponseRecorder) {
+				var theater models.Theater
+				ConvertAPIResponse(r, &theater)
+				assert.Equal(t, "Fake Theater Updated", theater.Name)
+				assert.Equal(t, int64(2), theater.Version)
+				assert.Equal(t, `"2"`, r.Header().Get("ETag"))
+			},
+		},
+		apiTestCase{
+			name:      "It should return Conflict since the Theater was modified since version 1",
+			method:    "PUT",
+			url:       "/theaters/theater/" + HexID,
+			body:      `{"name": "Fake Theater Stale", "shortName": "Fake"}`,
+			status:    http.StatusConflict,
+			authToken: adminAuthToken,
+			header:    http.Header{"If-Match": {`"1"`}},
+		},
+		apiTestCase{
+			name:      "It should return BadRequest since 0 is not a version",
+			method:    "PUT",
+			url:       "/theaters/theater/" + HexID,
+			body:      `{"name": "Fake Theater Stale", "shortName": "Fake"}`,
+			status:    http.StatusBadRequest,
+			authToken: adminAuthToken,
+			header:    http.Header{"If-Match": {`"0"`}},
+		},
+		apiTestCase{
+			name:      "It should return Conflict since the version in the body is outdated",
+			method:    "PUT",
+			url:       "/theaters/theater/" + HexID,
+			body:      `{"name": "Fake Theater Stale", "shortName": "Fake", "version": 1}`,
+			status:    http.StatusConflict,
+			authToken: adminAuthToken,
+		},
 		apiTestCase{
 			name:      "It should return OK because the token is a valid admin token",
 			method:    "GET",
@@ -30,4 +30,6 @@ func AddRoutes(r *gin.Engine, data persistence.DataAccessLayer, emitter messageq
 	s.ServePrices(v2)
 	s.ServeMovies(v2)
 	s.ServeNotifications(v2)
+	s.ServeSearch(v2)
+	s.ServeCache(v2)
 }
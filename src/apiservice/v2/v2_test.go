@@ -9,16 +9,12 @@ import (
 	"testing"
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
 	"github.com/dsbezerra/amenic/src/lib/util/apiutil"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
-const (
-	testConnection = "mongodb://localhost/amenic-test"
-)
-
 type apiTestCase struct {
 	name       string
 	method     string
@@ -27,6 +23,7 @@ type apiTestCase struct {
 	authToken  string
 	status     int
 	onResponse func(r *httptest.ResponseRecorder)
+	header     http.Header
 }
 
 type mockRouter struct {
@@ -34,8 +31,11 @@ type mockRouter struct {
 	data persistence.DataAccessLayer
 }
 
-func (r *mockRouter) Call(method, URL, body, authToken string) *httptest.ResponseRecorder {
+func (r *mockRouter) Call(method, URL, body, authToken string, header http.Header) *httptest.ResponseRecorder {
 	req, _ := http.NewRequest(method, URL, bytes.NewBufferString(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
 	req.Header.Set("Content-Type", "application/json")
 	if authToken != "" {
 		req.Header.Set("Authorization", "Bearer "+authToken)
@@ -47,7 +47,7 @@ func (r *mockRouter) Call(method, URL, body, authToken string) *httptest.Respons
 
 func (r *mockRouter) RunTests(t *testing.T, tests []apiTestCase) {
 	for _, test := range tests {
-		res := r.Call(test.method, test.url, test.body, test.authToken)
+		res := r.Call(test.method, test.url, test.body, test.authToken, test.header)
 		assert.Equal(t, test.status, res.Code, test.name)
 		if res != nil && test.onResponse != nil {
 			test.onResponse(res)
@@ -64,6 +64,7 @@ func newAPITestCase(name, method, url, body string, status int, authToken string
 		authToken,
 		status,
 		onResponse,
+		nil,
 	}
 }
 
@@ -72,7 +73,7 @@ func NewMockRouter(data persistence.DataAccessLayer) *mockRouter {
 }
 
 func NewMockDataAccessLayer() persistence.DataAccessLayer {
-	data, err := mongolayer.NewMongoDAL(testConnection)
+	data, err := memlayer.NewMemDAL()
 	if err != nil {
 		log.Fatal(err)
 	}
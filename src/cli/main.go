@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/dsbezerra/amenic/src/lib/config"
+	"github.com/dsbezerra/amenic/src/lib/dataset"
+	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/dblayer"
+	"github.com/sirupsen/logrus"
+)
+
+const usage = `Usage: cli [-dbtype type] [-db connection] [-amqp url] <command> [arguments]
+
+Commands:
+  migrate up            applies all pending migrations
+  migrate down          reverts the latest applied migration
+  migrate to <version>  applies or reverts migrations until the given version
+  migrate status        lists migrations and when they were applied
+  export [-collections list] <file>
+                        exports all collections of the catalog or the given
+                        comma separated ones to a NDJSON file, compressed with
+                        gzip if it ends with .gz, or to stdout if it's -
+  import [-collections list] <file>
+                        imports all collections or the given ones from a file
+                        written by export, or from stdin if it's -
+  deadletters [-limit n] <queue>
+                        prints the events of a service queue, e.g.: Scraper,
+                        that failed too many times or couldn't be decoded
+  replay [-limit n] <queue>
+                        moves the dead-lettered events of a service queue
+                        back to it so they're handled again
+  schemas [dir]         writes the JSON Schema of every event to a file
+                        named after it in dir, or prints them all
+`
+
+var log = logrus.WithFields(logrus.Fields{"App": "CLI"})
+
+func main() {
+	dbType := flag.String("dbtype", "", "database type, MongoDB or SQLite, defaults to the DATABASE_TYPE environment variable")
+	connection := flag.String("db", "", "database connection, defaults to the DATABASE environment variable")
+	broker := flag.String("amqp", "", "message broker URL, defaults to the AMQP_URL environment variable")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if args[0] == "schemas" {
+		// Doesn't need any configuration.
+		if err := exportSchemas(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *dbType == "" || *connection == "" || *broker == "" {
+		settings, err := config.LoadConfiguration()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *dbType == "" {
+			*dbType = string(settings.DBType)
+		}
+		if *connection == "" {
+			*connection = settings.DBConnection
+		}
+		if *broker == "" {
+			*broker = settings.AMQPMessageBroker
+		}
+	}
+
+	var err error
+	switch args[0] {
+	case "migrate", "export", "import":
+		err = runDataCommand(config.DBType(*dbType), *connection, args)
+	case "deadletters", "replay":
+		err = runDeadLetterCommand(*broker, args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runDataCommand(dbType config.DBType, connection string, args []string) error {
+	data, err := dblayer.NewPersistenceLayer(dbType, connection)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	switch args[0] {
+	case "migrate":
+		return migrate(data, args[1:])
+	case "export":
+		return exportDataset(data, args[1:])
+	case "import":
+		return importDataset(data, args[1:])
+	}
+	return fmt.Errorf("unknown command %s", args[0])
+}
+
+func runDeadLetterCommand(broker string, args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "maximum number of events, defaults to all")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("missing queue")
+	}
+	queue := fs.Arg(0)
+
+	conn, err := messagequeue.Dial(broker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if args[0] == "replay" {
+		replayed, err := messagequeue.ReplayDeadLetters(conn, queue, *limit)
+		log.Infof("Replayed %d events", replayed)
+		return err
+	}
+
+	letters, err := messagequeue.DeadLetters(conn, queue, *limit)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, l := range letters {
+		if err := enc.Encode(l); err != nil {
+			return err
+		}
+	}
+	log.Infof("Found %d events in %s", len(letters), messagequeue.DeadLetterQueueName(queue))
+	return nil
+}
+
+// exportSchemas writes the schemas of contracts.Catalog.
+func exportSchemas(args []string) error {
+	schemas := contracts.Schemas()
+	if len(args) == 0 {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(schemas)
+	}
+
+	dir := args[0]
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, schema := range schemas {
+		b, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(dir, name+".schema.json")
+		if err = ioutil.WriteFile(file, append(b, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	log.Infof("Wrote %d schemas to %s", len(schemas), dir)
+	return nil
+}
+
+func migrate(data persistence.DataAccessLayer, args []string) error {
+	migrator, ok := data.(persistence.Migrator)
+	if !ok {
+		return fmt.Errorf("%T doesn't support migrations", data)
+	}
+
+	if len(args) == 0 {
+		args = []string{"up"}
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Migrate()
+
+	case "down":
+		status, err := migrator.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		version := 0
+		for i := len(status) - 1; i >= 0; i-- {
+			if status[i].AppliedAt != nil {
+				// Go back to the previous migration of the latest applied.
+				if i > 0 {
+					version = status[i-1].Version
+				}
+				return migrator.MigrateTo(version)
+			}
+		}
+		log.Info("There are no applied migrations.")
+		return nil
+
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("missing version")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return err
+		}
+		return migrator.MigrateTo(version)
+
+	case "status":
+		status, err := migrator.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-20s  %s\n", s.Version, applied, s.Description)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown migrate command %s", args[0])
+}
+
+// parseDatasetArgs parses the arguments of export and import.
+func parseDatasetArgs(name string, args []string) ([]string, string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	list := fs.String("collections", "", "comma separated collections, defaults to all")
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+	if fs.NArg() != 1 {
+		return nil, "", fmt.Errorf("missing file")
+	}
+	collections, err := dataset.ParseCollections(*list)
+	return collections, fs.Arg(0), err
+}
+
+func exportDataset(data persistence.DataAccessLayer, args []string) error {
+	collections, file, err := parseDatasetArgs("export", args)
+	if err != nil {
+		return err
+	}
+
+	var counts map[string]int
+	if file == "-" {
+		counts, err = dataset.Export(data, os.Stdout, collections...)
+	} else {
+		counts, err = dataset.ExportFile(data, file, true, collections...)
+	}
+	if err != nil {
+		return err
+	}
+	for _, c := range collections {
+		log.Infof("Exported %d %s", counts[c], c)
+	}
+	return nil
+}
+
+func importDataset(data persistence.DataAccessLayer, args []string) error {
+	collections, file, err := parseDatasetArgs("import", args)
+	if err != nil {
+		return err
+	}
+
+	var result dataset.Result
+	if file == "-" {
+		result, err = dataset.Import(data, os.Stdin, collections...)
+	} else {
+		result, err = dataset.ImportFile(data, file, collections...)
+	}
+	if err != nil {
+		return err
+	}
+	for _, c := range collections {
+		if counts, ok := result[c]; ok {
+			log.Infof("Imported %s: %d inserted, %d updated (%d remapped), %d deleted",
+				c, counts.Inserted, counts.Updated, counts.Remapped, counts.Deleted)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package contracts
+
+// Event is implemented by every contract, see messagequeue.Event.
+type Event interface {
+	EventName() string
+}
+
+// Catalog lists an empty value of every event exchanged by the services.
+// New contracts must be added here so their schema is exported and checked.
+var Catalog = []Event{
+	&EventCommandDispatched{},
+	&EventImageUpload{},
+	&EventImageUploaded{},
+	&EventMovieCreated{},
+	&EventMovieDeleted{},
+	&EventScraperFinished{},
+	&EventStaticDispatched{},
+}
+
+// Schemas returns the schema of every event in the catalog by event name.
+func Schemas() map[string]*Schema {
+	schemas := make(map[string]*Schema, len(Catalog))
+	for _, event := range Catalog {
+		schemas[event.EventName()] = EventSchema(event)
+	}
+	return schemas
+}
+
+// Validate checks the payload of the event with the given name against its
+// schema. Events that aren't in the catalog are always valid.
+func Validate(name string, payload []byte) error {
+	for _, event := range Catalog {
+		if event.EventName() == name {
+			return EventSchema(event).Validate(payload)
+		}
+	}
+	return nil
+}
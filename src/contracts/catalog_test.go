@@ -0,0 +1,92 @@
+package contracts
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Run go test -update after an intended change to the wire format, and
+// make sure every service handles both formats before deploying it.
+var update = flag.Bool("update", false, "update the golden schemas in testdata")
+
+func TestSchemasGolden(t *testing.T) {
+	for name, schema := range Schemas() {
+		got, err := json.MarshalIndent(schema, "", "  ")
+		if !assert.NoError(t, err) {
+			continue
+		}
+		got = append(got, '\n')
+
+		golden := filepath.Join("testdata", name+".schema.json")
+		if *update {
+			assert.NoError(t, ioutil.WriteFile(golden, got, 0644))
+			continue
+		}
+		want, err := ioutil.ReadFile(golden)
+		if !assert.NoError(t, err, "missing golden schema of %s, run go test -update", name) {
+			continue
+		}
+		assert.Equal(t, string(want), string(got), "wire format of %s changed", name)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	names := map[string]bool{}
+	for _, event := range Catalog {
+		name := event.EventName()
+		assert.False(t, names[name], "event %s is listed twice", name)
+		names[name] = true
+
+		// Serialized events always match their schema.
+		payload, err := json.Marshal(event)
+		if assert.NoError(t, err) {
+			assert.NoError(t, Validate(name, payload), "event %s", name)
+		}
+	}
+
+	// EventName must not change the event.
+	deleted := &EventMovieDeleted{MovieID: "id"}
+	assert.Equal(t, "movieDeleted", deleted.EventName())
+	assert.Equal(t, EventMovieDeleted{MovieID: "id"}, *deleted)
+}
+
+func TestValidate(t *testing.T) {
+	valid, err := json.Marshal(&EventCommandDispatched{
+		TaskID:           "task",
+		Args:             []string{"-type", "home"},
+		DispatchTime:     time.Now(),
+		ExecutionTimeout: time.Minute,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, Validate("commandDispatched", valid))
+
+	tests := []struct {
+		payload string
+		err     string
+	}{
+		{`[]`, "payload: expected object, got array"},
+		{`{"id":""}`, "payload: missing property args"},
+		{`{"id":"","task_id":"","name":"","type":"","args":[1],"dispatch_time":"2020-01-02T15:04:05Z","execution_timeout":0}`,
+			"args[0]: expected string, got integer"},
+		{`{"id":"","task_id":"","name":"","type":"","args":null,"dispatch_time":"yesterday","execution_timeout":0}`,
+			`dispatch_time: invalid date-time "yesterday"`},
+		{`{"id":"","task_id":"","name":"","type":"","args":null,"dispatch_time":"2020-01-02T15:04:05Z","execution_timeout":1.5}`,
+			"execution_timeout: expected integer, got number"},
+		{`{"id":"","task_id":"","name":"","type":"","args":null,"dispatch_time":"2020-01-02T15:04:05Z","execution_timeout":0,"taskId":""}`,
+			"payload: unknown property taskId"},
+	}
+	for _, test := range tests {
+		assert.EqualError(t, Validate("commandDispatched", []byte(test.payload)), test.err)
+	}
+
+	assert.Error(t, Validate("commandDispatched", []byte(`{`)))
+	assert.NoError(t, Validate("unknown", []byte(`{`)))
+}
@@ -3,7 +3,7 @@ package contracts
 // EventImageUploaded is emitted whenever a image is uploaded
 type EventImageUploaded struct {
 	MovieID string `json:"movie_id"`
-	Name    string `json:"url"`
+	Name    string `json:"name"`
 }
 
 // EventName returns the event's name
@@ -8,6 +8,5 @@ type EventMovieDeleted struct {
 
 // EventName returns the event's name
 func (e *EventMovieDeleted) EventName() string {
-	e.Name = "movieDeleted"
-	return e.Name
+	return "movieDeleted"
 }
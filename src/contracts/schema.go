@@ -0,0 +1,248 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SchemaDraft is the JSON Schema version of the generated schemas.
+const SchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// Schema is the subset of JSON Schema needed to describe event payloads.
+type Schema struct {
+	Draft                string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 SchemaType         `json:"type"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// SchemaType is a list of JSON types, written as a single string if there's
+// only one.
+type SchemaType []string
+
+// MarshalJSON ...
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON ...
+func (t *SchemaType) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*t = SchemaType{single}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(t))
+}
+
+// EventSchema returns the schema of the JSON payload of event. Fields
+// without omitempty are required, since they're always serialized, and
+// unknown fields are rejected so misspelled ones are caught.
+func EventSchema(event Event) *Schema {
+	t := reflect.TypeOf(event)
+	if t.Kind() == reflect.Ptr {
+		// Events are never null.
+		t = t.Elem()
+	}
+	s := typeSchema(t)
+	s.Draft = SchemaDraft
+	s.Title = event.EventName()
+	return s
+}
+
+func typeSchema(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var s *Schema
+	switch {
+	case t == timeType:
+		s = &Schema{Type: SchemaType{"string"}, Format: "date-time"}
+	case t == durationType:
+		// Nanoseconds.
+		s = &Schema{Type: SchemaType{"integer"}}
+	default:
+		switch t.Kind() {
+		case reflect.Bool:
+			s = &Schema{Type: SchemaType{"boolean"}}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			s = &Schema{Type: SchemaType{"integer"}}
+		case reflect.Float32, reflect.Float64:
+			s = &Schema{Type: SchemaType{"number"}}
+		case reflect.String:
+			s = &Schema{Type: SchemaType{"string"}}
+		case reflect.Slice, reflect.Array:
+			s = &Schema{Type: SchemaType{"array"}, Items: typeSchema(t.Elem())}
+			nullable = nullable || t.Kind() == reflect.Slice
+		case reflect.Map:
+			s = &Schema{Type: SchemaType{"object"}}
+			nullable = true
+		case reflect.Struct:
+			s = structSchema(t)
+		default:
+			panic(fmt.Sprintf("contracts: can't describe %s in a schema", t))
+		}
+	}
+
+	if nullable {
+		s.Type = append(s.Type, "null")
+	}
+	return s
+}
+
+func structSchema(t reflect.Type) *Schema {
+	closed := false
+	s := &Schema{
+		Type:                 SchemaType{"object"},
+		Properties:           make(map[string]*Schema),
+		Required:             []string{},
+		AdditionalProperties: &closed,
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, omitEmpty := jsonName(f)
+		if name == "-" {
+			continue
+		}
+		s.Properties[name] = typeSchema(f.Type)
+		if !omitEmpty {
+			s.Required = append(s.Required, name)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// jsonName returns the name of the field in JSON and if it's omitted when
+// empty.
+func jsonName(f reflect.StructField) (string, bool) {
+	parts := strings.Split(f.Tag.Get("json"), ",")
+	name := parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
+
+// Validate checks that payload is a JSON document matching the schema.
+func (s *Schema) Validate(payload []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("invalid JSON: %s", err)
+	}
+	return s.validate("", v)
+}
+
+func (s *Schema) validate(path string, v interface{}) error {
+	actual := jsonType(v)
+	matched := false
+	for _, t := range s.Type {
+		if t == actual || (t == "number" && actual == "integer") {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return fmt.Errorf("%s: expected %s, got %s", pathName(path), strings.Join(s.Type, " or "), actual)
+	}
+
+	switch value := v.(type) {
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+				return fmt.Errorf("%s: invalid date-time %q", pathName(path), value)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				return fmt.Errorf("%s: missing property %s", pathName(path), name)
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unknown property %s", pathName(path), name)
+				}
+				continue
+			}
+			if err := prop.validate(path+"."+name, value[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonType returns the JSON type of a decoded value.
+func jsonType(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+func pathName(path string) string {
+	if path == "" {
+		return "payload"
+	}
+	return strings.TrimPrefix(path, ".")
+}
@@ -1,6 +1,7 @@
 package listener
 
 import (
+	"context"
 	"log"
 
 	"github.com/dsbezerra/amenic/src/contracts"
@@ -17,71 +18,81 @@ type EventProcessor struct {
 	EventEmitter  messagequeue.EventEmitter
 	Data          persistence.DataAccessLayer
 	Log           *logrus.Entry
+	// Processed, if set, makes the effects of some events happen only once
+	// even if they are redelivered.
+	Processed messagequeue.ProcessedEventStore
 }
 
 // ProcessEvents ...
 func (p *EventProcessor) ProcessEvents() error {
 	log.Println("Listening to events...")
 
-	var eventsList = []string{
-		"imageUpload",
-		"movieDeleted",
-	}
-
-	received, errors, err := p.EventListener.Listen(eventsList...)
+	errors, err := p.routes().Listen(p.EventListener)
 	if err != nil {
 		return err
 	}
 
-	for {
-		select {
-		case event := <-received:
-			p.handle(event)
-		case err = <-errors:
-			log.Printf("received error while processing message: %s", err)
-		}
+	for err = range errors {
+		log.Printf("received error while processing message: %s", err)
 	}
+	return nil
 }
 
-func (p *EventProcessor) handle(event messagequeue.Event) {
-	switch event.(type) {
-	case *contracts.EventImageUpload:
-		p.handleImageUpload(event.(*contracts.EventImageUpload))
-	case *contracts.EventMovieDeleted:
-		p.handleMovieDeleted(event.(*contracts.EventMovieDeleted))
-	default:
-		log.Printf("unknown event: %t", event)
+// routes returns a router calling the handler of each event.
+func (p *EventProcessor) routes() *messagequeue.Router {
+	router := messagequeue.NewRouter()
+	messagequeue.Handle(router, p.handleImageUpload)
+	messagequeue.Handle(router, p.handleMovieDeleted)
+
+	if p.Processed != nil {
+		// Uploads must not create duplicate images.
+		router.Use(func(next messagequeue.EventHandler) messagequeue.EventHandler {
+			return messagequeue.Idempotent(p.Processed, next, "imageUpload")
+		})
 	}
+	return router
+}
+
+// scoped returns a copy of p tagging every log entry with the event and the
+// correlation it belongs to.
+func (p *EventProcessor) scoped(ctx context.Context) *EventProcessor {
+	scoped := *p
+	scoped.Log = p.Log.WithFields(messagequeue.LogFields(ctx))
+	return &scoped
 }
 
-func (p *EventProcessor) handleImageUpload(e *contracts.EventImageUpload) {
+func (p *EventProcessor) handleImageUpload(ctx context.Context, e *contracts.EventImageUpload) error {
+	p = p.scoped(ctx)
+
 	movieID, err := primitive.ObjectIDFromHex(e.MovieID)
 	if err != nil {
 		p.Log.Errorf("Aborting image upload because '%s' is not a valid movie id", e.MovieID)
-		return
+		return messagequeue.Permanent(err)
 	}
 
 	// NOTE(diego): Defaulting to Cloudinary for now.
 	im, err := cloudinary.UploadWebImage(e.URL, e.ImageType)
 	if err != nil {
 		p.Log.Errorf("Error occurred while uploading image '%s'", e.URL)
-		return
+		return err
 	}
 
 	im.MovieID = movieID
 	err = p.Data.InsertImage(*im)
 	if err != nil {
 		p.Log.Errorf("Error occurred while inserting image '%s'", e.URL)
-		return
+		return err
 	}
+	return nil
 }
 
-func (p *EventProcessor) handleMovieDeleted(e *contracts.EventMovieDeleted) {
+func (p *EventProcessor) handleMovieDeleted(ctx context.Context, e *contracts.EventMovieDeleted) error {
+	p = p.scoped(ctx)
+
 	images, err := p.Data.GetMovieImages(e.MovieID, p.Data.DefaultQuery())
 	if err != nil {
-		// TODO(diego): Persist this event so we can handle it later?
 		p.Log.Errorf("Error occurred while getting movie '%s' images", e.MovieID)
-		return
+		return err
 	}
 
 	imageIdsToDelete := []string{}
@@ -104,6 +115,8 @@ func (p *EventProcessor) handleMovieDeleted(e *contracts.EventMovieDeleted) {
 	if size > 0 {
 		count, err := p.Data.DeleteImagesByIDs(imageIdsToDelete)
 		if err != nil {
+			// Not retried since the images were already deleted from
+			// Cloudinary, so they wouldn't be deleted again.
 			p.Log.Errorf("Error '%s' occurred while deleting images", err.Error())
 		} else {
 			if count == int64(size) {
@@ -113,4 +126,5 @@ func (p *EventProcessor) handleMovieDeleted(e *contracts.EventMovieDeleted) {
 			}
 		}
 	}
+	return nil
 }
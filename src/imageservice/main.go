@@ -15,12 +15,12 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/config"
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/dblayer"
+	"github.com/dsbezerra/amenic/src/lib/shared"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
 )
 
 type ImageFile struct {
@@ -69,29 +69,35 @@ func main() {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Config = settings
+	// Catch events that don't match their contract during development.
+	messagequeue.ValidatePayloads = !settings.IsProduction
 
-	conn, err := amqp.Dial(settings.AMQPMessageBroker)
+	broker, err := shared.OpenBroker(settings)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
+	defer broker.Close()
 
-	eventEmitter, err := messagequeue.NewAMQPEventEmitter(conn, "events")
+	eventEmitter, err := broker.Emitter(ServiceName)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Emitter = eventEmitter
 
-	eventListener, err := messagequeue.NewAMQPEventListener(conn, "events", ServiceName)
+	eventListener, err := broker.Listener(ServiceName)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Listener = eventListener
 
-	data, err := mongolayer.NewMongoDAL(settings.DBConnection)
+	data, err := dblayer.NewPersistenceLayer(settings.DBType, settings.DBConnection)
+	if err != nil {
+		ctx.Log.Fatal(err)
+	}
+	err = data.Setup()
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
-	data.Setup()
 	defer data.Close()
 
 	ctx.Data = data
@@ -104,6 +110,7 @@ func main() {
 		Data:          data,
 		Log:           ctx.Log,
 		EventListener: eventListener,
+		Processed:     shared.NewProcessedEventStore(data, ServiceName),
 	}
 	go p.ProcessEvents()
 	go task.RunAll(data)
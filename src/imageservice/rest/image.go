@@ -7,7 +7,6 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"github.com/dsbezerra/amenic/src/lib/util/apiutil"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -115,7 +114,7 @@ func (s *ImageService) Upload(c *gin.Context) {
 					// }
 
 					filter := data.DefaultQuery().AddCondition("_id", ID)
-					_, err = data.FindMovieAndUpdate(filter, bson.M{"$set": bson.M{k: result.SecureURL}})
+					_, err = data.FindMovieAndUpdate(filter, persistence.Update{Set: map[string]interface{}{k: result.SecureURL}})
 					if err != nil {
 						// TODO: Diagnostic or ignore.
 					} else {
@@ -9,8 +9,6 @@ import (
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 type checksumJob struct {
@@ -27,10 +25,9 @@ type checksumResult struct {
 func EnsureImagesChecksum(data persistence.DataAccessLayer) error {
 	startTime := time.Now()
 
-	var q persistence.Query = &mongolayer.QueryOptions{Conditions: bson.M{}}
-	q.AddCondition("checksum", bson.M{
-		"$in": []interface{}{nil, ""},
-	})
+	q := data.DefaultQuery().
+		Where(persistence.In("checksum", nil, "")).
+		SetLimit(-1)
 	images, err := data.GetImages(q)
 	if err != nil {
 		return err
@@ -0,0 +1,339 @@
+// Package dataset exports the catalog (theaters, cities, scrapers, tasks,
+// movies and prices) to NDJSON archives and imports them back, so a new
+// environment can be seeded or a bug reproduced locally with real data.
+//
+// The first line of an archive is a Header and every other line a Record
+// holding a single document.
+package dataset
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// Format identifies dataset archives.
+	Format = "amenic-dataset"
+
+	// Version is the version of the archives written by Export. Import reads
+	// archives up to this version.
+	Version = 1
+
+	// maxLineSize is the maximum size of a single line of an archive.
+	maxLineSize = 16 * 1024 * 1024
+)
+
+// Names of the collections that can be exported.
+const (
+	CollectionCities   = "cities"
+	CollectionTheaters = "theaters"
+	CollectionScrapers = "scrapers"
+	CollectionTasks    = "tasks"
+	CollectionMovies   = "movies"
+	CollectionPrices   = "prices"
+)
+
+// Collections lists every collection that can be exported in the order they
+// are exported and imported, so documents are always imported after the
+// ones they reference.
+var Collections = []string{
+	CollectionCities,
+	CollectionTheaters,
+	CollectionScrapers,
+	CollectionTasks,
+	CollectionMovies,
+	CollectionPrices,
+}
+
+// Header is the first line of an archive.
+type Header struct {
+	Format      string    `json:"format"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Collections []string  `json:"collections"`
+}
+
+// Record is a single document of an archive. Documents are stored as MongoDB
+// extended JSON, so no field or type is lost.
+type Record struct {
+	Collection string          `json:"collection"`
+	Document   json.RawMessage `json:"document"`
+}
+
+// Counts holds how many documents of a collection were imported.
+type Counts struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Remapped int `json:"remapped"`          // Documents stored with a different ID, also counted as updated
+	Deleted  int `json:"deleted,omitempty"` // Documents replaced by the imported ones, see importPrice
+}
+
+// Result holds the Counts of each imported collection.
+type Result map[string]*Counts
+
+// ParseCollections parses a comma separated list of collections. An empty
+// list means all collections.
+func ParseCollections(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return Collections, nil
+	}
+	var result []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if !contains(Collections, name) {
+			return nil, fmt.Errorf("unknown collection %q", name)
+		}
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+// Export writes the documents of the given collections, all if none is given,
+// to w and returns how many were written of each one.
+func Export(data persistence.DataAccessLayer, w io.Writer, collections ...string) (map[string]int, error) {
+	if len(collections) == 0 {
+		collections = Collections
+	}
+	for _, name := range collections {
+		if !contains(Collections, name) {
+			return nil, fmt.Errorf("unknown collection %q", name)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	header := Header{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, name := range Collections {
+		if contains(collections, name) {
+			header.Collections = append(header.Collections, name)
+		}
+	}
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, name := range header.Collections {
+		docs, err := fetch(data, name)
+		if err != nil {
+			return counts, fmt.Errorf("exporting %s: %v", name, err)
+		}
+		for _, doc := range docs {
+			b, err := bson.MarshalExtJSON(doc, true, false)
+			if err != nil {
+				return counts, fmt.Errorf("exporting %s: %v", name, err)
+			}
+			if err := enc.Encode(Record{Collection: name, Document: b}); err != nil {
+				return counts, err
+			}
+			counts[name]++
+		}
+	}
+	return counts, nil
+}
+
+// Import reads an archive written by Export from r and stores the documents of
+// the given collections, all if none is given. Importing the same archive
+// again changes nothing: documents with the same ID or natural key, e.g. the
+// internal ID of theaters, are updated instead of inserted. In the latter
+// case the stored ID is kept and references to it are remapped. Documents
+// are stored in a single transaction, so nothing is stored if any of them
+// fails.
+func Import(data persistence.DataAccessLayer, r io.Reader, collections ...string) (Result, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("archive is empty")
+	}
+	var header Header
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != Format {
+		return nil, fmt.Errorf("not a dataset archive")
+	}
+	if header.Version < 1 || header.Version > Version {
+		return nil, fmt.Errorf("unsupported dataset version %d", header.Version)
+	}
+	if len(collections) == 0 {
+		collections = Collections
+	}
+
+	// Records are grouped first, so they're imported in order regardless of
+	// how they're sorted in the archive.
+	records := make(map[string][]json.RawMessage)
+	line := 1
+	for scanner.Scan() {
+		line++
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if !contains(Collections, record.Collection) {
+			return nil, fmt.Errorf("line %d: unknown collection %q", line, record.Collection)
+		}
+		if contains(collections, record.Collection) {
+			records[record.Collection] = append(records[record.Collection], record.Document)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var result Result
+	err := data.WithTransaction(func(tx persistence.DataAccessLayer) error {
+		// The transaction may be retried, so nothing is kept between runs.
+		i := newImporter(tx)
+		for _, name := range Collections {
+			for _, doc := range records[name] {
+				if err := i.importDocument(name, doc); err != nil {
+					return fmt.Errorf("importing %s: %v", name, err)
+				}
+			}
+		}
+		result = i.result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExportFile is like Export but writes to the file at path, compressed with
+// gzip when it ends with .gz. An existing file is only replaced when
+// overwrite is true, otherwise an error satisfying os.IsExist is returned.
+func ExportFile(data persistence.DataAccessLayer, path string, overwrite bool, collections ...string) (map[string]int, error) {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flag |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flag, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts map[string]int
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(f)
+		counts, err = Export(data, gz, collections...)
+		if cerr := gz.Close(); err == nil {
+			err = cerr
+		}
+	} else {
+		counts, err = Export(data, f, collections...)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return counts, err
+}
+
+// ImportFile is like Import but reads the file at path, decompressed with
+// gzip when it ends with .gz.
+func ImportFile(data persistence.DataAccessLayer, path string, collections ...string) (Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return Import(data, r, collections...)
+}
+
+// fetch returns all documents of the collection without the fields that are
+// only filled in query results.
+func fetch(data persistence.DataAccessLayer, name string) ([]interface{}, error) {
+	query := data.DefaultQuery().SetSort("_id").SetLimit(-1)
+
+	var result []interface{}
+	switch name {
+	case CollectionCities:
+		cities, err := data.GetCities(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cities {
+			c.State = nil
+			result = append(result, c)
+		}
+	case CollectionTheaters:
+		theaters, err := data.GetTheaters(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range theaters {
+			t.City, t.Prices, t.Sessions, t.Distance = nil, nil, nil, 0
+			result = append(result, t)
+		}
+	case CollectionScrapers:
+		scrapers, err := data.GetScrapers(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range scrapers {
+			// Runs aren't exported.
+			s.LastRun, s.Theater = primitive.NilObjectID, nil
+			result = append(result, s)
+		}
+	case CollectionTasks:
+		tasks, err := data.GetTasks(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tasks {
+			result = append(result, t)
+		}
+	case CollectionMovies:
+		movies, err := data.GetMovies(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range movies {
+			m.Theaters, m.Scores, m.Sessions = nil, nil, nil
+			result = append(result, m)
+		}
+	case CollectionPrices:
+		prices, err := data.GetPrices(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range prices {
+			p.Theater = nil
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package dataset
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestingData(t *testing.T) persistence.DataAccessLayer {
+	data, err := memlayer.NewMemDAL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestExportImport(t *testing.T) {
+	src := newTestingData(t)
+	defer src.Close()
+
+	city := models.City{ID: primitive.NewObjectID(), Name: "Belo Horizonte", StateID: "MG"}
+	theater := models.Theater{ID: primitive.NewObjectID(), CityID: city.ID, InternalID: "bh-1", Name: "Cine BH"}
+	movie := models.Movie{ID: primitive.NewObjectID(), TmdbID: 1, Slug: "bacurau", Title: "Bacurau", LockFlags: models.MovieLockTitle}
+	assert.NoError(t, src.InsertCity(city))
+	assert.NoError(t, src.InsertTheater(theater))
+	assert.NoError(t, src.InsertScraper(models.Scraper{ID: primitive.NewObjectID(), TheaterID: theater.ID, Type: "prices", Provider: "cinemais"}))
+	assert.NoError(t, src.InsertTask(models.Task{ID: "task-id", Service: "scraper", Type: models.TaskStartScraper, Cron: []string{"0 0 * * *"}}))
+	assert.NoError(t, src.InsertMovie(movie))
+	assert.NoError(t, src.InsertPrices(
+		models.Price{ID: primitive.NewObjectID(), TheaterID: theater.ID, Label: "Inteira", Full: 20},
+		models.Price{ID: primitive.NewObjectID(), TheaterID: theater.ID, Label: "Promocional", Full: 12},
+	))
+
+	var archive bytes.Buffer
+	counts, err := Export(src, &archive)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]int{"cities": 1, "theaters": 1, "scrapers": 1, "tasks": 1, "movies": 1, "prices": 2}, counts)
+
+	// The destination already has the city with another ID.
+	dst := newTestingData(t)
+	defer dst.Close()
+	existingCity := models.City{ID: primitive.NewObjectID(), Name: "Belo Horizonte", StateID: "MG"}
+	assert.NoError(t, dst.InsertCity(existingCity))
+
+	result, err := Import(dst, bytes.NewReader(archive.Bytes()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, Counts{Updated: 1, Remapped: 1}, *result[CollectionCities])
+	assert.Equal(t, Counts{Inserted: 1}, *result[CollectionTheaters])
+	assert.Equal(t, Counts{Inserted: 2}, *result[CollectionPrices])
+
+	stored, err := dst.GetTheater(theater.ID.Hex(), dst.DefaultQuery())
+	if assert.NoError(t, err) {
+		assert.Equal(t, existingCity.ID, stored.CityID)
+	}
+	storedMovie, err := dst.GetMovie(movie.ID.Hex(), dst.DefaultQuery())
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.MovieLockTitle, storedMovie.LockFlags)
+	}
+
+	// Importing again inserts nothing new.
+	result, err = Import(dst, bytes.NewReader(archive.Bytes()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, name := range Collections {
+		if name != CollectionPrices {
+			assert.Zero(t, result[name].Inserted, name)
+		}
+	}
+	// Prices are replaced.
+	assert.Equal(t, Counts{Inserted: 2, Deleted: 2}, *result[CollectionPrices])
+
+	cities, err := dst.GetCities(dst.DefaultQuery().SetLimit(-1))
+	assert.NoError(t, err)
+	assert.Len(t, cities, 1)
+	prices, err := dst.GetPrices(dst.DefaultQuery().SetLimit(-1))
+	assert.NoError(t, err)
+	assert.Len(t, prices, 2)
+}
+
+func TestImportInvalidArchive(t *testing.T) {
+	data := newTestingData(t)
+	defer data.Close()
+
+	_, err := Import(data, strings.NewReader(""))
+	assert.Error(t, err)
+
+	_, err = Import(data, strings.NewReader(`{"format":"other","version":1}`))
+	assert.Error(t, err)
+
+	_, err = Import(data, strings.NewReader(`{"format":"amenic-dataset","version":2}`))
+	assert.Error(t, err)
+
+	_, err = Import(data, strings.NewReader(`{"format":"amenic-dataset","version":1}
+{"collection":"users","document":{}}`))
+	assert.Error(t, err)
+
+	// Nothing is stored when a document fails.
+	_, err = Import(data, strings.NewReader(`{"format":"amenic-dataset","version":1}
+{"collection":"cities","document":{"_id":{"$oid":"5d6e8e1f2a6b4c0001a1b2c3"},"name":"Palmas"}}
+{"collection":"movies","document":{"_id":"invalid"}}`))
+	assert.Error(t, err)
+	cities, err := data.GetCities(data.DefaultQuery().SetLimit(-1))
+	assert.NoError(t, err)
+	assert.Empty(t, cities)
+
+	_, err = ParseCollections("movies, users")
+	assert.Error(t, err)
+}
@@ -0,0 +1,247 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// importer stores the documents of an archive, keeping track of the IDs that
+// were remapped to the ones of documents already stored.
+type importer struct {
+	data   persistence.DataAccessLayer
+	ids    map[primitive.ObjectID]primitive.ObjectID
+	prices map[primitive.ObjectID]bool // Theaters whose prices were replaced
+	result Result
+}
+
+func newImporter(data persistence.DataAccessLayer) *importer {
+	return &importer{
+		data:   data,
+		ids:    make(map[primitive.ObjectID]primitive.ObjectID),
+		prices: make(map[primitive.ObjectID]bool),
+		result: make(Result),
+	}
+}
+
+func (i *importer) importDocument(collection string, doc json.RawMessage) error {
+	switch collection {
+	case CollectionCities:
+		var city models.City
+		if err := bson.UnmarshalExtJSON(doc, true, &city); err != nil {
+			return err
+		}
+		return i.importCity(city)
+	case CollectionTheaters:
+		var theater models.Theater
+		if err := bson.UnmarshalExtJSON(doc, true, &theater); err != nil {
+			return err
+		}
+		return i.importTheater(theater)
+	case CollectionScrapers:
+		var scraper models.Scraper
+		if err := bson.UnmarshalExtJSON(doc, true, &scraper); err != nil {
+			return err
+		}
+		return i.importScraper(scraper)
+	case CollectionTasks:
+		var task models.Task
+		if err := bson.UnmarshalExtJSON(doc, true, &task); err != nil {
+			return err
+		}
+		return i.importTask(task)
+	case CollectionMovies:
+		var movie models.Movie
+		if err := bson.UnmarshalExtJSON(doc, true, &movie); err != nil {
+			return err
+		}
+		return i.importMovie(movie)
+	case CollectionPrices:
+		var price models.Price
+		if err := bson.UnmarshalExtJSON(doc, true, &price); err != nil {
+			return err
+		}
+		return i.importPrice(price)
+	}
+	return fmt.Errorf("unknown collection %q", collection)
+}
+
+func (i *importer) importCity(city models.City) error {
+	existing, err := i.findCity(i.byID(city.ID))
+	if err == persistence.ErrNotFound {
+		existing, err = i.findCity(i.data.DefaultQuery().
+			AddCondition("name", city.Name).
+			AddCondition("stateId", city.StateID))
+	}
+	if err == persistence.ErrNotFound {
+		return i.insert(CollectionCities, i.data.InsertCity(city))
+	}
+	if err != nil {
+		return err
+	}
+
+	i.remap(CollectionCities, &city.ID, existing.ID)
+	_, err = i.data.UpdateCity(city.ID.Hex(), city)
+	return err
+}
+
+func (i *importer) importTheater(theater models.Theater) error {
+	theater.CityID = i.mapped(theater.CityID)
+	theater.Version = 0 // Imported data always wins
+
+	existing, err := i.data.FindTheater(i.byID(theater.ID))
+	if err == persistence.ErrNotFound {
+		query := i.data.DefaultQuery()
+		if theater.InternalID != "" {
+			query.AddCondition("internalId", theater.InternalID)
+		} else {
+			query.AddCondition("name", theater.Name).AddCondition("cityId", theater.CityID)
+		}
+		existing, err = i.data.FindTheater(query)
+	}
+	if err == persistence.ErrNotFound {
+		return i.insert(CollectionTheaters, i.data.InsertTheater(theater))
+	}
+	if err != nil {
+		return err
+	}
+
+	i.remap(CollectionTheaters, &theater.ID, existing.ID)
+	_, err = i.data.UpdateTheater(theater.ID.Hex(), theater)
+	return err
+}
+
+func (i *importer) importScraper(scraper models.Scraper) error {
+	scraper.TheaterID = i.mapped(scraper.TheaterID)
+
+	existing, err := i.data.FindScraper(i.byID(scraper.ID))
+	if err == persistence.ErrNotFound {
+		existing, err = i.data.FindScraper(i.data.DefaultQuery().
+			AddCondition("theaterId", scraper.TheaterID).
+			AddCondition("type", scraper.Type).
+			AddCondition("provider", scraper.Provider))
+	}
+	if err == persistence.ErrNotFound {
+		return i.insert(CollectionScrapers, i.data.InsertScraper(scraper))
+	}
+	if err != nil {
+		return err
+	}
+
+	i.remap(CollectionScrapers, &scraper.ID, existing.ID)
+	_, err = i.data.UpdateScraper(scraper.ID.Hex(), scraper)
+	return err
+}
+
+func (i *importer) importTask(task models.Task) error {
+	// Task IDs are generated from their type and args, so they're natural keys.
+	_, err := i.data.GetTask(task.ID, i.data.DefaultQuery())
+	if err == persistence.ErrNotFound {
+		return i.insert(CollectionTasks, i.data.InsertTask(task))
+	}
+	if err != nil {
+		return err
+	}
+
+	i.counts(CollectionTasks).Updated++
+	_, err = i.data.UpdateTask(task.ID, task)
+	return err
+}
+
+func (i *importer) importMovie(movie models.Movie) error {
+	movie.Version = 0 // Imported data always wins
+
+	existing, err := i.data.FindMovie(i.byID(movie.ID))
+	if err == persistence.ErrNotFound && movie.TmdbID != 0 {
+		existing, err = i.data.FindMovie(i.data.DefaultQuery().AddCondition("tmdbId", movie.TmdbID))
+	}
+	if err == persistence.ErrNotFound && movie.Slug != "" {
+		existing, err = i.data.FindMovie(i.data.DefaultQuery().AddCondition("slug", movie.Slug))
+	}
+	if err == persistence.ErrNotFound {
+		return i.insert(CollectionMovies, i.data.InsertMovie(movie))
+	}
+	if err != nil {
+		return err
+	}
+
+	i.remap(CollectionMovies, &movie.ID, existing.ID)
+	_, err = i.data.UpdateMovie(movie.ID.Hex(), movie)
+	return err
+}
+
+// importPrice replaces the stored prices of the theater of price by the ones
+// in the archive. Prices have no natural key, so that's the only way to
+// import them more than once without duplicates.
+func (i *importer) importPrice(price models.Price) error {
+	price.TheaterID = i.mapped(price.TheaterID)
+	if !i.prices[price.TheaterID] {
+		deleted, err := i.data.DeletePrices(i.data.DefaultQuery().
+			AddCondition("theaterId", price.TheaterID).
+			SetLimit(-1))
+		if err != nil {
+			return err
+		}
+		i.prices[price.TheaterID] = true
+		i.counts(CollectionPrices).Deleted += int(deleted)
+	}
+	return i.insert(CollectionPrices, i.data.InsertPrice(price))
+}
+
+// findCity is like FindTheater and others, which cities lack.
+func (i *importer) findCity(query persistence.Query) (*models.City, error) {
+	cities, err := i.data.GetCities(query.SetLimit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(cities) == 0 {
+		return nil, persistence.ErrNotFound
+	}
+	return &cities[0], nil
+}
+
+// byID returns a query for the document with the given ID.
+func (i *importer) byID(ID primitive.ObjectID) persistence.Query {
+	return i.data.DefaultQuery().AddCondition("_id", ID)
+}
+
+// remap replaces ID with the one of the stored document, if they differ, so
+// documents referencing it are remapped too. The document is counted as
+// updated.
+func (i *importer) remap(collection string, ID *primitive.ObjectID, stored primitive.ObjectID) {
+	c := i.counts(collection)
+	c.Updated++
+	if *ID != stored {
+		i.ids[*ID] = stored
+		*ID = stored
+		c.Remapped++
+	}
+}
+
+// mapped returns the ID a reference must use after the import.
+func (i *importer) mapped(ID primitive.ObjectID) primitive.ObjectID {
+	if stored, ok := i.ids[ID]; ok {
+		return stored
+	}
+	return ID
+}
+
+func (i *importer) insert(collection string, err error) error {
+	if err == nil {
+		i.counts(collection).Inserted++
+	}
+	return err
+}
+
+func (i *importer) counts(collection string) *Counts {
+	c, ok := i.result[collection]
+	if !ok {
+		c = &Counts{}
+		i.result[collection] = c
+	}
+	return c
+}
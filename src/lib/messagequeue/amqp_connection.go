@@ -0,0 +1,302 @@
+package messagequeue
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	// publisherPoolSize is how many idle publisher channels are kept.
+	publisherPoolSize = 8
+
+	// connectTimeout is how long opening a channel waits for the connection
+	// to be recovered.
+	connectTimeout = 30 * time.Second
+)
+
+var (
+	// ErrConnectionClosed is returned when using a closed Connection.
+	ErrConnectionClosed = errors.New("connection closed")
+
+	// ReconnectPolicy is how long a Connection waits between attempts to
+	// recover from a lost connection.
+	ReconnectPolicy = RetryPolicy{
+		InitialDelay: time.Second,
+		Multiplier:   2,
+		MaxDelay:     time.Minute,
+	}
+)
+
+// Connection is an AMQP connection recovered automatically when lost, e.g.:
+// when RabbitMQ restarts.
+//
+// Exchanges, queues and bindings declared with Declare are declared again
+// after recovering, and listeners resume consuming. Events are published on a
+// pool of channels in confirm mode, see Publish.
+type Connection struct {
+	url string
+
+	mu       sync.Mutex
+	conn     *amqp.Connection
+	ready    chan struct{} // Closed once connected
+	gen      int           // Incremented on each connection
+	topology []func(channel *amqp.Channel) error
+	pool     chan *confirmChannel
+	closed   bool
+}
+
+// confirmChannel is a channel in confirm mode.
+type confirmChannel struct {
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+	gen      int // Generation of the connection the channel belongs to
+}
+
+// Dial connects to the broker at url. Only the first connection must
+// succeed, later ones are retried until Close is called.
+func Dial(url string) (*Connection, error) {
+	c := &Connection{
+		url:   url,
+		ready: make(chan struct{}),
+		pool:  make(chan *confirmChannel, publisherPoolSize),
+	}
+	err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes the connection. It's not recovered anymore and every listener
+// stops.
+func (c *Connection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// IsClosed reports whether Close was called.
+func (c *Connection) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// Declare runs fn, which declares exchanges, queues or bindings, now and
+// each time the connection is recovered.
+func (c *Connection) Declare(fn func(channel *amqp.Channel) error) error {
+	channel, err := c.Channel()
+	if err != nil {
+		return err
+	}
+	defer channel.Close()
+
+	err = fn(channel)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.topology = append(c.topology, fn)
+	c.mu.Unlock()
+	return nil
+}
+
+// Channel opens a channel. While the connection is being recovered it waits
+// for it, up to connectTimeout.
+func (c *Connection) Channel() (*amqp.Channel, error) {
+	timeout := time.After(connectTimeout)
+	for {
+		c.mu.Lock()
+		conn, ready, closed := c.conn, c.ready, c.closed
+		c.mu.Unlock()
+		if closed {
+			return nil, ErrConnectionClosed
+		}
+
+		if conn != nil {
+			channel, err := conn.Channel()
+			if err == nil || !conn.IsClosed() {
+				return channel, err
+			}
+			// Lost, but not noticed yet.
+			c.disconnected(conn)
+			continue
+		}
+
+		select {
+		case <-ready:
+		case <-timeout:
+			return nil, errors.New("not connected to the broker")
+		}
+	}
+}
+
+// Publish publishes msg and waits until the broker confirms it. Channels are
+// taken from a pool and put back once the confirmation arrives.
+func (c *Connection) Publish(exchange, key string, msg amqp.Publishing) error {
+	cc, err := c.confirmChannel()
+	if err != nil {
+		return err
+	}
+
+	err = cc.channel.Publish(exchange, key, false, false, msg)
+	if err != nil {
+		cc.channel.Close()
+		return err
+	}
+
+	select {
+	case confirm, ok := <-cc.confirms:
+		if !ok {
+			return errors.New("channel closed before the event was confirmed")
+		}
+		c.release(cc)
+		if !confirm.Ack {
+			return fmt.Errorf("message %s was rejected by the broker", msg.MessageId)
+		}
+		return nil
+	case <-time.After(confirmTimeout):
+		// A late confirmation would be taken for the one of the next message.
+		cc.channel.Close()
+		return fmt.Errorf("timed out waiting for the confirmation of message %s", msg.MessageId)
+	}
+}
+
+// confirmChannel takes a channel from the pool or opens a new one.
+func (c *Connection) confirmChannel() (*confirmChannel, error) {
+	for {
+		select {
+		case cc := <-c.pool:
+			if cc.gen == c.generation() {
+				return cc, nil
+			}
+			// It belongs to a lost connection.
+			cc.channel.Close()
+			continue
+		default:
+		}
+
+		gen := c.generation()
+		channel, err := c.Channel()
+		if err != nil {
+			return nil, err
+		}
+		err = channel.Confirm(false)
+		if err != nil {
+			channel.Close()
+			return nil, fmt.Errorf("could not put channel in confirm mode: %s", err)
+		}
+		return &confirmChannel{
+			channel:  channel,
+			confirms: channel.NotifyPublish(make(chan amqp.Confirmation, 1)),
+			gen:      gen,
+		}, nil
+	}
+}
+
+// release puts a channel back in the pool, or closes it if the pool is full.
+func (c *Connection) release(cc *confirmChannel) {
+	select {
+	case c.pool <- cc:
+	default:
+		cc.channel.Close()
+	}
+}
+
+func (c *Connection) generation() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// connect dials the broker and declares the topology again.
+func (c *Connection) connect() error {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	topology := c.topology
+	c.mu.Unlock()
+	if len(topology) > 0 {
+		channel, err := conn.Channel()
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		for _, fn := range topology {
+			if err = fn(channel); err != nil {
+				break
+			}
+		}
+		channel.Close()
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("could not declare topology: %s", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		conn.Close()
+		return ErrConnectionClosed
+	}
+	c.conn = conn
+	c.gen++
+	close(c.ready)
+
+	go c.watch(conn)
+	return nil
+}
+
+// watch recovers the connection once it's lost.
+func (c *Connection) watch(conn *amqp.Connection) {
+	reason, lost := <-conn.NotifyClose(make(chan *amqp.Error, 1))
+	c.disconnected(conn)
+	if !lost || c.IsClosed() {
+		return
+	}
+
+	log.Printf("lost connection to the broker: %s", reason)
+	for attempt := 1; ; attempt++ {
+		time.Sleep(ReconnectPolicy.Delay(attempt))
+		if c.IsClosed() {
+			return
+		}
+		err := c.connect()
+		if err == nil {
+			log.Printf("recovered connection to the broker after %d attempts", attempt)
+			return
+		}
+		if err == ErrConnectionClosed {
+			return
+		}
+		log.Printf("couldn't recover connection to the broker: %s", err)
+	}
+}
+
+// disconnected forgets conn if it's the current connection, so Channel waits
+// for the next one.
+func (c *Connection) disconnected(conn *amqp.Connection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == conn {
+		c.conn = nil
+		c.ready = make(chan struct{})
+	}
+}
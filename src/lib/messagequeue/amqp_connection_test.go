@@ -0,0 +1,49 @@
+package messagequeue
+
+import (
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectionClosed(t *testing.T) {
+	c := &Connection{
+		ready: make(chan struct{}),
+		pool:  make(chan *confirmChannel, publisherPoolSize),
+	}
+	assert.False(t, c.IsClosed())
+	assert.NoError(t, c.Close())
+	assert.True(t, c.IsClosed())
+
+	_, err := c.Channel()
+	assert.Equal(t, ErrConnectionClosed, err)
+	assert.Equal(t, ErrConnectionClosed, c.Declare(func(*amqp.Channel) error { return nil }))
+	assert.Equal(t, ErrConnectionClosed, c.Publish("events", "movieCreated", amqp.Publishing{}))
+	assert.Empty(t, c.topology)
+}
+
+func TestConnectionDisconnected(t *testing.T) {
+	conn := &amqp.Connection{}
+	ready := make(chan struct{})
+	close(ready)
+	c := &Connection{conn: conn, ready: ready, gen: 1}
+
+	// Only the current connection is forgotten.
+	c.disconnected(&amqp.Connection{})
+	assert.Equal(t, conn, c.conn)
+
+	c.disconnected(conn)
+	assert.Nil(t, c.conn)
+	select {
+	case <-c.ready:
+		t.Error("a lost connection shouldn't be ready")
+	default:
+	}
+}
+
+func TestReconnectPolicy(t *testing.T) {
+	assert.Equal(t, ReconnectPolicy.InitialDelay, ReconnectPolicy.Delay(1))
+	assert.Equal(t, 2*ReconnectPolicy.InitialDelay, ReconnectPolicy.Delay(2))
+	assert.Equal(t, ReconnectPolicy.MaxDelay, ReconnectPolicy.Delay(100))
+}
@@ -0,0 +1,121 @@
+package messagequeue
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// DeadLetter is an event that couldn't be handled by a listener.
+type DeadLetter struct {
+	EventName     string          `json:"event_name"`
+	EventID       string          `json:"event_id,omitempty"`
+	Source        string          `json:"source,omitempty"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Retries       int             `json:"retries"`
+	Error         string          `json:"error"`
+	Timestamp     time.Time       `json:"timestamp,omitempty"`
+	Body          json.RawMessage `json:"body"`
+}
+
+// DeadLetters returns up to limit dead-lettered events of the given listener
+// queue, all if limit isn't positive, without removing them.
+func DeadLetters(conn *Connection, queue string, limit int) ([]DeadLetter, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	// Messages are never acknowledged, so closing the channel puts them back
+	// in the queue.
+	defer channel.Close()
+
+	result := []DeadLetter{}
+	for limit <= 0 || len(result) < limit {
+		msg, ok, err := channel.Get(DeadLetterQueueName(queue), false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		name, _ := msg.Headers[eventNameHeader].(string)
+		lastError, _ := msg.Headers[lastErrorHeader].(string)
+		body := json.RawMessage(msg.Body)
+		if !json.Valid(body) {
+			body, _ = json.Marshal(string(msg.Body))
+		}
+		result = append(result, DeadLetter{
+			EventName:     name,
+			EventID:       msg.MessageId,
+			Source:        msg.AppId,
+			CorrelationID: msg.CorrelationId,
+			Retries:       headerInt(msg.Headers, retryCountHeader),
+			Error:         lastError,
+			Timestamp:     msg.Timestamp,
+			Body:          body,
+		})
+	}
+	return result, nil
+}
+
+// ReplayDeadLetters moves up to limit dead-lettered events of the given
+// listener queue, all if limit isn't positive, back to the queue with their
+// retry count reset. It returns how many events were replayed.
+func ReplayDeadLetters(conn *Connection, queue string, limit int) (int, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return 0, err
+	}
+
+	defer channel.Close()
+
+	// Only events dead-lettered before the replay started are replayed, in
+	// case they fail again right away.
+	dead, err := channel.QueueInspect(DeadLetterQueueName(queue))
+	if err != nil {
+		return 0, err
+	}
+	if limit <= 0 || limit > dead.Messages {
+		limit = dead.Messages
+	}
+
+	replayed := 0
+	for replayed < limit {
+		msg, ok, err := channel.Get(DeadLetterQueueName(queue), false)
+		if err != nil {
+			return replayed, err
+		}
+		if !ok {
+			break
+		}
+
+		headers := amqp.Table{}
+		for k, v := range msg.Headers {
+			if k != retryCountHeader && k != lastErrorHeader {
+				headers[k] = v
+			}
+		}
+		err = channel.Publish("", queue, false, false, amqp.Publishing{
+			Headers:       headers,
+			ContentType:   msg.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			MessageId:     msg.MessageId,
+			AppId:         msg.AppId,
+			Timestamp:     msg.Timestamp,
+			CorrelationId: msg.CorrelationId,
+			Body:          msg.Body,
+		})
+		if err != nil {
+			msg.Nack(false, true)
+			return replayed, err
+		}
+		err = msg.Ack(false)
+		if err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
@@ -1,17 +1,25 @@
 package messagequeue
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/streadway/amqp"
 )
 
-// NewAMQPEventEmitter ...
-func NewAMQPEventEmitter(conn *amqp.Connection, exchange string) (EventEmitter, error) {
+// confirmTimeout is how long publishing waits for the broker to confirm an
+// event.
+const confirmTimeout = 30 * time.Second
+
+// NewAMQPEventEmitter returns an emitter publishing events to exchange on
+// behalf of the source service.
+func NewAMQPEventEmitter(conn *Connection, exchange string, source string) (EventEmitter, error) {
 	emitter := amqpEventEmitter{
 		conn:     conn,
 		exchange: exchange,
+		source:   source,
 	}
 
 	err := emitter.setup()
@@ -22,39 +30,68 @@ func NewAMQPEventEmitter(conn *amqp.Connection, exchange string) (EventEmitter,
 	return &emitter, nil
 }
 
-func (a *amqpEventEmitter) setup() error {
-	channel, err := a.conn.Channel()
+// NewAMQPPublisher returns a Publisher of events to exchange. It returns once
+// the broker confirmed the event, see Connection.Publish.
+func NewAMQPPublisher(conn *Connection, exchange string) (Publisher, error) {
+	emitter := amqpEventEmitter{
+		conn:     conn,
+		exchange: exchange,
+	}
+
+	err := emitter.setup()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer channel.Close()
+	return &emitter, nil
+}
 
+func (a *amqpEventEmitter) setup() error {
 	// Normally, all(many) of these options should be configurable.
 	// For our example, it'll probably do.
-	err = channel.ExchangeDeclare(a.exchange, "topic", true, false, false, false, nil)
-	return err
+	return a.conn.Declare(func(channel *amqp.Channel) error {
+		return channel.ExchangeDeclare(a.exchange, "topic", true, false, false, false, nil)
+	})
 }
 
 func (a *amqpEventEmitter) Emit(event Event) error {
-	channel, err := a.conn.Channel()
-	if err != nil {
-		return err
-	}
-
-	defer channel.Close()
+	return a.EmitContext(context.Background(), event)
+}
 
+// EmitContext publishes the event with its envelope, see newPublishing.
+func (a *amqpEventEmitter) EmitContext(ctx context.Context, event Event) error {
 	b, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("could not JSON-serialize event: %s", err)
 	}
-
-	msg := amqp.Publishing{
-		Headers:     amqp.Table{"x-event-name": event.EventName()},
-		ContentType: "application/json",
-		Body:        b,
+	if err = ValidatePayload(event.EventName(), b); err != nil {
+		return err
 	}
 
-	err = channel.Publish(a.exchange, event.EventName(), false, false, msg)
-	return err
+	return a.Publish(NewEnvelope(ctx, a.source, event), b)
+}
+
+// Publish publishes an already serialized event.
+func (a *amqpEventEmitter) Publish(e Envelope, payload []byte) error {
+	return a.conn.Publish(a.exchange, e.Name, newPublishing(e, payload))
+}
+
+// newPublishing returns the message of an event: the event ID, source,
+// timestamp, correlation ID and reply queue are message properties, its name
+// and schema version are headers.
+func newPublishing(e Envelope, payload []byte) amqp.Publishing {
+	return amqp.Publishing{
+		Headers: amqp.Table{
+			eventNameHeader:     e.Name,
+			schemaVersionHeader: int32(e.SchemaVersion),
+		},
+		ContentType:   "application/json",
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     e.ID,
+		AppId:         e.Source,
+		Timestamp:     e.Timestamp,
+		CorrelationId: e.CorrelationID,
+		ReplyTo:       e.ReplyTo,
+		Body:          payload,
+	}
 }
@@ -1,30 +1,51 @@
 package messagequeue
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/streadway/amqp"
 )
 
-const eventNameHeader = "x-event-name"
+const (
+	eventNameHeader     = "x-event-name"
+	schemaVersionHeader = "x-schema-version"
+	retryCountHeader    = "x-retry-count"
+	lastErrorHeader     = "x-last-error"
+
+	// prefetchCount is how many events are delivered before the previous ones
+	// are acknowledged.
+	prefetchCount = 10
+)
 
 type amqpEventListener struct {
-	conn     *amqp.Connection
+	conn     *Connection
 	exchange string
 	queue    string
 	mapper   EventMapper
+	retry    RetryPolicy
 }
 
 // NewAMQPEventListener ...
-func NewAMQPEventListener(conn *amqp.Connection, exchange string, queue string) (EventListener, error) {
+func NewAMQPEventListener(conn *Connection, exchange string, queue string) (EventListener, error) {
+	return NewAMQPEventListenerWithRetry(conn, exchange, queue, DefaultRetryPolicy)
+}
+
+// NewAMQPEventListenerWithRetry is like NewAMQPEventListener but retries
+// failed events with the given policy.
+func NewAMQPEventListenerWithRetry(conn *Connection, exchange string, queue string, retry RetryPolicy) (EventListener, error) {
 	listener := amqpEventListener{
 		conn:     conn,
 		exchange: exchange,
 		queue:    queue,
 		mapper:   NewEventMapper(),
+		retry:    retry,
 	}
 
-	err := listener.setup()
+	err := conn.Declare(listener.setup)
 	if err != nil {
 		return nil, err
 	}
@@ -32,84 +53,327 @@ func NewAMQPEventListener(conn *amqp.Connection, exchange string, queue string)
 	return &listener, nil
 }
 
-func (l *amqpEventListener) setup() error {
-	channel, err := l.conn.Channel()
+// setup declares the queue of the listener and the ones used to retry and
+// dead-letter its events.
+//
+// Retried events wait in a queue for each delay, e.g.: scraper.retry.5s,
+// until they expire and are moved back to the listener queue. Events that
+// can't be decoded or keep failing are published to the dead-letter exchange
+// of the events exchange, e.g.: events.dlx, and kept in a queue, e.g.:
+// scraper.dead, until they're replayed.
+func (l *amqpEventListener) setup(channel *amqp.Channel) error {
+	err := channel.ExchangeDeclare(l.exchange, "topic", true, false, false, false, nil)
 	if err != nil {
 		return err
 	}
 
-	defer channel.Close()
+	_, err = channel.QueueDeclare(l.queue, true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("could not declare queue %s: %s", l.queue, err)
+	}
+
+	for _, delay := range l.retry.Delays() {
+		name := retryQueueName(l.queue, delay)
+		_, err = channel.QueueDeclare(name, true, false, false, false, amqp.Table{
+			"x-message-ttl":             int64(delay / time.Millisecond),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": l.queue,
+		})
+		if err != nil {
+			return fmt.Errorf("could not declare queue %s: %s", name, err)
+		}
+	}
 
-	err = channel.ExchangeDeclare(l.exchange, "topic", true, false, false, false, nil)
+	dlx := deadLetterExchangeName(l.exchange)
+	err = channel.ExchangeDeclare(dlx, "direct", true, false, false, false, nil)
 	if err != nil {
 		return err
 	}
-
-	_, err = channel.QueueDeclare(l.queue, true, false, false, false, nil)
+	dead := DeadLetterQueueName(l.queue)
+	_, err = channel.QueueDeclare(dead, true, false, false, false, nil)
 	if err != nil {
-		return fmt.Errorf("could not declare queue %s: %s", l.queue, err)
+		return fmt.Errorf("could not declare queue %s: %s", dead, err)
+	}
+	err = channel.QueueBind(dead, l.queue, dlx, false, nil)
+	if err != nil {
+		return fmt.Errorf("could not bind queue %s to %s: %s", dead, dlx, err)
 	}
 
 	return nil
 }
 
-// Listen configures the event listener to listen for a set of events that are
-// specified by name as parameter.
-// This method will return two channels: One will contain successfully decoded
-// events, the other will contain errors for messages that could not be
-// successfully decoded.
-func (l *amqpEventListener) Listen(eventNames ...string) (<-chan Event, <-chan error, error) {
-	channel, err := l.conn.Channel()
-	if err != nil {
-		return nil, nil, err
+// Consume configures the event listener to call handler for a set of events
+// that are specified by name as parameter. Events are acknowledged once
+// handler succeeds, retried when it fails and dead-lettered when they fail
+// more than the retry policy allows or can't be decoded.
+//
+// Consuming resumes when the connection is recovered. The returned channel
+// is closed once the connection is closed.
+func (l *amqpEventListener) Consume(handler EventHandler, eventNames ...string) (<-chan error, error) {
+	return l.ConsumeConcurrently(handler, 1, eventNames...)
+}
+
+// ConsumeConcurrently is like Consume but handles up to concurrency events
+// at the same time. Each one is still acknowledged on its own once handled.
+func (l *amqpEventListener) ConsumeConcurrently(handler EventHandler, concurrency int, eventNames ...string) (<-chan error, error) {
+	if concurrency < 1 {
+		concurrency = 1
 	}
 
 	// Create binding between queue and exchange for each listened event type
-	for _, event := range eventNames {
-		if err := channel.QueueBind(l.queue, event, l.exchange, false, nil); err != nil {
-			return nil, nil, fmt.Errorf("could not bind event %s to queue %s: %s", event, l.queue, err)
+	err := l.conn.Declare(func(channel *amqp.Channel) error {
+		for _, event := range eventNames {
+			if err := channel.QueueBind(l.queue, event, l.exchange, false, nil); err != nil {
+				return fmt.Errorf("could not bind event %s to queue %s: %s", event, l.queue, err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	msgs, err := channel.Consume(l.queue, "", false, false, false, false, nil)
+	// Enough events must be delivered to keep every handler busy.
+	prefetch := prefetchCount
+	if concurrency > prefetch {
+		prefetch = concurrency
+	}
+
+	msgs, err := l.consume(prefetch)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not consume queue: %s", err)
+		return nil, err
 	}
 
-	events := make(chan Event)
 	errors := make(chan error)
 
 	go func() {
-		for msg := range msgs {
-			rawEventName, ok := msg.Headers[eventNameHeader]
-			if !ok {
-				errors <- fmt.Errorf("message did not contain %s header", eventNameHeader)
-				msg.Nack(false, false)
-				continue
-			}
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, concurrency)
+		defer func() {
+			wg.Wait()
+			close(errors)
+		}()
 
-			eventName, ok := rawEventName.(string)
-			if !ok {
-				errors <- fmt.Errorf("header %s did not contain string", eventNameHeader)
-				msg.Nack(false, false)
-				continue
+		for {
+			for msg := range msgs {
+				slots <- struct{}{}
+				wg.Add(1)
+				go func(msg amqp.Delivery) {
+					defer func() {
+						<-slots
+						wg.Done()
+					}()
+					if err := l.process(msg, handler); err != nil {
+						errors <- err
+					}
+				}(msg)
 			}
 
-			event, err := l.mapper.MapEvent(eventName, msg.Body)
-			if err != nil {
-				errors <- fmt.Errorf("could not unmarshal event %s: %s", eventName, err)
-				msg.Nack(false, false)
-				continue
+			// The channel or the connection was lost.
+			for attempt := 1; ; attempt++ {
+				if l.conn.IsClosed() {
+					return
+				}
+				msgs, err = l.consume(prefetch)
+				if err == nil {
+					break
+				}
+				errors <- fmt.Errorf("could not resume consuming queue %s: %s", l.queue, err)
+				time.Sleep(ReconnectPolicy.Delay(attempt))
 			}
-
-			events <- event
-			msg.Ack(false)
 		}
 	}()
 
-	return events, errors, nil
+	return errors, nil
+}
+
+// consume opens a channel delivering the events of the listener queue, up to
+// prefetch at a time.
+func (l *amqpEventListener) consume(prefetch int) (<-chan amqp.Delivery, error) {
+	channel, err := l.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	err = channel.Qos(prefetch, 0, false)
+	if err != nil {
+		channel.Close()
+		return nil, err
+	}
+
+	msgs, err := channel.Consume(l.queue, "", false, false, false, false, nil)
+	if err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("could not consume queue: %s", err)
+	}
+	return msgs, nil
+}
+
+// process handles a single message and acknowledges it. It returns an error
+// if handling failed, even if the message will be retried.
+func (l *amqpEventListener) process(msg amqp.Delivery, handler EventHandler) error {
+	retries := headerInt(msg.Headers, retryCountHeader)
+
+	envelope, event, err := l.decode(msg)
+	replies := newReplyState(envelope, l.queue, l.reply)
+	if err != nil {
+		err = l.deadLetter(msg, retries, err)
+		replies.failed(err)
+		return err
+	}
+
+	ctx := withRetryCount(WithEnvelope(context.Background(), envelope), retries)
+	err = callHandler(withReplies(ctx, replies), handler, event)
+	if err == nil {
+		replies.succeeded()
+		return msg.Ack(false)
+	}
+	if IsPermanent(err) || retries >= l.retry.MaxRetries {
+		err = l.deadLetter(msg, retries, fmt.Errorf("event %s failed: %s", event.EventName(), err))
+		replies.failed(err)
+		return err
+	}
+
+	delay := l.retry.Delay(retries + 1)
+	err = fmt.Errorf("event %s failed, retry %d in %s: %s", event.EventName(), retries+1, delay, err)
+	err = l.republish(msg, "", retryQueueName(l.queue, delay), retries+1, err)
+	replies.retrying(err)
+	return err
+}
+
+// reply publishes a reply to the queue of a requester through the default
+// exchange, with the request ID as correlation ID.
+func (l *amqpEventListener) reply(replyTo string, r Reply) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return l.conn.Publish("", replyTo, amqp.Publishing{
+		ContentType:   "application/json",
+		AppId:         r.Source,
+		Timestamp:     r.Timestamp,
+		CorrelationId: r.RequestID,
+		Body:          b,
+	})
+}
+
+func (l *amqpEventListener) decode(msg amqp.Delivery) (Envelope, Event, error) {
+	envelope, err := deliveryEnvelope(msg)
+	if err != nil {
+		return envelope, nil, err
+	}
+
+	event, err := decodeEvent(l.mapper, envelope, msg.Body)
+	return envelope, event, err
+}
+
+// deliveryEnvelope returns the envelope msg was published with. Messages
+// published before events had envelopes only have the event name.
+func deliveryEnvelope(msg amqp.Delivery) (Envelope, error) {
+	rawEventName, ok := msg.Headers[eventNameHeader]
+	if !ok {
+		return Envelope{}, fmt.Errorf("message did not contain %s header", eventNameHeader)
+	}
+
+	eventName, ok := rawEventName.(string)
+	if !ok {
+		return Envelope{}, fmt.Errorf("header %s did not contain string", eventNameHeader)
+	}
+
+	version := headerInt(msg.Headers, schemaVersionHeader)
+	if version == 0 {
+		version = DefaultSchemaVersion
+	}
+
+	return Envelope{
+		ID:            msg.MessageId,
+		Name:          eventName,
+		Source:        msg.AppId,
+		Timestamp:     msg.Timestamp,
+		CorrelationID: msg.CorrelationId,
+		SchemaVersion: version,
+		ReplyTo:       msg.ReplyTo,
+	}, nil
+}
+
+// deadLetter moves msg to the dead-letter queue of the listener.
+func (l *amqpEventListener) deadLetter(msg amqp.Delivery, retries int, cause error) error {
+	err := fmt.Errorf("%s, moved to %s", cause, DeadLetterQueueName(l.queue))
+	return l.republish(msg, deadLetterExchangeName(l.exchange), l.queue, retries, err)
+}
+
+// republish publishes a copy of msg with the given retry count and error and
+// acknowledges msg once the broker confirms the copy, so it's never lost. If
+// publishing fails msg is requeued. The returned error is cause or the one
+// that prevented republishing.
+func (l *amqpEventListener) republish(msg amqp.Delivery, exchange, key string, retries int, cause error) error {
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[retryCountHeader] = int32(retries)
+	headers[lastErrorHeader] = cause.Error()
+
+	err := l.conn.Publish(exchange, key, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   msg.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     msg.MessageId,
+		AppId:         msg.AppId,
+		Timestamp:     msg.Timestamp,
+		CorrelationId: msg.CorrelationId,
+		ReplyTo:       msg.ReplyTo,
+		Body:          msg.Body,
+	})
+	if err != nil {
+		msg.Nack(false, true)
+		return fmt.Errorf("could not republish message (%s): %s", cause, err)
+	}
+	msg.Ack(false)
+	return cause
 }
 
 func (l *amqpEventListener) Mapper() EventMapper {
 	return l.mapper
 }
+
+// callHandler calls handler recovering from panics, so a bad event can't
+// stop the listener.
+func callHandler(ctx context.Context, handler EventHandler, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, event)
+}
+
+func retryQueueName(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queue, delay)
+}
+
+func deadLetterExchangeName(exchange string) string {
+	return exchange + ".dlx"
+}
+
+// DeadLetterQueueName returns the name of the queue holding the dead-lettered
+// events of the given listener queue.
+func DeadLetterQueueName(queue string) string {
+	return queue + ".dead"
+}
+
+// headerInt returns the integer value of a header, which depends on how it
+// was encoded, or zero.
+func headerInt(headers amqp.Table, name string) int {
+	switch v := headers[name].(type) {
+	case int:
+		return v
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package messagequeue
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// NewAMQPRequester returns a Requester publishing events to exchange on
+// behalf of the source service. Replies are sent to a queue used only by this
+// requester, deleted once it stops consuming it.
+func NewAMQPRequester(conn *Connection, exchange string, source string) (Requester, error) {
+	emitter := &amqpEventEmitter{
+		conn:     conn,
+		exchange: exchange,
+		source:   source,
+	}
+	err := emitter.setup()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &amqpRequester{
+		requester: newRequester(emitter, source, replyQueueName(source)),
+		conn:      conn,
+	}
+
+	msgs, err := r.consume()
+	if err != nil {
+		return nil, err
+	}
+	go r.run(msgs)
+
+	return r, nil
+}
+
+type amqpRequester struct {
+	*requester
+	conn *Connection
+}
+
+// consume declares the reply queue, since it's deleted with its consumer
+// when the channel is lost, and consumes it.
+func (r *amqpRequester) consume() (<-chan amqp.Delivery, error) {
+	channel, err := r.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = channel.QueueDeclare(r.replyTo, false, true, false, false, nil)
+	if err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("could not declare queue %s: %s", r.replyTo, err)
+	}
+
+	msgs, err := channel.Consume(r.replyTo, "", true, true, false, false, nil)
+	if err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("could not consume queue %s: %s", r.replyTo, err)
+	}
+	return msgs, nil
+}
+
+// run delivers replies to their calls until the connection is closed.
+func (r *amqpRequester) run(msgs <-chan amqp.Delivery) {
+	for {
+		for msg := range msgs {
+			var reply Reply
+			if err := json.Unmarshal(msg.Body, &reply); err != nil {
+				log.Printf("invalid reply to request %s: %s", msg.CorrelationId, err)
+				continue
+			}
+			if reply.RequestID == "" {
+				reply.RequestID = msg.CorrelationId
+			}
+			r.deliver(reply)
+		}
+
+		// The channel or the connection was lost. Replies sent meanwhile
+		// are lost too.
+		for attempt := 1; ; attempt++ {
+			if r.conn.IsClosed() {
+				return
+			}
+			var err error
+			msgs, err = r.consume()
+			if err == nil {
+				break
+			}
+			log.Printf("could not resume consuming queue %s: %s", r.replyTo, err)
+			time.Sleep(ReconnectPolicy.Delay(attempt))
+		}
+	}
+}
+
+// replyQueueName returns a new name for the reply queue of a requester of the
+// given service, e.g.: Admin.replies.1b4e28ba.
+func replyQueueName(source string) string {
+	return fmt.Sprintf("%s.replies.%s", source, newEventID()[:8])
+}
@@ -0,0 +1,98 @@
+package messagequeue
+
+import (
+	"github.com/nats-io/nats.go"
+)
+
+// Message broker types, see config.ServiceConfig.MessageBrokerType.
+const (
+	BrokerAMQP = "amqp" // RabbitMQ or another AMQP 0.9.1 broker
+	BrokerNATS = "nats" // NATS with JetStream
+)
+
+// Broker creates the emitters, listeners, publishers and requesters of a
+// service on a connection to a message broker, so services don't depend on
+// which one is used.
+type Broker interface {
+	// Emitter returns an emitter of events on behalf of the source service.
+	Emitter(source string) (EventEmitter, error)
+	// Listener returns a listener of the events delivered to queue, named
+	// after the service.
+	Listener(queue string) (EventListener, error)
+	// Publisher returns a Publisher of already serialized events.
+	Publisher() (Publisher, error)
+	// Requester returns a requester on behalf of the source service.
+	Requester(source string) (Requester, error)
+	// Close closes the connection.
+	Close() error
+}
+
+// NewAMQPBroker returns a Broker of events published to exchange through
+// conn.
+func NewAMQPBroker(conn *Connection, exchange string) Broker {
+	return &amqpBroker{conn, exchange}
+}
+
+type amqpBroker struct {
+	conn     *Connection
+	exchange string
+}
+
+func (b *amqpBroker) Emitter(source string) (EventEmitter, error) {
+	return NewAMQPEventEmitter(b.conn, b.exchange, source)
+}
+
+func (b *amqpBroker) Listener(queue string) (EventListener, error) {
+	return NewAMQPEventListener(b.conn, b.exchange, queue)
+}
+
+func (b *amqpBroker) Publisher() (Publisher, error) {
+	return NewAMQPPublisher(b.conn, b.exchange)
+}
+
+func (b *amqpBroker) Requester(source string) (Requester, error) {
+	return NewAMQPRequester(b.conn, b.exchange, source)
+}
+
+func (b *amqpBroker) Close() error {
+	return b.conn.Close()
+}
+
+// NewNATSBroker returns a Broker of events published to the JetStream stream
+// through nc.
+func NewNATSBroker(nc *nats.Conn, stream string) Broker {
+	return &natsBroker{nc, stream}
+}
+
+type natsBroker struct {
+	nc     *nats.Conn
+	stream string
+}
+
+func (b *natsBroker) Emitter(source string) (EventEmitter, error) {
+	return NewNATSEventEmitter(b.nc, b.stream, source)
+}
+
+func (b *natsBroker) Listener(queue string) (EventListener, error) {
+	return NewNATSEventListener(b.nc, b.stream, queue)
+}
+
+func (b *natsBroker) Publisher() (Publisher, error) {
+	return NewNATSPublisher(b.nc, b.stream)
+}
+
+func (b *natsBroker) Requester(source string) (Requester, error) {
+	return NewNATSRequester(b.nc, b.stream, source)
+}
+
+// Close closes the connection once the pending messages are sent and the
+// events being handled are acknowledged.
+func (b *natsBroker) Close() error {
+	closed := b.nc.StatusChanged(nats.CLOSED)
+	if err := b.nc.Drain(); err != nil {
+		return err
+	}
+	// Draining gives up after a timeout, closing the connection anyway.
+	<-closed
+	return nil
+}
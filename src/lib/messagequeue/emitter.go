@@ -1,18 +1,23 @@
 package messagequeue
 
 import (
-	"github.com/streadway/amqp"
+	"context"
 )
 
 // EventEmitter ...
 type EventEmitter interface {
+	// Emit publishes an event starting a new correlation.
 	Emit(event Event) error
+	// EmitContext publishes an event correlated to the one being handled in
+	// ctx, if any.
+	EmitContext(ctx context.Context, event Event) error
 }
 
 // ampqEventEmitter ...
 type amqpEventEmitter struct {
-	conn     *amqp.Connection
+	conn     *Connection
 	exchange string
+	source   string
 	events   chan *emittedEvent
 }
 
@@ -0,0 +1,156 @@
+package messagequeue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultSchemaVersion is the schema version of events that don't
+	// implement VersionedEvent and of messages published without one.
+	DefaultSchemaVersion = 1
+)
+
+// Envelope holds the metadata every emitted event is published with.
+type Envelope struct {
+	ID            string    `json:"id"`             // Unique ID of the event
+	Name          string    `json:"name"`           // Name of the event
+	Source        string    `json:"source"`         // Service that emitted the event
+	Timestamp     time.Time `json:"timestamp"`      // When the event was emitted
+	CorrelationID string    `json:"correlation_id"` // ID shared by the events caused by the same one
+	SchemaVersion int       `json:"schema_version"` // Version of the event payload
+	// ReplyTo is the queue of the emitter of a request, see Requester.
+	ReplyTo string `json:"reply_to,omitempty"`
+}
+
+// VersionedEvent is implemented by events whose payload changed since their
+// first version.
+type VersionedEvent interface {
+	Event
+	SchemaVersion() int
+}
+
+// PayloadUpgrader is implemented by versioned events that can decode
+// payloads of previous versions. UpgradePayload converts a JSON payload of
+// the given version to the current one.
+type PayloadUpgrader interface {
+	VersionedEvent
+	UpgradePayload(version int, payload []byte) ([]byte, error)
+}
+
+type envelopeKey struct{}
+
+// NewEnvelope returns the envelope of an event emitted by source. If ctx
+// carries the envelope of the event being handled, the new event is
+// correlated to it, otherwise it starts a new correlation.
+func NewEnvelope(ctx context.Context, source string, event Event) Envelope {
+	e := Envelope{
+		ID:            newEventID(),
+		Name:          event.EventName(),
+		Source:        source,
+		Timestamp:     time.Now().UTC(),
+		SchemaVersion: SchemaVersion(event),
+	}
+	if parent, ok := EnvelopeFromContext(ctx); ok {
+		e.CorrelationID = parent.CorrelationID
+		if e.CorrelationID == "" {
+			e.CorrelationID = parent.ID
+		}
+	}
+	if e.CorrelationID == "" {
+		e.CorrelationID = e.ID
+	}
+	return e
+}
+
+// WithEnvelope returns a copy of ctx carrying the envelope of the event being
+// handled. Listeners call handlers with it.
+func WithEnvelope(ctx context.Context, e Envelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, e)
+}
+
+// EnvelopeFromContext returns the envelope of the event being handled.
+func EnvelopeFromContext(ctx context.Context) (Envelope, bool) {
+	if ctx == nil {
+		return Envelope{}, false
+	}
+	e, ok := ctx.Value(envelopeKey{}).(Envelope)
+	return e, ok
+}
+
+// LogFields returns the fields identifying the event being handled, to be
+// added to every log entry about it.
+func LogFields(ctx context.Context) map[string]interface{} {
+	e, ok := EnvelopeFromContext(ctx)
+	if !ok {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"event":          e.Name,
+		"event_id":       e.ID,
+		"correlation_id": e.CorrelationID,
+		"source":         e.Source,
+	}
+}
+
+// SchemaVersion returns the current schema version of the event.
+func SchemaVersion(event Event) int {
+	if v, ok := event.(VersionedEvent); ok {
+		return v.SchemaVersion()
+	}
+	return DefaultSchemaVersion
+}
+
+// decodeEvent decodes the payload of the event described by the envelope.
+// Payloads of previous versions are upgraded to the current one, newer ones
+// can't be decoded until the service is updated.
+func decodeEvent(mapper EventMapper, e Envelope, payload []byte) (Event, error) {
+	event, err := mapper.NewEvent(e.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	version := e.SchemaVersion
+	if version == 0 {
+		version = DefaultSchemaVersion
+	}
+	current := SchemaVersion(event)
+	switch {
+	case version > current:
+		return nil, fmt.Errorf("event %s has schema version %d, newer than %d", e.Name, version, current)
+	case version < current:
+		upgrader, ok := event.(PayloadUpgrader)
+		if !ok {
+			return nil, fmt.Errorf("event %s can't be upgraded from schema version %d to %d", e.Name, version, current)
+		}
+		payload, err = upgrader.UpgradePayload(version, payload)
+		if err != nil {
+			return nil, fmt.Errorf("could not upgrade event %s from schema version %d: %s", e.Name, version, err)
+		}
+	}
+
+	if err = ValidatePayload(e.Name, payload); err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(payload, event)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal event %s: %s", e.Name, err)
+	}
+	return event, nil
+}
+
+// newEventID returns a random version 4 UUID.
+func newEventID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// Still unique enough to tell events apart in the logs.
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
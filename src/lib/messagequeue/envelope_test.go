@@ -0,0 +1,129 @@
+package messagequeue
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// ratedEvent renamed its score field in version 2.
+type ratedEvent struct {
+	Rating int `json:"rating"`
+}
+
+func (e *ratedEvent) EventName() string { return "rated" }
+
+func (e *ratedEvent) SchemaVersion() int { return 2 }
+
+func (e *ratedEvent) UpgradePayload(version int, payload []byte) ([]byte, error) {
+	var v1 struct {
+		Score int `json:"score"`
+	}
+	if err := json.Unmarshal(payload, &v1); err != nil {
+		return nil, err
+	}
+	return json.Marshal(ratedEvent{Rating: v1.Score})
+}
+
+func TestNewEnvelope(t *testing.T) {
+	event := &contracts.EventScraperFinished{}
+
+	first := NewEnvelope(context.Background(), "Admin", event)
+	assert.NotEmpty(t, first.ID)
+	assert.Equal(t, "scraperFinished", first.Name)
+	assert.Equal(t, "Admin", first.Source)
+	assert.Equal(t, first.ID, first.CorrelationID)
+	assert.Equal(t, DefaultSchemaVersion, first.SchemaVersion)
+	assert.False(t, first.Timestamp.IsZero())
+
+	// Follow-up events keep the correlation ID of the first one.
+	ctx := WithEnvelope(context.Background(), first)
+	second := NewEnvelope(ctx, "Scraper", event)
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, first.ID, second.CorrelationID)
+	third := NewEnvelope(WithEnvelope(ctx, second), "API", event)
+	assert.Equal(t, first.ID, third.CorrelationID)
+
+	// Messages published without an envelope start a correlation.
+	legacy := NewEnvelope(WithEnvelope(ctx, Envelope{Name: "scraperFinished"}), "API", event)
+	assert.Equal(t, legacy.ID, legacy.CorrelationID)
+
+	fields := LogFields(ctx)
+	assert.Equal(t, first.ID, fields["correlation_id"])
+	assert.Equal(t, "Admin", fields["source"])
+	assert.Empty(t, LogFields(context.Background()))
+}
+
+func TestDecodeEvent(t *testing.T) {
+	mapper := NewDynamicEventMapper()
+	assert.NoError(t, mapper.(*DynamicEventMapper).RegisterMapping(reflect.TypeOf(ratedEvent{})))
+
+	event, err := decodeEvent(mapper, Envelope{Name: "rated", SchemaVersion: 2}, []byte(`{"rating":4}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, event.(*ratedEvent).Rating)
+	}
+
+	// Previous versions are upgraded.
+	event, err = decodeEvent(mapper, Envelope{Name: "rated", SchemaVersion: 1}, []byte(`{"score":3}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, event.(*ratedEvent).Rating)
+	}
+
+	_, err = decodeEvent(mapper, Envelope{Name: "rated", SchemaVersion: 3}, []byte(`{"rating":4}`))
+	assert.EqualError(t, err, "event rated has schema version 3, newer than 2")
+
+	// Events without versions can't be upgraded.
+	_, err = decodeEvent(NewEventMapper(), Envelope{Name: "movieCreated", SchemaVersion: 2}, []byte(`{}`))
+	assert.Error(t, err)
+	_, err = decodeEvent(NewEventMapper(), Envelope{Name: "movieCreated"}, []byte(`{"id":"id"}`))
+	assert.NoError(t, err)
+}
+
+func TestMemoryBrokerCorrelation(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+
+	scraper := broker.Emitter("Scraper")
+	envelopes := make(chan Envelope, 2)
+	consume(t, broker.Listener("Scraper"), func(ctx context.Context, event Event) error {
+		e, _ := EnvelopeFromContext(ctx)
+		envelopes <- e
+		return scraper.EmitContext(ctx, &contracts.EventScraperFinished{})
+	}, "commandDispatched")
+	consume(t, broker.Listener("API"), func(ctx context.Context, event Event) error {
+		e, _ := EnvelopeFromContext(ctx)
+		envelopes <- e
+		return nil
+	}, "scraperFinished")
+
+	assert.NoError(t, broker.Emitter("Admin").Emit(&contracts.EventCommandDispatched{}))
+	broker.Wait()
+
+	command, finished := <-envelopes, <-envelopes
+	assert.Equal(t, "Admin", command.Source)
+	assert.Equal(t, "Scraper", finished.Source)
+	assert.Equal(t, "scraperFinished", finished.Name)
+	assert.Equal(t, command.ID, finished.CorrelationID)
+}
+
+func TestValidatePayloads(t *testing.T) {
+	ValidatePayloads = true
+	defer func() { ValidatePayloads = false }()
+
+	// A producer with an outdated contract.
+	_, err := decodeEvent(NewEventMapper(), Envelope{Name: "imageUploaded"}, []byte(`{"movie_id":"id","url":"poster.jpg"}`))
+	assert.EqualError(t, err, "event imageUploaded doesn't match its schema: payload: missing property name")
+
+	_, err = decodeEvent(NewEventMapper(), Envelope{Name: "imageUploaded"}, []byte(`{"movie_id":"id","name":"poster"}`))
+	assert.NoError(t, err)
+
+	// Events outside of the catalog aren't checked.
+	mapper := NewDynamicEventMapper()
+	assert.NoError(t, mapper.(*DynamicEventMapper).RegisterMapping(reflect.TypeOf(ratedEvent{})))
+	_, err = decodeEvent(mapper, Envelope{Name: "rated", SchemaVersion: 2}, []byte(`{"rating":4,"extra":true}`))
+	assert.NoError(t, err)
+}
@@ -1,6 +1,13 @@
 package messagequeue
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrTimeout is the error of commands received after their execution timeout,
+// see CheckAbort.
+var ErrTimeout = errors.New("timeout reached")
 
 // Event ...
 type Event interface {
@@ -0,0 +1,56 @@
+package messagequeue
+
+import (
+	"context"
+	"fmt"
+)
+
+// ProcessedEventStore remembers the events a consumer already handled.
+type ProcessedEventStore interface {
+	// IsProcessed reports whether the event was handled by the consumer.
+	IsProcessed(e Envelope) (bool, error)
+	// MarkProcessed records that the event was handled by the consumer.
+	MarkProcessed(e Envelope) error
+}
+
+// Idempotent returns a handler calling handler at most once for each event,
+// identified by its envelope ID, of the given event names. Other events,
+// and events published without an ID, are always handled.
+//
+// Events are marked as processed once handler succeeds, so failed ones are
+// still retried. Only redeliveries are skipped: two deliveries of the same
+// event handled at the same time by different listeners can both run.
+func Idempotent(store ProcessedEventStore, handler EventHandler, eventNames ...string) EventHandler {
+	names := make(map[string]bool, len(eventNames))
+	for _, name := range eventNames {
+		names[name] = true
+	}
+
+	return func(ctx context.Context, event Event) error {
+		e, ok := EnvelopeFromContext(ctx)
+		if !ok || e.ID == "" || !names[event.EventName()] {
+			return handler(ctx, event)
+		}
+
+		processed, err := store.IsProcessed(e)
+		if err != nil {
+			return fmt.Errorf("could not check if event %s was processed: %s", e.ID, err)
+		}
+		if processed {
+			return nil
+		}
+
+		err = handler(ctx, event)
+		if err != nil {
+			return err
+		}
+
+		// The effects already happened and a retry would repeat them, so the
+		// event is only dead-lettered to report it.
+		err = store.MarkProcessed(e)
+		if err != nil {
+			return Permanent(fmt.Errorf("could not mark event %s as processed: %s", e.ID, err))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryProcessedEvents is a ProcessedEventStore keeping IDs in a map.
+type memoryProcessedEvents struct {
+	mu  sync.Mutex
+	ids map[string]bool
+}
+
+func (s *memoryProcessedEvents) IsProcessed(e Envelope) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ids[e.ID], nil
+}
+
+func (s *memoryProcessedEvents) MarkProcessed(e Envelope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ids == nil {
+		s.ids = make(map[string]bool)
+	}
+	s.ids[e.ID] = true
+	return nil
+}
+
+func TestIdempotent(t *testing.T) {
+	store := &memoryProcessedEvents{}
+	calls := 0
+	fail := true
+	handler := Idempotent(store, func(ctx context.Context, event Event) error {
+		calls++
+		if fail {
+			return errors.New("cloudinary is down")
+		}
+		return nil
+	}, "imageUpload")
+
+	upload := WithEnvelope(context.Background(), Envelope{ID: "upload", Name: "imageUpload"})
+	event := &contracts.EventImageUpload{}
+
+	// Failed events are handled again.
+	assert.Error(t, handler(upload, event))
+	fail = false
+	assert.NoError(t, handler(upload, event))
+	assert.Equal(t, 2, calls)
+
+	// Redeliveries are skipped.
+	assert.NoError(t, handler(upload, event))
+	assert.Equal(t, 2, calls)
+	assert.NoError(t, handler(WithEnvelope(context.Background(), Envelope{ID: "other"}), event))
+	assert.Equal(t, 3, calls)
+
+	// Other events and events without ID are always handled.
+	deleted := WithEnvelope(context.Background(), Envelope{ID: "deleted", Name: "movieDeleted"})
+	assert.NoError(t, handler(deleted, &contracts.EventMovieDeleted{}))
+	assert.NoError(t, handler(deleted, &contracts.EventMovieDeleted{}))
+	assert.NoError(t, handler(context.Background(), event))
+	assert.NoError(t, handler(context.Background(), event))
+	assert.Equal(t, 7, calls)
+}
@@ -1,7 +1,21 @@
 package messagequeue
 
+import "context"
+
+// EventHandler processes a single event. The event is only acknowledged when
+// it returns nil, otherwise it's retried later, see RetryPolicy. Errors
+// wrapped with Permanent are never retried.
+type EventHandler func(ctx context.Context, event Event) error
+
 // EventListener describes an interface for a class that can listen to events.
 type EventListener interface {
-	Listen(events ...string) (<-chan Event, <-chan error, error)
+	// Consume calls handler for each of the events with the given names. The
+	// returned channel receives errors of events that failed or couldn't be
+	// decoded and must be drained.
+	Consume(handler EventHandler, events ...string) (<-chan error, error)
+	// ConsumeConcurrently is like Consume but calls handler for up to
+	// concurrency events at the same time, so events may be handled out of
+	// order.
+	ConsumeConcurrently(handler EventHandler, concurrency int, events ...string) (<-chan error, error)
 	Mapper() EventMapper
 }
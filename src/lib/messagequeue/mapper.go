@@ -3,6 +3,8 @@ package messagequeue
 // EventMapper ...
 type EventMapper interface {
 	MapEvent(string, interface{}) (Event, error)
+	// NewEvent returns an empty event of the given name.
+	NewEvent(string) (Event, error)
 }
 
 // NewEventMapper ...
@@ -22,17 +22,9 @@ func NewDynamicEventMapper() EventMapper {
 
 // MapEvent ...
 func (e *DynamicEventMapper) MapEvent(eventName string, serialized interface{}) (Event, error) {
-	typ, ok := e.typeMap[eventName]
-	if !ok {
-		return nil, fmt.Errorf("no mapping configured for event %s", eventName)
-	}
-
-	instance := reflect.New(typ)
-	iface := instance.Interface()
-
-	event, ok := iface.(Event)
-	if !ok {
-		return nil, fmt.Errorf("type %T does not implement the Event interface", iface)
+	event, err := e.NewEvent(eventName)
+	if err != nil {
+		return nil, err
 	}
 
 	switch s := serialized.(type) {
@@ -60,6 +52,24 @@ func (e *DynamicEventMapper) MapEvent(eventName string, serialized interface{})
 	return event, nil
 }
 
+// NewEvent ...
+func (e *DynamicEventMapper) NewEvent(eventName string) (Event, error) {
+	typ, ok := e.typeMap[eventName]
+	if !ok {
+		return nil, fmt.Errorf("no mapping configured for event %s", eventName)
+	}
+
+	instance := reflect.New(typ)
+	iface := instance.Interface()
+
+	event, ok := iface.(Event)
+	if !ok {
+		return nil, fmt.Errorf("type %T does not implement the Event interface", iface)
+	}
+
+	return event, nil
+}
+
 // RegisterMapping ...
 func (e *DynamicEventMapper) RegisterMapping(eventType reflect.Type) error {
 	instance := reflect.New(eventType).Interface()
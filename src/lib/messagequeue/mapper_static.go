@@ -3,29 +3,39 @@ package messagequeue
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"sync"
 
 	"github.com/dsbezerra/amenic/src/contracts"
 	"github.com/mitchellh/mapstructure"
 )
 
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]reflect.Type)
+)
+
 // StaticEventMapper ...
 type StaticEventMapper struct{}
 
+// RegisterEvent makes every StaticEventMapper decode events with the name of
+// event into its type, which must be a pointer to a struct.
+func RegisterEvent(event Event) {
+	typ := reflect.TypeOf(event)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	registryMu.Lock()
+	registry[event.EventName()] = typ
+	registryMu.Unlock()
+}
+
 // MapEvent ...
 func (e *StaticEventMapper) MapEvent(eventName string, serialized interface{}) (Event, error) {
-	var event Event
-
-	switch eventName {
-	case "commandDispatched":
-		event = &contracts.EventCommandDispatched{}
-	case "movieCreated":
-		event = &contracts.EventMovieCreated{}
-	case "scraperFinished":
-		event = &contracts.EventScraperFinished{}
-	case "staticDispatched":
-		event = &contracts.EventStaticDispatched{}
-	default:
-		return nil, fmt.Errorf("unknown event type %s", eventName)
+	event, err := e.NewEvent(eventName)
+	if err != nil {
+		return nil, err
 	}
 
 	switch s := serialized.(type) {
@@ -52,3 +62,35 @@ func (e *StaticEventMapper) MapEvent(eventName string, serialized interface{}) (
 
 	return event, nil
 }
+
+// NewEvent ...
+func (e *StaticEventMapper) NewEvent(eventName string) (Event, error) {
+	var event Event
+
+	switch eventName {
+	case "commandDispatched":
+		event = &contracts.EventCommandDispatched{}
+	case "imageUpload":
+		event = &contracts.EventImageUpload{}
+	case "imageUploaded":
+		event = &contracts.EventImageUploaded{}
+	case "movieCreated":
+		event = &contracts.EventMovieCreated{}
+	case "movieDeleted":
+		event = &contracts.EventMovieDeleted{}
+	case "scraperFinished":
+		event = &contracts.EventScraperFinished{}
+	case "staticDispatched":
+		event = &contracts.EventStaticDispatched{}
+	default:
+		registryMu.RLock()
+		typ, ok := registry[eventName]
+		registryMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown event type %s", eventName)
+		}
+		event = reflect.New(typ).Interface().(Event)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,410 @@
+package messagequeue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryBroker is an in-process message broker. Its emitter and listeners
+// behave like the AMQP ones sharing a single topic exchange: events are
+// copied to every queue with a binding matching their name, and listeners of
+// the same queue compete for its events. Events are serialized, so handlers
+// never share them, and retried or dead-lettered like in AMQP.
+//
+// Several services in the same process, or tests, can share a MemoryBroker
+// instead of a running RabbitMQ.
+type MemoryBroker struct {
+	mu      sync.Mutex
+	cond    *sync.Cond // Signaled when messages are queued or handled
+	queues  map[string]*memoryQueue
+	retry   RetryPolicy
+	pending int // Messages queued or being handled, see Wait
+	closed  bool
+}
+
+type memoryQueue struct {
+	name     string
+	bindings map[string]bool
+	messages []memoryMessage
+	dead     []memoryMessage
+}
+
+type memoryMessage struct {
+	envelope  Envelope
+	body      []byte
+	retries   int
+	lastError string
+}
+
+type memoryEventEmitter struct {
+	broker *MemoryBroker
+	source string
+}
+
+type memoryEventListener struct {
+	broker *MemoryBroker
+	queue  string
+	mapper EventMapper
+}
+
+// NewMemoryBroker ...
+func NewMemoryBroker() *MemoryBroker {
+	return NewMemoryBrokerWithRetry(DefaultRetryPolicy)
+}
+
+// NewMemoryBrokerWithRetry is like NewMemoryBroker but retries failed events
+// with the given policy.
+func NewMemoryBrokerWithRetry(retry RetryPolicy) *MemoryBroker {
+	b := &MemoryBroker{
+		queues: make(map[string]*memoryQueue),
+		retry:  retry,
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// Emitter returns an EventEmitter publishing to the broker on behalf of the
+// source service.
+func (b *MemoryBroker) Emitter(source string) EventEmitter {
+	return &memoryEventEmitter{broker: b, source: source}
+}
+
+// Publisher returns a Publisher routing events to the queues of the broker.
+func (b *MemoryBroker) Publisher() Publisher {
+	return &memoryEventEmitter{broker: b}
+}
+
+// Requester returns a Requester emitting events on behalf of the source
+// service, with replies sent to a queue of its own.
+func (b *MemoryBroker) Requester(source string) Requester {
+	queue := replyQueueName(source)
+	b.mu.Lock()
+	b.declare(queue)
+	b.mu.Unlock()
+
+	r := newRequester(b.Publisher(), source, queue)
+	go func() {
+		for {
+			m, ok := b.pop(queue)
+			if !ok {
+				return
+			}
+			var reply Reply
+			if err := json.Unmarshal(m.body, &reply); err == nil {
+				r.deliver(reply)
+			}
+			b.done()
+		}
+	}()
+	return r
+}
+
+// Listener returns an EventListener consuming the given queue, which is
+// created if needed. Like in AMQP, events emitted before the queue is bound to
+// them are lost.
+func (b *MemoryBroker) Listener(queue string) EventListener {
+	b.mu.Lock()
+	b.declare(queue)
+	b.mu.Unlock()
+	return &memoryEventListener{
+		broker: b,
+		queue:  queue,
+		mapper: NewEventMapper(),
+	}
+}
+
+// Wait blocks until every emitted event was handled, including retries, or
+// dead-lettered.
+func (b *MemoryBroker) Wait() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.pending > 0 {
+		b.cond.Wait()
+	}
+}
+
+// Close stops every listener, closing their error channels. Queued events
+// are dropped.
+func (b *MemoryBroker) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.pending = 0
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
+
+// DeadLetters returns the dead-lettered events of the given queue.
+func (b *MemoryBroker) DeadLetters(queue string) []DeadLetter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := []DeadLetter{}
+	if q, ok := b.queues[queue]; ok {
+		for _, m := range q.dead {
+			result = append(result, DeadLetter{
+				EventName:     m.envelope.Name,
+				EventID:       m.envelope.ID,
+				Source:        m.envelope.Source,
+				CorrelationID: m.envelope.CorrelationID,
+				Retries:       m.retries,
+				Error:         m.lastError,
+				Timestamp:     m.envelope.Timestamp,
+				Body:          json.RawMessage(m.body),
+			})
+		}
+	}
+	return result
+}
+
+// ReplayDeadLetters moves the dead-lettered events of the given queue back to
+// it with their retry count reset. It returns how many events were replayed.
+func (b *MemoryBroker) ReplayDeadLetters(queue string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queue]
+	if !ok {
+		return 0
+	}
+	replayed := len(q.dead)
+	for _, m := range q.dead {
+		m.retries, m.lastError = 0, ""
+		b.push(q, m)
+	}
+	q.dead = nil
+	return replayed
+}
+
+// declare returns the queue with the given name, creating it if needed. The
+// caller must hold the lock.
+func (b *MemoryBroker) declare(name string) *memoryQueue {
+	q, ok := b.queues[name]
+	if !ok {
+		q = &memoryQueue{name: name, bindings: make(map[string]bool)}
+		b.queues[name] = q
+	}
+	return q
+}
+
+// push appends a message to the queue. The caller must hold the lock.
+func (b *MemoryBroker) push(q *memoryQueue, m memoryMessage) {
+	q.messages = append(q.messages, m)
+	b.pending++
+	b.cond.Broadcast()
+}
+
+// pop blocks until the queue has a message and removes it. It's not ok if
+// the broker was closed.
+func (b *MemoryBroker) pop(name string) (memoryMessage, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.declare(name)
+	for len(q.messages) == 0 && !b.closed {
+		b.cond.Wait()
+	}
+	if b.closed {
+		return memoryMessage{}, false
+	}
+	m := q.messages[0]
+	q.messages = q.messages[1:]
+	return m, true
+}
+
+// done marks a popped message as handled.
+func (b *MemoryBroker) done() {
+	b.mu.Lock()
+	b.pending--
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
+
+// requeue puts a failed message back in its queue after the retry delay. It
+// stays pending while it waits.
+func (b *MemoryBroker) requeue(name string, m memoryMessage, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		q := b.declare(name)
+		q.messages = append(q.messages, m)
+		b.cond.Broadcast()
+	})
+}
+
+// reply queues a reply like the default exchange of AMQP, straight to the
+// queue with the given name.
+func (b *MemoryBroker) reply(queue string, r Reply) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if q, ok := b.queues[queue]; ok {
+		b.push(q, memoryMessage{body: body})
+	}
+	return nil
+}
+
+// deadLetter moves a failed message to the dead letters of its queue.
+func (b *MemoryBroker) deadLetter(name string, m memoryMessage) {
+	b.mu.Lock()
+	q := b.declare(name)
+	q.dead = append(q.dead, m)
+	b.mu.Unlock()
+	b.done()
+}
+
+func (e *memoryEventEmitter) Emit(event Event) error {
+	return e.EmitContext(context.Background(), event)
+}
+
+func (e *memoryEventEmitter) EmitContext(ctx context.Context, event Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not JSON-serialize event: %s", err)
+	}
+	if err = ValidatePayload(event.EventName(), b); err != nil {
+		return err
+	}
+
+	return e.Publish(NewEnvelope(ctx, e.source, event), b)
+}
+
+// Publish routes an already serialized event, like Emit.
+func (e *memoryEventEmitter) Publish(envelope Envelope, payload []byte) error {
+	m := memoryMessage{
+		envelope: envelope,
+		body:     payload,
+	}
+
+	e.broker.mu.Lock()
+	defer e.broker.mu.Unlock()
+	for _, q := range e.broker.queues {
+		for pattern := range q.bindings {
+			if matchTopic(pattern, m.envelope.Name) {
+				e.broker.push(q, m)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Consume binds the queue of the listener to the given events and calls
+// handler for each of them, see amqpEventListener.Consume.
+func (l *memoryEventListener) Consume(handler EventHandler, eventNames ...string) (<-chan error, error) {
+	return l.ConsumeConcurrently(handler, 1, eventNames...)
+}
+
+// ConsumeConcurrently is like Consume but handles up to concurrency events
+// at the same time.
+func (l *memoryEventListener) ConsumeConcurrently(handler EventHandler, concurrency int, eventNames ...string) (<-chan error, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	l.broker.mu.Lock()
+	q := l.broker.declare(l.queue)
+	for _, name := range eventNames {
+		q.bindings[name] = true
+	}
+	l.broker.mu.Unlock()
+
+	errors := make(chan error)
+
+	// Each worker competes for the events of the queue, like another listener.
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				m, ok := l.broker.pop(l.queue)
+				if !ok {
+					return
+				}
+				if err := l.process(m, handler); err != nil {
+					errors <- err
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(errors)
+	}()
+
+	return errors, nil
+}
+
+// process handles a single message, see amqpEventListener.process.
+func (l *memoryEventListener) process(m memoryMessage, handler EventHandler) error {
+	name := m.envelope.Name
+	event, err := decodeEvent(l.mapper, m.envelope, m.body)
+	replies := newReplyState(m.envelope, l.queue, l.broker.reply)
+	if err != nil {
+		err = fmt.Errorf("%s, moved to %s", err, DeadLetterQueueName(l.queue))
+		m.lastError = err.Error()
+		replies.failed(err)
+		l.broker.deadLetter(l.queue, m)
+		return err
+	}
+
+	ctx := withRetryCount(WithEnvelope(context.Background(), m.envelope), m.retries)
+	err = callHandler(withReplies(ctx, replies), handler, event)
+	if err == nil {
+		replies.succeeded()
+		l.broker.done()
+		return nil
+	}
+	retry := l.broker.retry
+	if IsPermanent(err) || m.retries >= retry.MaxRetries {
+		err = fmt.Errorf("event %s failed: %s, moved to %s", name, err, DeadLetterQueueName(l.queue))
+		m.lastError = err.Error()
+		replies.failed(err)
+		l.broker.deadLetter(l.queue, m)
+		return err
+	}
+
+	m.retries++
+	delay := retry.Delay(m.retries)
+	err = fmt.Errorf("event %s failed, retry %d in %s: %s", name, m.retries, delay, err)
+	m.lastError = err.Error()
+	replies.retrying(err)
+	l.broker.requeue(l.queue, m, delay)
+	return err
+}
+
+func (l *memoryEventListener) Mapper() EventMapper {
+	return l.mapper
+}
+
+// matchTopic reports whether the routing key matches the binding pattern of
+// a topic exchange, where * matches a single word and # zero or more words.
+func matchTopic(pattern, key string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(key, "."))
+}
+
+func matchWords(pattern, key []string) bool {
+	if len(pattern) == 0 {
+		return len(key) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(key); i++ {
+			if matchWords(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(key) > 0 && matchWords(pattern[1:], key[1:])
+	}
+	return len(key) > 0 && pattern[0] == key[0] && matchWords(pattern[1:], key[1:])
+}
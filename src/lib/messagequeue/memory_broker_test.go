@@ -0,0 +1,127 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// counter counts handled events by name.
+type counter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *counter) handle(ctx context.Context, event Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	c.counts[event.EventName()]++
+	return nil
+}
+
+func consume(t *testing.T, l EventListener, handler EventHandler, events ...string) {
+	errs, err := l.Consume(handler, events...)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	go func() {
+		for range errs {
+		}
+	}()
+}
+
+func TestMemoryBrokerRouting(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+
+	// Listeners of the same queue compete, each queue gets a copy.
+	var api1, api2, image counter
+	consume(t, broker.Listener("api"), api1.handle, "movieCreated")
+	consume(t, broker.Listener("api"), api2.handle, "movieCreated")
+	consume(t, broker.Listener("image"), image.handle, "movieCreated", "movieDeleted")
+
+	emitter := broker.Emitter("test")
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, emitter.Emit(&contracts.EventMovieCreated{ID: "id"}))
+	}
+	assert.NoError(t, emitter.Emit(&contracts.EventMovieDeleted{MovieID: "id"}))
+	assert.NoError(t, emitter.Emit(&contracts.EventStaticDispatched{})) // Not bound
+	broker.Wait()
+
+	assert.Equal(t, 10, api1.counts["movieCreated"]+api2.counts["movieCreated"])
+	assert.Zero(t, api1.counts["movieDeleted"]+api2.counts["movieDeleted"])
+	assert.Equal(t, map[string]int{"movieCreated": 10, "movieDeleted": 1}, image.counts)
+}
+
+func TestMemoryBrokerRetries(t *testing.T) {
+	broker := NewMemoryBrokerWithRetry(RetryPolicy{MaxRetries: 2, InitialDelay: time.Millisecond, Multiplier: 2})
+	defer broker.Close()
+
+	var mu sync.Mutex
+	var retries []int
+	fail := true
+	consume(t, broker.Listener("image"), func(ctx context.Context, event Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		retries = append(retries, RetryCount(ctx))
+		e := event.(*contracts.EventMovieDeleted)
+		if e.MovieID == "invalid" {
+			return Permanent(errors.New("invalid movie id"))
+		}
+		if fail {
+			return errors.New("database is down")
+		}
+		return nil
+	}, "movieDeleted")
+
+	emitter := broker.Emitter("test")
+	assert.NoError(t, emitter.Emit(&contracts.EventMovieDeleted{MovieID: "id"}))
+	broker.Wait()
+	assert.Equal(t, []int{0, 1, 2}, retries)
+
+	retries = nil
+	assert.NoError(t, emitter.Emit(&contracts.EventMovieDeleted{MovieID: "invalid"}))
+	broker.Wait()
+	assert.Equal(t, []int{0}, retries)
+
+	dead := broker.DeadLetters("image")
+	if assert.Len(t, dead, 2) {
+		assert.Equal(t, "movieDeleted", dead[0].EventName)
+		assert.Equal(t, 2, dead[0].Retries)
+		assert.Contains(t, dead[0].Error, "database is down")
+		assert.Contains(t, string(dead[0].Body), `"movie_id":"id"`)
+		assert.Equal(t, 0, dead[1].Retries)
+		assert.Contains(t, dead[1].Error, "invalid movie id")
+	}
+
+	// Replayed events are handled again from scratch.
+	mu.Lock()
+	fail, retries = false, nil
+	mu.Unlock()
+	assert.Equal(t, 2, broker.ReplayDeadLetters("image"))
+	broker.Wait()
+	assert.ElementsMatch(t, []int{0, 0}, retries)
+	dead = broker.DeadLetters("image")
+	if assert.Len(t, dead, 1) {
+		assert.Contains(t, string(dead[0].Body), `"movie_id":"invalid"`)
+	}
+}
+
+func TestMatchTopic(t *testing.T) {
+	assert.True(t, matchTopic("movieCreated", "movieCreated"))
+	assert.False(t, matchTopic("movieCreated", "movieDeleted"))
+	assert.True(t, matchTopic("movie.*", "movie.created"))
+	assert.False(t, matchTopic("movie.*", "movie.created.now"))
+	assert.True(t, matchTopic("movie.#", "movie.created.now"))
+	assert.True(t, matchTopic("movie.#", "movie"))
+	assert.True(t, matchTopic("#", "anything.at.all"))
+	assert.False(t, matchTopic("*.created", "created"))
+}
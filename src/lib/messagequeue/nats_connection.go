@@ -0,0 +1,84 @@
+package messagequeue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DialNATS connects to the NATS server at url, e.g.: nats://localhost:4222.
+// The connection is recovered by the client, which keeps reconnecting with
+// ReconnectPolicy for as long as it's open, and subscriptions are resumed
+// once it succeeds.
+func DialNATS(url string) (*nats.Conn, error) {
+	nc, err := nats.Connect(url,
+		nats.MaxReconnects(-1),
+		nats.CustomReconnectDelay(func(attempts int) time.Duration {
+			return ReconnectPolicy.Delay(attempts)
+		}),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Printf("NATS connection lost: %s", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			log.Printf("NATS connection recovered: %s", nc.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to NATS at %s: %s", url, err)
+	}
+	return nc, nil
+}
+
+// setupStream creates or updates the stream holding the events of every
+// service, e.g.: events with subjects events.>. Events are kept until every
+// consumer listening to them acknowledges them, like queues bound to an
+// exchange.
+func setupStream(js jetstream.JetStream, stream string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	_, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      stream,
+		Subjects:  []string{stream + ".>"},
+		Retention: jetstream.InterestPolicy,
+		Storage:   jetstream.FileStorage,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create stream %s: %s", stream, err)
+	}
+
+	dead := deadLetterStreamName(stream)
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     dead,
+		Subjects: []string{dead + ".>"},
+		Storage:  jetstream.FileStorage,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create stream %s: %s", dead, err)
+	}
+	return nil
+}
+
+// eventSubject returns the subject events of the given name are published
+// to, e.g.: events.scraperFinished.
+func eventSubject(stream, name string) string {
+	return stream + "." + name
+}
+
+// deadLetterStreamName returns the name of the stream holding the
+// dead-lettered events of every queue, e.g.: events-dead.
+func deadLetterStreamName(stream string) string {
+	return stream + "-dead"
+}
+
+// deadLetterSubject returns the subject the dead-lettered events of a queue
+// are published to, e.g.: events-dead.Scraper.
+func deadLetterSubject(stream, queue string) string {
+	return deadLetterStreamName(stream) + "." + queue
+}
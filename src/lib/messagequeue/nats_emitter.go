@@ -0,0 +1,131 @@
+package messagequeue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	eventIDHeader       = "x-event-id"
+	sourceHeader        = "x-source"
+	timestampHeader     = "x-timestamp"
+	correlationIDHeader = "x-correlation-id"
+	replyToHeader       = "x-reply-to"
+)
+
+type natsEventEmitter struct {
+	js     jetstream.JetStream
+	stream string
+	source string
+}
+
+// NewNATSEventEmitter returns an emitter publishing events to the JetStream
+// stream on behalf of the source service. The stream is created if it
+// doesn't exist.
+func NewNATSEventEmitter(nc *nats.Conn, stream string, source string) (EventEmitter, error) {
+	return newNATSEventEmitter(nc, stream, source)
+}
+
+// NewNATSPublisher returns a Publisher of events to the JetStream stream. It
+// returns once the server acknowledged the event. Events published twice
+// with the same ID, e.g.: by a relay that failed to mark them as published,
+// are stored once.
+func NewNATSPublisher(nc *nats.Conn, stream string) (Publisher, error) {
+	return newNATSEventEmitter(nc, stream, "")
+}
+
+func newNATSEventEmitter(nc *nats.Conn, stream string, source string) (*natsEventEmitter, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setupStream(js, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	return &natsEventEmitter{
+		js:     js,
+		stream: stream,
+		source: source,
+	}, nil
+}
+
+func (n *natsEventEmitter) Emit(event Event) error {
+	return n.EmitContext(context.Background(), event)
+}
+
+// EmitContext publishes the event with its envelope, see newNATSMsg.
+func (n *natsEventEmitter) EmitContext(ctx context.Context, event Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not JSON-serialize event: %s", err)
+	}
+	if err = ValidatePayload(event.EventName(), b); err != nil {
+		return err
+	}
+
+	return n.Publish(NewEnvelope(ctx, n.source, event), b)
+}
+
+// Publish publishes an already serialized event.
+func (n *natsEventEmitter) Publish(e Envelope, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	_, err := n.js.PublishMsg(ctx, newNATSMsg(eventSubject(n.stream, e.Name), e, payload))
+	if err != nil {
+		return fmt.Errorf("could not publish event %s: %s", e.Name, err)
+	}
+	return nil
+}
+
+// newNATSMsg returns the message of an event. The envelope goes in headers
+// and the event ID is also the message ID JetStream drops duplicates by.
+func newNATSMsg(subject string, e Envelope, payload []byte) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Header.Set(nats.MsgIdHdr, e.ID)
+	msg.Header.Set(eventIDHeader, e.ID)
+	msg.Header.Set(eventNameHeader, e.Name)
+	msg.Header.Set(schemaVersionHeader, strconv.Itoa(e.SchemaVersion))
+	msg.Header.Set(sourceHeader, e.Source)
+	msg.Header.Set(timestampHeader, e.Timestamp.Format(time.RFC3339Nano))
+	msg.Header.Set(correlationIDHeader, e.CorrelationID)
+	if e.ReplyTo != "" {
+		msg.Header.Set(replyToHeader, e.ReplyTo)
+	}
+	msg.Data = payload
+	return msg
+}
+
+// natsMsgEnvelope returns the envelope a message was published with, see
+// newNATSMsg.
+func natsMsgEnvelope(header nats.Header) (Envelope, error) {
+	name := header.Get(eventNameHeader)
+	if name == "" {
+		return Envelope{}, fmt.Errorf("message did not contain %s header", eventNameHeader)
+	}
+
+	version, _ := strconv.Atoi(header.Get(schemaVersionHeader))
+	if version == 0 {
+		version = DefaultSchemaVersion
+	}
+	timestamp, _ := time.Parse(time.RFC3339Nano, header.Get(timestampHeader))
+
+	return Envelope{
+		ID:            header.Get(eventIDHeader),
+		Name:          name,
+		Source:        header.Get(sourceHeader),
+		Timestamp:     timestamp,
+		CorrelationID: header.Get(correlationIDHeader),
+		SchemaVersion: version,
+		ReplyTo:       header.Get(replyToHeader),
+	}, nil
+}
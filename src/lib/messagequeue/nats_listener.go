@@ -0,0 +1,241 @@
+package messagequeue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// natsAckWait is how long an event is handled before JetStream delivers it
+// again, assuming the listener is gone.
+const natsAckWait = 10 * time.Minute
+
+type natsEventListener struct {
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	stream string
+	queue  string
+	mapper EventMapper
+	retry  RetryPolicy
+}
+
+// NewNATSEventListener returns a listener of the events published to the
+// JetStream stream, delivered through a durable consumer named after queue,
+// e.g.: Scraper. Like a queue, the consumer keeps the events published while
+// the service is down.
+func NewNATSEventListener(nc *nats.Conn, stream string, queue string) (EventListener, error) {
+	return NewNATSEventListenerWithRetry(nc, stream, queue, DefaultRetryPolicy)
+}
+
+// NewNATSEventListenerWithRetry is like NewNATSEventListener but retries
+// failed events with the given policy.
+func NewNATSEventListenerWithRetry(nc *nats.Conn, stream string, queue string, retry RetryPolicy) (EventListener, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setupStream(js, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	return &natsEventListener{
+		nc:     nc,
+		js:     js,
+		stream: stream,
+		queue:  queue,
+		mapper: NewEventMapper(),
+		retry:  retry,
+	}, nil
+}
+
+// Consume configures the event listener to call handler for a set of events
+// that are specified by name as parameter. Events are acknowledged once
+// handler succeeds, redelivered after a delay when it fails and
+// dead-lettered when they fail more than the retry policy allows or can't be
+// decoded.
+//
+// The returned channel is closed once the connection is closed.
+func (l *natsEventListener) Consume(handler EventHandler, eventNames ...string) (<-chan error, error) {
+	return l.ConsumeConcurrently(handler, 1, eventNames...)
+}
+
+// ConsumeConcurrently is like Consume but handles up to concurrency events
+// at the same time. Each one is still acknowledged on its own once handled.
+func (l *natsEventListener) ConsumeConcurrently(handler EventHandler, concurrency int, eventNames ...string) (<-chan error, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Enough events must be delivered to keep every handler busy.
+	prefetch := prefetchCount
+	if concurrency > prefetch {
+		prefetch = concurrency
+	}
+
+	consumer, err := l.setup(prefetch, eventNames)
+	if err != nil {
+		return nil, err
+	}
+
+	errors := make(chan error)
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+
+	cc, err := consumer.Consume(func(msg jetstream.Msg) {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if err := l.process(msg, handler); err != nil {
+				errors <- err
+			}
+		}()
+	}, jetstream.PullMaxMessages(prefetch), jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+		// Mostly missed heartbeats while the connection is lost, the client
+		// keeps consuming once it's recovered.
+		log.Printf("error consuming %s: %s", l.queue, err)
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("could not consume %s: %s", l.queue, err)
+	}
+
+	closed := l.nc.StatusChanged(nats.CLOSED)
+	go func() {
+		select {
+		case <-closed:
+			cc.Stop()
+		case <-cc.Closed():
+		}
+		wg.Wait()
+		close(errors)
+	}()
+
+	return errors, nil
+}
+
+// setup creates or updates the durable consumer of the listener, filtered by
+// the subjects of the given events. Only events published after it's first
+// created are delivered.
+//
+// Retries are scheduled by the listener, so JetStream never gives up on an
+// event: it's terminated once dead-lettered.
+func (l *natsEventListener) setup(prefetch int, eventNames []string) (jetstream.Consumer, error) {
+	subjects := make([]string, len(eventNames))
+	for i, event := range eventNames {
+		subjects[i] = eventSubject(l.stream, event)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	consumer, err := l.js.CreateOrUpdateConsumer(ctx, l.stream, jetstream.ConsumerConfig{
+		Durable:        l.queue,
+		FilterSubjects: subjects,
+		DeliverPolicy:  jetstream.DeliverNewPolicy,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		AckWait:        natsAckWait,
+		MaxDeliver:     -1,
+		MaxAckPending:  prefetch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create consumer %s: %s", l.queue, err)
+	}
+	return consumer, nil
+}
+
+// process handles a single message and acknowledges it. It returns an error
+// if handling failed, even if the message will be retried.
+func (l *natsEventListener) process(msg jetstream.Msg, handler EventHandler) error {
+	retries := 0
+	if meta, err := msg.Metadata(); err == nil && meta.NumDelivered > 0 {
+		retries = int(meta.NumDelivered) - 1
+	}
+
+	envelope, err := natsMsgEnvelope(msg.Headers())
+	var event Event
+	if err == nil {
+		event, err = decodeEvent(l.mapper, envelope, msg.Data())
+	}
+	replies := newReplyState(envelope, l.queue, l.reply)
+	if err != nil {
+		err = l.deadLetter(msg, retries, err)
+		replies.failed(err)
+		return err
+	}
+
+	ctx := withRetryCount(WithEnvelope(context.Background(), envelope), retries)
+	err = callHandler(withReplies(ctx, replies), handler, event)
+	if err == nil {
+		replies.succeeded()
+		return msg.Ack()
+	}
+	if IsPermanent(err) || retries >= l.retry.MaxRetries {
+		err = l.deadLetter(msg, retries, fmt.Errorf("event %s failed: %s", event.EventName(), err))
+		replies.failed(err)
+		return err
+	}
+
+	delay := l.retry.Delay(retries + 1)
+	err = fmt.Errorf("event %s failed, retry %d in %s: %s", event.EventName(), retries+1, delay, err)
+	if nakErr := msg.NakWithDelay(delay); nakErr != nil {
+		return fmt.Errorf("could not schedule retry (%s): %s", err, nakErr)
+	}
+	replies.retrying(err)
+	return err
+}
+
+// deadLetter publishes a copy of msg with the given retry count and error to
+// the dead-letter stream and terminates msg. If publishing fails msg is
+// redelivered right away. The returned error is cause or the one that
+// prevented dead-lettering.
+func (l *natsEventListener) deadLetter(msg jetstream.Msg, retries int, cause error) error {
+	subject := deadLetterSubject(l.stream, l.queue)
+	cause = fmt.Errorf("%s, moved to %s", cause, subject)
+
+	dead := nats.NewMsg(subject)
+	for k, v := range msg.Headers() {
+		dead.Header[k] = v
+	}
+	// Other queues may dead-letter the same event.
+	dead.Header.Del(nats.MsgIdHdr)
+	dead.Header.Set(retryCountHeader, strconv.Itoa(retries))
+	dead.Header.Set(lastErrorHeader, cause.Error())
+	dead.Data = msg.Data()
+
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	_, err := l.js.PublishMsg(ctx, dead)
+	if err != nil {
+		msg.Nak()
+		return fmt.Errorf("could not dead-letter message (%s): %s", cause, err)
+	}
+	msg.Term()
+	return cause
+}
+
+// reply publishes a reply to the subject a requester subscribed to, see
+// NewNATSRequester.
+func (l *natsEventListener) reply(replyTo string, r Reply) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return l.nc.Publish(replyTo, b)
+}
+
+func (l *natsEventListener) Mapper() EventMapper {
+	return l.mapper
+}
@@ -0,0 +1,35 @@
+package messagequeue
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NewNATSRequester returns a Requester publishing events to the JetStream
+// stream on behalf of the source service. Replies are sent to a subject only
+// this requester subscribes to, e.g.: Admin.replies.1b4e28ba, and aren't
+// stored, so the ones sent while the connection is lost are lost too.
+func NewNATSRequester(nc *nats.Conn, stream string, source string) (Requester, error) {
+	emitter, err := newNATSEventEmitter(nc, stream, source)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newRequester(emitter, source, replyQueueName(source))
+	_, err = nc.Subscribe(r.replyTo, func(msg *nats.Msg) {
+		var reply Reply
+		if err := json.Unmarshal(msg.Data, &reply); err != nil {
+			log.Printf("invalid reply on %s: %s", msg.Subject, err)
+			return
+		}
+		r.deliver(reply)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not subscribe to %s: %s", r.replyTo, err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,51 @@
+package messagequeue
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strconv"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// RunEmbeddedNATS starts a NATS server with JetStream in this process,
+// listening at the host and port of url, e.g.: nats://localhost:4222, and
+// keeping streams in storeDir, or in a temporary directory if it's empty.
+// It returns once the server accepts connections. Calling shutdown stops it.
+func RunEmbeddedNATS(url string, storeDir string) (shutdown func(), err error) {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL %s: %s", url, err)
+	}
+	port := server.DEFAULT_PORT
+	if p := u.Port(); p != "" {
+		port, err = strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NATS URL %s: %s", url, err)
+		}
+	}
+
+	s, err := server.NewServer(&server.Options{
+		ServerName: "amenic",
+		Host:       u.Hostname(),
+		Port:       port,
+		JetStream:  true,
+		StoreDir:   storeDir,
+		NoSigs:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create NATS server: %s", err)
+	}
+	s.ConfigureLogger()
+
+	go s.Start()
+	if !s.ReadyForConnections(confirmTimeout) {
+		s.Shutdown()
+		return nil, fmt.Errorf("NATS server at %s did not start in %s", url, confirmTimeout)
+	}
+
+	return func() {
+		s.Shutdown()
+		s.WaitForShutdown()
+	}, nil
+}
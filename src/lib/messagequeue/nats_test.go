@@ -0,0 +1,169 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeJetStream records the messages published by a listener.
+type fakeJetStream struct {
+	jetstream.JetStream
+	published []*nats.Msg
+}
+
+func (js *fakeJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	js.published = append(js.published, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+// fakeMsg records how a message was acknowledged.
+type fakeMsg struct {
+	jetstream.Msg
+	msg       *nats.Msg
+	delivered uint64
+
+	acked, termed bool
+	nakDelay      time.Duration
+}
+
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{NumDelivered: m.delivered}, nil
+}
+func (m *fakeMsg) Data() []byte                           { return m.msg.Data }
+func (m *fakeMsg) Headers() nats.Header                   { return m.msg.Header }
+func (m *fakeMsg) Ack() error                             { m.acked = true; return nil }
+func (m *fakeMsg) Term() error                            { m.termed = true; return nil }
+func (m *fakeMsg) NakWithDelay(delay time.Duration) error { m.nakDelay = delay; return nil }
+
+func TestNATSMsgEnvelope(t *testing.T) {
+	e := NewEnvelope(context.Background(), "Admin", &contracts.EventScraperFinished{})
+	e.ReplyTo = "Admin.replies.1b4e28ba"
+
+	msg := newNATSMsg("events.scraperFinished", e, []byte(`{}`))
+	assert.Equal(t, e.ID, msg.Header.Get(nats.MsgIdHdr))
+
+	decoded, err := natsMsgEnvelope(msg.Header)
+	assert.NoError(t, err)
+	assert.True(t, e.Timestamp.Equal(decoded.Timestamp))
+	decoded.Timestamp = e.Timestamp
+	assert.Equal(t, e, decoded)
+
+	_, err = natsMsgEnvelope(nats.Header{})
+	assert.Error(t, err)
+}
+
+func TestNATSProcess(t *testing.T) {
+	js := &fakeJetStream{}
+	l := &natsEventListener{
+		js:     js,
+		stream: "events",
+		queue:  "Scraper",
+		mapper: NewEventMapper(),
+		retry:  RetryPolicy{MaxRetries: 1, InitialDelay: time.Second},
+	}
+	e := NewEnvelope(context.Background(), "Admin", &contracts.EventScraperFinished{})
+	newMsg := func(delivered uint64) *fakeMsg {
+		return &fakeMsg{
+			msg:       newNATSMsg("events.scraperFinished", e, []byte(`{}`)),
+			delivered: delivered,
+		}
+	}
+
+	msg := newMsg(1)
+	assert.NoError(t, l.process(msg, func(ctx context.Context, event Event) error {
+		got, _ := EnvelopeFromContext(ctx)
+		assert.Equal(t, e.ID, got.ID)
+		return nil
+	}))
+	assert.True(t, msg.acked)
+
+	// Failed events are redelivered after the retry delay...
+	failing := func(ctx context.Context, event Event) error {
+		return errors.New("database is down")
+	}
+	msg = newMsg(1)
+	assert.Error(t, l.process(msg, failing))
+	assert.Equal(t, time.Second, msg.nakDelay)
+	assert.False(t, msg.acked || msg.termed)
+	assert.Empty(t, js.published)
+
+	// ...until they're dead-lettered.
+	msg = newMsg(2)
+	assert.Error(t, l.process(msg, failing))
+	assert.True(t, msg.termed)
+	if assert.Len(t, js.published, 1) {
+		dead := js.published[0]
+		assert.Equal(t, "events-dead.Scraper", dead.Subject)
+		assert.Empty(t, dead.Header.Get(nats.MsgIdHdr))
+		assert.Equal(t, "1", dead.Header.Get(retryCountHeader))
+		assert.Contains(t, dead.Header.Get(lastErrorHeader), "database is down")
+
+		decoded, err := natsMsgEnvelope(dead.Header)
+		assert.NoError(t, err)
+		assert.Equal(t, e.ID, decoded.ID)
+	}
+
+	// Events that can't be decoded are dead-lettered right away.
+	msg = newMsg(1)
+	msg.msg.Data = []byte(`{`)
+	assert.Error(t, l.process(msg, failing))
+	assert.True(t, msg.termed)
+	assert.Len(t, js.published, 2)
+}
+
+func TestEmbeddedNATS(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := fmt.Sprintf("nats://%s", l.Addr())
+	l.Close()
+
+	shutdown, err := RunEmbeddedNATS(url, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown()
+
+	nc, err := DialNATS(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := NewNATSBroker(nc, "events")
+	defer broker.Close()
+
+	listener, err := broker.Listener("Scraper")
+	if !assert.NoError(t, err) {
+		return
+	}
+	handled := make(chan Event, 1)
+	_, err = listener.Consume(func(ctx context.Context, event Event) error {
+		handled <- event
+		return nil
+	}, "scraperFinished")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	emitter, err := broker.Emitter("Admin")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, emitter.Emit(&contracts.EventScraperFinished{ScraperID: "embedded"}))
+
+	select {
+	case e := <-handled:
+		assert.Equal(t, "embedded", e.(*contracts.EventScraperFinished).ScraperID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event wasn't delivered")
+	}
+}
@@ -0,0 +1,8 @@
+package messagequeue
+
+// Publisher publishes events that were already serialized, e.g.: stored in
+// an outbox. Publish returns once the broker has taken responsibility for the
+// event, so the caller may forget it.
+type Publisher interface {
+	Publish(e Envelope, payload []byte) error
+}
@@ -0,0 +1,85 @@
+package messagequeue
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy describes how events whose handler failed are retried.
+type RetryPolicy struct {
+	MaxRetries   int           // Retries before the event is dead-lettered
+	InitialDelay time.Duration // Delay of the first retry
+	Multiplier   float64       // Factor applied to the delay of each next retry
+	MaxDelay     time.Duration // Maximum delay of a retry
+}
+
+// DefaultRetryPolicy retries events 5 times, after 5s, 15s, 45s, 2m15s and
+// 6m45s.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:   5,
+	InitialDelay: 5 * time.Second,
+	Multiplier:   3,
+	MaxDelay:     10 * time.Minute,
+}
+
+// Delay returns the delay of the given retry, starting at 1.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := float64(p.InitialDelay)
+	for i := 1; i < retry; i++ {
+		delay *= p.Multiplier
+	}
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// Delays returns the distinct delays of all retries.
+func (p RetryPolicy) Delays() []time.Duration {
+	var result []time.Duration
+	for i := 1; i <= p.MaxRetries; i++ {
+		d := p.Delay(i)
+		if len(result) == 0 || result[len(result)-1] != d {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// PermanentError is an error that retrying won't fix, e.g.: an invalid
+// event.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Permanent wraps err so the event that caused it is dead-lettered right away
+// instead of retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent.
+func IsPermanent(err error) bool {
+	_, ok := err.(*PermanentError)
+	return ok
+}
+
+type retryCountKey struct{}
+
+func withRetryCount(ctx context.Context, retries int) context.Context {
+	return context.WithValue(ctx, retryCountKey{}, retries)
+}
+
+// RetryCount returns how many times the event being handled was retried. It's
+// zero in the first delivery.
+func RetryCount(ctx context.Context) int {
+	retries, _ := ctx.Value(retryCountKey{}).(int)
+	return retries
+}
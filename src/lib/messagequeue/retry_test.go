@@ -0,0 +1,40 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	p := DefaultRetryPolicy
+	assert.Equal(t, 5*time.Second, p.Delay(1))
+	assert.Equal(t, 15*time.Second, p.Delay(2))
+	assert.Equal(t, 6*time.Minute+45*time.Second, p.Delay(5))
+	assert.Equal(t, 10*time.Minute, p.Delay(6))
+
+	// Retries with the same delay share their queue
+	p = RetryPolicy{MaxRetries: 4, InitialDelay: time.Second, Multiplier: 10, MaxDelay: 30 * time.Second}
+	assert.Equal(t, []time.Duration{time.Second, 10 * time.Second, 30 * time.Second}, p.Delays())
+}
+
+func TestPermanent(t *testing.T) {
+	err := errors.New("invalid movie id")
+	assert.False(t, IsPermanent(err))
+	assert.True(t, IsPermanent(Permanent(err)))
+	assert.Equal(t, err.Error(), Permanent(err).Error())
+	assert.Nil(t, Permanent(nil))
+}
+
+func TestCallHandler(t *testing.T) {
+	ctx := withRetryCount(context.Background(), 2)
+	err := callHandler(ctx, func(ctx context.Context, event Event) error {
+		assert.Equal(t, 2, RetryCount(ctx))
+		panic("boom")
+	}, nil)
+	assert.EqualError(t, err, "handler panicked: boom")
+	assert.Equal(t, 0, RetryCount(context.Background()))
+}
@@ -0,0 +1,112 @@
+package messagequeue
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// DefaultConcurrency is how many events a Router handles at the same time
+// unless told otherwise.
+const DefaultConcurrency = 4
+
+// Middleware wraps the handler of every event of a Router, e.g.: Idempotent.
+type Middleware func(next EventHandler) EventHandler
+
+// Router calls the handler registered with Handle for the type of each
+// event, replacing a type switch over every event a service listens to.
+type Router struct {
+	// Concurrency is how many events are handled at the same time.
+	Concurrency int
+
+	mu         sync.RWMutex
+	handlers   map[string]EventHandler
+	middleware []Middleware
+}
+
+// NewRouter ...
+func NewRouter() *Router {
+	return &Router{
+		Concurrency: DefaultConcurrency,
+		handlers:    make(map[string]EventHandler),
+	}
+}
+
+// Handle registers fn as the handler of the events of type T, which must be
+// a pointer to a struct. Listening with the router binds its queue to them
+// and StaticEventMapper decodes them, even if it doesn't know the type.
+//
+// Like any EventHandler, fn returns an error to have the event retried.
+func Handle[T Event](r *Router, fn func(ctx context.Context, event T) error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("messagequeue: event type %s is not a pointer to a struct", typ))
+	}
+	event := reflect.New(typ.Elem()).Interface().(Event)
+	name := event.EventName()
+	RegisterEvent(event)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.handlers[name]; ok {
+		panic(fmt.Sprintf("messagequeue: event %s already has a handler", name))
+	}
+	r.handlers[name] = func(ctx context.Context, event Event) error {
+		e, ok := event.(T)
+		if !ok {
+			return Permanent(fmt.Errorf("event %s has type %T instead of %s", name, event, typ))
+		}
+		return fn(ctx, e)
+	}
+}
+
+// Use wraps the handlers of the router with middleware, the first one being
+// the outermost.
+func (r *Router) Use(middleware ...Middleware) {
+	r.mu.Lock()
+	r.middleware = append(r.middleware, middleware...)
+	r.mu.Unlock()
+}
+
+// Events returns the names of the events with a handler.
+func (r *Router) Events() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HandleEvent calls the handler of event through the middleware. Events
+// without a handler are never retried.
+func (r *Router) HandleEvent(ctx context.Context, event Event) error {
+	r.mu.RLock()
+	handler, ok := r.handlers[event.EventName()]
+	middleware := r.middleware
+	r.mu.RUnlock()
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for event %s", event.EventName()))
+	}
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler(ctx, event)
+}
+
+// Listen consumes the events with a handler from listener, handling up to
+// Concurrency of them at the same time. The returned channel must be
+// drained, see EventListener.Consume.
+func (r *Router) Listen(listener EventListener) (<-chan error, error) {
+	events := r.Events()
+	if len(events) == 0 {
+		return nil, fmt.Errorf("router has no handlers")
+	}
+	return listener.ConsumeConcurrently(r.HandleEvent, r.Concurrency, events...)
+}
@@ -0,0 +1,127 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// renamedEvent is only known through the router.
+type renamedEvent struct {
+	Title string `json:"title"`
+}
+
+func (e *renamedEvent) EventName() string { return "renamed" }
+
+func TestRouter(t *testing.T) {
+	broker := NewMemoryBrokerWithRetry(RetryPolicy{MaxRetries: 1, InitialDelay: time.Millisecond})
+	defer broker.Close()
+
+	var mu sync.Mutex
+	uploads, titles := []string{}, []string{}
+	failed := false
+
+	router := NewRouter()
+	Handle(router, func(ctx context.Context, e *contracts.EventImageUpload) error {
+		mu.Lock()
+		defer mu.Unlock()
+		uploads = append(uploads, e.URL)
+		return nil
+	})
+	Handle(router, func(ctx context.Context, e *renamedEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// Fail once to check it's retried.
+		if !failed {
+			failed = true
+			return errors.New("database is down")
+		}
+		titles = append(titles, e.Title)
+		return nil
+	})
+	assert.Equal(t, []string{"imageUpload", "renamed"}, router.Events())
+	assert.Panics(t, func() {
+		Handle(router, func(ctx context.Context, e *renamedEvent) error { return nil })
+	})
+
+	calls := 0
+	router.Use(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, event Event) error {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			return next(ctx, event)
+		}
+	})
+
+	errs, err := router.Listen(broker.Listener("image"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	go func() {
+		for range errs {
+		}
+	}()
+
+	emitter := broker.Emitter("test")
+	assert.NoError(t, emitter.Emit(&contracts.EventImageUpload{URL: "poster.jpg"}))
+	assert.NoError(t, emitter.Emit(&renamedEvent{Title: "Alien"}))
+	assert.NoError(t, emitter.Emit(&contracts.EventMovieDeleted{})) // Not bound
+	broker.Wait()
+
+	assert.Equal(t, []string{"poster.jpg"}, uploads)
+	assert.Equal(t, []string{"Alien"}, titles)
+	assert.Equal(t, 3, calls)
+	assert.Empty(t, broker.DeadLetters("image"))
+
+	// Events without a handler are dead-lettered.
+	assert.Error(t, router.HandleEvent(context.Background(), &contracts.EventMovieDeleted{}))
+	_, err = NewRouter().Listen(broker.Listener("empty"))
+	assert.Error(t, err)
+}
+
+func TestConsumeConcurrently(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+
+	var mu sync.Mutex
+	running, max := 0, 0
+	handler := func(ctx context.Context, event Event) error {
+		mu.Lock()
+		running++
+		if running > max {
+			max = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+
+	errs, err := broker.Listener("api").ConsumeConcurrently(handler, 3, "movieCreated")
+	if !assert.NoError(t, err) {
+		return
+	}
+	go func() {
+		for range errs {
+		}
+	}()
+
+	emitter := broker.Emitter("test")
+	for i := 0; i < 12; i++ {
+		assert.NoError(t, emitter.Emit(&contracts.EventMovieCreated{ID: "id"}))
+	}
+	broker.Wait()
+
+	assert.True(t, max > 1, "events weren't handled concurrently")
+	assert.True(t, max <= 3, "more than 3 events were handled at the same time")
+}
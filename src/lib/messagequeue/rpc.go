@@ -0,0 +1,306 @@
+package messagequeue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Statuses of a Reply.
+const (
+	ReplyProgress  = "progress"  // The handler reported its progress
+	ReplyRetrying  = "retrying"  // The handler failed and will run again
+	ReplySucceeded = "succeeded" // The handler succeeded, final
+	ReplyFailed    = "failed"    // The request was dead-lettered, final
+)
+
+// Reply is sent back to the service that emitted a request, see Requester.
+//
+// Listeners reply automatically once the handler of a request succeeds or
+// fails. Handlers can add a result with SetResult and send progress updates
+// with ReportProgress.
+type Reply struct {
+	RequestID string          `json:"request_id"`         // Envelope ID of the request
+	Source    string          `json:"source"`             // Queue of the listener that handled it, e.g.: Scraper
+	Status    string          `json:"status"`             // One of the Reply statuses
+	Progress  float64         `json:"progress,omitempty"` // From 0 to 1
+	Message   string          `json:"message,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// Final reports whether the reply is the last one of its request.
+func (r Reply) Final() bool {
+	return r.Status == ReplySucceeded || r.Status == ReplyFailed
+}
+
+// Requester emits events expecting replies from their handlers, e.g.:
+// commands whose result the emitter wants to know.
+type Requester interface {
+	// Request emits event asking the listener handling it to reply. Replies
+	// arriving after ctx is done are dropped, so it should have a deadline
+	// unless the call is always waited for.
+	Request(ctx context.Context, event Event) (*Call, error)
+}
+
+// Call collects the replies to a request.
+type Call struct {
+	ID string // Envelope ID of the request
+
+	mu      sync.Mutex
+	replies []Reply
+	done    chan struct{}
+}
+
+func newCall(id string) *Call {
+	return &Call{ID: id, done: make(chan struct{})}
+}
+
+// Replies returns the replies received so far, e.g.: to poll for progress.
+func (c *Call) Replies() []Reply {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Reply{}, c.replies...)
+}
+
+// Last returns the latest reply, if any.
+func (c *Call) Last() (Reply, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.replies) == 0 {
+		return Reply{}, false
+	}
+	return c.replies[len(c.replies)-1], true
+}
+
+// Done returns a channel closed once the final reply is received.
+func (c *Call) Done() <-chan struct{} {
+	return c.done
+}
+
+// Wait blocks until the final reply is received or ctx is done.
+func (c *Call) Wait(ctx context.Context) (Reply, error) {
+	select {
+	case <-c.done:
+		r, _ := c.Last()
+		return r, nil
+	case <-ctx.Done():
+		return Reply{}, ctx.Err()
+	}
+}
+
+// add records a reply. Replies after the final one, e.g.: from a second
+// listener bound to the same event, are dropped.
+func (c *Call) add(r Reply) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+		return true
+	default:
+	}
+	c.replies = append(c.replies, r)
+	if r.Final() {
+		close(c.done)
+	}
+	return r.Final()
+}
+
+// requester publishes requests and routes the replies arriving at its reply
+// queue to their calls.
+type requester struct {
+	publisher Publisher
+	source    string
+	replyTo   string
+
+	mu    sync.Mutex
+	calls map[string]*Call
+}
+
+func newRequester(publisher Publisher, source, replyTo string) *requester {
+	return &requester{
+		publisher: publisher,
+		source:    source,
+		replyTo:   replyTo,
+		calls:     make(map[string]*Call),
+	}
+}
+
+func (r *requester) Request(ctx context.Context, event Event) (*Call, error) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("could not JSON-serialize event: %s", err)
+	}
+	if err = ValidatePayload(event.EventName(), b); err != nil {
+		return nil, err
+	}
+
+	e := NewEnvelope(ctx, r.source, event)
+	e.ReplyTo = r.replyTo
+
+	call := newCall(e.ID)
+	r.mu.Lock()
+	r.calls[call.ID] = call
+	r.mu.Unlock()
+
+	err = r.publisher.Publish(e, b)
+	if err != nil {
+		r.forget(call.ID)
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			r.forget(call.ID)
+		}
+	}()
+	return call, nil
+}
+
+// deliver adds a reply to its call.
+func (r *requester) deliver(reply Reply) {
+	r.mu.Lock()
+	call, ok := r.calls[reply.RequestID]
+	r.mu.Unlock()
+	if ok && call.add(reply) {
+		r.forget(call.ID)
+	}
+}
+
+func (r *requester) forget(id string) {
+	r.mu.Lock()
+	delete(r.calls, id)
+	r.mu.Unlock()
+}
+
+type replyKey struct{}
+
+// replyState holds the replies of the request being handled.
+type replyState struct {
+	request Envelope
+	source  string
+	send    func(replyTo string, r Reply) error
+
+	mu      sync.Mutex
+	result  json.RawMessage
+	ignored bool
+}
+
+// newReplyState returns the reply state of e, or nil if it isn't a request.
+func newReplyState(e Envelope, source string, send func(replyTo string, r Reply) error) *replyState {
+	if e.ReplyTo == "" {
+		return nil
+	}
+	return &replyState{request: e, source: source, send: send}
+}
+
+// withReplies returns a copy of ctx used by the handler of a request to
+// reply. It's ctx itself if s is nil.
+func withReplies(ctx context.Context, s *replyState) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, replyKey{}, s)
+}
+
+func repliesFromContext(ctx context.Context) *replyState {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(replyKey{}).(*replyState)
+	return s
+}
+
+// ReportProgress sends a progress update to the emitter of the request being
+// handled, if it's one. progress goes from 0 to 1.
+func ReportProgress(ctx context.Context, progress float64, message string) error {
+	return repliesFromContext(ctx).reply(Reply{
+		Status:   ReplyProgress,
+		Progress: progress,
+		Message:  message,
+	})
+}
+
+// SetResult sets the result sent to the emitter of the request being handled
+// once the handler succeeds.
+func SetResult(ctx context.Context, result interface{}) error {
+	s := repliesFromContext(ctx)
+	if s == nil {
+		return nil
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("could not JSON-serialize result: %s", err)
+	}
+	s.mu.Lock()
+	s.result = b
+	s.mu.Unlock()
+	return nil
+}
+
+// Ignore stops the listener from replying to the request being handled. It's
+// meant for events listened to by several services where only one of them
+// acts on each event, e.g.: commandDispatched.
+func Ignore(ctx context.Context) {
+	if s := repliesFromContext(ctx); s != nil {
+		s.mu.Lock()
+		s.ignored = true
+		s.mu.Unlock()
+	}
+}
+
+// succeeded sends the final reply of a request handled successfully.
+func (s *replyState) succeeded() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	result := s.result
+	s.mu.Unlock()
+	s.replyOrLog(Reply{Status: ReplySucceeded, Result: result})
+}
+
+// failed sends the final reply of a dead-lettered request.
+func (s *replyState) failed(err error) {
+	s.replyOrLog(Reply{Status: ReplyFailed, Error: err.Error()})
+}
+
+// retrying tells the emitter of a request that it failed and will be retried.
+func (s *replyState) retrying(err error) {
+	s.replyOrLog(Reply{Status: ReplyRetrying, Error: err.Error()})
+}
+
+func (s *replyState) reply(r Reply) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	ignored := s.ignored
+	s.mu.Unlock()
+	if ignored {
+		return nil
+	}
+
+	r.RequestID = s.request.ID
+	r.Source = s.source
+	r.Timestamp = time.Now().UTC()
+	err := s.send(s.request.ReplyTo, r)
+	if err != nil {
+		return fmt.Errorf("could not reply to request %s: %s", s.request.ID, err)
+	}
+	return nil
+}
+
+// replyOrLog sends a reply the listener can't do anything about if it fails,
+// so it's only logged.
+func (s *replyState) replyOrLog(r Reply) {
+	if err := s.reply(r); err != nil {
+		log.Print(err)
+	}
+}
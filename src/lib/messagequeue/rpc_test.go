@@ -0,0 +1,88 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestReply(t *testing.T) {
+	broker := NewMemoryBrokerWithRetry(RetryPolicy{MaxRetries: 1, InitialDelay: time.Millisecond})
+	defer broker.Close()
+
+	consume(t, broker.Listener("Scraper"), func(ctx context.Context, event Event) error {
+		e := event.(*contracts.EventCommandDispatched)
+		switch e.Type {
+		case "start_scraper":
+			assert.NoError(t, ReportProgress(ctx, 0.5, "half way"))
+			return SetResult(ctx, map[string]int{"queued": 3})
+		case "check_integrity":
+			return errors.New("database is down")
+		}
+		Ignore(ctx)
+		return nil
+	}, "commandDispatched")
+	// Another service listening to the same event.
+	consume(t, broker.Listener("Notification"), func(ctx context.Context, event Event) error {
+		Ignore(ctx)
+		return nil
+	}, "commandDispatched")
+
+	requester := broker.Requester("Admin")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	call, err := requester.Request(ctx, &contracts.EventCommandDispatched{Type: "start_scraper"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	reply, err := call.Wait(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, ReplySucceeded, reply.Status)
+	assert.Equal(t, "Scraper", reply.Source)
+	assert.Equal(t, call.ID, reply.RequestID)
+	assert.JSONEq(t, `{"queued":3}`, string(reply.Result))
+
+	replies := call.Replies()
+	if assert.Len(t, replies, 2) {
+		assert.Equal(t, ReplyProgress, replies[0].Status)
+		assert.Equal(t, 0.5, replies[0].Progress)
+		assert.Equal(t, "half way", replies[0].Message)
+	}
+
+	// Every failure is reported.
+	call, err = requester.Request(ctx, &contracts.EventCommandDispatched{Type: "check_integrity"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	reply, err = call.Wait(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, ReplyFailed, reply.Status)
+	assert.Contains(t, reply.Error, "database is down")
+	statuses := []string{}
+	for _, r := range call.Replies() {
+		statuses = append(statuses, r.Status)
+	}
+	assert.Equal(t, []string{ReplyRetrying, ReplyFailed}, statuses)
+
+	// Requests nobody handles are never answered.
+	call, err = requester.Request(ctx, &contracts.EventCommandDispatched{Type: "unknown"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	broker.Wait()
+	_, ok := call.Last()
+	assert.False(t, ok)
+	short, cancelShort := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelShort()
+	_, err = call.Wait(short)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// Events emitted as usual get no replies.
+	assert.NoError(t, broker.Emitter("Admin").Emit(&contracts.EventCommandDispatched{Type: "start_scraper"}))
+	broker.Wait()
+}
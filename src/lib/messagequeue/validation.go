@@ -0,0 +1,26 @@
+package messagequeue
+
+import (
+	"fmt"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+)
+
+// ValidatePayloads makes emitters refuse, and listeners dead-letter, events
+// whose payload doesn't match their schema in contracts.Catalog. Validating
+// has a cost, so services only enable it outside of production to catch
+// contract bugs before they're deployed.
+var ValidatePayloads = false
+
+// ValidatePayload checks the serialized event with the given name against its
+// schema if ValidatePayloads is set.
+func ValidatePayload(name string, payload []byte) error {
+	if !ValidatePayloads {
+		return nil
+	}
+	err := contracts.Validate(name, payload)
+	if err != nil {
+		return fmt.Errorf("event %s doesn't match its schema: %s", name, err)
+	}
+	return nil
+}
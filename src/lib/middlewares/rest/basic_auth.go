@@ -5,10 +5,8 @@ import (
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
 	"github.com/dsbezerra/amenic/src/lib/util/apiutil"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 // Credentials ...
@@ -78,8 +76,7 @@ func getAPIKey(data persistence.DataAccessLayer, key string) (*models.APIKey, er
 		return &c, nil
 	}
 
-	opts := &mongolayer.QueryOptions{Conditions: bson.M{"key": key}}
-	result, err := data.FindAPIKey(opts)
+	result, err := data.FindAPIKey(data.DefaultQuery().AddCondition("key", key))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,66 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// Emitter is a messagequeue.EventEmitter storing events in the outbox, from
+// where a Relay publishes them. Events emitted with the Emitter returned by
+// Tx are only published if the transaction commits.
+type Emitter struct {
+	Data   persistence.DataAccessLayer
+	Source string // Name of the emitting service
+}
+
+// NewEmitter ...
+func NewEmitter(data persistence.DataAccessLayer, source string) *Emitter {
+	return &Emitter{Data: data, Source: source}
+}
+
+// Tx returns an Emitter storing events in the given transaction.
+func (e *Emitter) Tx(tx persistence.DataAccessLayer) *Emitter {
+	return &Emitter{Data: tx, Source: e.Source}
+}
+
+// Emit ...
+func (e *Emitter) Emit(event messagequeue.Event) error {
+	return e.EmitContext(context.Background(), event)
+}
+
+// EmitContext ...
+func (e *Emitter) EmitContext(ctx context.Context, event messagequeue.Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not JSON-serialize event: %s", err)
+	}
+	if err = messagequeue.ValidatePayload(event.EventName(), b); err != nil {
+		return err
+	}
+
+	envelope := messagequeue.NewEnvelope(ctx, e.Source, event)
+	return e.Data.InsertOutboxEvent(models.OutboxEvent{
+		EventID:       envelope.ID,
+		Name:          envelope.Name,
+		Source:        envelope.Source,
+		CorrelationID: envelope.CorrelationID,
+		SchemaVersion: envelope.SchemaVersion,
+		Timestamp:     envelope.Timestamp,
+		Payload:       string(b),
+	})
+}
+
+// InTx returns an emitter storing events in the given transaction if emitter
+// is an Emitter. Other emitters are returned as they are and publish events
+// right away, even if the transaction is rolled back later.
+func InTx(emitter messagequeue.EventEmitter, tx persistence.DataAccessLayer) messagequeue.EventEmitter {
+	if e, ok := emitter.(*Emitter); ok {
+		return e.Tx(tx)
+	}
+	return emitter
+}
@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingPublisher fails after publishing a number of events.
+type failingPublisher struct {
+	next      messagequeue.Publisher
+	remaining int
+}
+
+func (p *failingPublisher) Publish(e messagequeue.Envelope, payload []byte) error {
+	if p.remaining == 0 {
+		return errors.New("broker is down")
+	}
+	p.remaining--
+	return p.next.Publish(e, payload)
+}
+
+func TestOutbox(t *testing.T) {
+	data, err := memlayer.NewMemDAL()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broker := messagequeue.NewMemoryBroker()
+	defer broker.Close()
+
+	handled := make(chan messagequeue.Envelope, 10)
+	errs, err := broker.Listener("api").Consume(func(ctx context.Context, event messagequeue.Event) error {
+		e, _ := messagequeue.EnvelopeFromContext(ctx)
+		handled <- e
+		return nil
+	}, "scraperFinished")
+	if !assert.NoError(t, err) {
+		return
+	}
+	go func() {
+		for range errs {
+		}
+	}()
+
+	emitter := NewEmitter(data, "Scraper")
+
+	// Events of rolled back transactions are never published.
+	err = data.WithTransaction(func(tx persistence.DataAccessLayer) error {
+		assert.NoError(t, emitter.Tx(tx).Emit(&contracts.EventScraperFinished{ScraperID: "rolled back"}))
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+
+	for _, id := range []string{"first", "second", "third"} {
+		err = data.WithTransaction(func(tx persistence.DataAccessLayer) error {
+			return InTx(emitter, tx).Emit(&contracts.EventScraperFinished{ScraperID: id})
+		})
+		assert.NoError(t, err)
+	}
+
+	// Events emitted by other services are left to their relays.
+	sent, err := NewRelay(data, broker.Publisher(), "API", logrus.WithField("App", "API")).Flush()
+	assert.NoError(t, err)
+	assert.Zero(t, sent)
+
+	publisher := &failingPublisher{next: broker.Publisher(), remaining: 1}
+	relay := NewRelay(data, publisher, "Scraper", logrus.WithField("App", "Scraper"))
+
+	// Events after a failed one wait for it.
+	sent, err = relay.Flush()
+	assert.EqualError(t, err, "broker is down")
+	assert.Equal(t, 1, sent)
+	pending, err := data.GetPendingOutboxEvents("Scraper", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 2) {
+		assert.Contains(t, pending[0].Payload, "second")
+		assert.Equal(t, 1, pending[0].Attempts)
+		assert.Equal(t, "broker is down", pending[0].LastError)
+		assert.Zero(t, pending[1].Attempts)
+	}
+
+	publisher.remaining = -1
+	sent, err = relay.Flush()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	pending, err = data.GetPendingOutboxEvents("Scraper", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	sent, err = relay.Flush()
+	assert.NoError(t, err)
+	assert.Zero(t, sent)
+
+	broker.Wait()
+	assert.Len(t, handled, 3)
+	first := <-handled
+	assert.Equal(t, "Scraper", first.Source)
+	assert.Equal(t, "scraperFinished", first.Name)
+	assert.NotEmpty(t, first.ID)
+}
+
+// rejectingPublisher fails the events whose payload contains bad.
+type rejectingPublisher struct {
+	next messagequeue.Publisher
+	bad  string
+}
+
+func (p *rejectingPublisher) Publish(e messagequeue.Envelope, payload []byte) error {
+	if strings.Contains(string(payload), p.bad) {
+		return errors.New("event rejected")
+	}
+	return p.next.Publish(e, payload)
+}
+
+func TestRelayMaxAttempts(t *testing.T) {
+	data, err := memlayer.NewMemDAL()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broker := messagequeue.NewMemoryBroker()
+	defer broker.Close()
+
+	emitter := NewEmitter(data, "Scraper")
+	for _, id := range []string{"bad", "good"} {
+		assert.NoError(t, emitter.Emit(&contracts.EventScraperFinished{ScraperID: id}))
+	}
+
+	relay := NewRelay(data, &rejectingPublisher{next: broker.Publisher(), bad: "bad"}, "Scraper", logrus.WithField("App", "Scraper"))
+	relay.MaxAttempts = 2
+
+	sent, err := relay.Flush()
+	assert.EqualError(t, err, "event rejected")
+	assert.Zero(t, sent)
+
+	// Once it fails MaxAttempts times the following events are relayed.
+	sent, err = relay.Flush()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	pending, err := data.GetPendingOutboxEvents("Scraper", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
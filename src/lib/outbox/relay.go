@@ -0,0 +1,146 @@
+package outbox
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultInterval is how often a Relay looks for pending events.
+	DefaultInterval = time.Second
+
+	// DefaultBatchSize is how many events a Relay loads at once.
+	DefaultBatchSize = 100
+
+	// DefaultMaxAttempts is how many times a Relay tries to publish an event
+	// before giving up on it.
+	DefaultMaxAttempts = 10
+)
+
+// Relay publishes the events stored in the outbox by the Source service in
+// the order they were emitted, and marks them as sent once the broker
+// confirms them.
+//
+// Services share the outbox, so each one runs a relay of its own events and
+// no event is published by two relays. An event is still published again if
+// marking it fails, so consumers should skip duplicates using the event ID,
+// see messagequeue.Idempotent.
+//
+// An event that fails MaxAttempts times is marked as failed and skipped, so
+// it doesn't hold back the following ones. Zero never gives up.
+type Relay struct {
+	Data        persistence.DataAccessLayer
+	Publisher   messagequeue.Publisher
+	Source      string
+	Log         *logrus.Entry
+	Interval    time.Duration
+	BatchSize   int64
+	MaxAttempts int
+}
+
+// NewRelay ...
+func NewRelay(data persistence.DataAccessLayer, publisher messagequeue.Publisher, source string, log *logrus.Entry) *Relay {
+	return &Relay{
+		Data:        data,
+		Publisher:   publisher,
+		Source:      source,
+		Log:         log,
+		Interval:    DefaultInterval,
+		BatchSize:   DefaultBatchSize,
+		MaxAttempts: DefaultMaxAttempts,
+	}
+}
+
+// Run publishes pending events every Interval. It never returns.
+func (r *Relay) Run() {
+	for {
+		sent, err := r.Flush()
+		if err != nil {
+			r.Log.Errorf("outbox relay stopped after %d events: %s", sent, err)
+		}
+		time.Sleep(r.Interval)
+	}
+}
+
+// Flush publishes pending events until none is left or one fails, so later
+// events aren't published before it, unless it failed MaxAttempts times. It
+// returns how many events were sent.
+func (r *Relay) Flush() (int, error) {
+	sent := 0
+	for {
+		events, err := r.Data.GetPendingOutboxEvents(r.Source, r.BatchSize)
+		if err != nil {
+			return sent, err
+		}
+		if len(events) == 0 {
+			return sent, nil
+		}
+
+		for _, e := range events {
+			failed, err := r.publish(e)
+			if failed {
+				r.Log.Errorf("outbox event %s failed %d times, skipping it: %s", e.EventID, e.Attempts+1, err)
+				continue
+			}
+			if err != nil {
+				return sent, err
+			}
+			sent++
+		}
+	}
+}
+
+// publish publishes a single event and records the result. It reports
+// whether the event was marked as failed.
+func (r *Relay) publish(e models.OutboxEvent) (bool, error) {
+	envelope := messagequeue.Envelope{
+		ID:            e.EventID,
+		Name:          e.Name,
+		Source:        e.Source,
+		Timestamp:     e.Timestamp,
+		CorrelationID: e.CorrelationID,
+		SchemaVersion: e.SchemaVersion,
+	}
+
+	err := r.Publisher.Publish(envelope, []byte(e.Payload))
+	if err != nil {
+		update := persistence.Update{
+			Set: map[string]interface{}{
+				"attempts":  e.Attempts + 1,
+				"lastError": err.Error(),
+			},
+		}
+		failed := r.MaxAttempts > 0 && e.Attempts+1 >= r.MaxAttempts
+		if failed {
+			update.Set["failedAt"] = time.Now().UTC()
+		}
+		n, uerr := r.Data.UpdateOutboxEvent(e.ID.Hex(), update)
+		if uerr == nil && n == 0 {
+			uerr = fmt.Errorf("event not found")
+		}
+		if uerr != nil {
+			// Unless it's marked it would be loaded again right away.
+			r.Log.Errorf("couldn't record failure of outbox event %s: %s", e.EventID, uerr)
+			return false, err
+		}
+		return failed, err
+	}
+
+	now := time.Now().UTC()
+	n, err := r.Data.UpdateOutboxEvent(e.ID.Hex(), persistence.Update{
+		Set: map[string]interface{}{
+			"sentAt":    now,
+			"expiresAt": now.Add(models.OutboxRetention),
+		},
+	})
+	if err == nil && n == 0 {
+		// It would be loaded and published again forever.
+		err = fmt.Errorf("couldn't mark outbox event %s as sent", e.EventID)
+	}
+	return false, err
+}
@@ -0,0 +1,126 @@
+// Package bsonutil converts persistence conditions and updates to the BSON
+// documents shared by the backends built on MongoDB queries.
+package bsonutil
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ConditionToBSON converts a persistence.Condition to its MongoDB filter,
+// which memlayer evaluates too.
+func ConditionToBSON(condition persistence.Condition) bson.M {
+	switch c := condition.(type) {
+	case persistence.EqCondition:
+		return bson.M{c.Field: c.Value}
+
+	case persistence.InCondition:
+		return bson.M{c.Field: bson.M{"$in": c.Values}}
+
+	case persistence.NotInCondition:
+		return bson.M{c.Field: bson.M{"$nin": c.Values}}
+
+	case persistence.RangeCondition:
+		r := bson.M{}
+		if c.From != nil {
+			r["$gte"] = c.From
+		}
+		if c.To != nil {
+			r["$lte"] = c.To
+		}
+		if len(r) == 0 {
+			r["$exists"] = true
+		}
+		return bson.M{c.Field: r}
+
+	case persistence.TextCondition:
+		return bson.M{"$text": bson.M{"$search": c.Search}}
+
+	case persistence.PrefixCondition:
+		return bson.M{c.Field: bson.M{"$regex": "^" + regexp.QuoteMeta(c.Prefix)}}
+
+	case persistence.NearCondition:
+		center := bson.A{c.Point.Lng(), c.Point.Lat()}
+		return bson.M{c.Field: bson.M{"$geoWithin": bson.M{
+			"$centerSphere": bson.A{center, c.Radius / models.EarthRadius},
+		}}}
+
+	case persistence.OrCondition:
+		return bson.M{"$or": conditionsToBSON(c.Conditions)}
+
+	case persistence.AndCondition:
+		return bson.M{"$and": conditionsToBSON(c.Conditions)}
+	}
+
+	panic(fmt.Sprintf("bsonutil: unsupported condition %T", condition))
+}
+
+func conditionsToBSON(conditions []persistence.Condition) []bson.M {
+	result := make([]bson.M, len(conditions))
+	for i, c := range conditions {
+		result[i] = ConditionToBSON(c)
+	}
+	return result
+}
+
+// MergeConditions adds the filter keys to conditions. Keys already present
+// are moved to an $and clause so neither of them is lost.
+func MergeConditions(conditions bson.M, filter bson.M) {
+	for k, v := range filter {
+		existing, ok := conditions[k]
+		if !ok {
+			conditions[k] = v
+			continue
+		}
+
+		var and []interface{}
+		if k == "$and" {
+			and = AppendClauses(AppendClauses(and, existing), v)
+		} else {
+			delete(conditions, k)
+			and = AppendClauses(and, conditions["$and"])
+			and = append(and, bson.M{k: existing}, bson.M{k: v})
+		}
+		conditions["$and"] = and
+	}
+}
+
+// AppendClauses appends the clauses of an $and or $or value to clauses.
+func AppendClauses(clauses []interface{}, value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+	case []interface{}:
+		clauses = append(clauses, v...)
+	case []bson.M:
+		for _, c := range v {
+			clauses = append(clauses, c)
+		}
+	case []bson.D:
+		for _, c := range v {
+			clauses = append(clauses, c)
+		}
+	default:
+		clauses = append(clauses, v)
+	}
+	return clauses
+}
+
+// UpdateToBSON converts a persistence.Update to its MongoDB update document.
+func UpdateToBSON(update persistence.Update) bson.M {
+	result := bson.M{}
+	if len(update.Set) > 0 {
+		result["$set"] = bson.M(update.Set)
+	}
+	if len(update.Unset) > 0 {
+		unset := bson.M{}
+		for _, f := range update.Unset {
+			unset[f] = ""
+		}
+		result["$unset"] = unset
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+package bsonutil
+
+import (
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestConditionToBSON(t *testing.T) {
+	assert.Equal(t, bson.M{"slug": "a"}, ConditionToBSON(persistence.Eq("slug", "a")))
+	assert.Equal(t, bson.M{"slug": bson.M{"$in": []interface{}{"a", "b"}}},
+		ConditionToBSON(persistence.In("slug", "a", "b")))
+	assert.Equal(t, bson.M{"slug": bson.M{"$nin": []interface{}{"a", "b"}}},
+		ConditionToBSON(persistence.NotIn("slug", "a", "b")))
+	assert.Equal(t, bson.M{"rating": bson.M{"$gte": 10, "$lte": 16}},
+		ConditionToBSON(persistence.Range("rating", 10, 16)))
+	assert.Equal(t, bson.M{"rating": bson.M{"$gte": 10}},
+		ConditionToBSON(persistence.Range("rating", 10, nil)))
+	assert.Equal(t, bson.M{"$text": bson.M{"$search": "title"}},
+		ConditionToBSON(persistence.Text("title")))
+	assert.Equal(t, bson.M{"searchKeys": bson.M{"$regex": "^a\\.b"}},
+		ConditionToBSON(persistence.Prefix("searchKeys", "a.b")))
+	assert.Equal(t, bson.M{"$or": []bson.M{{"slug": "a"}, {"rating": 10}}},
+		ConditionToBSON(persistence.Or(persistence.Eq("slug", "a"), persistence.Eq("rating", 10))))
+}
+
+func TestMergeConditions(t *testing.T) {
+	conditions := bson.M{"hidden": false}
+	MergeConditions(conditions, bson.M{"hidden": true})
+	assert.Equal(t, bson.M{"$and": []interface{}{bson.M{"hidden": false}, bson.M{"hidden": true}}}, conditions)
+}
+
+func TestUpdateToBSON(t *testing.T) {
+	assert.Equal(t, bson.M{
+		"$set":   bson.M{"poster": "url"},
+		"$unset": bson.M{"backdrop": ""},
+	}, UpdateToBSON(persistence.Update{
+		Set:   map[string]interface{}{"poster": "url"},
+		Unset: []string{"backdrop"},
+	}))
+}
@@ -0,0 +1,200 @@
+package cachelayer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+)
+
+const (
+	// DefaultTTL is how long results are kept when no TTL is given.
+	DefaultTTL = 5 * time.Minute
+
+	// DefaultMaxEntries is the number of results kept when no limit is given.
+	DefaultMaxEntries = 10000
+)
+
+// InvalidatingEvents are the events after which cached results may be
+// outdated.
+var InvalidatingEvents = []string{
+	"scraperFinished", // Sessions, prices and now playing movies were updated
+	"movieCreated",
+	"movieDeleted",
+}
+
+type (
+	// CacheDAL is a persistence.DataAccessLayer that keeps the results of
+	// reads made through another DataAccessLayer for a while.
+	//
+	// Only movies, sessions, theaters, prices, cities and states are cached.
+	// Results are keyed by method and query and are dropped when the TTL
+	// expires, when any of them is changed through CacheDAL or when one of
+	// InvalidatingEvents is handled. Changes made by other services and not
+	// announced by events are only seen after the TTL.
+	//
+	// Cached results are shared, so only the returned slice or struct is
+	// copied. Values referenced by them must not be modified.
+	CacheDAL struct {
+		persistence.DataAccessLayer
+
+		ttl        time.Duration
+		maxEntries int
+
+		mu         sync.Mutex
+		entries    map[string]entry
+		generation uint64
+
+		hits          uint64
+		misses        uint64
+		invalidations uint64
+	}
+
+	// Options configures a CacheDAL.
+	Options struct {
+		// TTL is how long a result is kept. Defaults to DefaultTTL.
+		TTL time.Duration
+		// MaxEntries is the maximum number of results kept. Defaults to
+		// DefaultMaxEntries.
+		MaxEntries int
+	}
+
+	// Stats reports how the cache is being used.
+	Stats struct {
+		Hits          uint64  `json:"hits"`
+		Misses        uint64  `json:"misses"`
+		HitRate       float64 `json:"hitRate"`
+		Entries       int     `json:"entries"`
+		Invalidations uint64  `json:"invalidations"`
+	}
+
+	entry struct {
+		value   interface{}
+		expires time.Time
+	}
+)
+
+// NewCacheDAL wraps data with a read-through cache.
+func NewCacheDAL(data persistence.DataAccessLayer, opts Options) *CacheDAL {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultMaxEntries
+	}
+	return &CacheDAL{
+		DataAccessLayer: data,
+		ttl:             opts.TTL,
+		maxEntries:      opts.MaxEntries,
+		entries:         make(map[string]entry),
+	}
+}
+
+// Invalidate drops every cached result.
+func (c *CacheDAL) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]entry)
+	c.generation++
+	atomic.AddUint64(&c.invalidations, 1)
+}
+
+// HandleEvent drops every cached result if the event is one of
+// InvalidatingEvents. It returns true if the cache was invalidated.
+func (c *CacheDAL) HandleEvent(event messagequeue.Event) bool {
+	name := event.EventName()
+	for _, e := range InvalidatingEvents {
+		if e == name {
+			c.Invalidate()
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the current cache statistics.
+func (c *CacheDAL) Stats() Stats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	result := Stats{
+		Hits:          atomic.LoadUint64(&c.hits),
+		Misses:        atomic.LoadUint64(&c.misses),
+		Entries:       entries,
+		Invalidations: atomic.LoadUint64(&c.invalidations),
+	}
+	if total := result.Hits + result.Misses; total > 0 {
+		result.HitRate = float64(result.Hits) / float64(total)
+	}
+	return result
+}
+
+// WithTransaction invalidates the cache after running fn. Reads made with tx
+// are never cached.
+func (c *CacheDAL) WithTransaction(fn func(tx persistence.DataAccessLayer) error) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.WithTransaction(fn)
+}
+
+// get returns the cached result for key, calling load and keeping its result
+// if there is none. Errors are never cached.
+func (c *CacheDAL) get(key string, load func() (interface{}, error)) (interface{}, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	generation := c.generation
+	c.mu.Unlock()
+
+	if ok && now.Before(e.expires) {
+		atomic.AddUint64(&c.hits, 1)
+		return e.value, nil
+	}
+	atomic.AddUint64(&c.misses, 1)
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Results loaded before an invalidation may already be outdated.
+	if generation == c.generation {
+		if len(c.entries) >= c.maxEntries {
+			c.evict(now)
+		}
+		c.entries[key] = entry{value: value, expires: now.Add(c.ttl)}
+	}
+	return value, nil
+}
+
+// evict drops expired results or, if none expired, the result closest to
+// expiration. Must be called with mu held.
+func (c *CacheDAL) evict(now time.Time) {
+	var oldest string
+	var oldestExpires time.Time
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+			continue
+		}
+		if oldest == "" || e.expires.Before(oldestExpires) {
+			oldest, oldestExpires = k, e.expires
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, oldest)
+	}
+}
+
+func (c *CacheDAL) count(key string, load func() (int64, error)) (int64, error) {
+	value, err := c.get(key, func() (interface{}, error) { return load() })
+	if err != nil {
+		return 0, err
+	}
+	return value.(int64), nil
+}
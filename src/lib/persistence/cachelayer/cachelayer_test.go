@@ -0,0 +1,110 @@
+package cachelayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getTestingCacheDAL(t *testing.T, opts Options) (*CacheDAL, persistence.DataAccessLayer) {
+	data, err := memlayer.NewMemDAL()
+	assert.NoError(t, err)
+	assert.NoError(t, data.Setup())
+	return NewCacheDAL(data, opts), data
+}
+
+func TestCacheDAL(t *testing.T) {
+	cache, data := getTestingCacheDAL(t, Options{})
+
+	movie := models.Movie{ID: primitive.NewObjectID(), Title: "Bacurau"}
+	assert.NoError(t, cache.InsertMovie(movie))
+
+	get := func() *models.Movie {
+		result, err := cache.GetMovie(movie.ID.Hex(), cache.DefaultQuery())
+		assert.NoError(t, err)
+		return result
+	}
+
+	assert.Equal(t, "Bacurau", get().Title)
+	assert.Equal(t, "Bacurau", get().Title)
+	stats := cache.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 0.5, stats.HitRate)
+
+	// Returned values are copies
+	get().Title = "Changed"
+	assert.Equal(t, "Bacurau", get().Title)
+
+	// Changes made elsewhere are not seen until invalidation
+	_, err := data.UpdateMovie(movie.ID.Hex(), models.Movie{ID: movie.ID, Title: "Aquarius"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bacurau", get().Title)
+
+	assert.False(t, cache.HandleEvent(&contracts.EventStaticDispatched{}))
+	assert.Equal(t, "Bacurau", get().Title)
+	assert.True(t, cache.HandleEvent(&contracts.EventScraperFinished{}))
+	assert.Equal(t, "Aquarius", get().Title)
+
+	// Changes made through the cache invalidate it
+	_, err = cache.UpdateMovie(movie.ID.Hex(), models.Movie{ID: movie.ID, Title: "Bacurau"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bacurau", get().Title)
+
+	// Errors are not cached
+	assert.NoError(t, cache.DeleteMovie(movie.ID.Hex()))
+	_, err = cache.GetMovie(movie.ID.Hex(), cache.DefaultQuery())
+	assert.Equal(t, persistence.ErrNotFound, err)
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestCacheDALExpiration(t *testing.T) {
+	cache, data := getTestingCacheDAL(t, Options{TTL: 10 * time.Millisecond, MaxEntries: 2})
+
+	count := func(state string) int {
+		cities, err := cache.GetCities(cache.BuildCityQuery(map[string]string{"state": state}))
+		assert.NoError(t, err)
+		return len(cities)
+	}
+
+	assert.Equal(t, 0, count(models.MG))
+	assert.NoError(t, data.InsertCity(models.City{ID: primitive.NewObjectID(), Name: "Montes Claros", StateID: models.MG}))
+	assert.Equal(t, 0, count(models.MG))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, count(models.MG))
+
+	// Oldest entries are evicted when full
+	count(models.SP)
+	count(models.RJ)
+	assert.Equal(t, 2, cache.Stats().Entries)
+}
+
+func TestQueryKey(t *testing.T) {
+	id := primitive.NewObjectID()
+	now := time.Now()
+	later := now.Add(time.Hour)
+	utc := now.UTC()
+
+	a := (&memlayer.QueryOptions{}).
+		AddCondition("movieId", id).
+		AddCondition("startTime", bson.M{"$gte": &now, "$lt": later}).
+		SetSort("startTime")
+	b := (&memlayer.QueryOptions{}).
+		AddCondition("startTime", bson.M{"$lt": later, "$gte": &utc}).
+		AddCondition("movieId", id).
+		SetSort("startTime")
+	assert.Equal(t, queryKey("GetSessions", a), queryKey("GetSessions", b))
+
+	b.SetLimit(5)
+	assert.NotEqual(t, queryKey("GetSessions", a), queryKey("GetSessions", b))
+	assert.NotEqual(t, queryKey("GetSessions", a), queryKey("GetMovies", a))
+	assert.NotEqual(t, queryKey("GetMovie", "a", a), queryKey("GetMovie", "b", a))
+	assert.NotEqual(t, queryKey("Get", int32(1)), queryKey("Get", int64(1)))
+}
@@ -0,0 +1,53 @@
+package cachelayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// InsertCity ...
+func (c *CacheDAL) InsertCity(city models.City) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.InsertCity(city)
+}
+
+// GetCity ...
+func (c *CacheDAL) GetCity(id string, query persistence.Query) (*models.City, error) {
+	value, err := c.get(queryKey("GetCity", id, query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetCity(id, query)
+	})
+	if err != nil || value.(*models.City) == nil {
+		return nil, err
+	}
+	result := *value.(*models.City)
+	return &result, nil
+}
+
+// GetCities ...
+func (c *CacheDAL) GetCities(query persistence.Query) ([]models.City, error) {
+	value, err := c.get(queryKey("GetCities", query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetCities(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.City(nil), value.([]models.City)...), nil
+}
+
+// DeleteCity ...
+func (c *CacheDAL) DeleteCity(id string) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeleteCity(id)
+}
+
+// UpdateCity ...
+func (c *CacheDAL) UpdateCity(id string, city models.City) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.UpdateCity(id, city)
+}
+
+// DeleteCities ...
+func (c *CacheDAL) DeleteCities(query persistence.Query) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeleteCities(query)
+}
@@ -0,0 +1,124 @@
+package cachelayer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// queryKey builds the cache key of a call to method with the given
+// arguments. Equal queries produce the same key even if their maps were
+// built in a different order or their values are referenced by different
+// pointers.
+func queryKey(method string, args ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(method)
+	for _, arg := range args {
+		b.WriteByte('|')
+		if query, ok := arg.(persistence.Query); ok && query != nil {
+			writeKey(&b, reflect.ValueOf(query.GetConditions()))
+			b.WriteByte(';')
+			writeKey(&b, reflect.ValueOf(query.GetFields()))
+			b.WriteByte(';')
+			writeKey(&b, reflect.ValueOf(query.GetSort()))
+			fmt.Fprintf(&b, ";%d;%d;%t;", query.GetLimit(), query.GetSkip(), query.Sorting())
+			writeKey(&b, reflect.ValueOf(query.GetIncludes()))
+			continue
+		}
+		writeKey(&b, reflect.ValueOf(arg))
+	}
+	return b.String()
+}
+
+func writeKey(b *strings.Builder, v reflect.Value) {
+	if !v.IsValid() {
+		b.WriteString("nil")
+		return
+	}
+
+	if v.Type() == timeType && v.CanInterface() {
+		// Formatted because equal instants may have different wall clock
+		// and monotonic readings.
+		b.WriteString(v.Interface().(time.Time).UTC().Format(time.RFC3339Nano))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		writeKey(b, v.Elem())
+
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]reflect.Value, v.Len())
+		for _, k := range v.MapKeys() {
+			var kb strings.Builder
+			writeKey(&kb, k)
+			keys = append(keys, kb.String())
+			values[kb.String()] = v.MapIndex(k)
+		}
+		sort.Strings(keys)
+		b.WriteByte('{')
+		for _, k := range keys {
+			b.WriteString(k)
+			b.WriteByte(':')
+			writeKey(b, values[k])
+			b.WriteByte(',')
+		}
+		b.WriteByte('}')
+
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// Byte arrays, e.g.: ObjectIDs.
+			for i := 0; i < v.Len(); i++ {
+				fmt.Fprintf(b, "%02x", v.Index(i).Uint())
+			}
+			return
+		}
+		b.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			writeKey(b, v.Index(i))
+			b.WriteByte(',')
+		}
+		b.WriteByte(']')
+
+	case reflect.Struct:
+		b.WriteString(v.Type().String())
+		b.WriteByte('{')
+		for i := 0; i < v.NumField(); i++ {
+			b.WriteString(v.Type().Field(i).Name)
+			b.WriteByte(':')
+			writeKey(b, v.Field(i))
+			b.WriteByte(',')
+		}
+		b.WriteByte('}')
+
+	case reflect.String:
+		fmt.Fprintf(b, "%q", v.String())
+
+	case reflect.Bool:
+		fmt.Fprintf(b, "%t", v.Bool())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fmt.Fprintf(b, "%s(%d)", v.Type(), v.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		fmt.Fprintf(b, "%s(%d)", v.Type(), v.Uint())
+
+	case reflect.Float32, reflect.Float64:
+		fmt.Fprintf(b, "%s(%g)", v.Type(), v.Float())
+
+	default:
+		// Functions, channels and so on are compared by address.
+		fmt.Fprintf(b, "%s(%v)", v.Type(), v)
+	}
+}
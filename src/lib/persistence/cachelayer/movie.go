@@ -0,0 +1,117 @@
+package cachelayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// CountMovies ...
+func (c *CacheDAL) CountMovies(query persistence.Query) (int64, error) {
+	return c.count(queryKey("CountMovies", query), func() (int64, error) {
+		return c.DataAccessLayer.CountMovies(query)
+	})
+}
+
+// InsertMovie ...
+func (c *CacheDAL) InsertMovie(movie models.Movie) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.InsertMovie(movie)
+}
+
+// FindMovie ...
+func (c *CacheDAL) FindMovie(query persistence.Query) (*models.Movie, error) {
+	value, err := c.get(queryKey("FindMovie", query), func() (interface{}, error) {
+		return c.DataAccessLayer.FindMovie(query)
+	})
+	if err != nil || value.(*models.Movie) == nil {
+		return nil, err
+	}
+	result := *value.(*models.Movie)
+	return &result, nil
+}
+
+// FindMovieAndUpdate ...
+func (c *CacheDAL) FindMovieAndUpdate(query persistence.Query, update persistence.Update) (*models.Movie, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.FindMovieAndUpdate(query, update)
+}
+
+// GetMovie ...
+func (c *CacheDAL) GetMovie(id string, query persistence.Query) (*models.Movie, error) {
+	value, err := c.get(queryKey("GetMovie", id, query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetMovie(id, query)
+	})
+	if err != nil || value.(*models.Movie) == nil {
+		return nil, err
+	}
+	result := *value.(*models.Movie)
+	return &result, nil
+}
+
+// GetMovies ...
+func (c *CacheDAL) GetMovies(query persistence.Query) ([]models.Movie, error) {
+	value, err := c.get(queryKey("GetMovies", query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetMovies(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.Movie(nil), value.([]models.Movie)...), nil
+}
+
+// OldGetNowPlayingMovies ...
+func (c *CacheDAL) OldGetNowPlayingMovies(query persistence.Query) ([]models.Movie, error) {
+	value, err := c.get(queryKey("OldGetNowPlayingMovies", query), func() (interface{}, error) {
+		return c.DataAccessLayer.OldGetNowPlayingMovies(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.Movie(nil), value.([]models.Movie)...), nil
+}
+
+// GetNowPlayingMovies ...
+func (c *CacheDAL) GetNowPlayingMovies(query persistence.Query) ([]models.Movie, error) {
+	value, err := c.get(queryKey("GetNowPlayingMovies", query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetNowPlayingMovies(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.Movie(nil), value.([]models.Movie)...), nil
+}
+
+// GetUpcomingMovies ...
+func (c *CacheDAL) GetUpcomingMovies(query persistence.Query) ([]models.Movie, error) {
+	value, err := c.get(queryKey("GetUpcomingMovies", query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetUpcomingMovies(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.Movie(nil), value.([]models.Movie)...), nil
+}
+
+// DeleteMovie ...
+func (c *CacheDAL) DeleteMovie(id string) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeleteMovie(id)
+}
+
+// DeleteMovies ...
+func (c *CacheDAL) DeleteMovies(query persistence.Query) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeleteMovies(query)
+}
+
+// PatchMovie ...
+func (c *CacheDAL) PatchMovie(id string, version int64, update persistence.Update) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.PatchMovie(id, version, update)
+}
+
+// UpdateMovie ...
+func (c *CacheDAL) UpdateMovie(id string, m models.Movie) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.UpdateMovie(id, m)
+}
@@ -0,0 +1,53 @@
+package cachelayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// InsertPrice ...
+func (c *CacheDAL) InsertPrice(price models.Price) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.InsertPrice(price)
+}
+
+// InsertPrices ...
+func (c *CacheDAL) InsertPrices(prices ...models.Price) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.InsertPrices(prices...)
+}
+
+// GetPrice ...
+func (c *CacheDAL) GetPrice(id string, query persistence.Query) (*models.Price, error) {
+	value, err := c.get(queryKey("GetPrice", id, query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetPrice(id, query)
+	})
+	if err != nil || value.(*models.Price) == nil {
+		return nil, err
+	}
+	result := *value.(*models.Price)
+	return &result, nil
+}
+
+// GetPrices ...
+func (c *CacheDAL) GetPrices(query persistence.Query) ([]models.Price, error) {
+	value, err := c.get(queryKey("GetPrices", query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetPrices(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.Price(nil), value.([]models.Price)...), nil
+}
+
+// DeletePrice ...
+func (c *CacheDAL) DeletePrice(id string) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeletePrice(id)
+}
+
+// DeletePrices ...
+func (c *CacheDAL) DeletePrices(query persistence.Query) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeletePrices(query)
+}
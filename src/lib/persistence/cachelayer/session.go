@@ -0,0 +1,67 @@
+package cachelayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertSession ...
+func (c *CacheDAL) InsertSession(session models.Session) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.InsertSession(session)
+}
+
+// InsertSessions ...
+func (c *CacheDAL) InsertSessions(sessions ...models.Session) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.InsertSessions(sessions...)
+}
+
+// GetSession ...
+func (c *CacheDAL) GetSession(id string, query persistence.Query) (*models.Session, error) {
+	value, err := c.get(queryKey("GetSession", id, query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetSession(id, query)
+	})
+	if err != nil || value.(*models.Session) == nil {
+		return nil, err
+	}
+	result := *value.(*models.Session)
+	return &result, nil
+}
+
+// GetSessions ...
+func (c *CacheDAL) GetSessions(query persistence.Query) ([]models.Session, error) {
+	value, err := c.get(queryKey("GetSessions", query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetSessions(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.Session(nil), value.([]models.Session)...), nil
+}
+
+// DeleteSession ...
+func (c *CacheDAL) DeleteSession(id string) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeleteSession(id)
+}
+
+// SyncSessions ...
+func (c *CacheDAL) SyncSessions(theaterID primitive.ObjectID, window scheduleutil.Period, sessions []models.Session) (*persistence.SyncSummary, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.SyncSessions(theaterID, window, sessions)
+}
+
+// UpdateSessions ...
+func (c *CacheDAL) UpdateSessions(query persistence.Query, update persistence.Update) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.UpdateSessions(query, update)
+}
+
+// DeleteSessions ...
+func (c *CacheDAL) DeleteSessions(query persistence.Query) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeleteSessions(query)
+}
@@ -0,0 +1,65 @@
+package cachelayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// InsertState ...
+func (c *CacheDAL) InsertState(state models.State) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.InsertState(state)
+}
+
+// FindState ...
+func (c *CacheDAL) FindState(query persistence.Query) (*models.State, error) {
+	value, err := c.get(queryKey("FindState", query), func() (interface{}, error) {
+		return c.DataAccessLayer.FindState(query)
+	})
+	if err != nil || value.(*models.State) == nil {
+		return nil, err
+	}
+	result := *value.(*models.State)
+	return &result, nil
+}
+
+// GetState ...
+func (c *CacheDAL) GetState(id string, query persistence.Query) (*models.State, error) {
+	value, err := c.get(queryKey("GetState", id, query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetState(id, query)
+	})
+	if err != nil || value.(*models.State) == nil {
+		return nil, err
+	}
+	result := *value.(*models.State)
+	return &result, nil
+}
+
+// GetStates ...
+func (c *CacheDAL) GetStates(query persistence.Query) ([]models.State, error) {
+	value, err := c.get(queryKey("GetStates", query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetStates(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.State(nil), value.([]models.State)...), nil
+}
+
+// UpdateState ...
+func (c *CacheDAL) UpdateState(id string, s models.State) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.UpdateState(id, s)
+}
+
+// DeleteState ...
+func (c *CacheDAL) DeleteState(id string) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeleteState(id)
+}
+
+// DeleteStates ...
+func (c *CacheDAL) DeleteStates(query persistence.Query) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeleteStates(query)
+}
@@ -0,0 +1,72 @@
+package cachelayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// CountTheaters ...
+func (c *CacheDAL) CountTheaters(query persistence.Query) (int64, error) {
+	return c.count(queryKey("CountTheaters", query), func() (int64, error) {
+		return c.DataAccessLayer.CountTheaters(query)
+	})
+}
+
+// InsertTheater ...
+func (c *CacheDAL) InsertTheater(theater models.Theater) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.InsertTheater(theater)
+}
+
+// FindTheater ...
+func (c *CacheDAL) FindTheater(query persistence.Query) (*models.Theater, error) {
+	value, err := c.get(queryKey("FindTheater", query), func() (interface{}, error) {
+		return c.DataAccessLayer.FindTheater(query)
+	})
+	if err != nil || value.(*models.Theater) == nil {
+		return nil, err
+	}
+	result := *value.(*models.Theater)
+	return &result, nil
+}
+
+// GetTheater ...
+func (c *CacheDAL) GetTheater(id string, query persistence.Query) (*models.Theater, error) {
+	value, err := c.get(queryKey("GetTheater", id, query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetTheater(id, query)
+	})
+	if err != nil || value.(*models.Theater) == nil {
+		return nil, err
+	}
+	result := *value.(*models.Theater)
+	return &result, nil
+}
+
+// GetTheaters ...
+func (c *CacheDAL) GetTheaters(query persistence.Query) ([]models.Theater, error) {
+	value, err := c.get(queryKey("GetTheaters", query), func() (interface{}, error) {
+		return c.DataAccessLayer.GetTheaters(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.Theater(nil), value.([]models.Theater)...), nil
+}
+
+// DeleteTheater ...
+func (c *CacheDAL) DeleteTheater(id string) error {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeleteTheater(id)
+}
+
+// DeleteTheaters ...
+func (c *CacheDAL) DeleteTheaters(query persistence.Query) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.DeleteTheaters(query)
+}
+
+// UpdateTheater ...
+func (c *CacheDAL) UpdateTheater(id string, m models.Theater) (int64, error) {
+	defer c.Invalidate()
+	return c.DataAccessLayer.UpdateTheater(id, m)
+}
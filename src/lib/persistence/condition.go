@@ -0,0 +1,196 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+var (
+	// ErrNotFound is returned when a single resource is requested and no
+	// document matches the query.
+	ErrNotFound = errors.New("no documents in result")
+)
+
+// ConflictError is returned when a versioned document is updated with a
+// version different from the stored one, i.e. it was modified by someone else
+// since it was read.
+type ConflictError struct {
+	Collection string
+	ID         string
+	Version    int64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s document %s was modified since version %d", e.Collection, e.ID, e.Version)
+}
+
+// IsConflict reports whether err is a *ConflictError.
+func IsConflict(err error) bool {
+	_, ok := err.(*ConflictError)
+	return ok
+}
+
+// SortTextScore sorts the results of a text search by relevance, the most
+// relevant first. It's used as a field in Query.SetSort.
+const SortTextScore = "$textScore"
+
+// SortDistance sorts the results of a query with a NearCondition by distance,
+// the nearest first. It's used as a field in Query.SetSort.
+const SortDistance = "$distance"
+
+// DefaultNearRadius is the radius in meters of near queries without one.
+const DefaultNearRadius = 50000
+
+// Condition is a backend-neutral query condition. Each DataAccessLayer
+// implementation translates it to its own query language.
+type Condition interface {
+	condition()
+}
+
+type (
+	// EqCondition matches documents where Field is equal to Value.
+	EqCondition struct {
+		Field string
+		Value interface{}
+	}
+
+	// InCondition matches documents where Field is equal to any of Values.
+	InCondition struct {
+		Field  string
+		Values []interface{}
+	}
+
+	// NotInCondition matches documents where Field is equal to none of
+	// Values, including documents without Field.
+	NotInCondition struct {
+		Field  string
+		Values []interface{}
+	}
+
+	// RangeCondition matches documents where Field is between From and To,
+	// both inclusive. A nil bound means the range is open on that side.
+	RangeCondition struct {
+		Field string
+		From  interface{}
+		To    interface{}
+	}
+
+	// TextCondition matches documents by full text search on the fields
+	// indexed for text search.
+	TextCondition struct {
+		Search string
+	}
+
+	// PrefixCondition matches documents where Field, or any of its elements
+	// if it's an array, starts with Prefix.
+	PrefixCondition struct {
+		Field  string
+		Prefix string
+	}
+
+	// NearCondition matches documents where Field, a GeoJSON point, is at
+	// most Radius meters away from Point. Results have their distance set if
+	// the model supports it, e.g.: Theater.Distance.
+	NearCondition struct {
+		Field  string
+		Point  models.GeoPoint
+		Radius float64
+	}
+
+	// OrCondition matches documents satisfying at least one of Conditions.
+	OrCondition struct {
+		Conditions []Condition
+	}
+
+	// AndCondition matches documents satisfying all Conditions.
+	AndCondition struct {
+		Conditions []Condition
+	}
+)
+
+func (EqCondition) condition()     {}
+func (InCondition) condition()     {}
+func (NotInCondition) condition()  {}
+func (RangeCondition) condition()  {}
+func (TextCondition) condition()   {}
+func (PrefixCondition) condition() {}
+func (NearCondition) condition()   {}
+func (OrCondition) condition()     {}
+func (AndCondition) condition()    {}
+
+// Eq creates a condition that matches when field is equal to value.
+func Eq(field string, value interface{}) Condition {
+	return EqCondition{Field: field, Value: value}
+}
+
+// In creates a condition that matches when field is equal to any of values.
+func In(field string, values ...interface{}) Condition {
+	if values == nil {
+		values = make([]interface{}, 0)
+	}
+	return InCondition{Field: field, Values: values}
+}
+
+// NotIn creates a condition that matches when field is equal to none of
+// values.
+func NotIn(field string, values ...interface{}) Condition {
+	if values == nil {
+		values = make([]interface{}, 0)
+	}
+	return NotInCondition{Field: field, Values: values}
+}
+
+// Range creates a condition that matches when field is between from and to,
+// both inclusive. Use nil for an open bound.
+func Range(field string, from, to interface{}) Condition {
+	return RangeCondition{Field: field, From: from, To: to}
+}
+
+// Text creates a full text search condition.
+func Text(search string) Condition {
+	return TextCondition{Search: search}
+}
+
+// Prefix creates a condition that matches when field starts with prefix.
+func Prefix(field, prefix string) Condition {
+	return PrefixCondition{Field: field, Prefix: prefix}
+}
+
+// Near creates a condition that matches when field is at most radius meters
+// away from point.
+func Near(field string, point models.GeoPoint, radius float64) Condition {
+	return NearCondition{Field: field, Point: point, Radius: radius}
+}
+
+// Or creates a condition that matches when any of the conditions match.
+func Or(conditions ...Condition) Condition {
+	return OrCondition{Conditions: conditions}
+}
+
+// And creates a condition that matches when all of the conditions match.
+func And(conditions ...Condition) Condition {
+	return AndCondition{Conditions: conditions}
+}
+
+// Update describes the changes applied to a stored document.
+type Update struct {
+	Set   map[string]interface{} // Fields to set
+	Unset []string               // Fields to remove
+}
+
+// Changes reports whether update sets or unsets any of the given fields.
+func (u Update) Changes(fields ...string) bool {
+	for _, field := range fields {
+		if _, ok := u.Set[field]; ok {
+			return true
+		}
+		for _, unset := range u.Unset {
+			if unset == field {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package dblayer
+
+import (
+	"fmt"
+
+	"github.com/dsbezerra/amenic/src/lib/config"
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/sqllayer"
+)
+
+// NewPersistenceLayer creates the DataAccessLayer implementation of the given
+// database type.
+func NewPersistenceLayer(dbType config.DBType, connection string) (persistence.DataAccessLayer, error) {
+	switch dbType {
+	case config.MongoDB:
+		return mongolayer.NewMongoDAL(connection)
+	case config.SQLite:
+		return sqllayer.NewSQLDAL(connection)
+	}
+	return nil, fmt.Errorf("unknown database type %q", dbType)
+}
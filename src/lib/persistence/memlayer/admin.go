@@ -0,0 +1,51 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertAdmin ...
+func (m *MemDAL) InsertAdmin(admin models.Admin) error {
+	if admin.CreatedAt == nil {
+		admin.CreatedAt = getCurrentTime()
+	}
+	return m.InsertOne(CollectionAdmins, admin)
+}
+
+// FindAdmin ...
+func (m *MemDAL) FindAdmin(query persistence.Query) (*models.Admin, error) {
+	var result models.Admin
+	err := m.FindOne(CollectionAdmins, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetAdmin ...
+func (m *MemDAL) GetAdmin(id string, query persistence.Query) (*models.Admin, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindAdmin(query.AddCondition("_id", ID))
+}
+
+// GetAdmins ...
+func (m *MemDAL) GetAdmins(query persistence.Query) ([]models.Admin, error) {
+	var result []models.Admin
+	err := m.FindAll(CollectionAdmins, query, &result)
+	return result, err
+}
+
+// DeleteAdmin ...
+func (m *MemDAL) DeleteAdmin(id string) error {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return m.DeleteOne(CollectionAdmins, bson.M{"_id": ID})
+}
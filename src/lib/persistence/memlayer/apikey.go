@@ -0,0 +1,51 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertAPIKey ...
+func (m *MemDAL) InsertAPIKey(apikey models.APIKey) error {
+	if apikey.Timestamp == nil {
+		apikey.Timestamp = getCurrentTime()
+	}
+	return m.InsertOne(CollectionAPIKeys, apikey)
+}
+
+// FindAPIKey ...
+func (m *MemDAL) FindAPIKey(query persistence.Query) (*models.APIKey, error) {
+	var result models.APIKey
+	err := m.FindOne(CollectionAPIKeys, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetAPIKey ...
+func (m *MemDAL) GetAPIKey(id string, query persistence.Query) (*models.APIKey, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindAPIKey(query.AddCondition("_id", ID))
+}
+
+// GetAPIKeys ...
+func (m *MemDAL) GetAPIKeys(query persistence.Query) ([]models.APIKey, error) {
+	var result []models.APIKey
+	err := m.FindAll(CollectionAPIKeys, query, &result)
+	return result, err
+}
+
+// DeleteAPIKey ...
+func (m *MemDAL) DeleteAPIKey(id string) error {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return m.DeleteOne(CollectionAPIKeys, bson.M{"_id": ID})
+}
@@ -0,0 +1,112 @@
+package memlayer
+
+import (
+	"strings"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/searchutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertCity inserts the given City. Cities without a time zone use the
+// default time zone of their state.
+func (m *MemDAL) InsertCity(city models.City) error {
+	city.UpdateSearchKeys()
+	if city.TimeZone == "" && city.StateID != "" {
+		state, err := m.GetState(city.StateID, m.DefaultQuery())
+		if err == nil {
+			city.TimeZone = state.TimeZone
+		}
+	}
+	return m.InsertOne(CollectionCities, city)
+}
+
+// InsertCities ...
+func (m *MemDAL) InsertCities(cities ...models.City) error {
+	arr := make([]interface{}, len(cities))
+	for i, p := range cities {
+		p.UpdateSearchKeys()
+		arr[i] = p
+	}
+	return m.InsertMany(CollectionCities, arr)
+}
+
+// FindCity ...
+func (m *MemDAL) FindCity(query persistence.Query) (*models.City, error) {
+	var result models.City
+	err := m.FindOne(CollectionCities, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetCity ...
+func (m *MemDAL) GetCity(id string, query persistence.Query) (*models.City, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindCity(query.AddCondition("_id", ID))
+}
+
+// GetCities ...
+func (m *MemDAL) GetCities(query persistence.Query) ([]models.City, error) {
+	var result []models.City
+	err := m.FindAll(CollectionCities, query, &result)
+	return result, err
+}
+
+// UpdateCity ...
+func (m *MemDAL) UpdateCity(id string, mc models.City) (int64, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+	mc.UpdateSearchKeys()
+	mc.UpdatedAt = getCurrentTime()
+	return m.UpdateOne(CollectionCities, bson.M{"_id": ID}, bson.M{"$set": mc})
+}
+
+// DeleteCity ...
+func (m *MemDAL) DeleteCity(id string) error {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return m.DeleteOne(CollectionCities, bson.M{"_id": ID})
+}
+
+// DeleteCities ...
+func (m *MemDAL) DeleteCities(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionCities, query)
+}
+
+// BuildCityQuery converts a map of query string to memlayer syntax for City model
+func (m *MemDAL) BuildCityQuery(q map[string]string) persistence.Query {
+	query := BuildQuery("", q)
+	if len(q) > 0 {
+		ID, ok := q["id"]
+		if ok {
+			value, err := primitive.ObjectIDFromHex(ID)
+			if err == nil {
+				query.AddCondition("_id", value)
+			}
+		}
+		name, ok := q["name"]
+		if ok {
+			query.AddCondition("name", name)
+		}
+		state, ok := q["state"]
+		if ok {
+			query.AddCondition("stateId", strings.ToUpper(state))
+		}
+		search, ok := q["search"]
+		if ok {
+			query.Where(searchutil.Conditions(search)...)
+		}
+	}
+	return query
+}
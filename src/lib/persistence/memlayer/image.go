@@ -0,0 +1,88 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertImage ...
+func (m *MemDAL) InsertImage(image models.Image) error {
+	return m.InsertOne(CollectionImages, image)
+}
+
+// FindImage ...
+func (m *MemDAL) FindImage(query persistence.Query) (*models.Image, error) {
+	var result models.Image
+	err := m.FindOne(CollectionImages, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetImage ...
+func (m *MemDAL) GetImage(id string, query persistence.Query) (*models.Image, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindImage(query.AddCondition("_id", ID))
+}
+
+// GetImages ...
+func (m *MemDAL) GetImages(query persistence.Query) ([]models.Image, error) {
+	var result []models.Image
+	err := m.FindAll(CollectionImages, query, &result)
+	return result, err
+}
+
+// GetMovieImages ...
+func (m *MemDAL) GetMovieImages(id string, query persistence.Query) ([]models.Image, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetImages(query.AddCondition("movieId", ID))
+}
+
+// DeleteImage ...
+func (m *MemDAL) DeleteImage(id string) error {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return m.DeleteOne(CollectionImages, bson.M{"_id": ID})
+}
+
+// DeleteImages ...
+func (m *MemDAL) DeleteImages(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionImages, query)
+}
+
+// DeleteImagesByIDs ...
+func (m *MemDAL) DeleteImagesByIDs(ids []string) (int64, error) {
+	values := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		ID, err := primitive.ObjectIDFromHex(id)
+		if err == nil {
+			values = append(values, ID)
+		}
+	}
+	return m.DeleteImages(m.DefaultQuery().AddCondition("_id", bson.M{"$in": values}))
+}
+
+// UpdateImage ...
+func (m *MemDAL) UpdateImage(id string, mi models.Image) (int64, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+	return m.UpdateOne(CollectionImages, bson.M{"_id": ID}, bson.M{"$set": mi})
+}
+
+// BuildImageQuery converts a map of query string to memlayer syntax for Image model
+func (m *MemDAL) BuildImageQuery(q map[string]string) persistence.Query {
+	return BuildQuery("", q)
+}
@@ -0,0 +1,18 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// InsertIntegrityReport ...
+func (m *MemDAL) InsertIntegrityReport(report models.IntegrityReport) error {
+	return m.InsertOne(CollectionIntegrityReports, report)
+}
+
+// GetIntegrityReports ...
+func (m *MemDAL) GetIntegrityReports(query persistence.Query) ([]models.IntegrityReport, error) {
+	var result []models.IntegrityReport
+	err := m.FindAll(CollectionIntegrityReports, query, &result)
+	return result, err
+}
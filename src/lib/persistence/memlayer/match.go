@@ -0,0 +1,602 @@
+package memlayer
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// textFields lists the fields covered by the text index of each collection
+// and their weights, the same ones created by mongolayer migrations.
+var textFields = map[string][]textField{
+	CollectionMovies:   {{"title", 10}, {"originalTitle", 5}},
+	CollectionTheaters: {{"name", 10}, {"shortName", 5}},
+}
+
+type textField struct {
+	name   string
+	weight float64
+}
+
+// matches reports whether the document satisfies the given conditions. It
+// understands the subset of the MongoDB query language used by the services.
+func matches(collectionName string, doc bson.M, conditions interface{}) (bool, error) {
+	elements, ok := asElements(conditions)
+	if !ok {
+		if conditions == nil {
+			return true, nil
+		}
+		return false, fmt.Errorf("invalid conditions type %T", conditions)
+	}
+
+	for _, e := range elements {
+		var ok bool
+		var err error
+
+		switch e.Key {
+		case "$and", "$or", "$nor":
+			ok, err = matchesLogical(collectionName, doc, e.Key, e.Value)
+		case "$text":
+			ok, err = matchesText(collectionName, doc, e.Value)
+		default:
+			if strings.HasPrefix(e.Key, "$") {
+				return false, fmt.Errorf("unknown top level operator: %s", e.Key)
+			}
+			value, _ := getPath(doc, e.Key)
+			ok, err = matchesValue(value, e.Value)
+		}
+
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func matchesLogical(collectionName string, doc bson.M, op string, value interface{}) (bool, error) {
+	list, ok := asList(value)
+	if !ok || len(list) == 0 {
+		return false, fmt.Errorf("%s must be a nonempty array", op)
+	}
+	for _, c := range list {
+		ok, err := matches(collectionName, doc, c)
+		if err != nil {
+			return false, err
+		}
+		switch op {
+		case "$and":
+			if !ok {
+				return false, nil
+			}
+		case "$or":
+			if ok {
+				return true, nil
+			}
+		case "$nor":
+			if ok {
+				return false, nil
+			}
+		}
+	}
+	return op != "$or", nil
+}
+
+func matchesText(collectionName string, doc bson.M, value interface{}) (bool, error) {
+	search, err := textSearch(value)
+	if err != nil {
+		return false, err
+	}
+	score, err := textScore(collectionName, doc, search)
+	return score > 0, err
+}
+
+// textSearch returns the search string of a $text operator.
+func textSearch(value interface{}) (string, error) {
+	elements, ok := asElements(value)
+	if !ok {
+		return "", fmt.Errorf("$text expects an object")
+	}
+	var search string
+	for _, e := range elements {
+		if e.Key == "$search" {
+			search, _ = e.Value.(string)
+		}
+	}
+	return search, nil
+}
+
+// textScore approximates the relevance MongoDB gives to a document in a text
+// search. Each field adds its weight for every search term it contains, more
+// if the term is a bigger part of the field. Documents containing a negated
+// term score 0.
+func textScore(collectionName string, doc bson.M, search string) (float64, error) {
+	fields, ok := textFields[collectionName]
+	if !ok {
+		return 0, fmt.Errorf("text index required for $text query")
+	}
+
+	terms := make(map[string]bool)
+	negated := make(map[string]bool)
+	for _, term := range strings.Fields(search) {
+		if strings.HasPrefix(term, "-") {
+			for _, w := range tokenize(term[1:]) {
+				negated[w] = true
+			}
+		} else {
+			for _, w := range tokenize(term) {
+				terms[w] = true
+			}
+		}
+	}
+
+	score := 0.0
+	for _, f := range fields {
+		value, _ := getPath(doc, f.name)
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		words := tokenize(s)
+		freq := make(map[string]int, len(words))
+		for _, w := range words {
+			if negated[w] {
+				return 0, nil
+			}
+			freq[w]++
+		}
+		for t := range terms {
+			if freq[t] > 0 {
+				score += f.weight * (0.5 + 0.5*float64(freq[t])/float64(len(words)))
+			}
+		}
+	}
+	return score, nil
+}
+
+// tokenize splits text into case and diacritic insensitive words.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(stringutil.Fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// matchesGeoWithin checks whether value is a GeoJSON point inside the
+// $centerSphere of a $geoWithin operator, the only shape supported.
+func matchesGeoWithin(value interface{}, within interface{}) (bool, error) {
+	center, radius, err := centerSphere(within)
+	if err != nil {
+		return false, err
+	}
+	point, ok := toGeoPoint(value)
+	return ok && models.Distance(point, center) <= radius, nil
+}
+
+// centerSphere returns the center and the radius in meters of the
+// $centerSphere of a $geoWithin operator.
+func centerSphere(within interface{}) (models.GeoPoint, float64, error) {
+	elements, _ := asElements(within)
+	for _, e := range elements {
+		if e.Key != "$centerSphere" {
+			continue
+		}
+		sphere, ok := asList(e.Value)
+		if !ok || len(sphere) != 2 {
+			break
+		}
+		coordinates, ok := asList(sphere[0])
+		if !ok || len(coordinates) != 2 {
+			break
+		}
+		lng, ok1 := toFloat(coordinates[0])
+		lat, ok2 := toFloat(coordinates[1])
+		radians, ok3 := toFloat(sphere[1])
+		if !ok1 || !ok2 || !ok3 {
+			break
+		}
+		return models.NewGeoPoint(lat, lng), radians * models.EarthRadius, nil
+	}
+	return models.GeoPoint{}, 0, fmt.Errorf("$geoWithin only supports $centerSphere")
+}
+
+// toGeoPoint converts a GeoJSON point document to a GeoPoint.
+func toGeoPoint(value interface{}) (models.GeoPoint, bool) {
+	doc, ok := value.(bson.M)
+	if !ok || doc["type"] != "Point" {
+		return models.GeoPoint{}, false
+	}
+	coordinates, ok := asList(doc["coordinates"])
+	if !ok || len(coordinates) != 2 {
+		return models.GeoPoint{}, false
+	}
+	lng, ok1 := toFloat(coordinates[0])
+	lat, ok2 := toFloat(coordinates[1])
+	return models.NewGeoPoint(lat, lng), ok1 && ok2
+}
+
+// matchesValue checks a document value against a condition, which is either
+// an operator document or a value to test for equality.
+func matchesValue(value interface{}, condition interface{}) (bool, error) {
+	elements, ok := asElements(condition)
+	if !ok || len(elements) == 0 || !strings.HasPrefix(elements[0].Key, "$") {
+		return equals(value, normalizeValue(condition)), nil
+	}
+
+	for i := 0; i < len(elements); i++ {
+		e := elements[i]
+		var ok bool
+		var err error
+
+		switch e.Key {
+		case "$eq":
+			ok = equals(value, normalizeValue(e.Value))
+		case "$ne":
+			ok = !equals(value, normalizeValue(e.Value))
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = compareAny(value, normalizeValue(e.Value), e.Key)
+		case "$in", "$nin":
+			list, isList := asList(e.Value)
+			if !isList {
+				return false, fmt.Errorf("%s needs an array", e.Key)
+			}
+			for _, v := range list {
+				if equals(value, normalizeValue(v)) {
+					ok = true
+					break
+				}
+			}
+			if e.Key == "$nin" {
+				ok = !ok
+			}
+		case "$exists":
+			exists, _ := e.Value.(bool)
+			ok = (value != nil) == exists
+		case "$geoWithin":
+			ok, err = matchesGeoWithin(value, e.Value)
+		case "$regex":
+			options := ""
+			for _, o := range elements {
+				if o.Key == "$options" {
+					options, _ = o.Value.(string)
+				}
+			}
+			ok, err = matchesRegex(value, e.Value, options)
+		case "$options":
+			ok = true
+		case "$not":
+			ok, err = matchesValue(value, e.Value)
+			ok = !ok
+		case "$size":
+			list, isList := value.([]interface{})
+			size, _ := toFloat(normalizeValue(e.Value))
+			ok = isList && float64(len(list)) == size
+		case "$all":
+			list, isList := asList(e.Value)
+			if !isList {
+				return false, fmt.Errorf("$all needs an array")
+			}
+			ok = true
+			for _, v := range list {
+				if !equals(value, normalizeValue(v)) {
+					ok = false
+					break
+				}
+			}
+		default:
+			return false, fmt.Errorf("unknown operator: %s", e.Key)
+		}
+
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func matchesRegex(value interface{}, pattern interface{}, options string) (bool, error) {
+	var expr string
+	switch p := pattern.(type) {
+	case string:
+		expr = p
+	case primitive.Regex:
+		expr = p.Pattern
+		options += p.Options
+	default:
+		return false, fmt.Errorf("$regex has to be a string")
+	}
+
+	flags := ""
+	for _, o := range options {
+		if strings.ContainsRune("ims", o) && !strings.ContainsRune(flags, o) {
+			flags += string(o)
+		}
+	}
+	if flags != "" {
+		expr = fmt.Sprintf("(?%s)%s", flags, expr)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false, nil
+	}
+
+	for _, v := range expand(value) {
+		if s, ok := v.(string); ok && re.MatchString(s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// expand returns the value itself plus its elements if it's an array, since
+// conditions on array fields match if any of the elements match.
+func expand(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return append([]interface{}{value}, list...)
+	}
+	return []interface{}{value}
+}
+
+func equals(value interface{}, test interface{}) bool {
+	for _, v := range expand(value) {
+		if c, ok := compare(v, test); ok && c == 0 {
+			return true
+		}
+		if reflect.DeepEqual(v, test) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareAny(value interface{}, test interface{}, op string) bool {
+	for _, v := range expand(value) {
+		c, ok := compare(v, test)
+		if !ok {
+			continue
+		}
+		switch op {
+		case "$gt":
+			ok = c > 0
+		case "$gte":
+			ok = c >= 0
+		case "$lt":
+			ok = c < 0
+		case "$lte":
+			ok = c <= 0
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// compare compares two values of the same BSON type. The second result is
+// false when values are not comparable.
+func compare(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		if a == nil && b == nil {
+			return 0, true
+		}
+		return 0, false
+	}
+
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		return compareFloat(x, y), true
+	}
+
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if x == y {
+			return 0, true
+		} else if !x {
+			return -1, true
+		}
+		return 1, true
+	case primitive.ObjectID:
+		y, ok := b.(primitive.ObjectID)
+		if !ok {
+			return 0, false
+		}
+		return bytes.Compare(x[:], y[:]), true
+	case primitive.DateTime:
+		y, ok := toDateTime(b)
+		if !ok {
+			return 0, false
+		}
+		return compareFloat(float64(x), float64(y)), true
+	}
+
+	return 0, false
+}
+
+// compareForSort orders values of any type following MongoDB's comparison
+// order for BSON types.
+func compareForSort(a, b interface{}) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	c, _ := compare(a, b)
+	return c
+}
+
+func typeRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 1
+	case int32, int64, float64:
+		return 2
+	case string:
+		return 3
+	case bson.M:
+		return 4
+	case []interface{}:
+		return 5
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	}
+	return 10
+}
+
+func compareFloat(x, y float64) int {
+	if x < y {
+		return -1
+	} else if x > y {
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func toDateTime(v interface{}) (primitive.DateTime, bool) {
+	switch t := v.(type) {
+	case primitive.DateTime:
+		return t, true
+	case time.Time:
+		return primitive.DateTime(t.Unix()*1e3 + int64(t.Nanosecond()/1e6)), true
+	}
+	return 0, false
+}
+
+// asElements returns the ordered key/value pairs of a document-like value.
+func asElements(v interface{}) ([]bson.E, bool) {
+	switch d := v.(type) {
+	case bson.D:
+		return d, true
+	case bson.M:
+		result := make([]bson.E, 0, len(d))
+		for k, value := range d {
+			result = append(result, bson.E{Key: k, Value: value})
+		}
+		return result, true
+	case map[string]interface{}:
+		return asElements(bson.M(d))
+	}
+	return nil, false
+}
+
+// asList returns the elements of any slice value.
+func asList(v interface{}) ([]interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	result := make([]interface{}, rv.Len())
+	for i := range result {
+		result[i] = rv.Index(i).Interface()
+	}
+	return result, true
+}
+
+// getPath returns the value of a dotted field path. If an intermediate value
+// is an array, the path is resolved against each of its elements.
+func getPath(doc bson.M, path string) (interface{}, bool) {
+	var current interface{} = doc
+	parts := strings.Split(path, ".")
+	for i, p := range parts {
+		switch t := current.(type) {
+		case bson.M:
+			value, ok := t[p]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			rest := strings.Join(parts[i:], ".")
+			result := make([]interface{}, 0)
+			for _, e := range t {
+				if d, ok := e.(bson.M); ok {
+					if value, ok := getPath(d, rest); ok {
+						result = append(result, value)
+					}
+				}
+			}
+			if len(result) == 0 {
+				return nil, false
+			}
+			return result, true
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setPath sets the value of a dotted field path, creating any intermediate
+// documents if needed.
+func setPath(doc bson.M, path string, value interface{}) {
+	parts := strings.Split(path, ".")
+	current := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := current[p].(bson.M)
+		if !ok {
+			next = bson.M{}
+			current[p] = next
+		}
+		current = next
+	}
+	current[parts[len(parts)-1]] = value
+}
+
+// unsetPath removes the given dotted field path from the document.
+func unsetPath(doc bson.M, path string) {
+	parts := strings.Split(path, ".")
+	current := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := current[p].(bson.M)
+		if !ok {
+			return
+		}
+		current = next
+	}
+	delete(current, parts[len(parts)-1])
+}
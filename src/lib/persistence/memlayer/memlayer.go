@@ -0,0 +1,840 @@
+package memlayer
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/mathutil"
+	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollectionAdmins           = "admins"
+	CollectionAPIKeys          = "api_keys"
+	CollectionCities           = "cities"
+	CollectionImages           = "images"
+	CollectionIntegrityReports = "integrity_reports"
+	CollectionMovies           = "movies"
+	CollectionNotifications    = "notifications"
+	CollectionOutboxEvents     = "outbox_events"
+	CollectionPrices           = "prices"
+	CollectionProcessedEvents  = "processed_events"
+	CollectionScores           = "scores"
+	CollectionScrapers         = "scrapers"
+	CollectionScraperRuns      = "scraper_runs"
+	CollectionSessions         = "sessions"
+	CollectionSessionHistory   = "session_history"
+	CollectionStates           = "states"
+	CollectionTasks            = "tasks"
+	CollectionTheaters         = "theaters"
+)
+
+var (
+	// ErrDuplicateKey is returned when inserting a document whose _id is already
+	// stored in the collection.
+	ErrDuplicateKey = errors.New("duplicate key error")
+)
+
+type (
+	// MemDAL is an implementation of persistence.DataAccessLayer that
+	// evaluates queries by itself.
+	//
+	// Documents are kept as BSON documents, exactly as mongolayer would store
+	// them, so conditions, sort and projections behave the same way for both
+	// implementations. By default they are kept in memory, which is meant to
+	// be used in tests and local runs, but any Store can be used.
+	MemDAL struct {
+		store Store
+	}
+)
+
+// NewMemDAL ...
+func NewMemDAL() (persistence.DataAccessLayer, error) {
+	return NewMemDALWithStore(newMemoryStore()), nil
+}
+
+// NewMemDALWithStore creates a MemDAL that keeps documents in the given Store.
+func NewMemDALWithStore(store Store) *MemDAL {
+	return &MemDAL{store: store}
+}
+
+// Setup inserts the default states, as done by the mongolayer migrations.
+func (m *MemDAL) Setup() error {
+	now := getCurrentTime()
+	for _, state := range models.DefaultStates() {
+		_, err := m.GetState(state.ID, m.DefaultQuery())
+		if err == nil {
+			continue
+		}
+		state.CreatedAt = now
+		err = m.InsertState(state)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close ...
+func (m *MemDAL) Close() {}
+
+// DefaultQuery ...
+func (m *MemDAL) DefaultQuery() persistence.Query {
+	return DefaultOptions("")
+}
+
+// WithTransaction runs fn atomically using Store.Atomic. Nested calls run
+// in the current transaction.
+func (m *MemDAL) WithTransaction(fn func(tx persistence.DataAccessLayer) error) error {
+	return m.store.Atomic(func(s Store) error {
+		return fn(&MemDAL{store: s})
+	})
+}
+
+// Reset removes all documents from every collection. It only works with the
+// default Store.
+func (m *MemDAL) Reset() {
+	if s, ok := m.store.(*memoryStore); ok {
+		s.reset()
+	}
+}
+
+// InsertOne ...
+func (m *MemDAL) InsertOne(collectionName string, doc interface{}) error {
+	return m.InsertMany(collectionName, []interface{}{doc})
+}
+
+// InsertMany inserts all documents or none of them.
+func (m *MemDAL) InsertMany(collectionName string, docs []interface{}) error {
+	ids := make(map[interface{}]bool, len(docs))
+	inserted := make([]bson.M, 0, len(docs))
+	for _, d := range docs {
+		doc, err := toDocument(d)
+		if err != nil {
+			return err
+		}
+		if _, ok := doc["_id"]; !ok {
+			doc["_id"] = primitive.NewObjectID()
+		}
+		key := idKey(doc["_id"])
+		if ids[key] {
+			return fmt.Errorf("%s: collection %s _id %v", ErrDuplicateKey, collectionName, doc["_id"])
+		}
+		ids[key] = true
+		inserted = append(inserted, doc)
+	}
+	return m.store.Insert(collectionName, inserted)
+}
+
+// FindOne decodes the first document matching the given query into result.
+// It returns persistence.ErrNotFound if nothing matches.
+func (m *MemDAL) FindOne(collectionName string, query persistence.Query, result interface{}) error {
+	opts := toOptions(query)
+	opts.Limit = 1
+	docs, err := m.find(collectionName, opts)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return persistence.ErrNotFound
+	}
+	return decode(docs[0], result)
+}
+
+// FindAll decodes all documents matching the given query into result, which
+// must be a pointer to a slice.
+func (m *MemDAL) FindAll(collectionName string, query persistence.Query, result interface{}) error {
+	docs, err := m.find(collectionName, toOptions(query))
+	if err != nil {
+		return err
+	}
+	return decodeAll(docs, result)
+}
+
+// Count returns the total number of documents in the collection matching the
+// query conditions.
+func (m *MemDAL) Count(collectionName string, query persistence.Query) (int64, error) {
+	docs, err := m.filter(m.store, collectionName, toOptions(query).Conditions)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(docs)), nil
+}
+
+// UpdateOne applies the update document to the first document matching the
+// filter and returns the number of modified documents.
+func (m *MemDAL) UpdateOne(collectionName string, filter bson.M, update interface{}) (int64, error) {
+	var modified int64
+	err := m.store.Atomic(func(s Store) error {
+		doc, err := m.first(s, collectionName, filter)
+		if err != nil || doc == nil {
+			return err
+		}
+
+		updated, err := applyUpdate(doc, update)
+		if err != nil {
+			return err
+		}
+		if reflect.DeepEqual(doc, updated) {
+			return nil
+		}
+		modified = 1
+		return s.Replace(collectionName, []bson.M{updated})
+	})
+	return modified, err
+}
+
+// UpdateMany applies the update document to every document matching the
+// filter and returns the number of modified documents.
+func (m *MemDAL) UpdateMany(collectionName string, filter bson.M, update interface{}) (int64, error) {
+	var modified int64
+	err := m.store.Atomic(func(s Store) error {
+		docs, err := m.filter(s, collectionName, filter)
+		if err != nil {
+			return err
+		}
+
+		updates := make([]bson.M, 0)
+		for _, doc := range docs {
+			updated, err := applyUpdate(doc, update)
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(doc, updated) {
+				updates = append(updates, updated)
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+
+		// Only apply after every update succeeded
+		modified = int64(len(updates))
+		return s.Replace(collectionName, updates)
+	})
+	return modified, err
+}
+
+// FindOneAndUpdate applies the update document to the first document matching
+// the query and decodes the original document into result.
+func (m *MemDAL) FindOneAndUpdate(collectionName string, query persistence.Query, update interface{}, result interface{}) error {
+	opts := toOptions(query)
+	return m.store.Atomic(func(s Store) error {
+		original, err := m.first(s, collectionName, opts.Conditions)
+		if err != nil {
+			return err
+		}
+		if original == nil {
+			return persistence.ErrNotFound
+		}
+
+		updated, err := applyUpdate(original, update)
+		if err != nil {
+			return err
+		}
+		err = s.Replace(collectionName, []bson.M{updated})
+		if err != nil {
+			return err
+		}
+		return decode(project(original, opts.Fields), result)
+	})
+}
+
+// DeleteOne removes the first document matching the filter.
+func (m *MemDAL) DeleteOne(collectionName string, filter bson.M) error {
+	return m.store.Atomic(func(s Store) error {
+		doc, err := m.first(s, collectionName, filter)
+		if err != nil || doc == nil {
+			return err
+		}
+		return s.Delete(collectionName, []interface{}{doc["_id"]})
+	})
+}
+
+// DeleteMany removes all documents matching the query conditions.
+func (m *MemDAL) DeleteMany(collectionName string, query persistence.Query) (int64, error) {
+	var deleted int64
+	err := m.store.Atomic(func(s Store) error {
+		docs, err := m.filter(s, collectionName, toOptions(query).Conditions)
+		if err != nil || len(docs) == 0 {
+			return err
+		}
+
+		ids := make([]interface{}, len(docs))
+		for i, doc := range docs {
+			ids[i] = doc["_id"]
+		}
+		deleted = int64(len(ids))
+		return s.Delete(collectionName, ids)
+	})
+	return deleted, err
+}
+
+// filter returns the documents of the collection matching the conditions.
+func (m *MemDAL) filter(s Store, collectionName string, conditions bson.M) ([]bson.M, error) {
+	docs, err := s.Find(collectionName, conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]bson.M, 0)
+	for _, doc := range docs {
+		ok, err := matches(collectionName, doc, conditions)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, doc)
+		}
+	}
+	return result, nil
+}
+
+// first returns the first document matching the conditions or nil if
+// there's none.
+func (m *MemDAL) first(s Store, collectionName string, conditions bson.M) (bson.M, error) {
+	docs, err := m.filter(s, collectionName, conditions)
+	if err != nil || len(docs) == 0 {
+		return nil, err
+	}
+	return docs[0], nil
+}
+
+// find runs the equivalent of mongolayer's find/aggregate pipeline:
+// $match, $lookup(s), $sort, $skip, $limit and $project.
+func (m *MemDAL) find(collectionName string, opts *QueryOptions) ([]bson.M, error) {
+	result, err := m.filter(m.store, collectionName, opts.Conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, included := range opts.Includes {
+		result, err = m.lookup(collectionName, result, included)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, near, err := addDistances(result, opts.Conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range opts.Sort {
+		if f == persistence.SortTextScore {
+			result, err = addTextScores(collectionName, result, opts.Conditions)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	sortDocuments(result, opts.Sort)
+
+	if opts.Skip > 0 {
+		skip := mathutil.MinInt64(opts.Skip, int64(len(result)))
+		result = result[skip:]
+	}
+
+	if opts.Limit > 0 && opts.Limit < int64(len(result)) {
+		result = result[:opts.Limit]
+	}
+
+	fields := opts.Fields
+	if len(fields) > 0 {
+		fields = copyFields(fields)
+		for _, included := range opts.Includes {
+			if rel, ok := relationFor(collectionName, included.Field); ok {
+				fields[rel.as] = 1
+			}
+		}
+		if near {
+			fields[distanceField] = 1
+		}
+	}
+
+	for i, doc := range result {
+		result[i] = project(doc, fields)
+	}
+	return result, nil
+}
+
+// toDocument converts a value to its BSON document representation.
+func toDocument(v interface{}) (bson.M, error) {
+	switch d := v.(type) {
+	case bson.M:
+		return normalizeDocument(d)
+	case bson.D:
+		return normalizeDocument(d.Map())
+	}
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var result bson.M
+	err = bson.Unmarshal(raw, &result)
+	if err != nil {
+		return nil, err
+	}
+	return normalize(result).(bson.M), nil
+}
+
+func normalizeDocument(d bson.M) (bson.M, error) {
+	raw, err := bson.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalDocument(raw)
+}
+
+// UnmarshalDocument decodes a raw BSON document into the form used by MemDAL.
+// It's meant to be used by Store implementations.
+func UnmarshalDocument(data []byte) (bson.M, error) {
+	var result bson.M
+	err := bson.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return normalize(result).(bson.M), nil
+}
+
+// normalize makes sure nested documents are bson.M and arrays are []interface{}
+// so the rest of the package only needs to handle these two types.
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.M:
+		for k, value := range t {
+			t[k] = normalize(value)
+		}
+		return t
+	case bson.D:
+		result := bson.M{}
+		for _, e := range t {
+			result[e.Key] = normalize(e.Value)
+		}
+		return result
+	case primitive.A:
+		result := make([]interface{}, len(t))
+		for i, value := range t {
+			result[i] = normalize(value)
+		}
+		return result
+	case []interface{}:
+		for i, value := range t {
+			t[i] = normalize(value)
+		}
+		return t
+	}
+	return v
+}
+
+// normalizeValue converts a Go value to the type it would have once stored,
+// e.g.: time.Time becomes primitive.DateTime and int becomes int32/int64.
+func normalizeValue(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, string, bool, int32, int64, float64, primitive.ObjectID, primitive.DateTime, primitive.Regex:
+		return v
+	}
+	doc, err := toDocument(bson.M{"v": v})
+	if err != nil {
+		return v
+	}
+	return doc["v"]
+}
+
+func decode(doc bson.M, result interface{}) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(raw, result)
+}
+
+func decodeAll(docs []bson.M, result interface{}) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("result argument must be a slice address")
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	sliceType := rv.Elem().Type()
+	slice := reflect.MakeSlice(sliceType, 0, len(docs))
+	for _, doc := range docs {
+		elem := reflect.New(sliceType.Elem())
+		if err := decode(doc, elem.Interface()); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem.Elem())
+	}
+	rv.Elem().Set(slice)
+	return nil
+}
+
+func copyDocument(doc bson.M) bson.M {
+	result := make(bson.M, len(doc))
+	for k, v := range doc {
+		result[k] = copyValue(v)
+	}
+	return result
+}
+
+func copyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.M:
+		return copyDocument(t)
+	case []interface{}:
+		result := make([]interface{}, len(t))
+		for i, value := range t {
+			result[i] = copyValue(value)
+		}
+		return result
+	}
+	return v
+}
+
+func copyFields(fields bson.M) bson.M {
+	result := make(bson.M, len(fields))
+	for k, v := range fields {
+		result[k] = v
+	}
+	return result
+}
+
+// idKey returns a comparable key used to detect duplicated _id values.
+func idKey(id interface{}) interface{} {
+	switch id.(type) {
+	case bson.M, []interface{}:
+		return fmt.Sprintf("%v", id)
+	}
+	return id
+}
+
+// project applies a MongoDB-style projection to a copy of the document.
+func project(doc bson.M, fields bson.M) bson.M {
+	include := bson.M{}
+	exclude := bson.M{}
+	for f, v := range fields {
+		value, ok := toFloat(normalizeValue(v))
+		if !ok {
+			continue // e.g.: {$meta: "textScore"}
+		}
+		if value == 0 {
+			exclude[f] = true
+		} else {
+			include[f] = true
+		}
+	}
+
+	if len(include) == 0 {
+		result := copyDocument(doc)
+		for f := range exclude {
+			unsetPath(result, f)
+		}
+		return result
+	}
+
+	result := bson.M{}
+	if _, ok := exclude["_id"]; !ok {
+		if id, ok := doc["_id"]; ok {
+			result["_id"] = id
+		}
+	}
+	for f := range include {
+		if value, ok := getPath(doc, f); ok {
+			setPath(result, f, copyValue(value))
+		}
+	}
+	return result
+}
+
+// sortDocuments sorts documents in place using a stable sort so documents
+// with equal keys keep their natural order.
+func sortDocuments(docs []bson.M, fields []string) {
+	keys := make([]bson.E, 0, len(fields))
+	for _, f := range fields {
+		if f == "" {
+			continue
+		}
+		if f == persistence.SortTextScore {
+			keys = append(keys, bson.E{Key: textScoreField, Value: -1})
+			continue
+		}
+		if f == persistence.SortDistance {
+			keys = append(keys, bson.E{Key: distanceField, Value: 1})
+			continue
+		}
+		direction := 1
+		if f[0] == '-' {
+			direction = -1
+			f = f[1:]
+		} else if f[0] == '+' {
+			f = f[1:]
+		}
+		keys = append(keys, bson.E{Key: f, Value: direction})
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, k := range keys {
+			a, _ := getPath(docs[i], k.Key)
+			b, _ := getPath(docs[j], k.Key)
+			c := compareForSort(a, b)
+			if c != 0 {
+				return c*k.Value.(int) < 0
+			}
+		}
+		return false
+	})
+}
+
+const (
+	// textScoreField is the field holding the relevance of text search
+	// results, the same used by mongolayer.
+	textScoreField = "textScore"
+
+	// distanceField is the field holding the distance in meters of near
+	// query results, the same used by mongolayer.
+	distanceField = "distance"
+)
+
+// addDistances returns copies of docs with their distance to the center of
+// the $geoWithin condition in conditions set in distanceField, sorted by it
+// like mongolayer's $geoNear does. Docs are returned as they are, and the
+// second result is false, if there's no $geoWithin condition.
+func addDistances(docs []bson.M, conditions bson.M) ([]bson.M, bool, error) {
+	field, within, ok := findGeoWithin(conditions)
+	if !ok {
+		return docs, false, nil
+	}
+	center, _, err := centerSphere(within)
+	if err != nil {
+		return nil, false, err
+	}
+	result := make([]bson.M, len(docs))
+	for i, doc := range docs {
+		value, _ := getPath(doc, field)
+		point, _ := toGeoPoint(value)
+		result[i] = make(bson.M, len(doc)+1)
+		for k, v := range doc {
+			result[i][k] = v
+		}
+		result[i][distanceField] = models.Distance(center, point)
+	}
+	sortDocuments(result, []string{distanceField})
+	return result, true, nil
+}
+
+// findGeoWithin returns the field and the value of the $geoWithin condition,
+// which is either at the top level or in an $and clause.
+func findGeoWithin(conditions interface{}) (string, interface{}, bool) {
+	elements, _ := asElements(conditions)
+	for _, e := range elements {
+		if e.Key == "$and" {
+			clauses, _ := asList(e.Value)
+			for _, c := range clauses {
+				if field, within, ok := findGeoWithin(c); ok {
+					return field, within, ok
+				}
+			}
+			continue
+		}
+		operators, _ := asElements(e.Value)
+		for _, o := range operators {
+			if o.Key == "$geoWithin" {
+				return e.Key, o.Value, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// addTextScores returns copies of docs with the relevance of the text search
+// in conditions set in textScoreField.
+func addTextScores(collectionName string, docs []bson.M, conditions bson.M) ([]bson.M, error) {
+	search, ok, err := findTextSearch(conditions)
+	if err != nil || !ok {
+		return docs, err
+	}
+	result := make([]bson.M, len(docs))
+	for i, doc := range docs {
+		score, err := textScore(collectionName, doc, search)
+		if err != nil {
+			return nil, err
+		}
+		// Only the top level is copied since stored documents are shared.
+		result[i] = make(bson.M, len(doc)+1)
+		for k, v := range doc {
+			result[i][k] = v
+		}
+		result[i][textScoreField] = score
+	}
+	return result, nil
+}
+
+// findTextSearch returns the search string of the $text condition, which is
+// either at the top level or in an $and clause.
+func findTextSearch(conditions interface{}) (string, bool, error) {
+	elements, _ := asElements(conditions)
+	for _, e := range elements {
+		switch e.Key {
+		case "$text":
+			search, err := textSearch(e.Value)
+			return search, true, err
+		case "$and":
+			clauses, _ := asList(e.Value)
+			for _, c := range clauses {
+				if search, ok, err := findTextSearch(c); ok || err != nil {
+					return search, ok, err
+				}
+			}
+		}
+	}
+	return "", false, nil
+}
+
+// BuildQuery ...
+func BuildQuery(collectionName string, q map[string]string) *QueryOptions {
+	if q == nil {
+		q = make(map[string]string)
+	}
+	query := DefaultOptions(collectionName)
+	if len(q) > 0 {
+		fields, ok := q["fields"]
+		if ok {
+			query.Fields = parseFieldsQuery(fields)
+		}
+		sort, ok := q["sort"]
+		if ok {
+			query.SetSort(parseSortQuery(sort)...)
+		}
+		limit, ok := q["limit"]
+		if ok {
+			value, err := parseLimitQuery(limit)
+			if err == nil {
+				query.SetLimit(value)
+			}
+		}
+		skip, ok := q["skip"]
+		if ok {
+			value, err := parseSkipQuery(skip)
+			if err == nil {
+				query.SetSkip(value)
+			}
+		}
+		include, ok := q["include"]
+		if ok {
+			len := len(include)
+			if len > 0 && strings.HasPrefix(include, "{") && strings.HasSuffix(include, "}") {
+				query.SetIncludes(parseIncludeQuery(include[1 : len-1]))
+			}
+		}
+	}
+	return query
+}
+
+func parseFieldsQuery(fields string) bson.M {
+	result := bson.M{}
+	fields = strings.Replace(fields, " ", "", -1)
+	for _, f := range strings.Split(fields, ",") {
+		if len(f) == 0 {
+			continue
+		}
+		if f[0] == '-' {
+			result[f[1:]] = 0
+		} else if f[0] == '+' {
+			result[f[1:]] = 1
+		} else {
+			result[f] = 1
+		}
+	}
+	return result
+}
+
+func parseSortQuery(sort string) []string {
+	sort = strings.Replace(sort, " ", "", -1)
+	return strings.Split(sort, ",")
+}
+
+func parseLimitQuery(limit string) (int64, error) {
+	value, err := strconv.ParseInt(limit, 10, 32)
+	if err != nil {
+		return -1, err
+	}
+	return value, nil
+}
+
+func parseSkipQuery(skip string) (int64, error) {
+	value, err := strconv.ParseInt(skip, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return mathutil.MaxInt64(0, value), nil
+}
+
+func parseIncludeQuery(include string) []QueryInclude {
+	result := make([]QueryInclude, 0)
+	for _, v := range strings.Split(include, ",") {
+		name, remainder := stringutil.BreakByToken(v, '{')
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		i := QueryInclude{Field: name}
+		if remainder != "" {
+			fields, _ := stringutil.BreakByToken(remainder, '}')
+			if fields != "" {
+				for _, f := range strings.Split(fields, "\\n") {
+					i.Fields = append(i.Fields, strings.TrimSpace(f))
+				}
+			}
+		}
+
+		result = append(result, i)
+	}
+	return result
+}
+
+// updateVersioned applies update to the document with the given ID and
+// increments its version. If version isn't zero the document is only updated
+// while its stored version is the same, otherwise a *persistence.ConflictError
+// is returned.
+// The update must not change the version itself.
+func (m *MemDAL) updateVersioned(collectionName string, ID primitive.ObjectID, version int64, update bson.M) (int64, error) {
+	filter := bson.M{"_id": ID}
+	if version != 0 {
+		filter["version"] = version
+	}
+	update["$inc"] = bson.M{"version": 1}
+	modified, err := m.UpdateOne(collectionName, filter, update)
+	if err != nil || modified > 0 || version == 0 {
+		return modified, err
+	}
+	// Only a conflict if the document still exists.
+	count, err := m.Count(collectionName, m.DefaultQuery().AddCondition("_id", ID))
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, &persistence.ConflictError{Collection: collectionName, ID: ID.Hex(), Version: version}
+	}
+	return 0, nil
+}
+
+func getCurrentTime() *time.Time {
+	now := time.Now()
+	return &now
+}
@@ -0,0 +1,295 @@
+package memlayer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getTestingMemDAL() (persistence.DataAccessLayer, error) {
+	data, err := NewMemDAL()
+	if err != nil {
+		return nil, err
+	}
+	err = data.Setup()
+	return data, err
+}
+
+func TestConditions(t *testing.T) {
+	data, err := getTestingMemDAL()
+	assert.NoError(t, err)
+
+	cities := []models.City{
+		{ID: primitive.NewObjectID(), Name: "Montes Claros", StateID: models.MG},
+		{ID: primitive.NewObjectID(), Name: "Belo Horizonte", StateID: models.MG},
+		{ID: primitive.NewObjectID(), Name: "São Paulo", StateID: models.SP},
+	}
+	for _, c := range cities {
+		assert.NoError(t, data.InsertCity(c))
+	}
+
+	// Duplicated _id
+	assert.Error(t, data.InsertCity(cities[0]))
+
+	// Equality
+	result, err := data.GetCities(data.DefaultQuery().AddCondition("stateId", models.MG))
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+
+	// $in
+	result, err = data.GetCities(data.DefaultQuery().
+		AddCondition("_id", bson.M{"$in": []primitive.ObjectID{cities[0].ID, cities[2].ID}}))
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+
+	// $or and $regex
+	result, err = data.GetCities(data.DefaultQuery().AddCondition("$or", []bson.M{
+		{"name": bson.M{"$regex": "^montes", "$options": "i"}},
+		{"stateId": models.SP},
+	}))
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+
+	// $ne
+	count, err := data.(*MemDAL).Count(CollectionCities, data.DefaultQuery().
+		AddCondition("name", bson.D{{Key: "$ne", Value: "Montes Claros"}}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Unknown operator
+	_, err = data.GetCities(data.DefaultQuery().AddCondition("name", bson.M{"$foo": 1}))
+	assert.Error(t, err)
+
+	// Sort, skip and limit
+	result, err = data.GetCities(data.DefaultQuery().SetSort("-name").SetSkip(1).SetLimit(1))
+	assert.NoError(t, err)
+	if assert.Len(t, result, 1) {
+		assert.Equal(t, "Montes Claros", result[0].Name)
+	}
+
+	// Projection
+	city, err := data.GetCity(cities[2].ID.Hex(), data.DefaultQuery().SetFields("name"))
+	assert.NoError(t, err)
+	assert.Equal(t, cities[2].Name, city.Name)
+	assert.Empty(t, city.StateID)
+
+	city, err = data.GetCity(cities[2].ID.Hex(), data.DefaultQuery().Exclude("name"))
+	assert.NoError(t, err)
+	assert.Empty(t, city.Name)
+	assert.Equal(t, cities[2].StateID, city.StateID)
+
+	// Update
+	update := cities[2]
+	update.TimeZone = "America/Sao_Paulo"
+	modified, err := data.UpdateCity(update.ID.Hex(), update)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+
+	// Delete
+	deleted, err := data.DeleteCities(data.DefaultQuery().AddCondition("stateId", models.MG))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	_, err = data.GetCity(cities[0].ID.Hex(), data.DefaultQuery())
+	assert.Equal(t, persistence.ErrNotFound, err)
+}
+
+func TestWhere(t *testing.T) {
+	data, err := getTestingMemDAL()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	sessions := []models.Session{
+		{TheaterID: primitive.NewObjectID(), MovieSlug: "a", StartTime: timePtr(now.Add(-time.Hour))},
+		{TheaterID: primitive.NewObjectID(), MovieSlug: "b", StartTime: timePtr(now)},
+		{TheaterID: primitive.NewObjectID(), MovieSlug: "c", StartTime: timePtr(now.Add(time.Hour))},
+	}
+	assert.NoError(t, data.InsertSessions(sessions...))
+
+	get := func(conditions ...persistence.Condition) []models.Session {
+		result, err := data.GetSessions(data.DefaultQuery().Where(conditions...))
+		assert.NoError(t, err)
+		return result
+	}
+
+	assert.Len(t, get(persistence.Eq("movieSlug", "a")), 1)
+	assert.Len(t, get(persistence.In("movieSlug", "a", "c", "d")), 2)
+	assert.Len(t, get(persistence.In("movieSlug")), 0)
+	assert.Len(t, get(persistence.NotIn("movieSlug", "a", "c", "d")), 1)
+	assert.Len(t, get(persistence.NotIn("movieSlug")), 3)
+	assert.Len(t, get(persistence.Range("startTime", now, nil)), 2)
+	assert.Len(t, get(persistence.Range("startTime", nil, now)), 2)
+	assert.Len(t, get(persistence.Range("startTime", now, now)), 1)
+	assert.Len(t, get(persistence.Or(
+		persistence.Eq("movieSlug", "a"),
+		persistence.Range("startTime", now.Add(time.Minute), nil),
+	)), 2)
+	assert.Len(t, get(persistence.And(
+		persistence.In("movieSlug", "a", "b"),
+		persistence.Range("startTime", now, nil),
+	)), 1)
+
+	// Conditions on the same field are combined
+	assert.Len(t, get(
+		persistence.Range("startTime", now.Add(-time.Hour), nil),
+		persistence.Range("startTime", nil, now),
+	), 2)
+	assert.Len(t, get(
+		persistence.Eq("theaterId", sessions[1].TheaterID),
+		persistence.Range("startTime", now, nil),
+		persistence.Range("startTime", nil, now.Add(time.Hour)),
+	), 1)
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestWithTransaction(t *testing.T) {
+	data, err := getTestingMemDAL()
+	assert.NoError(t, err)
+
+	theaterID := primitive.NewObjectID()
+	assert.NoError(t, data.InsertPrices(models.Price{TheaterID: theaterID, Full: 10}))
+
+	replace := func(prices ...models.Price) error {
+		return data.WithTransaction(func(tx persistence.DataAccessLayer) error {
+			_, err := tx.DeletePrices(tx.DefaultQuery().AddCondition("theaterId", theaterID))
+			if err != nil {
+				return err
+			}
+			return tx.InsertPrices(prices...)
+		})
+	}
+
+	// Failed transactions are rolled back
+	ID := primitive.NewObjectID()
+	err = replace(
+		models.Price{ID: ID, TheaterID: theaterID, Full: 20},
+		models.Price{ID: ID, TheaterID: theaterID, Full: 30},
+	)
+	assert.Error(t, err)
+	prices, err := data.GetPrices(data.DefaultQuery())
+	assert.NoError(t, err)
+	if assert.Len(t, prices, 1) {
+		assert.Equal(t, float32(10), prices[0].Full)
+	}
+
+	// Successful transactions are kept
+	err = replace(models.Price{TheaterID: theaterID, Full: 20})
+	assert.NoError(t, err)
+	prices, err = data.GetPrices(data.DefaultQuery())
+	assert.NoError(t, err)
+	if assert.Len(t, prices, 1) {
+		assert.Equal(t, float32(20), prices[0].Full)
+	}
+
+	// Nested transactions are part of the outer one
+	err = data.WithTransaction(func(tx persistence.DataAccessLayer) error {
+		assert.NoError(t, tx.WithTransaction(func(tx persistence.DataAccessLayer) error {
+			return tx.InsertPrices(models.Price{TheaterID: theaterID, Full: 40})
+		}))
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+	count, err := data.(*MemDAL).Count(CollectionPrices, data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestTheaterIncludes(t *testing.T) {
+	data, err := getTestingMemDAL()
+	assert.NoError(t, err)
+
+	city := models.City{ID: primitive.NewObjectID(), Name: "some-city-name"}
+	theater := models.Theater{
+		ID:     primitive.NewObjectID(),
+		CityID: city.ID,
+		Name:   "theater-name",
+	}
+	assert.NoError(t, data.InsertCity(city))
+	assert.NoError(t, data.InsertTheater(theater))
+	assert.NoError(t, data.InsertPrices(
+		models.Price{TheaterID: theater.ID, Full: 20},
+		models.Price{TheaterID: theater.ID, Full: 10},
+	))
+
+	query := data.BuildTheaterQuery(map[string]string{
+		"include": "{city{name},prices}",
+	})
+	result, err := data.GetTheater(theater.ID.Hex(), query)
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.City) {
+		assert.Equal(t, city.Name, result.City.Name)
+	}
+	assert.Len(t, result.Prices, 2)
+
+	// Price including theater
+	prices, err := data.GetPrices(data.DefaultQuery().AddInclude("theater"))
+	assert.NoError(t, err)
+	if assert.Len(t, prices, 2) {
+		assert.Equal(t, theater.Name, prices[0].Theater.Name)
+	}
+}
+
+func TestTheaterNear(t *testing.T) {
+	data, err := getTestingMemDAL()
+	assert.NoError(t, err)
+
+	point := func(lat, lng float64) *models.GeoPoint {
+		p := models.NewGeoPoint(lat, lng)
+		return &p
+	}
+	theaters := []models.Theater{
+		{ID: primitive.NewObjectID(), Name: "Cine Savassi", Location: point(-19.9386, -43.9338)},
+		{ID: primitive.NewObjectID(), Name: "Cine Centro", Location: point(-19.9191, -43.9386)},
+		{ID: primitive.NewObjectID(), Name: "Cine Contagem", Location: point(-19.9317, -44.0539)},
+		{ID: primitive.NewObjectID(), Name: "Cine Sem Endereço"},
+	}
+	for _, th := range theaters {
+		assert.NoError(t, data.InsertTheater(th))
+	}
+
+	names := func(q map[string]string) ([]string, []float64) {
+		result, err := data.GetTheaters(data.BuildTheaterQuery(q))
+		assert.NoError(t, err)
+		names := make([]string, len(result))
+		distances := make([]float64, len(result))
+		for i, th := range result {
+			names[i], distances[i] = th.Name, th.Distance
+		}
+		return names, distances
+	}
+
+	// Nearest first
+	result, distances := names(map[string]string{"near": "-19.9200,-43.9380", "radius": "5000"})
+	assert.Equal(t, []string{"Cine Centro", "Cine Savassi"}, result)
+	assert.InDelta(t, 120, distances[0], 10)
+	assert.InDelta(t, 2120, distances[1], 50)
+
+	result, _ = names(map[string]string{"near": "-19.9200,-43.9380", "radius": "5000", "sort": "-distance"})
+	assert.Equal(t, []string{"Cine Savassi", "Cine Centro"}, result)
+
+	result, _ = names(map[string]string{"near": "-19.9200,-43.9380"})
+	assert.Equal(t, []string{"Cine Centro", "Cine Savassi", "Cine Contagem"}, result)
+
+	// Invalid points are ignored
+	result, _ = names(map[string]string{"near": "-91,-43.9380"})
+	assert.Len(t, result, 4)
+
+	// Distances are only kept by queries
+	theater := theaters[0]
+	theater.Distance = 10
+	_, err = data.UpdateTheater(theater.ID.Hex(), theater)
+	assert.NoError(t, err)
+	stored, err := data.GetTheater(theater.ID.Hex(), data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.Zero(t, stored.Distance)
+	assert.Equal(t, theater.Location, stored.Location)
+}
@@ -0,0 +1,377 @@
+package memlayer
+
+import (
+	"strconv"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/bsonutil"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/movieutil"
+	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
+	"github.com/dsbezerra/amenic/src/lib/util/searchutil"
+	"github.com/dsbezerra/amenic/src/lib/util/timeutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CountMovies ...
+func (m *MemDAL) CountMovies(query persistence.Query) (int64, error) {
+	return m.Count(CollectionMovies, query)
+}
+
+// InsertMovie ...
+func (m *MemDAL) InsertMovie(movie models.Movie) error {
+	movie.UpdateSearchKeys()
+	movie.Version = 1
+	return m.InsertOne(CollectionMovies, movie)
+}
+
+// FindMovie ...
+func (m *MemDAL) FindMovie(query persistence.Query) (*models.Movie, error) {
+	var result models.Movie
+	err := m.FindOne(CollectionMovies, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// FindMovieAndUpdate finds a Movie matching the query and patches it, returning the original. It
+// fails with a *persistence.ConflictError if the Movie is modified in between.
+func (m *MemDAL) FindMovieAndUpdate(query persistence.Query, update persistence.Update) (*models.Movie, error) {
+	result, err := m.FindMovie(query)
+	if err != nil {
+		return nil, err
+	}
+	_, err = m.PatchMovie(result.ID.Hex(), result.Version, update)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetMovie ...
+func (m *MemDAL) GetMovie(id string, query persistence.Query) (*models.Movie, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindMovie(query.AddCondition("_id", ID))
+}
+
+// GetMovies ...
+func (m *MemDAL) GetMovies(query persistence.Query) ([]models.Movie, error) {
+	var result []models.Movie
+	err := m.FindAll(CollectionMovies, query, &result)
+	return result, err
+}
+
+// GetMoviesByTitle ...
+func (m *MemDAL) GetMoviesByTitle(title string) ([]models.Movie, error) {
+	return m.GetMovies(DefaultOptions("").
+		Where(persistence.Text(title)).
+		SetSort(persistence.SortTextScore))
+}
+
+// GetNowPlayingMovies returns now playing movies for the given query condition
+func (m *MemDAL) GetNowPlayingMovies(query persistence.Query) ([]models.Movie, error) {
+	var result []models.Movie
+
+	opts := toOptions(query)
+
+	// If we don't have conditions let's return only the current
+	// now playing movies
+	conditions := opts.Conditions
+	if len(conditions) == 0 {
+		period := scheduleutil.GetWeekPeriod(nil)
+		conditions = bson.M{"startTime": bson.M{"$gte": period.Start}, "hidden": false}
+	}
+
+	sessions, err := m.find(CollectionSessions, &QueryOptions{Conditions: conditions})
+	if err != nil {
+		return nil, err
+	}
+
+	// Group sessions by movie keeping the order they were found.
+	ids := make([]interface{}, 0)
+	seen := make(map[interface{}]bool)
+	for _, s := range sessions {
+		ID := idKey(s["movieId"])
+		if !seen[ID] {
+			seen[ID] = true
+			ids = append(ids, s["movieId"])
+		}
+	}
+
+	movies, err := m.find(CollectionMovies, &QueryOptions{
+		Conditions: bson.M{"_id": bson.M{"$in": ids}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sorts everything by release date in descending order only if we define a custom sort,
+	// otherwise keep the grouping order.
+	if opts.Sorting() {
+		sortDocuments(movies, append([]string{"-releaseDate"}, opts.Sort...))
+	} else {
+		index := make(map[interface{}]int, len(ids))
+		for i, ID := range ids {
+			index[idKey(ID)] = i
+		}
+		movies = sortByIndex(movies, index)
+	}
+
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = defaultMovieFields()
+	}
+	for i, movie := range movies {
+		movies[i] = project(movie, fields)
+	}
+
+	err = decodeAll(movies, &result)
+	return result, err
+}
+
+// OldGetNowPlayingMovies retrieves all now playing movies for the given conditions.
+// **************   FOR STATIC home.json AND now_playing.json FILES   ****************
+func (m *MemDAL) OldGetNowPlayingMovies(query persistence.Query) ([]models.Movie, error) {
+	var result []models.Movie
+
+	period := scheduleutil.GetWeekPeriod(nil)
+
+	sessions, err := m.find(CollectionSessions, &QueryOptions{
+		Conditions: bson.M{"startTime": bson.M{"$gte": period.Start}, "hidden": false},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Group sessions by movie collecting the theaters playing each one.
+	ids := make([]interface{}, 0)
+	theaters := make(map[interface{}][]interface{})
+	for _, s := range sessions {
+		ID := idKey(s["movieId"])
+		if _, ok := theaters[ID]; !ok {
+			ids = append(ids, s["movieId"])
+			theaters[ID] = make([]interface{}, 0)
+		}
+		theaters[ID] = append(theaters[ID], s["theaterId"])
+	}
+
+	for _, ID := range ids {
+		movie, err := m.find(CollectionMovies, &QueryOptions{Conditions: bson.M{"_id": ID}, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(movie) == 0 {
+			continue
+		}
+
+		playing, err := m.find(CollectionTheaters, &QueryOptions{
+			Conditions: bson.M{"_id": bson.M{"$in": theaters[idKey(ID)]}},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		doc := project(movie[0], bson.M{
+			"_id":         1,
+			"title":       1,
+			"poster":      1,
+			"releaseDate": 1,
+		})
+		list := make([]interface{}, len(playing))
+		for i, t := range playing {
+			list[i] = t
+		}
+		doc["theaters"] = list
+
+		var mm models.Movie
+		if err := decode(doc, &mm); err != nil {
+			return nil, err
+		}
+		result = append(result, mm)
+	}
+
+	return result, nil
+}
+
+// GetUpcomingMovies ...
+func (m *MemDAL) GetUpcomingMovies(query persistence.Query) ([]models.Movie, error) {
+	// Retrieve start of the current day timestamp
+	startOfDay := timeutil.StartOfDay()
+	// Builds default query for this operation
+	opts := toOptions(query)
+	opts.
+		AddCondition("hidden", false).
+		AddCondition("releaseDate", bson.M{"$gt": startOfDay})
+	// Set default sort if we don't specify one
+	if !opts.Sorting() {
+		opts.Sort = []string{"+releaseDate"}
+	}
+	if len(opts.Fields) == 0 {
+		opts.Fields = defaultMovieFields()
+	}
+	return m.GetMovies(opts)
+}
+
+// UpdateMovie ...
+func (m *MemDAL) UpdateMovie(id string, mm models.Movie) (int64, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+	// Search keys can only be rebuilt when the titles are given.
+	if mm.Title != "" || mm.OriginalTitle != "" {
+		mm.UpdateSearchKeys()
+	}
+	mm.UpdatedAt = getCurrentTime()
+	version := mm.Version
+	mm.Version = 0
+	return m.updateVersioned(CollectionMovies, ID, version, bson.M{"$set": mm})
+}
+
+// PatchMovie ...
+func (m *MemDAL) PatchMovie(id string, version int64, update persistence.Update) (int64, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+	patch := persistence.Update{
+		Set:   map[string]interface{}{"updatedAt": getCurrentTime()},
+		Unset: update.Unset,
+	}
+	for field, value := range update.Set {
+		patch.Set[field] = value
+	}
+	// Search keys depend on both titles, so the current ones are needed.
+	if update.Changes("title", "originalTitle") {
+		movie, err := m.GetMovie(id, m.DefaultQuery().SetFields("title", "originalTitle"))
+		if err == persistence.ErrNotFound {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		keys := movieutil.SearchKeys(*movie, update)
+		if len(keys) > 0 {
+			patch.Set[searchutil.KeysField] = keys
+		} else {
+			patch.Unset = append(patch.Unset, searchutil.KeysField)
+		}
+	}
+	return m.updateVersioned(CollectionMovies, ID, version, bsonutil.UpdateToBSON(patch))
+}
+
+// DeleteMovie ...
+func (m *MemDAL) DeleteMovie(id string) error {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return m.DeleteOne(CollectionMovies, bson.M{"_id": ID})
+}
+
+// DeleteMovies ...
+func (m *MemDAL) DeleteMovies(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionMovies, query)
+}
+
+// BuildMovieQuery converts a map of query string to memlayer syntax for Movie model
+func (m *MemDAL) BuildMovieQuery(q map[string]string) persistence.Query {
+	query := BuildQuery("", q)
+	if len(q) > 0 {
+		// IDs
+		ID, ok := q["id"]
+		if ok {
+			value, err := primitive.ObjectIDFromHex(ID)
+			if err == nil {
+				query.AddCondition("_id", value)
+			}
+		}
+		ID, ok = q["claqueteId"]
+		if ok {
+			value, err := strconv.Atoi(ID)
+			if err == nil {
+				query.AddCondition("claqueteId", value)
+			}
+		}
+		ID, ok = q["tmdbId"]
+		if ok {
+			value, err := strconv.Atoi(ID)
+			if err == nil {
+				query.AddCondition("tmdbId", value)
+			}
+		}
+		ID, ok = q["imdbId"]
+		if ok {
+			query.AddCondition("imdbId", ID)
+		}
+
+		// Hidden
+		hidden, ok := q["hidden"]
+		if ok {
+			value, err := strconv.ParseBool(hidden)
+			if err == nil {
+				query.AddCondition("hidden", value)
+			}
+		}
+
+		for _, field := range []string{"backdrop", "poster", "trailer"} {
+			v, ok := q[field]
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseBool(v)
+			if err == nil {
+				prop := "$eq"
+				if value {
+					prop = "$ne"
+				}
+				query.AddCondition(field, bson.M{prop: ""})
+			}
+		}
+
+		rating, ok := q["rating"]
+		if ok {
+			value, err := strconv.Atoi(rating)
+			if err == nil && (value == -1 || value >= 10 && value <= 18) {
+				query.AddCondition("rating", value)
+			}
+		}
+
+		search, ok := q["search"]
+		if ok {
+			query.Where(searchutil.Conditions(search)...)
+		}
+	}
+	return query
+}
+
+func defaultMovieFields() bson.M {
+	return bson.M{
+		"_id":         1,
+		"poster":      1,
+		"title":       1,
+		"trailer":     1,
+		"rating":      1,
+		"releaseDate": 1,
+	}
+}
+
+// sortByIndex orders documents by the position of their _id in index.
+func sortByIndex(docs []bson.M, index map[interface{}]int) []bson.M {
+	result := make([]bson.M, 0, len(docs))
+	ordered := make([]bson.M, len(index))
+	for _, doc := range docs {
+		ordered[index[idKey(doc["_id"])]] = doc
+	}
+	for _, doc := range ordered {
+		if doc != nil {
+			result = append(result, doc)
+		}
+	}
+	return result
+}
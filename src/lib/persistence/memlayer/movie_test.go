@@ -0,0 +1,298 @@
+package memlayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMovie(t *testing.T) {
+	data, err := getTestingMemDAL()
+	defer data.Close()
+	assert.NoError(t, err)
+
+	doc := models.Movie{
+		ID:    primitive.NewObjectID(),
+		Title: "some-movie-title",
+	}
+
+	opts := DefaultOptions("")
+
+	// Insert
+	err = data.InsertMovie(doc)
+	assert.NoError(t, err)
+
+	// Find one with conditions
+	movie, err := data.FindMovie(DefaultOptions("").AddCondition("title", doc.Title))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, movie)
+
+	// Find one by id
+	movie, err = data.GetMovie(doc.ID.Hex(), opts)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, movie)
+
+	// Text search
+	movies, err := data.GetMovies(DefaultOptions("").AddCondition("$text", bson.M{"$search": "MOVIE"}))
+	assert.NoError(t, err)
+	assert.Len(t, movies, 1)
+
+	// Update
+	update := doc
+	update.Title = "updated-title"
+	modified, err := data.UpdateMovie(doc.ID.Hex(), update)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+
+	movie, err = data.GetMovie(doc.ID.Hex(), DefaultOptions(""))
+	assert.NoError(t, err)
+	assert.Equal(t, movie.Title, update.Title)
+
+	// Find and update returns the original document
+	movie, err = data.FindMovieAndUpdate(DefaultOptions("").AddCondition("_id", doc.ID),
+		persistence.Update{Set: map[string]interface{}{"poster": "some-poster"}})
+	assert.NoError(t, err)
+	assert.Empty(t, movie.PosterURL)
+
+	version := movie.Version
+	movie, err = data.GetMovie(doc.ID.Hex(), DefaultOptions(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "some-poster", movie.PosterURL)
+	assert.Equal(t, version+1, movie.Version)
+
+	// Delete
+	err = data.DeleteMovie(doc.ID.Hex())
+	assert.NoError(t, err)
+
+	count, err := data.CountMovies(DefaultOptions(""))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestNowPlayingAndUpcomingMovies(t *testing.T) {
+	data, err := getTestingMemDAL()
+	assert.NoError(t, err)
+
+	period := scheduleutil.GetWeekPeriod(nil)
+	lastWeek := period.Start.AddDate(0, 0, -7)
+	older := period.Start.AddDate(0, -1, 0)
+	newer := period.Start.AddDate(0, 0, -1)
+	future := time.Now().AddDate(0, 1, 0)
+
+	theater := models.Theater{ID: primitive.NewObjectID(), Name: "theater-name"}
+	playing := []models.Movie{
+		{ID: primitive.NewObjectID(), Title: "older", ReleaseDate: &older},
+		{ID: primitive.NewObjectID(), Title: "newer", ReleaseDate: &newer},
+	}
+	notPlaying := models.Movie{ID: primitive.NewObjectID(), Title: "not-playing", ReleaseDate: &older}
+	upcoming := models.Movie{ID: primitive.NewObjectID(), Title: "upcoming", ReleaseDate: &future}
+
+	assert.NoError(t, data.InsertTheater(theater))
+	for _, m := range append(playing, notPlaying, upcoming) {
+		assert.NoError(t, data.InsertMovie(m))
+	}
+
+	start := period.Start.Add(time.Hour)
+	assert.NoError(t, data.InsertSessions(
+		models.Session{MovieID: playing[0].ID, TheaterID: theater.ID, StartTime: &start},
+		models.Session{MovieID: playing[1].ID, TheaterID: theater.ID, StartTime: &start},
+		models.Session{MovieID: playing[0].ID, TheaterID: theater.ID, StartTime: &start},
+		models.Session{MovieID: notPlaying.ID, TheaterID: theater.ID, StartTime: &lastWeek},
+	))
+
+	// Defaults to sessions of the current week
+	movies, err := data.GetNowPlayingMovies(nil)
+	assert.NoError(t, err)
+	if assert.Len(t, movies, 2) {
+		assert.Equal(t, "older", movies[0].Title)
+		assert.Equal(t, "newer", movies[1].Title)
+	}
+
+	// Custom sort is preceded by release date
+	movies, err = data.GetNowPlayingMovies(data.BuildSessionQuery(map[string]string{
+		"theaterId": theater.ID.Hex(),
+		"sort":      "title",
+	}))
+	assert.NoError(t, err)
+	if assert.Len(t, movies, 3) {
+		assert.Equal(t, "newer", movies[0].Title)
+		assert.Equal(t, "not-playing", movies[1].Title)
+		assert.Equal(t, "older", movies[2].Title)
+	}
+
+	movies, err = data.GetUpcomingMovies(data.DefaultQuery())
+	assert.NoError(t, err)
+	if assert.Len(t, movies, 1) {
+		assert.Equal(t, "upcoming", movies[0].Title)
+	}
+
+	movies, err = data.OldGetNowPlayingMovies(nil)
+	assert.NoError(t, err)
+	if assert.Len(t, movies, 2) {
+		assert.Len(t, movies[0].Theaters, 1)
+	}
+
+	// Sessions including movie
+	sessions, err := data.GetSessions(data.DefaultQuery().
+		AddCondition("movieId", playing[1].ID).
+		AddInclude("movie"))
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 1) && assert.NotNil(t, sessions[0].Movie) {
+		assert.Equal(t, "newer", sessions[0].Movie.Title)
+	}
+}
+
+func TestMovieSearch(t *testing.T) {
+	data, err := getTestingMemDAL()
+	assert.NoError(t, err)
+
+	movies := []models.Movie{
+		{ID: primitive.NewObjectID(), Title: "Ação e Reação"},
+		{ID: primitive.NewObjectID(), Title: "Ação", OriginalTitle: "Action"},
+		{ID: primitive.NewObjectID(), Title: "Coração Valente", OriginalTitle: "Braveheart"},
+	}
+	for _, m := range movies {
+		assert.NoError(t, data.InsertMovie(m))
+	}
+
+	// Search keys are updated when titles are
+	movie, err := data.GetMovie(movies[1].ID.Hex(), data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acao", "action"}, movie.SearchKeys)
+	_, err = data.UpdateMovie(movies[1].ID.Hex(), models.Movie{Title: "Ação!", OriginalTitle: "Action!"})
+	assert.NoError(t, err)
+	_, err = data.UpdateMovie(movies[1].ID.Hex(), models.Movie{Runtime: 90})
+	assert.NoError(t, err)
+	movie, err = data.GetMovie(movies[1].ID.Hex(), data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acao", "action"}, movie.SearchKeys)
+
+	titles := func(query persistence.Query) []string {
+		result, err := data.GetMovies(query)
+		assert.NoError(t, err)
+		titles := make([]string, len(result))
+		for i, m := range result {
+			titles[i] = m.Title
+		}
+		return titles
+	}
+
+	// search matches the prefix of every word ignoring accents
+	assert.Equal(t, []string{"Ação e Reação", "Ação!"},
+		titles(data.BuildMovieQuery(map[string]string{"search": "ACAO"}).SetSort("title")))
+	assert.Equal(t, []string{"Ação e Reação"},
+		titles(data.BuildMovieQuery(map[string]string{"search": "reac aç"})))
+	assert.Equal(t, []string{"Coração Valente"},
+		titles(data.BuildMovieQuery(map[string]string{"search": "brave"})))
+	assert.Equal(t, []string{"Coração Valente"},
+		titles(data.BuildMovieQuery(map[string]string{"search": "(valente)"})))
+	assert.Empty(t, titles(data.DefaultQuery().Where(persistence.Prefix("title", "A.ão"))))
+
+	// Text search results are sorted by relevance
+	assert.Equal(t, []string{"Ação!", "Ação e Reação"},
+		titles(data.DefaultQuery().
+			Where(persistence.Text("acao")).
+			SetSort(persistence.SortTextScore)))
+	assert.Equal(t, []string{"Coração Valente", "Ação e Reação"},
+		titles(data.DefaultQuery().
+			Where(persistence.Text("reacao valente coracao")).
+			SetSort(persistence.SortTextScore)))
+}
+
+func TestMovieVersion(t *testing.T) {
+	data, err := getTestingMemDAL()
+	defer data.Close()
+	assert.NoError(t, err)
+
+	doc := models.Movie{
+		ID:    primitive.NewObjectID(),
+		Title: "Aquarius",
+	}
+	assert.NoError(t, data.InsertMovie(doc))
+
+	movie, err := data.GetMovie(doc.ID.Hex(), DefaultOptions(""))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), movie.Version)
+	}
+
+	// Update with the current version
+	stale := *movie
+	movie.Title = "Bacurau"
+	n, err := data.UpdateMovie(doc.ID.Hex(), *movie)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// Update with an outdated version
+	stale.Title = "O Som ao Redor"
+	n, err = data.UpdateMovie(doc.ID.Hex(), stale)
+	assert.True(t, persistence.IsConflict(err))
+	assert.Equal(t, int64(0), n)
+
+	// Update without version is unconditional
+	n, err = data.UpdateMovie(doc.ID.Hex(), models.Movie{Synopsis: "Synopsis"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	movie, err = data.GetMovie(doc.ID.Hex(), DefaultOptions(""))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Bacurau", movie.Title)
+		assert.Equal(t, int64(3), movie.Version)
+	}
+
+	// Missing documents are not a conflict
+	n, err = data.UpdateMovie(primitive.NewObjectID().Hex(), models.Movie{Version: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	// Documents stored before versioning get one once updated
+	old := primitive.NewObjectID()
+	assert.NoError(t, data.(*MemDAL).store.Insert(CollectionMovies, []bson.M{{"_id": old, "title": "Cinema Novo"}}))
+	n, err = data.UpdateMovie(old.Hex(), models.Movie{Title: "Aquarius"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = data.UpdateMovie(old.Hex(), models.Movie{Title: "Bacurau", Version: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestMoviePatch(t *testing.T) {
+	data, err := getTestingMemDAL()
+	defer data.Close()
+	assert.NoError(t, err)
+
+	doc := models.Movie{
+		ID:            primitive.NewObjectID(),
+		Title:         "Aquarius",
+		OriginalTitle: "Aquarius",
+		Synopsis:      "Synopsis",
+	}
+	assert.NoError(t, data.InsertMovie(doc))
+
+	n, err := data.PatchMovie(doc.ID.Hex(), 1, persistence.Update{
+		Set:   map[string]interface{}{"title": "Bacurau"},
+		Unset: []string{"synopsis"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	movie, err := data.GetMovie(doc.ID.Hex(), DefaultOptions(""))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Bacurau", movie.Title)
+		assert.Equal(t, "Aquarius", movie.OriginalTitle)
+		assert.Empty(t, movie.Synopsis)
+		assert.Equal(t, []string{"bacurau", "aquarius"}, movie.SearchKeys)
+		assert.Equal(t, int64(2), movie.Version)
+	}
+
+	// Patch with an outdated version
+	_, err = data.PatchMovie(doc.ID.Hex(), 1, persistence.Update{
+		Set: map[string]interface{}{"synopsis": "Stale"},
+	})
+	assert.True(t, persistence.IsConflict(err))
+}
@@ -0,0 +1,58 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertNotification ...
+func (m *MemDAL) InsertNotification(notification models.Notification) error {
+	return m.InsertOne(CollectionNotifications, notification)
+}
+
+// FindNotification ...
+func (m *MemDAL) FindNotification(query persistence.Query) (*models.Notification, error) {
+	var result models.Notification
+	err := m.FindOne(CollectionNotifications, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetNotification ...
+func (m *MemDAL) GetNotification(id string, query persistence.Query) (*models.Notification, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindNotification(query.AddCondition("_id", ID))
+}
+
+// GetNotifications ...
+func (m *MemDAL) GetNotifications(query persistence.Query) ([]models.Notification, error) {
+	var result []models.Notification
+	err := m.FindAll(CollectionNotifications, query, &result)
+	return result, err
+}
+
+// DeleteNotification ...
+func (m *MemDAL) DeleteNotification(id string) error {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return m.DeleteOne(CollectionNotifications, bson.M{"_id": ID})
+}
+
+// DeleteNotifications ...
+func (m *MemDAL) DeleteNotifications(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionNotifications, query)
+}
+
+// BuildNotificationQuery ...
+func (m *MemDAL) BuildNotificationQuery(q map[string]string) persistence.Query {
+	return BuildQuery("", q)
+}
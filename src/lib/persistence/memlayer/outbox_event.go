@@ -0,0 +1,48 @@
+package memlayer
+
+import (
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/bsonutil"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertOutboxEvent ...
+func (m *MemDAL) InsertOutboxEvent(event models.OutboxEvent) error {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	return m.store.Atomic(func(s Store) error {
+		tx := &MemDAL{store: s}
+		// Expired events are removed here, like the TTL index of mongolayer does.
+		query := tx.DefaultQuery().Where(persistence.Range("expiresAt", nil, time.Now().UTC()))
+		_, err := tx.DeleteMany(CollectionOutboxEvents, query)
+		if err != nil {
+			return err
+		}
+		return tx.InsertOne(CollectionOutboxEvents, event)
+	})
+}
+
+// GetPendingOutboxEvents ...
+func (m *MemDAL) GetPendingOutboxEvents(source string, limit int64) ([]models.OutboxEvent, error) {
+	var result []models.OutboxEvent
+	query := m.DefaultQuery().
+		Where(persistence.Eq("source", source), persistence.Eq("sentAt", nil), persistence.Eq("failedAt", nil)).
+		SetSort("timestamp").
+		SetLimit(limit)
+	err := m.FindAll(CollectionOutboxEvents, query, &result)
+	return result, err
+}
+
+// UpdateOutboxEvent ...
+func (m *MemDAL) UpdateOutboxEvent(id string, update persistence.Update) (int64, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+	return m.UpdateOne(CollectionOutboxEvents, bson.M{"_id": ID}, bsonutil.UpdateToBSON(update))
+}
@@ -0,0 +1,82 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertPrice ...
+func (m *MemDAL) InsertPrice(price models.Price) error {
+	if price.ID.IsZero() {
+		price.ID = primitive.NewObjectID()
+	}
+	return m.InsertOne(CollectionPrices, price)
+}
+
+// InsertPrices ...
+func (m *MemDAL) InsertPrices(prices ...models.Price) error {
+	arr := make([]interface{}, len(prices))
+	for i, p := range prices {
+		if p.ID.IsZero() {
+			p.ID = primitive.NewObjectID()
+		}
+		arr[i] = p
+	}
+	return m.InsertMany(CollectionPrices, arr)
+}
+
+// FindPrice ...
+func (m *MemDAL) FindPrice(query persistence.Query) (*models.Price, error) {
+	var result models.Price
+	err := m.FindOne(CollectionPrices, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetPrice ...
+func (m *MemDAL) GetPrice(id string, query persistence.Query) (*models.Price, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindPrice(query.AddCondition("_id", ID))
+}
+
+// GetPrices ...
+func (m *MemDAL) GetPrices(query persistence.Query) ([]models.Price, error) {
+	var result []models.Price
+	err := m.FindAll(CollectionPrices, query, &result)
+	return result, err
+}
+
+// DeletePrice ...
+func (m *MemDAL) DeletePrice(id string) error {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return m.DeleteOne(CollectionPrices, bson.M{"_id": ID})
+}
+
+// DeletePrices ...
+func (m *MemDAL) DeletePrices(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionPrices, query)
+}
+
+// BuildPriceQuery converts a map of query string to memlayer syntax for Price model
+func (m *MemDAL) BuildPriceQuery(q map[string]string) persistence.Query {
+	query := BuildQuery("", q)
+	if len(q) > 0 {
+		if theater, ok := q["theaterId"]; ok {
+			value, err := primitive.ObjectIDFromHex(theater)
+			if err == nil {
+				query.AddCondition("theaterId", value).SetLimit(-1)
+			}
+		}
+	}
+	return query
+}
@@ -0,0 +1,39 @@
+package memlayer
+
+import (
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// SaveProcessedEvent ...
+func (m *MemDAL) SaveProcessedEvent(event models.ProcessedEvent) error {
+	return m.store.Atomic(func(s Store) error {
+		tx := &MemDAL{store: s}
+		// Expired events are removed here, like the TTL index of mongolayer does.
+		query := tx.DefaultQuery().Where(persistence.Or(
+			persistence.Eq("_id", event.ID),
+			persistence.Range("expiresAt", nil, time.Now().UTC()),
+		))
+		_, err := tx.DeleteMany(CollectionProcessedEvents, query)
+		if err != nil {
+			return err
+		}
+		return tx.InsertOne(CollectionProcessedEvents, event)
+	})
+}
+
+// GetProcessedEvent ...
+func (m *MemDAL) GetProcessedEvent(id string) (*models.ProcessedEvent, error) {
+	var result models.ProcessedEvent
+	query := m.DefaultQuery().Where(
+		persistence.Eq("_id", id),
+		persistence.Range("expiresAt", time.Now().UTC(), nil),
+	)
+	err := m.FindOne(CollectionProcessedEvents, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,48 @@
+package memlayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessedEvent(t *testing.T) {
+	data, err := getTestingMemDAL()
+	assert.NoError(t, err)
+
+	now := time.Now().UTC()
+	event := models.ProcessedEvent{
+		ID:          models.ProcessedEventID("Image", "event"),
+		Consumer:    "Image",
+		EventID:     "event",
+		EventName:   "imageUpload",
+		ProcessedAt: now,
+		ExpiresAt:   now.Add(time.Hour),
+	}
+	assert.NoError(t, data.SaveProcessedEvent(event))
+	result, err := data.GetProcessedEvent("Image:event")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "imageUpload", result.EventName)
+	}
+	_, err = data.GetProcessedEvent("Score:event")
+	assert.Equal(t, persistence.ErrNotFound, err)
+
+	// Expired events are not found and can be saved again.
+	event.ExpiresAt = now.Add(-time.Minute)
+	assert.NoError(t, data.SaveProcessedEvent(event))
+	_, err = data.GetProcessedEvent("Image:event")
+	assert.Equal(t, persistence.ErrNotFound, err)
+
+	// Saving removes expired events.
+	event.ID = models.ProcessedEventID("Image", "other")
+	event.ExpiresAt = now.Add(time.Hour)
+	assert.NoError(t, data.SaveProcessedEvent(event))
+	var all []models.ProcessedEvent
+	assert.NoError(t, data.(*MemDAL).FindAll(CollectionProcessedEvents, data.DefaultQuery(), &all))
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, "Image:other", all[0].ID)
+	}
+}
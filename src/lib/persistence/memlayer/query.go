@@ -0,0 +1,178 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/bsonutil"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type (
+	// QueryInclude is a relation to be included in the results
+	QueryInclude struct {
+		Field  string // Name of the model to include, if plural it must include an array of the model
+		Fields []string
+	}
+
+	// QueryOptions implements persistence.Query
+	QueryOptions struct {
+		Conditions bson.M
+		Fields     bson.M
+		Sort       []string
+		Limit      int64
+		Skip       int64
+		Includes   []QueryInclude
+
+		sorting bool
+	}
+)
+
+// DefaultOptions ...
+func DefaultOptions(collectionName string) *QueryOptions {
+	return &QueryOptions{
+		Fields:     bson.M{},
+		Conditions: bson.M{},
+		Limit:      10,
+		Skip:       0,
+	}
+}
+
+// toOptions converts any persistence.Query to *QueryOptions, so queries built
+// for other implementations can still be used here.
+func toOptions(query persistence.Query) *QueryOptions {
+	if query == nil {
+		return &QueryOptions{Conditions: bson.M{}, Fields: bson.M{}}
+	}
+	if opts, ok := query.(*QueryOptions); ok {
+		if opts.Conditions == nil {
+			opts.Conditions = bson.M{}
+		}
+		return opts
+	}
+
+	result := &QueryOptions{
+		Conditions: bson.M{},
+		Fields:     bson.M{},
+		Sort:       query.GetSort(),
+		Limit:      query.GetLimit(),
+		Skip:       query.GetSkip(),
+		sorting:    query.Sorting(),
+	}
+	if conditions, err := toDocument(query.GetConditions()); err == nil {
+		result.Conditions = conditions
+	}
+	if fields, err := toDocument(query.GetFields()); err == nil {
+		result.Fields = fields
+	}
+	return result
+}
+
+func (q *QueryOptions) AddCondition(name string, value interface{}) persistence.Query {
+	if q.Conditions == nil {
+		q.Conditions = bson.M{}
+	}
+	q.Conditions[name] = value
+	return q
+}
+
+// Where adds the given conditions to the query.
+func (q *QueryOptions) Where(conditions ...persistence.Condition) persistence.Query {
+	if q.Conditions == nil {
+		q.Conditions = bson.M{}
+	}
+	for _, c := range conditions {
+		bsonutil.MergeConditions(q.Conditions, bsonutil.ConditionToBSON(c))
+	}
+	return q
+}
+
+func (q *QueryOptions) GetConditions() interface{} {
+	return q.Conditions
+}
+
+func (q *QueryOptions) GetCondition(name string) interface{} {
+	return q.Conditions[name]
+}
+
+func (q *QueryOptions) AddField(field string) persistence.Query {
+	if q.Fields == nil {
+		q.Fields = bson.M{}
+	}
+	q.Fields[field] = 1
+	return q
+}
+
+func (q *QueryOptions) SetFields(fields ...string) persistence.Query {
+	q.Fields = bson.M{}
+	for _, f := range fields {
+		q.Fields[f] = 1
+	}
+	return q
+}
+
+func (q *QueryOptions) Exclude(fields ...string) persistence.Query {
+	if q.Fields == nil {
+		q.Fields = bson.M{}
+	}
+	for _, f := range fields {
+		q.Fields[f] = 0
+	}
+	return q
+}
+
+func (q *QueryOptions) GetFields() interface{} {
+	return q.Fields
+}
+
+func (q *QueryOptions) SetSort(sort ...string) persistence.Query {
+	q.Sort = sort
+	q.sorting = len(sort) > 0
+	return q
+}
+
+func (q *QueryOptions) GetSort() []string {
+	return q.Sort
+}
+
+func (q *QueryOptions) Sorting() bool {
+	return q.sorting || len(q.Sort) > 0
+}
+
+func (q *QueryOptions) SetSkip(skip int64) persistence.Query {
+	q.Skip = skip
+	return q
+}
+
+func (q *QueryOptions) GetSkip() int64 {
+	return q.Skip
+}
+
+func (q *QueryOptions) SetLimit(limit int64) persistence.Query {
+	q.Limit = limit
+	return q
+}
+
+func (q *QueryOptions) GetLimit() int64 {
+	return q.Limit
+}
+
+func (q *QueryOptions) HasInclude() bool {
+	return len(q.Includes) > 0
+}
+
+func (q *QueryOptions) AddInclude(include ...string) persistence.Query {
+	for _, i := range include {
+		q.Includes = append(q.Includes, QueryInclude{
+			Field: i,
+		})
+	}
+	return q
+}
+
+func (q *QueryOptions) SetIncludes(includes interface{}) persistence.Query {
+	q.Includes = includes.([]QueryInclude)
+	return q
+}
+
+func (q *QueryOptions) GetIncludes() interface{} {
+	return q.Includes
+}
@@ -0,0 +1,80 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertScore ...
+func (m *MemDAL) InsertScore(score models.Score) error {
+	score.Version = 1
+	return m.InsertOne(CollectionScores, score)
+}
+
+// FindScore ...
+func (m *MemDAL) FindScore(query persistence.Query) (*models.Score, error) {
+	var result models.Score
+	err := m.FindOne(CollectionScores, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetScore ...
+func (m *MemDAL) GetScore(id string, query persistence.Query) (*models.Score, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindScore(query.AddCondition("_id", ID))
+}
+
+// GetScores ...
+func (m *MemDAL) GetScores(query persistence.Query) ([]models.Score, error) {
+	var result []models.Score
+	err := m.FindAll(CollectionScores, query, &result)
+	return result, err
+}
+
+// UpdateScore ...
+func (m *MemDAL) UpdateScore(id string, ms models.Score) (int64, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+	ms.UpdatedAt = getCurrentTime()
+	version := ms.Version
+	ms.Version = 0
+	return m.updateVersioned(CollectionScores, ID, version, bson.M{"$set": ms})
+}
+
+// DeleteScore ...
+func (m *MemDAL) DeleteScore(id string) error {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return m.DeleteOne(CollectionScores, bson.M{"_id": ID})
+}
+
+// DeleteScores ...
+func (m *MemDAL) DeleteScores(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionScores, query)
+}
+
+// BuildScoreQuery ...
+func (m *MemDAL) BuildScoreQuery(q map[string]string) persistence.Query {
+	query := BuildQuery("", q)
+	if len(q) > 0 {
+		if movie, ok := q["movieId"]; ok {
+			value, err := primitive.ObjectIDFromHex(movie)
+			if err == nil {
+				query.AddCondition("movieId", value)
+			}
+		}
+	}
+	return query
+}
@@ -0,0 +1,53 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertScraper ...
+func (m *MemDAL) InsertScraper(scraper models.Scraper) error {
+	return m.InsertOne(CollectionScrapers, scraper)
+}
+
+// FindScraper ...
+func (m *MemDAL) FindScraper(query persistence.Query) (*models.Scraper, error) {
+	var result models.Scraper
+	err := m.FindOne(CollectionScrapers, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetScraper ...
+func (m *MemDAL) GetScraper(id string, query persistence.Query) (*models.Scraper, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindScraper(query.AddCondition("_id", ID))
+}
+
+// GetScrapers ...
+func (m *MemDAL) GetScrapers(query persistence.Query) ([]models.Scraper, error) {
+	var result []models.Scraper
+	err := m.FindAll(CollectionScrapers, query, &result)
+	return result, err
+}
+
+// UpdateScraper ...
+func (m *MemDAL) UpdateScraper(id string, ms models.Scraper) (int64, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+	return m.UpdateOne(CollectionScrapers, bson.M{"_id": ID}, bson.M{"$set": ms})
+}
+
+// BuildScraperQuery converts a map of query string to memlayer syntax for Scraper model
+func (m *MemDAL) BuildScraperQuery(q map[string]string) persistence.Query {
+	return BuildQuery("", q)
+}
@@ -0,0 +1,52 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertScraperRun ...
+func (m *MemDAL) InsertScraperRun(scraperRun models.ScraperRun) error {
+	err := m.InsertOne(CollectionScraperRuns, scraperRun)
+	if err != nil {
+		return err
+	}
+	if scraperRun.Scraper != nil {
+		scraperRun.Scraper.Theater = nil // Make sure we don't store theater...
+		scraperRun.Scraper.LastRun = scraperRun.ID
+		_, err = m.UpdateScraper(scraperRun.ScraperID.Hex(), *scraperRun.Scraper)
+	}
+	return err
+}
+
+// FindScraperRun ...
+func (m *MemDAL) FindScraperRun(query persistence.Query) (*models.ScraperRun, error) {
+	var result models.ScraperRun
+	err := m.FindOne(CollectionScraperRuns, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetScraperRun ...
+func (m *MemDAL) GetScraperRun(id string, query persistence.Query) (*models.ScraperRun, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindScraperRun(query.AddCondition("_id", ID))
+}
+
+// GetScraperRuns ...
+func (m *MemDAL) GetScraperRuns(query persistence.Query) ([]models.ScraperRun, error) {
+	var result []models.ScraperRun
+	err := m.FindAll(CollectionScraperRuns, query, &result)
+	return result, err
+}
+
+// DeleteScraperRuns ...
+func (m *MemDAL) DeleteScraperRuns(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionScraperRuns, query)
+}
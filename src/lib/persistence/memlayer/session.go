@@ -0,0 +1,177 @@
+package memlayer
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/bsonutil"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertSession ...
+func (m *MemDAL) InsertSession(session models.Session) error {
+	return m.InsertOne(CollectionSessions, session)
+}
+
+// InsertSessions ...
+func (m *MemDAL) InsertSessions(sessions ...models.Session) error {
+	arr := make([]interface{}, len(sessions))
+	for i, p := range sessions {
+		arr[i] = p
+	}
+	return m.InsertMany(CollectionSessions, arr)
+}
+
+// FindSession ...
+func (m *MemDAL) FindSession(query persistence.Query) (*models.Session, error) {
+	var result models.Session
+	err := m.FindOne(CollectionSessions, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetSession ...
+func (m *MemDAL) GetSession(id string, query persistence.Query) (*models.Session, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindSession(query.AddCondition("_id", ID))
+}
+
+// GetSessions ...
+func (m *MemDAL) GetSessions(query persistence.Query) ([]models.Session, error) {
+	var result []models.Session
+	err := m.FindAll(CollectionSessions, query, &result)
+	return result, err
+}
+
+// DeleteSession ...
+func (m *MemDAL) DeleteSession(id string) error {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return m.DeleteOne(CollectionSessions, bson.M{"_id": ID})
+}
+
+// SyncSessions ...
+func (m *MemDAL) SyncSessions(theaterID primitive.ObjectID, window scheduleutil.Period, sessions []models.Session) (*persistence.SyncSummary, error) {
+	var summary *persistence.SyncSummary
+	err := m.WithTransaction(func(tx persistence.DataAccessLayer) error {
+		var err error
+		summary, err = tx.(*MemDAL).syncSessions(theaterID, window, sessions)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (m *MemDAL) syncSessions(theaterID primitive.ObjectID, window scheduleutil.Period, sessions []models.Session) (*persistence.SyncSummary, error) {
+	existing, err := m.GetSessions(m.DefaultQuery().
+		Where(
+			persistence.Eq("theaterId", theaterID),
+			persistence.Range("startTime", window.Start, window.End),
+		).
+		SetLimit(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	diff := persistence.DiffSessions(existing, sessions)
+	if len(diff.Insert) > 0 {
+		now := getCurrentTime()
+		for i := range diff.Insert {
+			diff.Insert[i].TheaterID = theaterID
+			diff.Insert[i].Hidden = false
+			if diff.Insert[i].CreatedAt == nil {
+				diff.Insert[i].CreatedAt = now
+			}
+		}
+		err = m.InsertSessions(diff.Insert...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = m.setSessionsHidden(diff.Hide, true)
+	if err != nil {
+		return nil, err
+	}
+	err = m.setSessionsHidden(diff.Show, false)
+	if err != nil {
+		return nil, err
+	}
+	return diff.Summary(), nil
+}
+
+func (m *MemDAL) setSessionsHidden(IDs []primitive.ObjectID, hidden bool) error {
+	if len(IDs) == 0 {
+		return nil
+	}
+	_, err := m.UpdateMany(CollectionSessions,
+		bson.M{"_id": bson.M{"$in": IDs}},
+		bson.M{"$set": bson.M{"hidden": hidden}},
+	)
+	return err
+}
+
+// DeleteSessions ...
+func (m *MemDAL) DeleteSessions(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionSessions, query)
+}
+
+// UpdateSessions ...
+func (m *MemDAL) UpdateSessions(query persistence.Query, update persistence.Update) (int64, error) {
+	return m.UpdateMany(CollectionSessions, toOptions(query).Conditions, bsonutil.UpdateToBSON(update))
+}
+
+// BuildSessionQuery ...
+func (m *MemDAL) BuildSessionQuery(q map[string]string) persistence.Query {
+	query := BuildQuery("", q)
+
+	// Sessions removed by scrapers are hidden unless requested otherwise
+	hidden := false
+	if v, ok := q["hidden"]; ok {
+		value, err := strconv.ParseBool(v)
+		if err == nil {
+			hidden = value
+		}
+	}
+	query.AddCondition("hidden", hidden)
+
+	if len(q) > 0 {
+		if theater, ok := q["theaterId"]; ok {
+			value, err := primitive.ObjectIDFromHex(theater)
+			if err == nil {
+				query.AddCondition("theaterId", value).SetLimit(-1)
+			}
+		} else if theaterIds, ok := q["theaterIds"]; ok {
+			values := []primitive.ObjectID{}
+			for _, ID := range strings.Split(theaterIds, ",") {
+				value, err := primitive.ObjectIDFromHex(ID)
+				if err == nil {
+					values = append(values, value)
+				}
+			}
+			if len(values) > 0 {
+				query.AddCondition("theaterId", bson.M{"$in": values}).SetLimit(-1)
+			}
+		}
+
+		if movie, ok := q["movieId"]; ok {
+			value, err := primitive.ObjectIDFromHex(movie)
+			if err == nil {
+				query.AddCondition("movieId", value)
+			}
+		}
+	}
+	return query
+}
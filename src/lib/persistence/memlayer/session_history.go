@@ -0,0 +1,41 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetSessionHistories ...
+func (m *MemDAL) GetSessionHistories(query persistence.Query) ([]models.SessionHistory, error) {
+	var result []models.SessionHistory
+	err := m.FindAll(CollectionSessionHistory, query, &result)
+	return result, err
+}
+
+// SaveSessionHistories ...
+func (m *MemDAL) SaveSessionHistories(histories ...models.SessionHistory) error {
+	if len(histories) == 0 {
+		return nil
+	}
+	now := getCurrentTime()
+	ids := make([]interface{}, len(histories))
+	docs := make([]interface{}, len(histories))
+	for i, h := range histories {
+		if h.CreatedAt == nil {
+			h.CreatedAt = now
+		}
+		h.UpdatedAt = now
+		ids[i] = h.ID
+		docs[i] = h
+	}
+	return m.WithTransaction(func(tx persistence.DataAccessLayer) error {
+		t := tx.(*MemDAL)
+		_, err := t.DeleteMany(CollectionSessionHistory, t.DefaultQuery().
+			AddCondition("_id", bson.M{"$in": ids}))
+		if err != nil {
+			return err
+		}
+		return t.InsertMany(CollectionSessionHistory, docs)
+	})
+}
@@ -0,0 +1,87 @@
+package memlayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSyncSessions(t *testing.T) {
+	data, err := getTestingMemDAL()
+	assert.NoError(t, err)
+
+	theaterID := primitive.NewObjectID()
+	movieID := primitive.NewObjectID()
+	start := time.Date(2019, 8, 1, 14, 0, 0, 0, time.UTC)
+	window := scheduleutil.Period{Start: start, End: start.Add(24 * time.Hour)}
+
+	session := func(hours int, room uint) models.Session {
+		t := start.Add(time.Duration(hours) * time.Hour)
+		return models.Session{
+			MovieID:   movieID,
+			Room:      room,
+			Format:    models.Format2D,
+			Version:   models.VersionDubbed,
+			StartTime: &t,
+		}
+	}
+
+	summary, err := data.SyncSessions(theaterID, window, []models.Session{
+		session(0, 1),
+		session(2, 1),
+		session(4, 2),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &persistence.SyncSummary{Added: 3}, summary)
+
+	before, err := data.GetSessions(data.BuildSessionQuery(map[string]string{"sort": "startTime"}))
+	assert.NoError(t, err)
+	assert.Len(t, before, 3)
+
+	// Second run moves a session to another room and drops another one
+	summary, err = data.SyncSessions(theaterID, window, []models.Session{
+		session(0, 1),
+		session(4, 3),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &persistence.SyncSummary{Added: 1, Removed: 2, Unchanged: 1}, summary)
+
+	after, err := data.GetSessions(data.BuildSessionQuery(map[string]string{"sort": "startTime"}))
+	assert.NoError(t, err)
+	if assert.Len(t, after, 2) {
+		// Unchanged sessions keep their IDs
+		assert.Equal(t, before[0].ID, after[0].ID)
+		assert.Equal(t, uint(3), after[1].Room)
+		assert.Equal(t, theaterID, after[1].TheaterID)
+	}
+
+	hidden, err := data.GetSessions(data.BuildSessionQuery(map[string]string{"hidden": "true"}))
+	assert.NoError(t, err)
+	assert.Len(t, hidden, 2)
+
+	// Removed sessions are shown again if they come back
+	summary, err = data.SyncSessions(theaterID, window, []models.Session{
+		session(0, 1),
+		session(2, 1),
+		session(4, 3),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &persistence.SyncSummary{Added: 1, Unchanged: 2}, summary)
+
+	session2, err := data.GetSession(before[1].ID.Hex(), data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.False(t, session2.Hidden)
+
+	// Sessions outside the window are left untouched
+	summary, err = data.SyncSessions(theaterID, scheduleutil.Period{
+		Start: window.End,
+		End:   window.End.Add(24 * time.Hour),
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &persistence.SyncSummary{}, summary)
+}
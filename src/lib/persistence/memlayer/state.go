@@ -0,0 +1,78 @@
+package memlayer
+
+import (
+	"strings"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// InsertState ...
+func (m *MemDAL) InsertState(state models.State) error {
+	state.ID = strings.ToUpper(state.ID)
+	return m.InsertOne(CollectionStates, state)
+}
+
+// FindState ...
+func (m *MemDAL) FindState(query persistence.Query) (*models.State, error) {
+	var result models.State
+	err := m.FindOne(CollectionStates, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetState ...
+func (m *MemDAL) GetState(id string, query persistence.Query) (*models.State, error) {
+	return m.FindState(query.AddCondition("_id", strings.ToUpper(id)))
+}
+
+// GetStates ...
+func (m *MemDAL) GetStates(query persistence.Query) ([]models.State, error) {
+	var result []models.State
+	err := m.FindAll(CollectionStates, query, &result)
+	return result, err
+}
+
+// UpdateState ...
+func (m *MemDAL) UpdateState(id string, s models.State) (int64, error) {
+	s.ID = strings.ToUpper(id)
+	s.UpdatedAt = getCurrentTime()
+	return m.UpdateOne(CollectionStates, bson.M{"_id": s.ID}, bson.M{"$set": s})
+}
+
+// DeleteState ...
+func (m *MemDAL) DeleteState(id string) error {
+	return m.DeleteOne(CollectionStates, bson.M{"_id": strings.ToUpper(id)})
+}
+
+// DeleteStates ...
+func (m *MemDAL) DeleteStates(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionStates, query)
+}
+
+// BuildStateQuery converts a map of query string to memlayer syntax for State model
+func (m *MemDAL) BuildStateQuery(q map[string]string) persistence.Query {
+	query := BuildQuery("", q)
+	if _, ok := q["limit"]; !ok {
+		// There are only a few states, so all of them are returned by default.
+		query.SetLimit(-1)
+	}
+	if len(q) > 0 {
+		ID, ok := q["id"]
+		if ok {
+			query.AddCondition("_id", strings.ToUpper(ID))
+		}
+		name, ok := q["name"]
+		if ok {
+			query.AddCondition("name", name)
+		}
+		timeZone, ok := q["timeZone"]
+		if ok {
+			query.AddCondition("timeZone", timeZone)
+		}
+	}
+	return query
+}
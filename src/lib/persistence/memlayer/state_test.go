@@ -0,0 +1,55 @@
+package memlayer
+
+import (
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestState(t *testing.T) {
+	data, err := getTestingMemDAL()
+	assert.NoError(t, err)
+
+	// Setup inserts the default states
+	states, err := data.GetStates(data.BuildStateQuery(map[string]string{}))
+	assert.NoError(t, err)
+	assert.Len(t, states, len(models.DefaultStates()))
+	assert.NoError(t, data.Setup())
+
+	state, err := data.GetState("mg", data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, "Minas Gerais", state.Name)
+	assert.Equal(t, "America/Sao_Paulo", state.TimeZone)
+
+	_, err = data.GetState("XX", data.DefaultQuery())
+	assert.Equal(t, persistence.ErrNotFound, err)
+
+	// Update
+	_, err = data.UpdateState(models.AC, models.State{Name: "Acre", TimeZone: "America/Eirunepe"})
+	assert.NoError(t, err)
+	states, err = data.GetStates(data.BuildStateQuery(map[string]string{"timeZone": "America/Eirunepe"}))
+	assert.NoError(t, err)
+	if assert.Len(t, states, 1) {
+		assert.Equal(t, models.AC, states[0].ID)
+		assert.NotNil(t, states[0].UpdatedAt)
+	}
+
+	// Cities without time zone use the state default
+	city := models.City{ID: primitive.NewObjectID(), Name: "Rio Branco", StateID: models.AC}
+	assert.NoError(t, data.InsertCity(city))
+	result, err := data.GetCity(city.ID.Hex(), data.DefaultQuery().AddInclude("state"))
+	assert.NoError(t, err)
+	assert.Equal(t, "America/Eirunepe", result.TimeZone)
+	if assert.NotNil(t, result.State) {
+		assert.Equal(t, "Acre", result.State.Name)
+	}
+
+	// Delete
+	assert.NoError(t, data.DeleteState(models.AC))
+	deleted, err := data.DeleteStates(data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(models.DefaultStates())-1), deleted)
+}
@@ -0,0 +1,191 @@
+package memlayer
+
+import (
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type (
+	// Store keeps the documents of each collection. MemDAL evaluates
+	// conditions, updates, includes and projections by itself, so a Store
+	// only needs to save and load documents.
+	//
+	// Documents given to and returned by a Store must only contain bson.M
+	// documents and []interface{} arrays, see UnmarshalDocument.
+	Store interface {
+		// Find returns the documents of the collection in insertion order. The
+		// filter is only a hint, it may be used to skip documents that can't
+		// match but documents are matched again by MemDAL.
+		Find(collectionName string, filter bson.M) ([]bson.M, error)
+
+		// Insert adds the documents to the collection. If any of the _id values
+		// is already stored it returns ErrDuplicateKey and inserts nothing.
+		Insert(collectionName string, docs []bson.M) error
+
+		// Replace stores each document in place of the one with the same _id.
+		Replace(collectionName string, docs []bson.M) error
+
+		// Delete removes the documents with the given _id values.
+		Delete(collectionName string, ids []interface{}) error
+
+		// Atomic runs fn with a Store whose changes are kept only if fn returns
+		// nil. Calling Atomic on that Store runs fn as part of the same unit.
+		Atomic(fn func(s Store) error) error
+	}
+
+	// memoryStore keeps documents in memory.
+	memoryStore struct {
+		mu          sync.RWMutex
+		txMu        sync.Mutex
+		collections map[string][]bson.M
+	}
+
+	// memoryTx is the Store given to Atomic callbacks. It saves a copy of each
+	// collection before changing it so they can be restored.
+	memoryTx struct {
+		*memoryStore
+		saved map[string][]bson.M
+	}
+)
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		collections: make(map[string][]bson.M),
+	}
+}
+
+// Find returns all documents of the collection. Documents are never modified
+// in place so they can be shared.
+func (s *memoryStore) Find(collectionName string, filter bson.M) ([]bson.M, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]bson.M(nil), s.collections[collectionName]...), nil
+}
+
+// Insert ...
+func (s *memoryStore) Insert(collectionName string, docs []bson.M) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing := s.collections[collectionName]
+	ids := make(map[interface{}]bool, len(existing)+len(docs))
+	for _, doc := range existing {
+		ids[idKey(doc["_id"])] = true
+	}
+	for _, doc := range docs {
+		key := idKey(doc["_id"])
+		if ids[key] {
+			return fmt.Errorf("%s: collection %s _id %v", ErrDuplicateKey, collectionName, doc["_id"])
+		}
+		ids[key] = true
+	}
+
+	s.collections[collectionName] = append(existing[:len(existing):len(existing)], docs...)
+	return nil
+}
+
+// Replace ...
+func (s *memoryStore) Replace(collectionName string, docs []bson.M) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replacements := make(map[interface{}]bson.M, len(docs))
+	for _, doc := range docs {
+		replacements[idKey(doc["_id"])] = doc
+	}
+
+	existing := s.collections[collectionName]
+	result := make([]bson.M, len(existing))
+	for i, doc := range existing {
+		if r, ok := replacements[idKey(doc["_id"])]; ok {
+			doc = r
+		}
+		result[i] = doc
+	}
+	s.collections[collectionName] = result
+	return nil
+}
+
+// Delete ...
+func (s *memoryStore) Delete(collectionName string, ids []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := make(map[interface{}]bool, len(ids))
+	for _, id := range ids {
+		deleted[idKey(id)] = true
+	}
+
+	existing := s.collections[collectionName]
+	kept := make([]bson.M, 0, len(existing))
+	for _, doc := range existing {
+		if !deleted[idKey(doc["_id"])] {
+			kept = append(kept, doc)
+		}
+	}
+	s.collections[collectionName] = kept
+	return nil
+}
+
+// Atomic runs one fn at a time and restores the collections it changed if
+// it returns an error.
+//
+// NOTE: There's no isolation, changes made by other goroutines without Atomic
+// while fn is running are discarded too if it fails.
+func (s *memoryStore) Atomic(fn func(s Store) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	tx := &memoryTx{s, make(map[string][]bson.M)}
+	err := fn(tx)
+	if err != nil {
+		s.mu.Lock()
+		for name, docs := range tx.saved {
+			s.collections[name] = docs
+		}
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// reset removes all documents from every collection.
+func (s *memoryStore) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.collections = make(map[string][]bson.M)
+}
+
+// Insert ...
+func (tx *memoryTx) Insert(collectionName string, docs []bson.M) error {
+	tx.save(collectionName)
+	return tx.memoryStore.Insert(collectionName, docs)
+}
+
+// Replace ...
+func (tx *memoryTx) Replace(collectionName string, docs []bson.M) error {
+	tx.save(collectionName)
+	return tx.memoryStore.Replace(collectionName, docs)
+}
+
+// Delete ...
+func (tx *memoryTx) Delete(collectionName string, ids []interface{}) error {
+	tx.save(collectionName)
+	return tx.memoryStore.Delete(collectionName, ids)
+}
+
+// Atomic runs fn as part of the current unit.
+func (tx *memoryTx) Atomic(fn func(s Store) error) error {
+	return fn(tx)
+}
+
+// save keeps the documents of the collection the first time it's changed.
+func (tx *memoryTx) save(collectionName string) {
+	if _, ok := tx.saved[collectionName]; ok {
+		return
+	}
+	tx.mu.RLock()
+	tx.saved[collectionName] = tx.collections[collectionName]
+	tx.mu.RUnlock()
+}
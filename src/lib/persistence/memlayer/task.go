@@ -0,0 +1,69 @@
+package memlayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// InsertTask ...
+func (m *MemDAL) InsertTask(task models.Task) error {
+	return m.InsertOne(CollectionTasks, task)
+}
+
+// FindTask ...
+func (m *MemDAL) FindTask(query persistence.Query) (*models.Task, error) {
+	var result models.Task
+	err := m.FindOne(CollectionTasks, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetTask ...
+func (m *MemDAL) GetTask(id string, query persistence.Query) (*models.Task, error) {
+	return m.FindTask(query.AddCondition("_id", id))
+}
+
+// GetTasks ...
+func (m *MemDAL) GetTasks(query persistence.Query) ([]models.Task, error) {
+	var result []models.Task
+	err := m.FindAll(CollectionTasks, query, &result)
+	return result, err
+}
+
+// UpdateTask ...
+func (m *MemDAL) UpdateTask(id string, mt models.Task) (int64, error) {
+	return m.UpdateOne(CollectionTasks, bson.M{"_id": id}, bson.M{"$set": mt})
+}
+
+// DeleteTask ...
+func (m *MemDAL) DeleteTask(id string) error {
+	return m.DeleteOne(CollectionTasks, bson.M{"_id": id})
+}
+
+// DeleteTasks ...
+func (m *MemDAL) DeleteTasks(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionTasks, query)
+}
+
+// EnsureTasksExists inserts the given tasks that are not stored yet.
+func (m *MemDAL) EnsureTasksExists(mp map[string]models.Task) {
+	if m == nil {
+		return
+	}
+	for _, v := range mp {
+		ID := v.ID
+		if ID == "" {
+			_, ID = v.GenerateID()
+			v.ID = ID
+		}
+		if ID != "" {
+			_, err := m.GetTask(ID, m.DefaultQuery())
+			if err == persistence.ErrNotFound {
+				m.InsertTask(v)
+			}
+		}
+	}
+}
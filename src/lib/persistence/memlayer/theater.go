@@ -0,0 +1,131 @@
+package memlayer
+
+import (
+	"strconv"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/searchutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CountTheaters ...
+func (m *MemDAL) CountTheaters(query persistence.Query) (int64, error) {
+	return m.Count(CollectionTheaters, query)
+}
+
+// InsertTheater ...
+func (m *MemDAL) InsertTheater(theater models.Theater) error {
+	theater.UpdateSearchKeys()
+	theater.Distance = 0 // Only set in query results
+	theater.Version = 1
+	return m.InsertOne(CollectionTheaters, theater)
+}
+
+// FindTheater ...
+func (m *MemDAL) FindTheater(query persistence.Query) (*models.Theater, error) {
+	var result models.Theater
+	err := m.FindOne(CollectionTheaters, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetTheater ...
+func (m *MemDAL) GetTheater(id string, query persistence.Query) (*models.Theater, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindTheater(query.AddCondition("_id", ID))
+}
+
+// GetTheaters ...
+func (m *MemDAL) GetTheaters(query persistence.Query) ([]models.Theater, error) {
+	var result []models.Theater
+	err := m.FindAll(CollectionTheaters, query, &result)
+	return result, err
+}
+
+// DeleteTheater ...
+func (m *MemDAL) DeleteTheater(id string) error {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return m.DeleteOne(CollectionTheaters, bson.M{"_id": ID})
+}
+
+// DeleteTheaters ...
+func (m *MemDAL) DeleteTheaters(query persistence.Query) (int64, error) {
+	return m.DeleteMany(CollectionTheaters, query)
+}
+
+// UpdateTheater ...
+func (m *MemDAL) UpdateTheater(id string, mt models.Theater) (int64, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+	mt.ID = ID // _id isn't omitted when empty and can't be modified
+	mt.UpdateSearchKeys()
+	mt.Distance = 0 // Only set in query results
+	mt.UpdatedAt = getCurrentTime()
+	version := mt.Version
+	mt.Version = 0
+	return m.updateVersioned(CollectionTheaters, ID, version, bson.M{"$set": mt})
+}
+
+// BuildTheaterQuery converts a map of query string to memlayer syntax for Theater model
+func (m *MemDAL) BuildTheaterQuery(q map[string]string) persistence.Query {
+	query := BuildQuery("", q)
+	if len(q) > 0 {
+		ID, ok := q["id"]
+		if ok {
+			value, err := primitive.ObjectIDFromHex(ID)
+			if err == nil {
+				query.AddCondition("_id", value)
+			}
+		}
+
+		ID, ok = q["internalId"]
+		if ok {
+			query.AddCondition("internalId", ID)
+		}
+
+		// Hidden
+		hidden, ok := q["hidden"]
+		if ok {
+			value, err := strconv.ParseBool(hidden)
+			if err == nil {
+				query.AddCondition("hidden", value)
+			}
+		}
+
+		search, ok := q["search"]
+		if ok {
+			query.Where(searchutil.Conditions(search)...)
+		}
+
+		// Near, e.g.: near=-19.9191,-43.9386&radius=10000. The radius is in
+		// meters and results are sorted by distance unless sorted otherwise.
+		near, ok := q["near"]
+		if ok {
+			point, err := models.ParseGeoPoint(near)
+			if err == nil {
+				radius := float64(persistence.DefaultNearRadius)
+				value, err := strconv.ParseFloat(q["radius"], 64)
+				if err == nil && value > 0 {
+					radius = value
+				}
+				query.Where(persistence.Near("location", point, radius))
+				if _, ok := q["sort"]; !ok {
+					query.SetSort(persistence.SortDistance)
+				}
+			}
+		}
+	}
+	return query
+}
@@ -0,0 +1,94 @@
+package memlayer
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// applyUpdate returns a copy of the document with the given update operators
+// applied. Supported operators are $set, $unset, $inc, $push and $addToSet.
+func applyUpdate(doc bson.M, update interface{}) (bson.M, error) {
+	elements, ok := asElements(update)
+	if !ok {
+		return nil, fmt.Errorf("invalid update type %T", update)
+	}
+	if len(elements) == 0 {
+		return nil, errors.New("update document must not be empty")
+	}
+
+	result := copyDocument(doc)
+	for _, e := range elements {
+		if !strings.HasPrefix(e.Key, "$") {
+			return nil, errors.New("update document requires atomic operators")
+		}
+
+		values, err := toDocument(e.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		for path, value := range values {
+			if path == "_id" && e.Key == "$set" {
+				if !equals(result["_id"], value) {
+					return nil, errors.New("performing an update on the path '_id' would modify the immutable field '_id'")
+				}
+				continue
+			}
+
+			switch e.Key {
+			case "$set":
+				setPath(result, path, value)
+			case "$unset":
+				unsetPath(result, path)
+			case "$inc":
+				inc, ok := toFloat(value)
+				if !ok {
+					return nil, fmt.Errorf("cannot increment with non-numeric argument: %s", path)
+				}
+				current, _ := getPath(result, path)
+				if current == nil {
+					setPath(result, path, value)
+					continue
+				}
+				n, ok := toFloat(current)
+				if !ok {
+					return nil, fmt.Errorf("cannot apply $inc to a value of non-numeric type: %s", path)
+				}
+				switch current.(type) {
+				case int32:
+					setPath(result, path, int32(n+inc))
+				case int64:
+					setPath(result, path, int64(n+inc))
+				default:
+					setPath(result, path, n+inc)
+				}
+			case "$push", "$addToSet":
+				current, _ := getPath(result, path)
+				list, ok := current.([]interface{})
+				if current != nil && !ok {
+					return nil, fmt.Errorf("the field '%s' must be an array", path)
+				}
+				if e.Key == "$addToSet" {
+					exists := false
+					for _, v := range list {
+						if reflect.DeepEqual(v, value) {
+							exists = true
+							break
+						}
+					}
+					if exists {
+						continue
+					}
+				}
+				setPath(result, path, append(list, value))
+			default:
+				return nil, fmt.Errorf("unknown modifier: %s", e.Key)
+			}
+		}
+	}
+	return result, nil
+}
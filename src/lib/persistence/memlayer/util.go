@@ -0,0 +1,153 @@
+package memlayer
+
+import (
+	"strings"
+
+	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// relation describes how documents of a collection are joined with documents
+// of another one. It mirrors the lookups built by mongolayer.
+type relation struct {
+	from         string
+	localField   string
+	foreignField string
+	as           string
+	unwind       bool
+}
+
+var relations = map[string]map[string]relation{
+	CollectionTheaters: {
+		CollectionPrices:   {from: CollectionPrices, localField: "_id", foreignField: "theaterId", as: "prices"},
+		CollectionSessions: {from: CollectionSessions, localField: "_id", foreignField: "theaterId", as: "sessions"},
+		CollectionCities:   {from: CollectionCities, localField: "cityId", foreignField: "_id", as: "city", unwind: true},
+	},
+	CollectionMovies: {
+		// NOTE: scores are kept as an array since models.Movie.Scores is a slice.
+		CollectionScores:   {from: CollectionScores, localField: "_id", foreignField: "movieId", as: "scores"},
+		CollectionSessions: {from: CollectionSessions, localField: "_id", foreignField: "movieId", as: "sessions"},
+	},
+	CollectionImages: {
+		CollectionMovies: {from: CollectionMovies, localField: "movieId", foreignField: "_id", as: "movie", unwind: true},
+	},
+	CollectionSessions: {
+		CollectionTheaters: {from: CollectionTheaters, localField: "theaterId", foreignField: "_id", as: "theater", unwind: true},
+		CollectionMovies:   {from: CollectionMovies, localField: "movieId", foreignField: "_id", as: "movie", unwind: true},
+	},
+	CollectionPrices: {
+		CollectionTheaters: {from: CollectionTheaters, localField: "theaterId", foreignField: "_id", as: "theater", unwind: true},
+	},
+	CollectionCities: {
+		CollectionStates: {from: CollectionStates, localField: "stateId", foreignField: "_id", as: "state", unwind: true},
+	},
+}
+
+func getCollectionName(s string) string {
+	switch strings.TrimSpace(s) {
+	case "apikeys", "apikey", "api_keys":
+		return CollectionAPIKeys
+	case "cities", "city":
+		return CollectionCities
+	case "images", "image":
+		return CollectionImages
+	case "integrity_reports", "integrity_report":
+		return CollectionIntegrityReports
+	case "movies", "movie":
+		return CollectionMovies
+	case "notifications", "notification":
+		return CollectionNotifications
+	case "prices", "price":
+		return CollectionPrices
+	case "scrapers", "scraper":
+		return CollectionScrapers
+	case "scraper_runs", "scraper_run", "runs", "run":
+		return CollectionScraperRuns
+	case "scores", "score":
+		return CollectionScores
+	case "sessions", "session", "showtimes", "showtime":
+		return CollectionSessions
+	case "session_history":
+		return CollectionSessionHistory
+	case "states", "state":
+		return CollectionStates
+	case "theaters", "theater":
+		return CollectionTheaters
+	}
+	return ""
+}
+
+func relationFor(collectionName, include string) (relation, bool) {
+	rel, ok := relations[collectionName][getCollectionName(include)]
+	return rel, ok
+}
+
+// lookup joins each document with the documents of the included collection.
+// Includes without a defined relation are ignored.
+func (m *MemDAL) lookup(collectionName string, docs []bson.M, include QueryInclude) ([]bson.M, error) {
+	rel, ok := relationFor(collectionName, include.Field)
+	if !ok {
+		return docs, nil
+	}
+
+	locals := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		if local, _ := getPath(doc, rel.localField); local != nil {
+			locals = append(locals, local)
+		}
+	}
+	foreign, err := m.store.Find(rel.from, bson.M{rel.foreignField: bson.M{"$in": locals}})
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: Temporary.
+	//
+	// Same default options used by mongolayer for sessions includes.
+	if rel.from == CollectionSessions {
+		period := scheduleutil.GetWeekPeriod(nil)
+		filtered := make([]bson.M, 0)
+		for _, f := range foreign {
+			if compareAny(f["startTime"], normalizeValue(period.Start), "$gte") && f["hidden"] != true {
+				filtered = append(filtered, f)
+			}
+		}
+		sortDocuments(filtered, []string{"-movieId", "version", "format", "startTime"})
+		foreign = filtered
+	}
+
+	fields := bson.M{}
+	for _, f := range include.Fields {
+		if f != "" {
+			fields[f] = 1
+		}
+	}
+
+	result := make([]bson.M, 0, len(docs))
+	for _, doc := range docs {
+		local, _ := getPath(doc, rel.localField)
+
+		joined := make([]interface{}, 0)
+		for _, f := range foreign {
+			value, _ := getPath(f, rel.foreignField)
+			if local != nil && equals(value, local) {
+				joined = append(joined, project(f, fields))
+			}
+		}
+
+		if !rel.unwind {
+			d := copyDocument(doc)
+			d[rel.as] = joined
+			result = append(result, d)
+			continue
+		}
+
+		// $unwind drops documents without a match
+		for _, j := range joined {
+			d := copyDocument(doc)
+			d[rel.as] = j
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LatestMigration is the version given to MigrateTo to apply every migration.
+const LatestMigration = -1
+
+// Migrator is implemented by data access layers which keep their schema
+// under versioned migrations.
+type Migrator interface {
+	// Migrate applies all pending migrations.
+	Migrate() error
+
+	// MigrateTo applies or reverts migrations until the schema is at the given
+	// version. Version zero reverts every migration and LatestMigration
+	// applies all of them.
+	// @param	version{int} - Target schema version
+	MigrateTo(version int) error
+
+	// MigrationStatus lists every known migration and when it was applied.
+	MigrationStatus() ([]MigrationStatus, error)
+}
+
+// MigrationStatus describes a single migration.
+type MigrationStatus struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	AppliedAt   *time.Time `json:"appliedAt,omitempty"`
+}
+
+// MigrationStep is a migration bound to the database of a backend. Up applies
+// the migration and Down reverts it, both also record it as applied or not.
+// Down is nil for irreversible migrations.
+type MigrationStep struct {
+	Version     int
+	Description string
+	Up          func() error
+	Down        func() error
+}
+
+// ValidateMigrations makes sure versions are positive, unique and sorted.
+func ValidateMigrations(steps []MigrationStep) error {
+	if len(steps) == 0 {
+		return errors.New("there are no migrations")
+	}
+	sorted := sort.SliceIsSorted(steps, func(i, j int) bool {
+		return steps[i].Version < steps[j].Version
+	})
+	if !sorted {
+		return errors.New("migrations must be sorted by version")
+	}
+	for i, mm := range steps {
+		if mm.Version <= 0 {
+			return fmt.Errorf("migration %q has invalid version %d", mm.Description, mm.Version)
+		}
+		if i > 0 && steps[i-1].Version == mm.Version {
+			return fmt.Errorf("duplicated migration version %d", mm.Version)
+		}
+		if mm.Up == nil {
+			return fmt.Errorf("migration %d is missing its up function", mm.Version)
+		}
+	}
+	return nil
+}
+
+// RunMigrations applies or reverts steps until the schema is at the given
+// version, see Migrator.MigrateTo. Applied holds the versions already applied.
+// Newer migrations are reverted first, from the newest to the oldest, then
+// the missing ones are applied in order. Backends run it holding their lock
+// or transaction.
+func RunMigrations(steps []MigrationStep, applied map[int]time.Time, version int) error {
+	if err := ValidateMigrations(steps); err != nil {
+		return err
+	}
+	if version == LatestMigration {
+		version = steps[len(steps)-1].Version
+	}
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		mm := steps[i]
+		if mm.Version <= version {
+			break
+		}
+		if _, ok := applied[mm.Version]; !ok {
+			continue
+		}
+		if mm.Down == nil {
+			return fmt.Errorf("migration %d (%s) can't be reverted", mm.Version, mm.Description)
+		}
+		if err := mm.Down(); err != nil {
+			return fmt.Errorf("reverting migration %d (%s): %v", mm.Version, mm.Description, err)
+		}
+	}
+
+	for _, mm := range steps {
+		if mm.Version > version {
+			break
+		}
+		if _, ok := applied[mm.Version]; ok {
+			continue
+		}
+		if err := mm.Up(); err != nil {
+			return fmt.Errorf("applying migration %d (%s): %v", mm.Version, mm.Description, err)
+		}
+	}
+	return nil
+}
+
+// MigrationStatuses lists every step and when it was applied.
+func MigrationStatuses(steps []MigrationStep, applied map[int]time.Time) []MigrationStatus {
+	result := make([]MigrationStatus, len(steps))
+	for i, mm := range steps {
+		result[i] = MigrationStatus{
+			Version:     mm.Version,
+			Description: mm.Description,
+		}
+		if t, ok := applied[mm.Version]; ok {
+			result[i].AppliedAt = &t
+		}
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMigrations(t *testing.T) {
+	up := func() error { return nil }
+	assert.Error(t, ValidateMigrations(nil))
+	assert.Error(t, ValidateMigrations([]MigrationStep{{Version: 0, Up: up}}))
+	assert.Error(t, ValidateMigrations([]MigrationStep{{Version: 1}}))
+	assert.Error(t, ValidateMigrations([]MigrationStep{{Version: 2, Up: up}, {Version: 1, Up: up}}))
+	assert.Error(t, ValidateMigrations([]MigrationStep{{Version: 1, Up: up}, {Version: 1, Up: up}}))
+	assert.NoError(t, ValidateMigrations([]MigrationStep{{Version: 1, Up: up}, {Version: 3, Up: up}}))
+}
+
+func TestRunMigrations(t *testing.T) {
+	var calls []string
+	applied := make(map[int]time.Time)
+	step := func(version int, reversible bool) MigrationStep {
+		s := MigrationStep{
+			Version: version,
+			Up: func() error {
+				calls = append(calls, fmt.Sprintf("up %d", version))
+				applied[version] = time.Now()
+				return nil
+			},
+		}
+		if reversible {
+			s.Down = func() error {
+				calls = append(calls, fmt.Sprintf("down %d", version))
+				delete(applied, version)
+				return nil
+			}
+		}
+		return s
+	}
+	steps := []MigrationStep{step(1, false), step(2, true), step(3, true)}
+
+	assert.NoError(t, RunMigrations(steps, applied, 2))
+	assert.NoError(t, RunMigrations(steps, applied, LatestMigration))
+	assert.Equal(t, []string{"up 1", "up 2", "up 3"}, calls)
+
+	calls = nil
+	assert.NoError(t, RunMigrations(steps, applied, 1))
+	assert.Equal(t, []string{"down 3", "down 2"}, calls)
+
+	assert.EqualError(t, RunMigrations(steps, applied, 0), "migration 1 () can't be reverted")
+
+	steps[1].Up = func() error { return errors.New("failed") }
+	assert.EqualError(t, RunMigrations(steps, applied, 2), "applying migration 2 (): failed")
+
+	statuses := MigrationStatuses(steps, applied)
+	if assert.Len(t, statuses, 3) {
+		assert.NotNil(t, statuses[0].AppliedAt)
+		assert.Nil(t, statuses[1].AppliedAt)
+	}
+}
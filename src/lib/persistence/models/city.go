@@ -3,15 +3,26 @@ package models
 import (
 	"time"
 
+	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// City represents a city.
+// City represents a city. Its state is the code of the state in JSON, as it
+// was before states had their own collection, and the included State is
+// under stateInfo.
 type City struct {
-	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id"`
-	State     State              `json:"state,omitempty" bson:"state,omitempty"`
-	Name      string             `json:"name,omitempty" bson:"name"`
-	TimeZone  string             `json:"timeZone,omitempty" bson:"timeZone"`
-	CreatedAt *time.Time         `json:"createdAt,omitempty" bson:"createdAt"`
-	UpdatedAt *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt"`
+	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id"`
+	StateID    string             `json:"state,omitempty" bson:"stateId,omitempty"`
+	Name       string             `json:"name,omitempty" bson:"name"`
+	TimeZone   string             `json:"timeZone,omitempty" bson:"timeZone"`
+	CreatedAt  *time.Time         `json:"createdAt,omitempty" bson:"createdAt"`
+	UpdatedAt  *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt"`
+	State      *State             `json:"stateInfo,omitempty" bson:"state,omitempty"`
+	SearchKeys []string           `json:"-" bson:"searchKeys,omitempty"` // See UpdateSearchKeys
+}
+
+// UpdateSearchKeys sets SearchKeys to the folded words of the name. They're
+// used for accent insensitive prefix search.
+func (c *City) UpdateSearchKeys() {
+	c.SearchKeys = stringutil.SearchKeys(c.Name)
 }
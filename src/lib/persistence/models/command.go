@@ -24,6 +24,15 @@ const (
 
 	// CommandSyncScores syncs scores of now playing movies
 	CommandSyncScores = "sync_scores"
+
+	// CommandExportDataset exports all collections of the catalog or the one(s) specified in args to a file.
+	CommandExportDataset = "export_dataset"
+
+	// CommandImportDataset imports all collections or the one(s) specified in args from a file.
+	CommandImportDataset = "import_dataset"
+
+	// CommandCheckIntegrity reports integrity issues and repairs them if specified in args.
+	CommandCheckIntegrity = "check_integrity"
 )
 
 // Command ...
@@ -67,7 +76,10 @@ func (cmd *Command) IsValid() bool {
 		cmd.Name == CommandClearShowtimes ||
 		cmd.Name == CommandStartScraper ||
 		cmd.Name == CommandCheckOpeningMovies ||
-		cmd.Name == CommandSyncScores)
+		cmd.Name == CommandSyncScores ||
+		cmd.Name == CommandExportDataset ||
+		cmd.Name == CommandImportDataset ||
+		cmd.Name == CommandCheckIntegrity)
 
 	return result
 }
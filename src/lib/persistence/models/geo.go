@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EarthRadius is the radius of the Earth in meters, the same used by MongoDB
+// in spherical queries.
+const EarthRadius = 6378100.0
+
+// GeoPoint is a GeoJSON point.
+type GeoPoint struct {
+	Type        string    `json:"type" bson:"type"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"` // Longitude and latitude, in this order.
+}
+
+// NewGeoPoint creates a GeoPoint with the given latitude and longitude.
+func NewGeoPoint(lat, lng float64) GeoPoint {
+	return GeoPoint{Type: "Point", Coordinates: []float64{lng, lat}}
+}
+
+// ParseGeoPoint parses a point in the lat,lng format, e.g.:
+// -19.9191,-43.9386.
+func ParseGeoPoint(s string) (GeoPoint, error) {
+	values := strings.Split(s, ",")
+	if len(values) != 2 {
+		return GeoPoint{}, fmt.Errorf("invalid point %q: expected lat,lng", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(values[0]), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return GeoPoint{}, fmt.Errorf("invalid latitude %q", values[0])
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(values[1]), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return GeoPoint{}, fmt.Errorf("invalid longitude %q", values[1])
+	}
+	return NewGeoPoint(lat, lng), nil
+}
+
+// Lat returns the latitude of the point.
+func (p GeoPoint) Lat() float64 {
+	if len(p.Coordinates) < 2 {
+		return 0
+	}
+	return p.Coordinates[1]
+}
+
+// Lng returns the longitude of the point.
+func (p GeoPoint) Lng() float64 {
+	if len(p.Coordinates) < 1 {
+		return 0
+	}
+	return p.Coordinates[0]
+}
+
+// Distance returns the great-circle distance in meters between two points.
+func Distance(a, b GeoPoint) float64 {
+	rad := math.Pi / 180
+	dLat := (b.Lat() - a.Lat()) * rad
+	dLng := (b.Lng() - a.Lng()) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.Lat()*rad)*math.Cos(b.Lat()*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
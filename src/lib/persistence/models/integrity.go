@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// IntegrityOrphaned is the kind of issue of references to documents that
+	// don't exist.
+	IntegrityOrphaned = "orphaned"
+
+	// IntegrityUnresolved is the kind of issue of sessions whose movie was
+	// never found, so they have no movie ID.
+	IntegrityUnresolved = "unresolved"
+
+	// IntegrityRematched is the repair of sessions matched again to a movie.
+	IntegrityRematched = "rematched"
+
+	// IntegrityHidden is the repair of sessions that couldn't be matched.
+	IntegrityHidden = "hidden"
+
+	// MaxIntegrityIssues is the maximum number of issues listed in a report.
+	// Every issue is counted regardless.
+	MaxIntegrityIssues = 1000
+)
+
+// IntegrityReport is the result of a check of the references between
+// collections.
+type IntegrityReport struct {
+	ID        primitive.ObjectID        `json:"_id" bson:"_id"`
+	Repair    bool                      `json:"repair" bson:"repair"`       // Whether issues were repaired
+	Checked   map[string]int            `json:"checked" bson:"checked"`     // Number of documents checked by collection
+	Counts    map[string]IntegrityCount `json:"counts" bson:"counts"`       // Number of issues by collection
+	Issues    []IntegrityIssue          `json:"issues" bson:"issues"`       // Up to MaxIntegrityIssues issues
+	Truncated bool                      `json:"truncated" bson:"truncated"` // Whether some issues were not listed
+	StartTime *time.Time                `json:"startTime" bson:"startTime"`
+	EndTime   *time.Time                `json:"endTime" bson:"endTime"`
+}
+
+// IntegrityCount holds the number of issues of a collection by kind and
+// repair.
+type IntegrityCount struct {
+	Orphaned   int `json:"orphaned" bson:"orphaned"`
+	Unresolved int `json:"unresolved" bson:"unresolved"`
+	Rematched  int `json:"rematched" bson:"rematched"`
+	Hidden     int `json:"hidden" bson:"hidden"`
+}
+
+// IntegrityIssue is a reference of a document that couldn't be resolved.
+type IntegrityIssue struct {
+	Collection string             `json:"collection" bson:"collection"` // Collection of the document
+	DocumentID primitive.ObjectID `json:"documentId" bson:"documentId"`
+	Field      string             `json:"field" bson:"field"`                             // Field holding the reference, e.g.: movieId
+	Reference  primitive.ObjectID `json:"reference,omitempty" bson:"reference,omitempty"` // Missing when unresolved
+	Kind       string             `json:"kind" bson:"kind"`                               // IntegrityOrphaned or IntegrityUnresolved
+	Repair     string             `json:"repair,omitempty" bson:"repair,omitempty"`       // IntegrityRematched, IntegrityHidden or none
+}
+
+// NewIntegrityReport creates an empty report of a check started at start.
+func NewIntegrityReport(repair bool, start time.Time) *IntegrityReport {
+	return &IntegrityReport{
+		ID:        primitive.NewObjectID(),
+		Repair:    repair,
+		Checked:   make(map[string]int),
+		Counts:    make(map[string]IntegrityCount),
+		StartTime: &start,
+	}
+}
+
+// OrphanedIssue creates the issue of the reference in field of the document
+// ID of collection to a document that doesn't exist.
+func OrphanedIssue(collection string, ID primitive.ObjectID, field string, reference primitive.ObjectID) IntegrityIssue {
+	return IntegrityIssue{
+		Collection: collection,
+		DocumentID: ID,
+		Field:      field,
+		Reference:  reference,
+		Kind:       IntegrityOrphaned,
+	}
+}
+
+// AddIssue counts the given issue and lists it while the report has less
+// than MaxIntegrityIssues issues.
+func (r *IntegrityReport) AddIssue(issue IntegrityIssue) {
+	if r.Counts == nil {
+		r.Counts = make(map[string]IntegrityCount)
+	}
+	c := r.Counts[issue.Collection]
+	switch issue.Kind {
+	case IntegrityOrphaned:
+		c.Orphaned++
+	case IntegrityUnresolved:
+		c.Unresolved++
+	}
+	switch issue.Repair {
+	case IntegrityRematched:
+		c.Rematched++
+	case IntegrityHidden:
+		c.Hidden++
+	}
+	r.Counts[issue.Collection] = c
+
+	if len(r.Issues) < MaxIntegrityIssues {
+		r.Issues = append(r.Issues, issue)
+	} else {
+		r.Truncated = true
+	}
+}
+
+// IssueCount returns the total number of issues found.
+func (r *IntegrityReport) IssueCount() int {
+	total := 0
+	for _, c := range r.Counts {
+		total += c.Orphaned + c.Unresolved
+	}
+	return total
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -15,9 +16,58 @@ const (
 	MovieLockTitle
 	// MovieLockSynopsis locks Synopsis
 	MovieLockSynopsis
-	// TODO: Add more as necessary
+	// MovieLockHidden locks Hidden
+	MovieLockHidden
+	// MovieLockClaqueteID locks ClaqueteID
+	MovieLockClaqueteID
+	// MovieLockTmdbID locks TmdbID
+	MovieLockTmdbID
+	// MovieLockImdbID locks ImdbID
+	MovieLockImdbID
+	// MovieLockSlug locks Slug
+	MovieLockSlug
+	// MovieLockCast locks Cast
+	MovieLockCast
+	// MovieLockPoster locks PosterURL
+	MovieLockPoster
+	// MovieLockBackdrop locks BackdropURL
+	MovieLockBackdrop
+	// MovieLockTrailer locks Trailer
+	MovieLockTrailer
+	// MovieLockGenres locks Genres
+	MovieLockGenres
+	// MovieLockRating locks Rating
+	MovieLockRating
+	// MovieLockRuntime locks Runtime
+	MovieLockRuntime
+	// MovieLockDistributor locks Distributor
+	MovieLockDistributor
+	// MovieLockReleaseDate locks ReleaseDate
+	MovieLockReleaseDate
 )
 
+// MovieLocks maps the name of every editable Movie field, the same in JSON
+// and BSON, to its lock flag.
+var MovieLocks = map[string]uint64{
+	"originalTitle": MovieLockOriginalTitle,
+	"title":         MovieLockTitle,
+	"synopsis":      MovieLockSynopsis,
+	"hidden":        MovieLockHidden,
+	"claqueteId":    MovieLockClaqueteID,
+	"tmdbId":        MovieLockTmdbID,
+	"imdbId":        MovieLockImdbID,
+	"slug":          MovieLockSlug,
+	"cast":          MovieLockCast,
+	"poster":        MovieLockPoster,
+	"backdrop":      MovieLockBackdrop,
+	"trailer":       MovieLockTrailer,
+	"genres":        MovieLockGenres,
+	"rating":        MovieLockRating,
+	"runtime":       MovieLockRuntime,
+	"studio":        MovieLockDistributor,
+	"releaseDate":   MovieLockReleaseDate,
+}
+
 // Movie represents a movie
 type Movie struct {
 	ID            primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
@@ -40,8 +90,16 @@ type Movie struct {
 	ReleaseDate   *time.Time         `json:"releaseDate,omitempty" bson:"releaseDate,omitempty"`
 	CreatedAt     *time.Time         `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
 	UpdatedAt     *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	Version       int64              `json:"version,omitempty" bson:"version,omitempty"` // Incremented by every update, see persistence.ConflictError
 	Theaters      []Theater          `json:"cinemas,omitempty" bson:"theaters,omitempty"`
 	Scores        []Score            `json:"scores,omitempty" bson:"scores,omitempty"`
 	Sessions      []Session          `json:"sessions,omitempty" bson:"sessions,omitempty"`
 	LockFlags     uint64             `json:"-" bson:"lockFlags,omitempty"`
+	SearchKeys    []string           `json:"-" bson:"searchKeys,omitempty"` // See UpdateSearchKeys
+}
+
+// UpdateSearchKeys sets SearchKeys to the folded words of the title and
+// original title. They're used for accent insensitive prefix search.
+func (m *Movie) UpdateSearchKeys() {
+	m.SearchKeys = stringutil.SearchKeys(m.Title, m.OriginalTitle)
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxRetention is how long sent outbox events are kept.
+const OutboxRetention = 7 * 24 * time.Hour
+
+// OutboxEvent is an event stored in the same transaction as the writes that
+// caused it, until it's published.
+type OutboxEvent struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id"`
+	EventID       string             `json:"event_id" bson:"eventId"`             // ID of the event envelope.
+	Name          string             `json:"name" bson:"name"`                    // Name of the event.
+	Source        string             `json:"source" bson:"source"`                // Service that emitted the event.
+	CorrelationID string             `json:"correlation_id" bson:"correlationId"` // Correlation ID of the event envelope.
+	SchemaVersion int                `json:"schema_version" bson:"schemaVersion"` // Version of the payload.
+	Timestamp     time.Time          `json:"timestamp" bson:"timestamp"`          // When the event was emitted.
+	Payload       string             `json:"payload" bson:"payload"`              // JSON-serialized event.
+	Attempts      int                `json:"attempts" bson:"attempts"`            // Failed publish attempts.
+	LastError     string             `json:"last_error,omitempty" bson:"lastError,omitempty"`
+	SentAt        *time.Time         `json:"sent_at,omitempty" bson:"sentAt,omitempty"`       // When the broker confirmed the event.
+	ExpiresAt     *time.Time         `json:"expires_at,omitempty" bson:"expiresAt,omitempty"` // When the sent event can be removed.
+	FailedAt      *time.Time         `json:"failed_at,omitempty" bson:"failedAt,omitempty"`   // When the event was given up on, it's kept for inspection.
+}
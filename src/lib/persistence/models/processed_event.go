@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// DefaultProcessedEventTTL is how long processed events are remembered. It
+// must be longer than the time an event may be redelivered, including retries.
+const DefaultProcessedEventTTL = 7 * 24 * time.Hour
+
+// ProcessedEvent records that a consumer handled an event, so redeliveries of
+// the same event can be skipped.
+type ProcessedEvent struct {
+	ID            string    `json:"id" bson:"_id"`                       // See ProcessedEventID.
+	Consumer      string    `json:"consumer" bson:"consumer"`            // Service that handled the event.
+	EventID       string    `json:"event_id" bson:"eventId"`             // ID of the event envelope.
+	EventName     string    `json:"event_name" bson:"eventName"`         // Name of the event.
+	CorrelationID string    `json:"correlation_id" bson:"correlationId"` // Correlation ID of the event envelope.
+	ProcessedAt   time.Time `json:"processed_at" bson:"processedAt"`     // When the event was handled.
+	ExpiresAt     time.Time `json:"expires_at" bson:"expiresAt"`         // When the record can be removed.
+}
+
+// ProcessedEventID returns the ID of the record of an event handled by the
+// given consumer. Each consumer handles every event once.
+func ProcessedEventID(consumer, eventID string) string {
+	return consumer + ":" + eventID
+}
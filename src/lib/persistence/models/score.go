@@ -22,4 +22,5 @@ type Score struct {
 	KeepSynced bool       `json:"keepSynced,omitempty" bson:"keepSynced"`
 	CreatedAt  *time.Time `json:"created_at,omitempty" bson:"createdAt"`
 	UpdatedAt  *time.Time `json:"updated_at,omitempty" bson:"updatedAt"`
+	Version    int64      `json:"version,omitempty" bson:"version,omitempty"` // Incremented by every update, see persistence.ConflictError
 }
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionHistory summarizes the sessions of a movie in a theater in a single
+// day. Sessions are moved to the history once they're old enough so the
+// sessions collection only keeps recent ones.
+type SessionHistory struct {
+	ID             string             `json:"_id" bson:"_id"` // See SessionHistoryID
+	MovieID        primitive.ObjectID `json:"movieId,omitempty" bson:"movieId,omitempty"`
+	MovieSlug      string             `json:"movieSlug,omitempty" bson:"movieSlug,omitempty"`
+	TheaterID      primitive.ObjectID `json:"theaterId" bson:"theaterId"`
+	Date           string             `json:"date" bson:"date"`         // Day of the sessions in the theater time zone, e.g.: 2019-08-01
+	Sessions       int                `json:"sessions" bson:"sessions"` // Number of sessions
+	Formats        map[string]int     `json:"formats" bson:"formats"`   // Number of sessions by format
+	Versions       map[string]int     `json:"versions" bson:"versions"` // Number of sessions by version
+	Rooms          []uint             `json:"rooms" bson:"rooms"`
+	FirstStartTime *time.Time         `json:"firstStartTime" bson:"firstStartTime"`
+	LastStartTime  *time.Time         `json:"lastStartTime" bson:"lastStartTime"`
+	CreatedAt      *time.Time         `json:"createdAt,omitempty" bson:"createdAt"`
+	UpdatedAt      *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt"`
+}
+
+// SessionHistoryID returns the ID of the SessionHistory of the given
+// session. Sessions without a movie ID are grouped by movie slug.
+func SessionHistoryID(s Session, date string) string {
+	movie := s.MovieSlug
+	if !s.MovieID.IsZero() {
+		movie = s.MovieID.Hex()
+	}
+	return fmt.Sprintf("%s:%s:%s", movie, s.TheaterID.Hex(), date)
+}
+
+// AddSession adds the given session to the history. The session must belong
+// to this history.
+func (h *SessionHistory) AddSession(s Session) {
+	if h.MovieID.IsZero() {
+		h.MovieID = s.MovieID
+	}
+	if h.MovieSlug == "" {
+		h.MovieSlug = s.MovieSlug
+	}
+	h.TheaterID = s.TheaterID
+
+	h.Sessions++
+	if h.Formats == nil {
+		h.Formats = make(map[string]int)
+	}
+	if s.Format != "" {
+		h.Formats[s.Format]++
+	}
+	if h.Versions == nil {
+		h.Versions = make(map[string]int)
+	}
+	if s.Version != "" {
+		h.Versions[s.Version]++
+	}
+
+	h.addRoom(s.Room)
+
+	h.addStartTime(s.StartTime, s.StartTime)
+}
+
+// Merge adds the sessions summarized by another history of the same movie,
+// theater and day.
+func (h *SessionHistory) Merge(o SessionHistory) {
+	if h.MovieID.IsZero() {
+		h.MovieID = o.MovieID
+	}
+	if h.MovieSlug == "" {
+		h.MovieSlug = o.MovieSlug
+	}
+
+	h.Sessions += o.Sessions
+	if h.Formats == nil {
+		h.Formats = make(map[string]int)
+	}
+	for k, v := range o.Formats {
+		h.Formats[k] += v
+	}
+	if h.Versions == nil {
+		h.Versions = make(map[string]int)
+	}
+	for k, v := range o.Versions {
+		h.Versions[k] += v
+	}
+	for _, r := range o.Rooms {
+		h.addRoom(r)
+	}
+	h.addStartTime(o.FirstStartTime, o.LastStartTime)
+}
+
+func (h *SessionHistory) addRoom(room uint) {
+	for _, r := range h.Rooms {
+		if r == room {
+			return
+		}
+	}
+	h.Rooms = append(h.Rooms, room)
+	sort.Slice(h.Rooms, func(i, j int) bool { return h.Rooms[i] < h.Rooms[j] })
+}
+
+func (h *SessionHistory) addStartTime(first, last *time.Time) {
+	if first != nil && (h.FirstStartTime == nil || first.Before(*h.FirstStartTime)) {
+		h.FirstStartTime = first
+	}
+	if last != nil && (h.LastStartTime == nil || last.After(*h.LastStartTime)) {
+		h.LastStartTime = last
+	}
+}
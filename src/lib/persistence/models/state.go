@@ -1,115 +1,78 @@
 package models
 
-type State string
+import "time"
 
-const (
-	AC State = "AC"
-	AL State = "AL"
-	AP State = "AP"
-	AM State = "AM"
-	BA State = "BA"
-	CE State = "CE"
-	DF State = "DF"
-	ES State = "ES"
-	GO State = "GO"
-	MA State = "MA"
-	MT State = "MT"
-	MS State = "MS"
-	MG State = "MG"
-	PA State = "PA"
-	PB State = "PB"
-	PR State = "PR"
-	PE State = "PE"
-	PI State = "PI"
-	RJ State = "RJ"
-	RN State = "RN"
-	RS State = "RS"
-	RO State = "RO"
-	RR State = "RR"
-	SC State = "SC"
-	SP State = "SP"
-	SE State = "SE"
-	TO State = "TO"
-)
-
-// GetStateList ...
-func GetStateList() []State {
-	return []State{AC, AL, AP, AM, BA, CE, DF, ES, GO, MA, MT, MS, MG, PA, PB, PR, PE, PI, RJ, RN, RS, RO, RR, SC, SP, SE, TO}
+// State represents a brazilian state. Its ID is the two letter code of the
+// state, which is referenced by City.StateID.
+type State struct {
+	ID        string     `json:"_id,omitempty" bson:"_id"`
+	Name      string     `json:"name,omitempty" bson:"name"`
+	TimeZone  string     `json:"timeZone,omitempty" bson:"timeZone"` // Default time zone of cities in this state.
+	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty" bson:"updatedAt"`
 }
 
-// GetState ...
-func GetState(s string) (State, bool) {
-	states := GetStateList()
-	for _, state := range states {
-		if string(state) == s {
-			return state, true
-		}
-	}
-	return "", false
-}
-
-// Name returns the name of the State
-func (s *State) Name() string {
-	return GetStateName(*s)
-}
+// State codes
+const (
+	AC = "AC"
+	AL = "AL"
+	AP = "AP"
+	AM = "AM"
+	BA = "BA"
+	CE = "CE"
+	DF = "DF"
+	ES = "ES"
+	GO = "GO"
+	MA = "MA"
+	MT = "MT"
+	MS = "MS"
+	MG = "MG"
+	PA = "PA"
+	PB = "PB"
+	PR = "PR"
+	PE = "PE"
+	PI = "PI"
+	RJ = "RJ"
+	RN = "RN"
+	RS = "RS"
+	RO = "RO"
+	RR = "RR"
+	SC = "SC"
+	SP = "SP"
+	SE = "SE"
+	TO = "TO"
+)
 
-// GetStateName returns the name of the given State
-func GetStateName(state State) string {
-	switch state {
-	case AC:
-		return "Acre"
-	case AL:
-		return "Alagoas"
-	case AP:
-		return "Amapá"
-	case AM:
-		return "Amazonas"
-	case BA:
-		return "Bahia"
-	case CE:
-		return "Ceará"
-	case DF:
-		return "Distrito Federal"
-	case ES:
-		return "Espírito do Santo"
-	case GO:
-		return "Goiás"
-	case MA:
-		return "Maranhão"
-	case MT:
-		return "Mato Grosso"
-	case MS:
-		return "Mato Grosso do Sul"
-	case MG:
-		return "Minas Gerais"
-	case PA:
-		return "Pará"
-	case PB:
-		return "Paraíba"
-	case PR:
-		return "Paraná"
-	case PE:
-		return "Pernambuco"
-	case PI:
-		return "Piauí"
-	case RJ:
-		return "Rio de Janeiro"
-	case RN:
-		return "Rio Grande do Norte"
-	case RS:
-		return "Rio Grade do Sul"
-	case RO:
-		return "Rondônia"
-	case RR:
-		return "Roraima"
-	case SC:
-		return "Santa Catarina"
-	case SP:
-		return "São Paulo"
-	case SE:
-		return "Sergipe"
-	case TO:
-		return "Tocantins"
+// DefaultStates returns all brazilian states with their names and default
+// time zones. It's used to seed the states collection.
+func DefaultStates() []State {
+	return []State{
+		{ID: AC, Name: "Acre", TimeZone: "America/Rio_Branco"},
+		{ID: AL, Name: "Alagoas", TimeZone: "America/Maceio"},
+		{ID: AP, Name: "Amapá", TimeZone: "America/Belem"},
+		{ID: AM, Name: "Amazonas", TimeZone: "America/Manaus"},
+		{ID: BA, Name: "Bahia", TimeZone: "America/Bahia"},
+		{ID: CE, Name: "Ceará", TimeZone: "America/Fortaleza"},
+		{ID: DF, Name: "Distrito Federal", TimeZone: "America/Sao_Paulo"},
+		{ID: ES, Name: "Espírito Santo", TimeZone: "America/Sao_Paulo"},
+		{ID: GO, Name: "Goiás", TimeZone: "America/Sao_Paulo"},
+		{ID: MA, Name: "Maranhão", TimeZone: "America/Fortaleza"},
+		{ID: MT, Name: "Mato Grosso", TimeZone: "America/Cuiaba"},
+		{ID: MS, Name: "Mato Grosso do Sul", TimeZone: "America/Campo_Grande"},
+		{ID: MG, Name: "Minas Gerais", TimeZone: "America/Sao_Paulo"},
+		{ID: PA, Name: "Pará", TimeZone: "America/Belem"},
+		{ID: PB, Name: "Paraíba", TimeZone: "America/Fortaleza"},
+		{ID: PR, Name: "Paraná", TimeZone: "America/Sao_Paulo"},
+		{ID: PE, Name: "Pernambuco", TimeZone: "America/Recife"},
+		{ID: PI, Name: "Piauí", TimeZone: "America/Fortaleza"},
+		{ID: RJ, Name: "Rio de Janeiro", TimeZone: "America/Sao_Paulo"},
+		{ID: RN, Name: "Rio Grande do Norte", TimeZone: "America/Fortaleza"},
+		{ID: RS, Name: "Rio Grande do Sul", TimeZone: "America/Sao_Paulo"},
+		{ID: RO, Name: "Rondônia", TimeZone: "America/Porto_Velho"},
+		{ID: RR, Name: "Roraima", TimeZone: "America/Boa_Vista"},
+		{ID: SC, Name: "Santa Catarina", TimeZone: "America/Sao_Paulo"},
+		{ID: SP, Name: "São Paulo", TimeZone: "America/Sao_Paulo"},
+		{ID: SE, Name: "Sergipe", TimeZone: "America/Maceio"},
+		{ID: TO, Name: "Tocantins", TimeZone: "America/Araguaina"},
 	}
-	return ""
 }
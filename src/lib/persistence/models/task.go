@@ -15,6 +15,8 @@ const (
 	TaskSyncScores         = "sync_scores"
 	TaskCheckOpeningMovies = "check_opening_movies"
 	TaskStartScraper       = "start_scraper"
+	TaskApplyRetention     = "apply_retention"
+	TaskCheckIntegrity     = "check_integrity"
 )
 
 // Task is a single unit of work for service to perform.
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -15,11 +16,40 @@ type Theater struct {
 	Name       string             `json:"name,omitempty" bson:"name"`
 	ShortName  string             `json:"shortName,omitempty" bson:"shortName"`
 	Images     *TheaterImages     `json:"images,omitempty" bson:"images"`
+	Address    *Address           `json:"address,omitempty" bson:"address,omitempty"`
+	Location   *GeoPoint          `json:"location,omitempty" bson:"location,omitempty"`
+	Contact    *Contact           `json:"contact,omitempty" bson:"contact,omitempty"`
 	CreatedAt  *time.Time         `json:"createdAt,omitempty" bson:"createdAt"`
 	UpdatedAt  *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt"`
+	Version    int64              `json:"version,omitempty" bson:"version,omitempty"` // Incremented by every update, see persistence.ConflictError
 	City       *City              `json:"city,omitempty" bson:"city,omitempty"`
 	Prices     []Price            `json:"prices,omitempty" bson:"prices,omitempty"`
 	Sessions   []Session          `json:"sessions,omitempty" bson:"sessions,omitempty"`
+	SearchKeys []string           `json:"-" bson:"searchKeys,omitempty"`                // See UpdateSearchKeys
+	Distance   float64            `json:"distance,omitempty" bson:"distance,omitempty"` // Meters from the point of a near query.
+}
+
+// UpdateSearchKeys sets SearchKeys to the folded words of the name and short
+// name. They're used for accent insensitive prefix search.
+func (t *Theater) UpdateSearchKeys() {
+	t.SearchKeys = stringutil.SearchKeys(t.Name, t.ShortName)
+}
+
+// Address is the street address of a theater. The city is given by
+// Theater.CityID.
+type Address struct {
+	Street       string `json:"street,omitempty" bson:"street,omitempty"`
+	Number       string `json:"number,omitempty" bson:"number,omitempty"`
+	Complement   string `json:"complement,omitempty" bson:"complement,omitempty"` // e.g.: Shopping name, floor
+	Neighborhood string `json:"neighborhood,omitempty" bson:"neighborhood,omitempty"`
+	PostalCode   string `json:"postalCode,omitempty" bson:"postalCode,omitempty"`
+}
+
+// Contact holds the ways of contacting a theater.
+type Contact struct {
+	Phone   string `json:"phone,omitempty" bson:"phone,omitempty"`
+	Email   string `json:"email,omitempty" bson:"email,omitempty"`
+	Website string `json:"website,omitempty" bson:"website,omitempty"`
 }
 
 // TheaterImages ...
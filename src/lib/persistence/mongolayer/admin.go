@@ -1,8 +1,6 @@
 package mongolayer
 
 import (
-	"context"
-
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,14 +12,14 @@ func (m *MongoDAL) InsertAdmin(admin models.Admin) error {
 	if admin.CreatedAt == nil {
 		admin.CreatedAt = getCurrentTime()
 	}
-	_, err := m.C(CollectionAdmins).InsertOne(context.Background(), admin)
+	_, err := m.C(CollectionAdmins).InsertOne(m.context(), admin)
 	return err
 }
 
 // FindAdmin ...
 func (m *MongoDAL) FindAdmin(query persistence.Query) (*models.Admin, error) {
 	var result models.Admin
-	err := m.C(CollectionAdmins).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionAdmins).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +38,7 @@ func (m *MongoDAL) GetAdmin(id string, query persistence.Query) (*models.Admin,
 // GetAdmins ...
 func (m *MongoDAL) GetAdmins(query persistence.Query) ([]models.Admin, error) {
 	var result []models.Admin
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionAdmins).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -56,6 +54,6 @@ func (m *MongoDAL) DeleteAdmin(id string) error {
 	if err != nil {
 		return err
 	}
-	_, err = m.C(CollectionAdmins).DeleteOne(context.Background(), bson.M{"_id": ID})
+	_, err = m.C(CollectionAdmins).DeleteOne(m.context(), bson.M{"_id": ID})
 	return err
 }
@@ -1,8 +1,6 @@
 package mongolayer
 
 import (
-	"context"
-
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,14 +12,14 @@ func (m *MongoDAL) InsertAPIKey(apikey models.APIKey) error {
 	if apikey.Timestamp == nil {
 		apikey.Timestamp = getCurrentTime()
 	}
-	_, err := m.C(CollectionAPIKeys).InsertOne(context.Background(), apikey)
+	_, err := m.C(CollectionAPIKeys).InsertOne(m.context(), apikey)
 	return err
 }
 
 // FindAPIKey ...
 func (m *MongoDAL) FindAPIKey(query persistence.Query) (*models.APIKey, error) {
 	var result models.APIKey
-	err := m.C(CollectionAPIKeys).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionAPIKeys).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +38,7 @@ func (m *MongoDAL) GetAPIKey(id string, query persistence.Query) (*models.APIKey
 // GetAPIKeys ...
 func (m *MongoDAL) GetAPIKeys(query persistence.Query) ([]models.APIKey, error) {
 	var result []models.APIKey
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionAPIKeys).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -56,6 +54,6 @@ func (m *MongoDAL) DeleteAPIKey(id string) error {
 	if err != nil {
 		return err
 	}
-	_, err = m.C(CollectionAPIKeys).DeleteOne(context.Background(), bson.M{"_id": ID})
+	_, err = m.C(CollectionAPIKeys).DeleteOne(m.context(), bson.M{"_id": ID})
 	return err
 }
@@ -1,17 +1,26 @@
 package mongolayer
 
 import (
-	"context"
+	"strings"
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/searchutil"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// InsertCity ...
+// InsertCity inserts the given City. Cities without a time zone use the
+// default time zone of their state.
 func (m *MongoDAL) InsertCity(city models.City) error {
-	_, err := m.C(CollectionCities).InsertOne(context.Background(), city)
+	city.UpdateSearchKeys()
+	if city.TimeZone == "" && city.StateID != "" {
+		state, err := m.GetState(city.StateID, m.DefaultQuery())
+		if err == nil {
+			city.TimeZone = state.TimeZone
+		}
+	}
+	_, err := m.C(CollectionCities).InsertOne(m.context(), city)
 	return err
 }
 
@@ -19,16 +28,17 @@ func (m *MongoDAL) InsertCity(city models.City) error {
 func (m *MongoDAL) InsertCities(cities ...models.City) error {
 	arr := make([]interface{}, len(cities))
 	for i, p := range cities {
+		p.UpdateSearchKeys()
 		arr[i] = p
 	}
-	_, err := m.C(CollectionCities).InsertMany(context.Background(), arr)
+	_, err := m.C(CollectionCities).InsertMany(m.context(), arr)
 	return err
 }
 
 // FindCity ...
 func (m *MongoDAL) FindCity(query persistence.Query) (*models.City, error) {
 	var result models.City
-	err := m.C(CollectionCities).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionCities).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +57,7 @@ func (m *MongoDAL) GetCity(id string, query persistence.Query) (*models.City, er
 // GetCities ...
 func (m *MongoDAL) GetCities(query persistence.Query) ([]models.City, error) {
 	var result []models.City
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionCities).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -63,8 +73,9 @@ func (m *MongoDAL) UpdateCity(id string, mc models.City) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	mc.UpdateSearchKeys()
 	mc.UpdatedAt = getCurrentTime()
-	result, err := m.C(CollectionCities).UpdateOne(context.Background(), bson.M{"_id": ID}, bson.M{"$set": mc})
+	result, err := m.C(CollectionCities).UpdateOne(m.context(), bson.M{"_id": ID}, bson.M{"$set": mc})
 	if err != nil {
 		return 0, err
 	}
@@ -77,13 +88,13 @@ func (m *MongoDAL) DeleteCity(id string) error {
 	if err != nil {
 		return err
 	}
-	_, err = m.C(CollectionCities).DeleteOne(context.Background(), bson.M{"_id": ID})
+	_, err = m.C(CollectionCities).DeleteOne(m.context(), bson.M{"_id": ID})
 	return err
 }
 
 // DeleteCities ...
 func (m *MongoDAL) DeleteCities(query persistence.Query) (int64, error) {
-	result, err := m.C(CollectionCities).DeleteMany(context.Background(), query.GetConditions())
+	result, err := m.C(CollectionCities).DeleteMany(m.context(), query.GetConditions())
 	if err != nil {
 		return 0, err
 	}
@@ -107,7 +118,11 @@ func (m *MongoDAL) BuildCityQuery(q map[string]string) persistence.Query {
 		}
 		state, ok := q["state"]
 		if ok {
-			query.AddCondition("state", state)
+			query.AddCondition("stateId", strings.ToUpper(state))
+		}
+		search, ok := q["search"]
+		if ok {
+			query.Where(searchutil.Conditions(search)...)
 		}
 	}
 	return query
@@ -20,9 +20,9 @@ func TestCity(t *testing.T) {
 
 	// Insert
 	cityDoc := models.City{
-		ID:    primitive.NewObjectID(),
-		Name:  "some-city-name",
-		State: models.MG,
+		ID:      primitive.NewObjectID(),
+		Name:    "some-city-name",
+		StateID: models.MG,
 	}
 	err = data.InsertCity(cityDoc)
 	assert.NoError(t, err)
@@ -0,0 +1,61 @@
+package mongolayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/bsonutil"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// geoNearStage returns the $geoNear stage of the NearCondition in conditions,
+// which sorts documents by the distance to its point and sets it in the
+// distance field. It returns nil if there's no NearCondition.
+//
+// The condition is kept in the following $match stage, $geoWithin isn't
+// enough to sort or to get distances.
+func geoNearStage(conditions interface{}) bson.D {
+	m, ok := conditions.(bson.M)
+	if !ok {
+		return nil
+	}
+	for field, value := range m {
+		if field == "$and" {
+			for _, c := range bsonutil.AppendClauses(nil, value) {
+				if stage := geoNearStage(c); stage != nil {
+					return stage
+				}
+			}
+			continue
+		}
+
+		operators, _ := value.(bson.M)
+		within, ok := operators["$geoWithin"].(bson.M)
+		if !ok {
+			continue
+		}
+		sphere, ok := within["$centerSphere"].(bson.A)
+		if !ok || len(sphere) != 2 {
+			continue
+		}
+		radians, _ := sphere[1].(float64)
+		return bson.D{{Key: "$geoNear", Value: bson.D{
+			{Key: "near", Value: bson.M{"type": "Point", "coordinates": sphere[0]}},
+			{Key: "distanceField", Value: distanceField},
+			{Key: "maxDistance", Value: radians * models.EarthRadius},
+			{Key: "spherical", Value: true},
+			{Key: "key", Value: field},
+		}}}
+	}
+	return nil
+}
+
+// decodeOne decodes a single result replacing the driver not found error
+// with persistence.ErrNotFound.
+func decodeOne(r *mongo.SingleResult, result interface{}) error {
+	err := r.Decode(result)
+	if err == mongo.ErrNoDocuments {
+		return persistence.ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package mongolayer
+
+import (
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/bsonutil"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWhere(t *testing.T) {
+	query := DefaultOptions("").
+		AddCondition("hidden", false).
+		Where(
+			persistence.Range("startTime", 1, nil),
+			persistence.Range("startTime", nil, 2),
+			persistence.And(persistence.Eq("slug", "a")),
+		)
+
+	assert.Equal(t, bson.M{
+		"hidden": false,
+		"$and": []interface{}{
+			bson.M{"startTime": bson.M{"$gte": 1}},
+			bson.M{"startTime": bson.M{"$lte": 2}},
+			bson.M{"slug": "a"},
+		},
+	}, query.GetConditions())
+
+}
+
+func TestSortToBSON(t *testing.T) {
+	assert.Equal(t, bson.D{
+		{Key: "textScore", Value: bson.M{"$meta": "textScore"}},
+		{Key: "title", Value: 1},
+		{Key: "releaseDate", Value: -1},
+	}, SortToBSON("", persistence.SortTextScore, "+title", "-releaseDate"))
+
+	opts := getFindOptions(DefaultOptions("").
+		SetFields("title").
+		SetSort(persistence.SortTextScore))
+	assert.Equal(t, bson.M{"title": 1, "textScore": bson.M{"$meta": "textScore"}}, opts.Projection)
+}
+
+func TestGeoNearStage(t *testing.T) {
+	point := models.NewGeoPoint(-19.92, -43.94)
+	near := bsonutil.ConditionToBSON(persistence.Near("location", point, models.EarthRadius/100))
+	assert.Equal(t, bson.M{"location": bson.M{"$geoWithin": bson.M{
+		"$centerSphere": bson.A{bson.A{-43.94, -19.92}, 0.01},
+	}}}, near)
+
+	expected := bson.D{{Key: "$geoNear", Value: bson.D{
+		{Key: "near", Value: bson.M{"type": "Point", "coordinates": bson.A{-43.94, -19.92}}},
+		{Key: "distanceField", Value: "distance"},
+		{Key: "maxDistance", Value: models.EarthRadius / 100},
+		{Key: "spherical", Value: true},
+		{Key: "key", Value: "location"},
+	}}}
+	assert.Equal(t, expected, geoNearStage(near))
+
+	query := DefaultOptions("").
+		AddCondition("hidden", false).
+		Where(persistence.Eq("location", nil), persistence.Near("location", point, models.EarthRadius/100))
+	assert.Equal(t, expected, geoNearStage(query.GetConditions()))
+	assert.Nil(t, geoNearStage(bson.M{"hidden": false}))
+}
@@ -1,8 +1,6 @@
 package mongolayer
 
 import (
-	"context"
-
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,14 +9,14 @@ import (
 
 // InsertImage ...
 func (m *MongoDAL) InsertImage(image models.Image) error {
-	_, err := m.C(CollectionImages).InsertOne(context.Background(), image)
+	_, err := m.C(CollectionImages).InsertOne(m.context(), image)
 	return err
 }
 
 // FindImage ...
 func (m *MongoDAL) FindImage(query persistence.Query) (*models.Image, error) {
 	var result models.Image
-	err := m.C(CollectionImages).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionImages).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -37,7 +35,7 @@ func (m *MongoDAL) GetImage(id string, query persistence.Query) (*models.Image,
 // GetImages ...
 func (m *MongoDAL) GetImages(query persistence.Query) ([]models.Image, error) {
 	var result []models.Image
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionImages).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -62,13 +60,13 @@ func (m *MongoDAL) DeleteImage(id string) error {
 	if err != nil {
 		return err
 	}
-	_, err = m.C(CollectionImages).DeleteOne(context.Background(), bson.M{"_id": ID})
+	_, err = m.C(CollectionImages).DeleteOne(m.context(), bson.M{"_id": ID})
 	return err
 }
 
 // DeleteImages ...
 func (m *MongoDAL) DeleteImages(query persistence.Query) (int64, error) {
-	result, err := m.C(CollectionImages).DeleteMany(context.Background(), query.GetConditions())
+	result, err := m.C(CollectionImages).DeleteMany(m.context(), query.GetConditions())
 	if err != nil {
 		return 0, err
 	}
@@ -86,7 +84,7 @@ func (m *MongoDAL) UpdateImage(id string, mi models.Image) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	result, err := m.C(CollectionImages).UpdateOne(context.Background(), bson.M{"_id": ID}, bson.M{"$set": mi})
+	result, err := m.C(CollectionImages).UpdateOne(m.context(), bson.M{"_id": ID}, bson.M{"$set": mi})
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,24 @@
+package mongolayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// InsertIntegrityReport ...
+func (m *MongoDAL) InsertIntegrityReport(report models.IntegrityReport) error {
+	return m.InsertOne(CollectionIntegrityReports, report)
+}
+
+// GetIntegrityReports ...
+func (m *MongoDAL) GetIntegrityReports(query persistence.Query) ([]models.IntegrityReport, error) {
+	var result []models.IntegrityReport
+	var ctx = m.context()
+	cursor, err := m.C(CollectionIntegrityReports).Find(ctx, query.GetConditions(), getFindOptions(query))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &result)
+	return result, err
+}
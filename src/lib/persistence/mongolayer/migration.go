@@ -0,0 +1,193 @@
+package mongolayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	CollectionMigrations     = "migrations"
+	CollectionMigrationLocks = "migration_locks"
+
+	migrationLockID      = "migrations"
+	migrationLockTTL     = 10 * time.Minute
+	migrationLockTimeout = 2 * time.Minute
+)
+
+var (
+	// ErrMigrationLocked is returned when the migration lock couldn't be
+	// acquired before the timeout.
+	ErrMigrationLocked = errors.New("migrations are locked by another process")
+)
+
+type (
+	// Migration is a versioned change to the database schema.
+	Migration struct {
+		Version     int
+		Description string
+
+		// Up applies the migration.
+		Up func(db *mongo.Database) error
+		// Down reverts the migration. It is nil for irreversible migrations.
+		Down func(db *mongo.Database) error
+	}
+
+	// migrationRecord is the document stored in the migrations collection for
+	// each applied migration.
+	migrationRecord struct {
+		Version     int       `bson:"_id"`
+		Description string    `bson:"description"`
+		AppliedAt   time.Time `bson:"appliedAt"`
+	}
+)
+
+// Migrate applies all pending migrations.
+func (m *MongoDAL) Migrate() error {
+	return m.MigrateTo(persistence.LatestMigration)
+}
+
+// MigrateTo applies or reverts migrations until the schema is at the given
+// version. Only one process runs migrations at a time.
+func (m *MongoDAL) MigrateTo(version int) error {
+	owner, err := m.lockMigrations()
+	if err != nil {
+		return err
+	}
+	defer m.unlockMigrations(owner)
+
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return err
+	}
+	return persistence.RunMigrations(m.migrationSteps(migrations), applied, version)
+}
+
+// MigrationStatus lists every known migration and when it was applied.
+func (m *MongoDAL) MigrationStatus() ([]persistence.MigrationStatus, error) {
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return persistence.MigrationStatuses(m.migrationSteps(migrations), applied), nil
+}
+
+// migrationSteps binds list to the database of m. Steps record the migration
+// in the migrations collection once it's applied or reverted.
+func (m *MongoDAL) migrationSteps(list []Migration) []persistence.MigrationStep {
+	result := make([]persistence.MigrationStep, len(list))
+	for i, mm := range list {
+		mm := mm
+		result[i] = persistence.MigrationStep{
+			Version:     mm.Version,
+			Description: mm.Description,
+		}
+		if mm.Up != nil {
+			result[i].Up = func() error {
+				if err := mm.Up(m.db); err != nil {
+					return err
+				}
+				_, err := m.C(CollectionMigrations).InsertOne(m.context(), migrationRecord{
+					Version:     mm.Version,
+					Description: mm.Description,
+					AppliedAt:   time.Now().UTC(),
+				})
+				return err
+			}
+		}
+		if mm.Down != nil {
+			result[i].Down = func() error {
+				if err := mm.Down(m.db); err != nil {
+					return err
+				}
+				_, err := m.C(CollectionMigrations).DeleteOne(m.context(), bson.M{"_id": mm.Version})
+				return err
+			}
+		}
+	}
+	return result
+}
+
+// appliedMigrations returns when each applied migration was applied.
+func (m *MongoDAL) appliedMigrations() (map[int]time.Time, error) {
+	ctx := m.context()
+	cursor, err := m.C(CollectionMigrations).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []migrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	result := make(map[int]time.Time, len(records))
+	for _, r := range records {
+		result[r.Version] = r.AppliedAt
+	}
+	return result, nil
+}
+
+// lockMigrations waits until no other process holds the migration lock and
+// acquires it. Locks expire so a crashed process doesn't block migrations
+// forever.
+func (m *MongoDAL) lockMigrations() (string, error) {
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano())
+
+	deadline := time.Now().Add(migrationLockTimeout)
+	for {
+		now := time.Now().UTC()
+		// If the lock is held and not expired the filter doesn't match, so the
+		// upsert fails with a duplicate key error.
+		_, err := m.C(CollectionMigrationLocks).UpdateOne(m.context(),
+			bson.M{"_id": migrationLockID, "expiresAt": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{
+				"owner":     owner,
+				"lockedAt":  now,
+				"expiresAt": now.Add(migrationLockTTL),
+			}},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			return owner, nil
+		}
+		if !isDuplicateKeyError(err) {
+			return "", err
+		}
+		if time.Now().After(deadline) {
+			return "", ErrMigrationLocked
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (m *MongoDAL) unlockMigrations(owner string) error {
+	_, err := m.C(CollectionMigrationLocks).DeleteOne(context.Background(), bson.M{
+		"_id":   migrationLockID,
+		"owner": owner,
+	})
+	return err
+}
+
+func isDuplicateKeyError(err error) bool {
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == 11000 {
+				return true
+			}
+		}
+	case mongo.CommandError:
+		return e.Code == 11000
+	}
+	return false
+}
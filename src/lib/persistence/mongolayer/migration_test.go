@@ -0,0 +1,52 @@
+package mongolayer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestValidateMigrations(t *testing.T) {
+	m := &MongoDAL{}
+	assert.NoError(t, persistence.ValidateMigrations(m.migrationSteps(migrations)))
+	assert.Error(t, persistence.ValidateMigrations(m.migrationSteps([]Migration{{Version: 1}})))
+}
+
+func TestIsDuplicateKeyError(t *testing.T) {
+	assert.True(t, isDuplicateKeyError(mongo.WriteException{
+		WriteErrors: mongo.WriteErrors{{Code: 11000}},
+	}))
+	assert.True(t, isDuplicateKeyError(mongo.CommandError{Code: 11000}))
+	assert.False(t, isDuplicateKeyError(mongo.CommandError{Code: 50}))
+	assert.False(t, isDuplicateKeyError(mongo.ErrNoDocuments))
+}
+
+func TestSteps(t *testing.T) {
+	var ran []int
+	step := func(i int, err error) func() error {
+		return func() error {
+			ran = append(ran, i)
+			return err
+		}
+	}
+	err := steps(step(1, nil), step(2, errors.New("failed")), step(3, nil))
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, []int{1, 2}, ran)
+}
+
+func TestIsTextIndex(t *testing.T) {
+	assert.True(t, isTextIndex(bson.M{
+		"name":    "originalTitle_text_title_text",
+		"key":     bson.M{"_fts": "text", "_ftsx": int32(1)},
+		"weights": bson.M{"originalTitle": int32(1), "title": int32(1)},
+	}))
+	assert.True(t, isTextIndex(bson.M{"key": bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}}}))
+	assert.False(t, isTextIndex(bson.M{"name": "title_1", "key": bson.M{"title": int32(1)}}))
+
+	assert.True(t, isIndexNotFoundError(mongo.CommandError{Code: 27, Name: "IndexNotFound"}))
+	assert.False(t, isIndexNotFoundError(mongo.CommandError{Code: 85}))
+}
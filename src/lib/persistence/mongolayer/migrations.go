@@ -0,0 +1,449 @@
+package mongolayer
+
+import (
+	"context"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations is the ordered list of schema migrations. New migrations must be
+// appended with a higher version and applied migrations must never change.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create initial indexes",
+		Up: func(db *mongo.Database) error {
+			return steps(
+				func() error { return createIndexes(db.Collection(CollectionAPIKeys), true, "key") },
+				func() error { return createIndexes(db.Collection(CollectionAPIKeys), false, "owner", "user_type") },
+
+				// TODO: See if we need to add this:
+				// https://docs.mongodb.com/manual/tutorial/specify-language-for-text-index/
+				func() error { return createTextIndex(db.Collection(CollectionMovies), "title", "originalTitle") },
+				func() error {
+					return createIndexes(db.Collection(CollectionMovies), false,
+						"tmdbId", "imdbId", "claqueteId", "slug", "title", "originalTitle", "hidden", "releaseDate")
+				},
+
+				func() error {
+					return createIndexes(db.Collection(CollectionCities), false, "name", "state", "timeZone")
+				},
+
+				func() error { return createTextIndex(db.Collection(CollectionTheaters), "name", "shortName") },
+				func() error {
+					return createIndexes(db.Collection(CollectionTheaters), false, "cityId", "internalId", "hidden")
+				},
+
+				func() error {
+					return createIndexes(db.Collection(CollectionScrapers), false, "theaterId", "type", "provider")
+				},
+				func() error { return createIndexes(db.Collection(CollectionScores), false, "movieId") },
+				func() error {
+					return createIndexes(db.Collection(CollectionSessions), false,
+						"movieSlug", "theaterId", "movieId", "startTime", "hidden", "room", "version", "format")
+				},
+				func() error { return createIndexes(db.Collection(CollectionPrices), false, "theaterId") },
+			)
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndexes(db,
+				CollectionAPIKeys,
+				CollectionMovies,
+				CollectionCities,
+				CollectionTheaters,
+				CollectionScrapers,
+				CollectionScores,
+				CollectionSessions,
+				CollectionPrices,
+			)
+		},
+	},
+	{
+		// Older documents were stored with the cinema naming.
+		Version:     2,
+		Description: "rename cinemaId to theaterId",
+		Up: func(db *mongo.Database) error {
+			return steps(
+				func() error { return renameField(db.Collection(CollectionPrices), "cinemaId", "theaterId") },
+				func() error { return renameField(db.Collection(CollectionPrices), "cinema_id", "theaterId") },
+				func() error { return renameField(db.Collection(CollectionSessions), "cinemaId", "theaterId") },
+			)
+		},
+	},
+	{
+		Version:     3,
+		Description: "create states and reference them from cities",
+		Up: func(db *mongo.Database) error {
+			return steps(
+				func() error { return insertStates(db.Collection(CollectionStates)) },
+				func() error { return renameField(db.Collection(CollectionCities), "state", "stateId") },
+				func() error { return dropIndex(db.Collection(CollectionCities), "state_1") },
+				func() error { return createIndexes(db.Collection(CollectionCities), false, "stateId") },
+				func() error { return createIndexes(db.Collection(CollectionStates), false, "name") },
+			)
+		},
+		Down: func(db *mongo.Database) error {
+			return steps(
+				func() error { return dropIndex(db.Collection(CollectionCities), "stateId_1") },
+				func() error { return renameField(db.Collection(CollectionCities), "stateId", "state") },
+				func() error { return createIndexes(db.Collection(CollectionCities), false, "state") },
+				func() error { return db.Collection(CollectionStates).Drop(context.Background()) },
+			)
+		},
+	},
+	{
+		Version:     4,
+		Description: "create session history and scraper runs indexes",
+		Up: func(db *mongo.Database) error {
+			return steps(
+				func() error {
+					return createIndexes(db.Collection(CollectionSessionHistory), false, "movieId", "theaterId", "date")
+				},
+				func() error {
+					return createIndexes(db.Collection(CollectionScraperRuns), false, "scraper_id", "start_time")
+				},
+			)
+		},
+		Down: func(db *mongo.Database) error {
+			return steps(
+				func() error { return dropIndexes(db, CollectionSessionHistory) },
+				func() error { return dropIndex(db.Collection(CollectionScraperRuns), "scraper_id_1") },
+				func() error { return dropIndex(db.Collection(CollectionScraperRuns), "start_time_1") },
+			)
+		},
+	},
+	{
+		// Search keys are the folded words of titles and names, used for
+		// accent insensitive prefix search. Text indexes are recreated to use
+		// Portuguese stemming and to rank titles above original titles.
+		Version:     5,
+		Description: "create search keys and portuguese text indexes",
+		Up: func(db *mongo.Database) error {
+			return steps(
+				func() error { return dropTextIndex(db.Collection(CollectionMovies)) },
+				func() error {
+					return createWeightedTextIndex(db.Collection(CollectionMovies), "portuguese",
+						bson.D{{Key: "title", Value: 10}, {Key: "originalTitle", Value: 5}})
+				},
+				func() error { return dropTextIndex(db.Collection(CollectionTheaters)) },
+				func() error {
+					return createWeightedTextIndex(db.Collection(CollectionTheaters), "portuguese",
+						bson.D{{Key: "name", Value: 10}, {Key: "shortName", Value: 5}})
+				},
+
+				func() error { return updateSearchKeys(db.Collection(CollectionMovies), "title", "originalTitle") },
+				func() error { return updateSearchKeys(db.Collection(CollectionTheaters), "name", "shortName") },
+				func() error { return updateSearchKeys(db.Collection(CollectionCities), "name") },
+				func() error { return createIndexes(db.Collection(CollectionMovies), false, "searchKeys") },
+				func() error { return createIndexes(db.Collection(CollectionTheaters), false, "searchKeys") },
+				func() error { return createIndexes(db.Collection(CollectionCities), false, "searchKeys") },
+			)
+		},
+		Down: func(db *mongo.Database) error {
+			return steps(
+				func() error { return dropTextIndex(db.Collection(CollectionMovies)) },
+				func() error { return createTextIndex(db.Collection(CollectionMovies), "title", "originalTitle") },
+				func() error { return dropTextIndex(db.Collection(CollectionTheaters)) },
+				func() error { return createTextIndex(db.Collection(CollectionTheaters), "name", "shortName") },
+
+				func() error { return dropIndex(db.Collection(CollectionMovies), "searchKeys_1") },
+				func() error { return dropIndex(db.Collection(CollectionTheaters), "searchKeys_1") },
+				func() error { return dropIndex(db.Collection(CollectionCities), "searchKeys_1") },
+				func() error { return unsetField(db.Collection(CollectionMovies), "searchKeys") },
+				func() error { return unsetField(db.Collection(CollectionTheaters), "searchKeys") },
+				func() error { return unsetField(db.Collection(CollectionCities), "searchKeys") },
+			)
+		},
+	},
+	{
+		Version:     6,
+		Description: "create theaters location index",
+		Up: func(db *mongo.Database) error {
+			return createGeoIndex(db.Collection(CollectionTheaters), "location")
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndex(db.Collection(CollectionTheaters), "location_2dsphere")
+		},
+	},
+	{
+		Version:     7,
+		Description: "create integrity reports index",
+		Up: func(db *mongo.Database) error {
+			return createIndexes(db.Collection(CollectionIntegrityReports), false, "startTime")
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndexes(db, CollectionIntegrityReports)
+		},
+	},
+	{
+		// Processed events are removed once they expire.
+		Version:     8,
+		Description: "create processed events TTL index",
+		Up: func(db *mongo.Database) error {
+			return createTTLIndex(db.Collection(CollectionProcessedEvents), "expiresAt")
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndexes(db, CollectionProcessedEvents)
+		},
+	},
+	{
+		// Sent outbox events are removed once they expire.
+		Version:     9,
+		Description: "create outbox events indexes",
+		Up: func(db *mongo.Database) error {
+			return steps(
+				func() error { return createIndexes(db.Collection(CollectionOutboxEvents), false, "timestamp") },
+				func() error { return createTTLIndex(db.Collection(CollectionOutboxEvents), "expiresAt") },
+			)
+		},
+		Down: func(db *mongo.Database) error {
+			return dropIndexes(db, CollectionOutboxEvents)
+		},
+	},
+	{
+		// Documents stored before they were versioned can't be updated
+		// conditionally by clients until they have a version.
+		Version:     10,
+		Description: "set the version of unversioned documents",
+		Up: func(db *mongo.Database) error {
+			return steps(
+				func() error { return setMissingVersion(db.Collection(CollectionMovies)) },
+				func() error { return setMissingVersion(db.Collection(CollectionTheaters)) },
+				func() error { return setMissingVersion(db.Collection(CollectionScores)) },
+			)
+		},
+		Down: func(db *mongo.Database) error {
+			// Versions are valid either way.
+			return nil
+		},
+	},
+}
+
+// insertStates inserts the default states. States already stored are kept.
+func insertStates(c *mongo.Collection) error {
+	now := getCurrentTime()
+	for _, state := range models.DefaultStates() {
+		state.CreatedAt = now
+		_, err := c.UpdateOne(context.Background(),
+			bson.M{"_id": state.ID},
+			bson.M{"$setOnInsert": state},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createIndexes(c *mongo.Collection, unique bool, keys ...string) error {
+	indexModels := make([]mongo.IndexModel, 0)
+	for _, k := range keys {
+		indexModels = append(indexModels, mongo.IndexModel{
+			Keys: bson.M{
+				k: 1,
+			},
+			Options: (&options.IndexOptions{}).
+				SetBackground(true).
+				SetUnique(unique).
+				SetSparse(true),
+		})
+	}
+	_, err := c.Indexes().CreateMany(context.Background(), indexModels)
+	return err
+}
+
+func createTextIndex(c *mongo.Collection, keys ...string) error {
+	indexKeys := bson.D{}
+	for _, k := range keys {
+		indexKeys = append(indexKeys, bson.E{Key: k, Value: "text"})
+	}
+	_, err := c.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: indexKeys,
+	})
+	return err
+}
+
+// createWeightedTextIndex creates a text index on the fields of weights using
+// the stemming rules of the given language.
+func createWeightedTextIndex(c *mongo.Collection, language string, weights bson.D) error {
+	indexKeys := bson.D{}
+	for _, w := range weights {
+		indexKeys = append(indexKeys, bson.E{Key: w.Key, Value: "text"})
+	}
+	_, err := c.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: indexKeys,
+		Options: (&options.IndexOptions{}).
+			SetBackground(true).
+			SetDefaultLanguage(language).
+			SetWeights(weights),
+	})
+	return err
+}
+
+// createTTLIndex creates an index removing documents once the time in the
+// given field is reached.
+func createTTLIndex(c *mongo.Collection, key string) error {
+	_, err := c.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.M{key: 1},
+		Options: (&options.IndexOptions{}).
+			SetBackground(true).
+			SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// createGeoIndex creates a 2dsphere index, required by near queries, on a
+// field holding GeoJSON points.
+func createGeoIndex(c *mongo.Collection, key string) error {
+	_, err := c.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: key, Value: "2dsphere"}},
+		Options: (&options.IndexOptions{}).SetBackground(true),
+	})
+	return err
+}
+
+// updateSearchKeys sets the searchKeys of every document to the folded words
+// of the given fields, the same as the UpdateSearchKeys method of models.
+func updateSearchKeys(c *mongo.Collection, fields ...string) error {
+	ctx := context.Background()
+	projection := bson.M{}
+	for _, f := range fields {
+		projection[f] = 1
+	}
+	cursor, err := c.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		texts := make([]string, len(fields))
+		for i, f := range fields {
+			texts[i], _ = doc[f].(string)
+		}
+		_, err := c.UpdateOne(ctx,
+			bson.M{"_id": doc["_id"]},
+			bson.M{"$set": bson.M{"searchKeys": stringutil.SearchKeys(texts...)}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func dropIndexes(db *mongo.Database, collectionNames ...string) error {
+	for _, name := range collectionNames {
+		_, err := db.Collection(name).Indexes().DropAll(context.Background())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropIndex(c *mongo.Collection, name string) error {
+	_, err := c.Indexes().DropOne(context.Background(), name)
+	return err
+}
+
+// dropTextIndex drops the text index of a collection, if any. Its name
+// depends on the order of its keys, which wasn't always the same, so it's
+// found by its weights.
+func dropTextIndex(c *mongo.Collection) error {
+	ctx := context.Background()
+	cursor, err := c.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+	var specs []bson.M
+	if err := cursor.All(ctx, &specs); err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		if !isTextIndex(spec) {
+			continue
+		}
+		name, _ := spec["name"].(string)
+		_, err := c.Indexes().DropOne(ctx, name)
+		if err != nil && !isIndexNotFoundError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTextIndex reports whether an index specification, as listed by the
+// server, is of a text index.
+func isTextIndex(spec bson.M) bool {
+	if _, ok := spec["weights"]; ok {
+		return true
+	}
+	switch key := spec["key"].(type) {
+	case bson.M:
+		_, ok := key["_fts"]
+		return ok
+	case bson.D:
+		for _, e := range key {
+			if e.Key == "_fts" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isIndexNotFoundError(err error) bool {
+	e, ok := err.(mongo.CommandError)
+	return ok && (e.Code == 27 || e.Name == "IndexNotFound")
+}
+
+// setMissingVersion sets the version of documents without one to 1, the
+// version of inserted documents.
+func setMissingVersion(c *mongo.Collection) error {
+	_, err := c.UpdateMany(context.Background(),
+		bson.M{"$or": bson.A{
+			bson.M{"version": bson.M{"$exists": false}},
+			bson.M{"version": 0},
+		}},
+		bson.M{"$set": bson.M{"version": int64(1)}},
+	)
+	return err
+}
+
+func renameField(c *mongo.Collection, from, to string) error {
+	_, err := c.UpdateMany(context.Background(),
+		bson.M{from: bson.M{"$exists": true}},
+		bson.M{"$rename": bson.M{from: to}},
+	)
+	return err
+}
+
+func unsetField(c *mongo.Collection, name string) error {
+	_, err := c.UpdateMany(context.Background(),
+		bson.M{name: bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{name: ""}},
+	)
+	return err
+}
+
+// steps runs each step of a migration in order, stopping at the first one
+// that fails.
+func steps(fns ...func() error) error {
+	for _, fn := range fns {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
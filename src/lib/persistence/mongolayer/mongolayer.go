@@ -2,34 +2,40 @@ package mongolayer
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/bsonutil"
 	"github.com/dsbezerra/amenic/src/lib/util/mathutil"
 	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const (
-	CollectionAdmins        = "admins"
-	CollectionAPIKeys       = "api_keys"
-	CollectionCities        = "cities"
-	CollectionImages        = "images"
-	CollectionMovies        = "movies"
-	CollectionNotifications = "notifications"
-	CollectionPrices        = "prices"
-	CollectionScores        = "scores"
-	CollectionScrapers      = "scrapers"
-	CollectionScraperRuns   = "scraper_runs"
-	CollectionSessions      = "sessions"
-	CollectionTasks         = "tasks"
-	CollectionTheaters      = "theaters"
+	CollectionAdmins           = "admins"
+	CollectionAPIKeys          = "api_keys"
+	CollectionCities           = "cities"
+	CollectionImages           = "images"
+	CollectionIntegrityReports = "integrity_reports"
+	CollectionMovies           = "movies"
+	CollectionNotifications    = "notifications"
+	CollectionOutboxEvents     = "outbox_events"
+	CollectionPrices           = "prices"
+	CollectionProcessedEvents  = "processed_events"
+	CollectionScores           = "scores"
+	CollectionScrapers         = "scrapers"
+	CollectionScraperRuns      = "scraper_runs"
+	CollectionSessions         = "sessions"
+	CollectionSessionHistory   = "session_history"
+	CollectionStates           = "states"
+	CollectionTasks            = "tasks"
+	CollectionTheaters         = "theaters"
 )
 
 type (
@@ -73,6 +79,9 @@ const (
 	Scrapers
 	ScraperRuns
 	Sessions
+	States
+	SessionHistory
+	IntegrityReports
 )
 
 type (
@@ -81,6 +90,9 @@ type (
 		client *mongo.Client
 		db     *mongo.Database
 		name   string
+
+		// Session context used by operations when running inside a transaction
+		sctx mongo.SessionContext
 	}
 )
 
@@ -111,10 +123,46 @@ func (m *MongoDAL) DefaultQuery() persistence.Query {
 // Close ...
 func (m *MongoDAL) Close() {
 	if m.client != nil {
-		m.client.Disconnect(context.Background())
+		m.client.Disconnect(m.context())
 	}
 }
 
+// WithTransaction runs fn inside a MongoDB transaction. Every operation made
+// through tx is committed if fn returns nil and aborted otherwise. Nested calls
+// run in the current transaction.
+//
+// NOTE: Transactions require a replica set deployment.
+func (m *MongoDAL) WithTransaction(fn func(tx persistence.DataAccessLayer) error) error {
+	if m.sctx != nil {
+		return fn(m)
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(context.Background(), func(sctx mongo.SessionContext) (interface{}, error) {
+		tx := &MongoDAL{
+			client: m.client,
+			db:     m.db,
+			name:   m.name,
+			sctx:   sctx,
+		}
+		return nil, fn(tx)
+	})
+	return err
+}
+
+// context returns the context used by database operations.
+func (m *MongoDAL) context() context.Context {
+	if m.sctx != nil {
+		return m.sctx
+	}
+	return context.Background()
+}
+
 // C ...
 func (m *MongoDAL) C(collectionName string) *mongo.Collection {
 	return m.db.Collection(collectionName)
@@ -122,7 +170,7 @@ func (m *MongoDAL) C(collectionName string) *mongo.Collection {
 
 // AggregateOne ...
 func (m *MongoDAL) AggregateOne(collectionName string, id interface{}, pipeline interface{}, result interface{}) error {
-	ctx := context.Background()
+	ctx := m.context()
 	cursor, err := m.db.Collection(collectionName).Aggregate(ctx, pipeline)
 	if err != nil {
 		return err
@@ -140,7 +188,7 @@ func (m *MongoDAL) AggregateOneWithQuery(collectionName string, id interface{},
 
 // AggregateAll ...
 func (m *MongoDAL) AggregateAll(collectionName string, pipeline interface{}, result interface{}) error {
-	ctx := context.Background()
+	ctx := m.context()
 	cursor, err := m.db.Collection(collectionName).Aggregate(ctx, pipeline)
 	if err != nil {
 		return err
@@ -156,18 +204,18 @@ func (m *MongoDAL) AggregateAllWithQuery(collectionName string, query persistenc
 
 // Count returns the total number of documents in the collection.
 func (m *MongoDAL) Count(collectionName string, query persistence.Query) (int64, error) {
-	return m.db.Collection(collectionName).CountDocuments(context.Background(), query.GetConditions())
+	return m.db.Collection(collectionName).CountDocuments(m.context(), query.GetConditions())
 }
 
 // InsertOne ...
 func (m *MongoDAL) InsertOne(collectionName string, doc interface{}) error {
-	_, err := m.db.Collection(collectionName).InsertOne(context.Background(), doc)
+	_, err := m.db.Collection(collectionName).InsertOne(m.context(), doc)
 	return err
 }
 
 // InsertMany ...
 func (m *MongoDAL) InsertMany(collectionName string, docs []interface{}) error {
-	_, err := m.db.Collection(collectionName).InsertMany(context.Background(), docs)
+	_, err := m.db.Collection(collectionName).InsertMany(m.context(), docs)
 	return err
 }
 
@@ -176,8 +224,8 @@ func (m *MongoDAL) FindOne(collectionName string, query persistence.Query, resul
 	options := options.FindOneOptions{
 		Projection: query.GetFields(),
 	}
-	doc := m.db.Collection(collectionName).FindOne(context.Background(), query.GetConditions(), &options)
-	err := doc.Decode(&result)
+	doc := m.db.Collection(collectionName).FindOne(m.context(), query.GetConditions(), &options)
+	err := decodeOne(doc, &result)
 	return result, err
 }
 
@@ -194,13 +242,13 @@ func (m *MongoDAL) Get(collectionName string, id interface{}, query persistence.
 
 // DeleteOne ...
 func (m *MongoDAL) DeleteOne(collectionName string, id interface{}) error {
-	_, err := m.db.Collection(collectionName).DeleteOne(context.Background(), bson.M{"_id": id})
+	_, err := m.db.Collection(collectionName).DeleteOne(m.context(), bson.M{"_id": id})
 	return err
 }
 
 // UpdateId ...
 func (m *MongoDAL) UpdateId(collectionName string, id interface{}, data interface{}) (int64, error) {
-	result, err := m.db.Collection(collectionName).UpdateOne(context.Background(), collectionName, bson.M{"_id": id})
+	result, err := m.db.Collection(collectionName).UpdateOne(m.context(), collectionName, bson.M{"_id": id})
 	if err != nil {
 		return 0, err
 	}
@@ -212,20 +260,9 @@ func getFindOneOptions(query persistence.Query) *options.FindOneOptions {
 }
 
 func getFindOptions(query persistence.Query) *options.FindOptions {
-	sort := bson.M{}
-	for _, v := range query.GetSort() {
-		value := 1
-		if v[0] == '-' {
-			value = -1
-			v = v[1:]
-		} else if v[0] == '+' {
-			v = v[1:]
-		}
-		sort[v] = value
-	}
 	opts := options.FindOptions{
-		Projection: query.GetFields(),
-		Sort:       sort,
+		Projection: withTextScore(query.GetFields(), query.GetSort()),
+		Sort:       SortToBSON("", query.GetSort()...),
 	}
 
 	limit := query.GetLimit()
@@ -245,162 +282,24 @@ func getFindOneAndUpdateOptions(query persistence.Query) *options.FindOneAndUpda
 	return &options.FindOneAndUpdateOptions{Projection: query.GetFields()}
 }
 
-// Setup ...
-func (m *MongoDAL) Setup() {
-	// notificationsCollection := m.C(CollectionNotifications)
-
-	// APIKeys
-	apiKeysCollection := m.C(CollectionAPIKeys)
-	EnsureUniqueIndex(apiKeysCollection, "key")
-	EnsureIndexes(apiKeysCollection, []string{
-		"owner",
-		"user_type",
-	})
-
-	// Movies
-	moviesCollection := m.C(CollectionMovies)
-	// TODO: See if we need to add this:
-	// https://docs.mongodb.com/manual/tutorial/specify-language-for-text-index/
-	EnsureTextIndexes(moviesCollection, []string{
-		"title",
-		"originalTitle",
-	})
-	EnsureIndexes(moviesCollection, []string{
-		"tmdbId",
-		"imdbId",
-		"claqueteId",
-		"slug",
-		"title",
-		"originalTitle",
-		"hidden",
-		"releaseDate",
-	})
-
-	// Cities
-	citiesCollection := m.C(CollectionCities)
-	EnsureIndexes(citiesCollection, []string{
-		"name",
-		"state",
-		"timeZone",
-	})
-
-	// Theaters
-	theatersCollection := m.C(CollectionTheaters)
-	EnsureTextIndexes(theatersCollection, []string{
-		"name",
-		"shortName",
-	})
-	EnsureIndexes(theatersCollection, []string{
-		"cityId",
-		"internalId",
-		"hidden",
-	})
-
-	// Scrapers
-	scrapersCollection := m.C(CollectionScrapers)
-	EnsureIndexes(scrapersCollection, []string{
-		"theaterId",
-		"type",
-		"provider",
-	})
-
-	// scraperRunsCollection := m.C(CollectionScraperRuns)
-
-	scoresCollection := m.C(CollectionScores)
-	EnsureIndex(scoresCollection, "movieId")
-
-	sessionsCollection := m.C(CollectionSessions)
-	EnsureIndexes(sessionsCollection, []string{
-		"movieSlug",
-		"theaterId",
-		"movieId",
-		"startTime",
-		"hidden",
-		"room",
-		"version",
-		"format",
-	})
-
-	pricesCollection := m.C(CollectionPrices)
-	EnsureIndex(pricesCollection, "theaterId")
-
-	// EnsureUniqueIndex(notificationsCollection, "nowPlaying")
+// Setup runs pending migrations.
+func (m *MongoDAL) Setup() error {
+	return m.Migrate()
 }
 
-// EnsureTextIndexes ...
-func EnsureTextIndexes(c *mongo.Collection, keys []string) {
-	indexKeys := bson.M{}
-	for _, k := range keys {
-		indexKeys[k] = "text"
-	}
-	model := mongo.IndexModel{
-		Keys: indexKeys,
-	}
-	_, err := c.Indexes().CreateOne(context.Background(), model)
-	if err != nil {
-		fmt.Printf("Error: %s\n", err.Error())
-	}
-}
-
-// EnsureIndex ensures that a given key in a given collection is an index.
-func EnsureIndex(c *mongo.Collection, key string) {
-	ensureIndex(c, []string{key}, false, true, true)
-}
-
-// EnsureIndexes ...
-func EnsureIndexes(c *mongo.Collection, keys []string) {
-	ensureIndexes(c, keys, false, true, true)
-}
-
-// EnsureUniqueIndex ensures that a given key in a given collection is an unique index.
-func EnsureUniqueIndex(c *mongo.Collection, key string) {
-	ensureIndex(c, []string{key}, true, true, true)
-}
-
-// EnsureUniqueIndexes ...
-func EnsureUniqueIndexes(c *mongo.Collection, keys []string) {
-	ensureIndexes(c, keys, true, true, true)
-}
-
-func ensureIndexes(c *mongo.Collection, keys []string, unique, bg, sparse bool) {
-	indexModels := make([]mongo.IndexModel, 0)
-	for _, k := range keys {
-		indexModels = append(indexModels, mongo.IndexModel{
-			Keys: bson.M{
-				k: 1,
-			},
-			Options: (&options.IndexOptions{}).
-				SetBackground(bg).
-				SetUnique(unique).
-				SetSparse(sparse),
-		})
-	}
-	_, err := c.Indexes().CreateMany(context.Background(), indexModels)
-	if err != nil {
-		fmt.Printf("Error: %s\n", err.Error())
-	}
+func (q *QueryOptions) AddCondition(name string, value interface{}) persistence.Query {
+	q.Conditions[name] = value
+	return q
 }
 
-func ensureIndex(c *mongo.Collection, keys []string, unique, bg, sparse bool) {
-	indexKeys := bson.M{}
-	for _, k := range keys {
-		indexKeys[k] = 1
-	}
-	model := mongo.IndexModel{
-		Keys: indexKeys,
-		Options: (&options.IndexOptions{}).
-			SetBackground(bg).
-			SetUnique(unique).
-			SetSparse(sparse),
+// Where adds the given conditions to the query.
+func (q *QueryOptions) Where(conditions ...persistence.Condition) persistence.Query {
+	if q.Conditions == nil {
+		q.Conditions = bson.M{}
 	}
-	_, err := c.Indexes().CreateOne(context.Background(), model)
-	if err != nil {
-		fmt.Printf("Error: %s\n", err.Error())
+	for _, c := range conditions {
+		bsonutil.MergeConditions(q.Conditions, bsonutil.ConditionToBSON(c))
 	}
-}
-
-func (q *QueryOptions) AddCondition(name string, value interface{}) persistence.Query {
-	q.Conditions[name] = value
 	return q
 }
 
@@ -417,8 +316,21 @@ func (q *QueryOptions) AddField(field string) persistence.Query {
 	return q
 }
 
-func (q *QueryOptions) SetFields(fields interface{}) persistence.Query {
-	q.Fields = fields.(bson.M)
+func (q *QueryOptions) SetFields(fields ...string) persistence.Query {
+	q.Fields = bson.M{}
+	for _, f := range fields {
+		q.Fields[f] = 1
+	}
+	return q
+}
+
+func (q *QueryOptions) Exclude(fields ...string) persistence.Query {
+	if q.Fields == nil {
+		q.Fields = bson.M{}
+	}
+	for _, f := range fields {
+		q.Fields[f] = 0
+	}
 	return q
 }
 
@@ -490,7 +402,7 @@ func BuildQuery(collectionName string, q map[string]string) persistence.Query {
 	if len(q) > 0 {
 		fields, ok := q["fields"]
 		if ok {
-			query.SetFields(parseFieldsQuery(fields))
+			query.Fields = parseFieldsQuery(fields)
 		}
 		sort, ok := q["sort"]
 		if ok {
@@ -593,6 +505,35 @@ func parseIncludeQuery(include string) []QueryInclude {
 	return result
 }
 
+// updateVersioned applies update to the document with the given ID and
+// increments its version. If version isn't zero the document is only updated
+// while its stored version is the same, otherwise a *persistence.ConflictError
+// is returned.
+// The update must not change the version itself.
+func (m *MongoDAL) updateVersioned(collectionName string, ID primitive.ObjectID, version int64, update bson.M) (int64, error) {
+	filter := bson.M{"_id": ID}
+	if version != 0 {
+		filter["version"] = version
+	}
+	update["$inc"] = bson.M{"version": 1}
+	C := m.C(collectionName)
+	result, err := C.UpdateOne(m.context(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	if result.MatchedCount == 0 && version != 0 {
+		// Only a conflict if the document still exists.
+		count, err := C.CountDocuments(m.context(), bson.M{"_id": ID})
+		if err != nil {
+			return 0, err
+		}
+		if count > 0 {
+			return 0, &persistence.ConflictError{Collection: collectionName, ID: ID.Hex(), Version: version}
+		}
+	}
+	return result.ModifiedCount, nil
+}
+
 func getCurrentTime() *time.Time {
 	now := time.Now()
 	return &now
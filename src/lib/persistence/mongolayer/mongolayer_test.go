@@ -9,6 +9,6 @@ func getTestingMongoDAL() (persistence.DataAccessLayer, error) {
 	if err != nil {
 		return nil, err
 	}
-	data.Setup()
+	err = data.Setup()
 	return data, err
 }
@@ -1,15 +1,17 @@
 package mongolayer
 
 import (
-	"context"
 	"fmt"
 	"strconv"
 
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/bsonutil"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/movieutil"
 	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
+	"github.com/dsbezerra/amenic/src/lib/util/searchutil"
 	"github.com/dsbezerra/amenic/src/lib/util/timeutil"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,12 +19,14 @@ import (
 
 // CountMovies ...
 func (m *MongoDAL) CountMovies(query persistence.Query) (int64, error) {
-	return m.C(CollectionMovies).CountDocuments(context.Background(), query.GetConditions())
+	return m.C(CollectionMovies).CountDocuments(m.context(), query.GetConditions())
 }
 
 // InsertMovie ...
 func (m *MongoDAL) InsertMovie(movie models.Movie) error {
-	_, err := m.C(CollectionMovies).InsertOne(context.Background(), movie)
+	movie.UpdateSearchKeys()
+	movie.Version = 1
+	_, err := m.C(CollectionMovies).InsertOne(m.context(), movie)
 	return err
 }
 
@@ -30,7 +34,7 @@ func (m *MongoDAL) InsertMovie(movie models.Movie) error {
 func (m *MongoDAL) FindMovie(query persistence.Query) (*models.Movie, error) {
 	var result models.Movie
 
-	var ctx = context.Background()
+	var ctx = m.context()
 	var cursor *mongo.Cursor
 	var err error
 
@@ -41,10 +45,12 @@ func (m *MongoDAL) FindMovie(query persistence.Query) (*models.Movie, error) {
 			defer cursor.Close(ctx)
 			if cursor.Next(ctx) {
 				err = cursor.Decode(&result)
+			} else if err = cursor.Err(); err == nil {
+				err = persistence.ErrNotFound
 			}
 		}
 	} else {
-		err = C.FindOne(ctx, query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+		err = decodeOne(C.FindOne(ctx, query.GetConditions(), getFindOneOptions(query)), &result)
 	}
 
 	if err != nil {
@@ -54,15 +60,18 @@ func (m *MongoDAL) FindMovie(query persistence.Query) (*models.Movie, error) {
 	return &result, err
 }
 
-// FindMovieAndUpdate finds a Movie matching the query and updates it, returning either the original or
-// updated.
-func (m *MongoDAL) FindMovieAndUpdate(query persistence.Query, update interface{}) (*models.Movie, error) {
-	var result models.Movie
-	err := m.C(CollectionMovies).FindOneAndUpdate(context.Background(), query.GetConditions(), update, getFindOneAndUpdateOptions(query)).Decode(&result)
+// FindMovieAndUpdate finds a Movie matching the query and patches it, returning the original. It
+// fails with a *persistence.ConflictError if the Movie is modified in between.
+func (m *MongoDAL) FindMovieAndUpdate(query persistence.Query, update persistence.Update) (*models.Movie, error) {
+	result, err := m.FindMovie(query)
 	if err != nil {
 		return nil, err
 	}
-	return &result, err
+	_, err = m.PatchMovie(result.ID.Hex(), result.Version, update)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 // GetMovie ...
@@ -78,7 +87,7 @@ func (m *MongoDAL) GetMovie(id string, query persistence.Query) (*models.Movie,
 func (m *MongoDAL) GetMovies(query persistence.Query) ([]models.Movie, error) {
 	// TODO: Implement aggregate for include queries
 	var result []models.Movie
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionMovies).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -91,11 +100,8 @@ func (m *MongoDAL) GetMovies(query persistence.Query) ([]models.Movie, error) {
 // GetMoviesByTitle ...
 func (m *MongoDAL) GetMoviesByTitle(title string) ([]models.Movie, error) {
 	opts := DefaultOptions("").
-		AddCondition("$text", bson.M{"$search": title}).
-		SetFields(bson.M{
-			"score": bson.M{"$meta": "textScore"},
-		}).
-		SetSort("$testScore:score")
+		Where(persistence.Text(title)).
+		SetSort(persistence.SortTextScore)
 	return m.GetMovies(opts)
 }
 
@@ -127,7 +133,10 @@ func (m *MongoDAL) GetNowPlayingMovies(query persistence.Query) ([]models.Movie,
 	// now playing movies
 	if len(and) == 0 {
 		period := scheduleutil.GetWeekPeriod(nil)
-		and = append(and, bson.M{"startTime": bson.M{"$gte": period.Start}})
+		and = append(and,
+			bson.M{"startTime": bson.M{"$gte": period.Start}},
+			bson.M{"hidden": false},
+		)
 	}
 
 	// Pipeline begin by finding all sessions, matching the given conditions, and grouping them by
@@ -194,7 +203,7 @@ func (m *MongoDAL) GetNowPlayingMovies(query persistence.Query) ([]models.Movie,
 	// will make sure ou movie documents are in the root
 	p = append(p, bson.M{"$replaceRoot": bson.M{"newRoot": "$movie"}})
 
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionSessions).Aggregate(ctx, p)
 	if err != nil {
 		return nil, err
@@ -213,7 +222,7 @@ func (m *MongoDAL) OldGetNowPlayingMovies(query persistence.Query) ([]models.Mov
 
 	pipe := []bson.M{
 		{
-			"$match": bson.M{"startTime": bson.M{"$gte": period.Start}},
+			"$match": bson.M{"startTime": bson.M{"$gte": period.Start}, "hidden": false},
 		},
 		{
 			"$group": bson.M{
@@ -263,7 +272,7 @@ func (m *MongoDAL) OldGetNowPlayingMovies(query persistence.Query) ([]models.Mov
 		},
 	}
 
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionSessions).Aggregate(ctx, pipe)
 	if err != nil {
 		return nil, err
@@ -305,12 +314,46 @@ func (m *MongoDAL) UpdateMovie(id string, mm models.Movie) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	// Search keys can only be rebuilt when the titles are given.
+	if mm.Title != "" || mm.OriginalTitle != "" {
+		mm.UpdateSearchKeys()
+	}
 	mm.UpdatedAt = getCurrentTime()
-	result, err := m.C(CollectionMovies).UpdateOne(context.Background(), bson.M{"_id": ID}, bson.M{"$set": mm})
+	version := mm.Version
+	mm.Version = 0
+	return m.updateVersioned(CollectionMovies, ID, version, bson.M{"$set": mm})
+}
+
+// PatchMovie ...
+func (m *MongoDAL) PatchMovie(id string, version int64, update persistence.Update) (int64, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return 0, err
 	}
-	return result.ModifiedCount, err
+	patch := persistence.Update{
+		Set:   map[string]interface{}{"updatedAt": getCurrentTime()},
+		Unset: update.Unset,
+	}
+	for field, value := range update.Set {
+		patch.Set[field] = value
+	}
+	// Search keys depend on both titles, so the current ones are needed.
+	if update.Changes("title", "originalTitle") {
+		movie, err := m.GetMovie(id, m.DefaultQuery().SetFields("title", "originalTitle"))
+		if err == persistence.ErrNotFound {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		keys := movieutil.SearchKeys(*movie, update)
+		if len(keys) > 0 {
+			patch.Set[searchutil.KeysField] = keys
+		} else {
+			patch.Unset = append(patch.Unset, searchutil.KeysField)
+		}
+	}
+	return m.updateVersioned(CollectionMovies, ID, version, bsonutil.UpdateToBSON(patch))
 }
 
 // DeleteMovie ...
@@ -319,13 +362,13 @@ func (m *MongoDAL) DeleteMovie(id string) error {
 	if err != nil {
 		return err
 	}
-	_, err = m.C(CollectionMovies).DeleteOne(context.Background(), bson.M{"_id": ID})
+	_, err = m.C(CollectionMovies).DeleteOne(m.context(), bson.M{"_id": ID})
 	return err
 }
 
 // DeleteMovies ...
 func (m *MongoDAL) DeleteMovies(query persistence.Query) (int64, error) {
-	result, err := m.C(CollectionMovies).DeleteMany(context.Background(), query.GetConditions())
+	result, err := m.C(CollectionMovies).DeleteMany(m.context(), query.GetConditions())
 	if err != nil {
 		return 0, err
 	}
@@ -418,10 +461,7 @@ func (m *MongoDAL) BuildMovieQuery(q map[string]string) persistence.Query {
 
 		search, ok := q["search"]
 		if ok {
-			query.AddCondition("$or", []bson.M{
-				bson.M{"title": bson.M{"$regex": search, "$options": "ig"}},
-				bson.M{"originalTitle": bson.M{"$regex": search, "$options": "ig"}},
-			})
+			query.Where(searchutil.Conditions(search)...)
 		}
 	}
 	return query
@@ -1,8 +1,6 @@
 package mongolayer
 
 import (
-	"context"
-
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,14 +9,14 @@ import (
 
 // InsertNotification ...
 func (m *MongoDAL) InsertNotification(notification models.Notification) error {
-	_, err := m.C(CollectionNotifications).InsertOne(context.Background(), notification)
+	_, err := m.C(CollectionNotifications).InsertOne(m.context(), notification)
 	return err
 }
 
 // FindNotification ...
 func (m *MongoDAL) FindNotification(query persistence.Query) (*models.Notification, error) {
 	var result models.Notification
-	err := m.C(CollectionNotifications).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionNotifications).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +36,7 @@ func (m *MongoDAL) GetNotification(id string, query persistence.Query) (*models.
 func (m *MongoDAL) GetNotifications(query persistence.Query) ([]models.Notification, error) {
 	// TODO: Implement aggregate if we have any include queries
 	var result []models.Notification
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionNotifications).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -54,13 +52,13 @@ func (m *MongoDAL) DeleteNotification(id string) error {
 	if err != nil {
 		return err
 	}
-	_, err = m.C(CollectionNotifications).DeleteOne(context.Background(), bson.M{"_id": ID})
+	_, err = m.C(CollectionNotifications).DeleteOne(m.context(), bson.M{"_id": ID})
 	return err
 }
 
 // DeleteNotifications ...
 func (m *MongoDAL) DeleteNotifications(query persistence.Query) (int64, error) {
-	result, err := m.C(CollectionNotifications).DeleteMany(context.Background(), query.GetConditions())
+	result, err := m.C(CollectionNotifications).DeleteMany(m.context(), query.GetConditions())
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,47 @@
+package mongolayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/bsonutil"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertOutboxEvent ...
+func (m *MongoDAL) InsertOutboxEvent(event models.OutboxEvent) error {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	return m.InsertOne(CollectionOutboxEvents, event)
+}
+
+// GetPendingOutboxEvents ...
+func (m *MongoDAL) GetPendingOutboxEvents(source string, limit int64) ([]models.OutboxEvent, error) {
+	var result []models.OutboxEvent
+	var ctx = m.context()
+	query := m.DefaultQuery().
+		Where(persistence.Eq("source", source), persistence.Eq("sentAt", nil), persistence.Eq("failedAt", nil)).
+		SetSort("timestamp").
+		SetLimit(limit)
+	cursor, err := m.C(CollectionOutboxEvents).Find(ctx, query.GetConditions(), getFindOptions(query))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &result)
+	return result, err
+}
+
+// UpdateOutboxEvent ...
+func (m *MongoDAL) UpdateOutboxEvent(id string, update persistence.Update) (int64, error) {
+	ID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, err
+	}
+	result, err := m.C(CollectionOutboxEvents).UpdateOne(m.context(), bson.M{"_id": ID}, bsonutil.UpdateToBSON(update))
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, err
+}
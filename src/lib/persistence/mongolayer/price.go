@@ -1,8 +1,6 @@
 package mongolayer
 
 import (
-	"context"
-
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,7 +12,7 @@ func (m *MongoDAL) InsertPrice(price models.Price) error {
 	if price.ID.IsZero() {
 		price.ID = primitive.NewObjectID()
 	}
-	_, err := m.C(CollectionPrices).InsertOne(context.Background(), price)
+	_, err := m.C(CollectionPrices).InsertOne(m.context(), price)
 	return err
 }
 
@@ -27,14 +25,14 @@ func (m *MongoDAL) InsertPrices(prices ...models.Price) error {
 		}
 		arr[i] = p
 	}
-	_, err := m.C(CollectionPrices).InsertMany(context.Background(), arr)
+	_, err := m.C(CollectionPrices).InsertMany(m.context(), arr)
 	return err
 }
 
 // FindPrice ...
 func (m *MongoDAL) FindPrice(query persistence.Query) (*models.Price, error) {
 	var result models.Price
-	err := m.C(CollectionPrices).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionPrices).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -54,7 +52,7 @@ func (m *MongoDAL) GetPrice(id string, query persistence.Query) (*models.Price,
 func (m *MongoDAL) GetPrices(query persistence.Query) ([]models.Price, error) {
 	// TODO: Implement aggregate if we have any include queries
 	var result []models.Price
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionPrices).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -70,13 +68,13 @@ func (m *MongoDAL) DeletePrice(id string) error {
 	if err != nil {
 		return err
 	}
-	_, err = m.C(CollectionPrices).DeleteOne(context.Background(), bson.M{"_id": ID})
+	_, err = m.C(CollectionPrices).DeleteOne(m.context(), bson.M{"_id": ID})
 	return err
 }
 
 // DeletePrices ...
 func (m *MongoDAL) DeletePrices(query persistence.Query) (int64, error) {
-	result, err := m.C(CollectionPrices).DeleteMany(context.Background(), query.GetConditions())
+	result, err := m.C(CollectionPrices).DeleteMany(m.context(), query.GetConditions())
 	if err != nil {
 		return 0, err
 	}
@@ -87,6 +85,12 @@ func (m *MongoDAL) DeletePrices(query persistence.Query) (int64, error) {
 func (m *MongoDAL) BuildPriceQuery(q map[string]string) persistence.Query {
 	query := BuildQuery("", q)
 	if len(q) > 0 {
+		if theater, ok := q["theaterId"]; ok {
+			value, err := primitive.ObjectIDFromHex(theater)
+			if err == nil {
+				query.AddCondition("theaterId", value).SetLimit(-1)
+			}
+		}
 	}
 	return query
 }
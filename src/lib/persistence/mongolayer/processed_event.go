@@ -0,0 +1,32 @@
+package mongolayer
+
+import (
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SaveProcessedEvent ...
+func (m *MongoDAL) SaveProcessedEvent(event models.ProcessedEvent) error {
+	// An expired event may still be stored until the TTL index removes it.
+	_, err := m.C(CollectionProcessedEvents).ReplaceOne(m.context(), bson.M{"_id": event.ID}, event,
+		options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetProcessedEvent ...
+func (m *MongoDAL) GetProcessedEvent(id string) (*models.ProcessedEvent, error) {
+	var result models.ProcessedEvent
+	query := m.DefaultQuery().Where(
+		persistence.Eq("_id", id),
+		persistence.Range("expiresAt", time.Now().UTC(), nil),
+	)
+	err := decodeOne(m.C(CollectionProcessedEvents).FindOne(m.context(), query.GetConditions()), &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -1,8 +1,6 @@
 package mongolayer
 
 import (
-	"context"
-
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,14 +9,15 @@ import (
 
 // InsertScore ...
 func (m *MongoDAL) InsertScore(score models.Score) error {
-	_, err := m.C(CollectionScores).InsertOne(context.Background(), score)
+	score.Version = 1
+	_, err := m.C(CollectionScores).InsertOne(m.context(), score)
 	return err
 }
 
 // FindScore ...
 func (m *MongoDAL) FindScore(query persistence.Query) (*models.Score, error) {
 	var result models.Score
-	err := m.C(CollectionScores).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionScores).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +37,7 @@ func (m *MongoDAL) GetScore(id string, query persistence.Query) (*models.Score,
 func (m *MongoDAL) GetScores(query persistence.Query) ([]models.Score, error) {
 	// TODO: Implement aggregate if we have any include queries
 	var result []models.Score
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionScores).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -55,11 +54,9 @@ func (m *MongoDAL) UpdateScore(id string, ms models.Score) (int64, error) {
 		return 0, err
 	}
 	ms.UpdatedAt = getCurrentTime()
-	result, err := m.C(CollectionTheaters).UpdateOne(context.Background(), bson.M{"_id": ID}, bson.M{"$set": ms})
-	if err != nil {
-		return 0, err
-	}
-	return result.ModifiedCount, err
+	version := ms.Version
+	ms.Version = 0
+	return m.updateVersioned(CollectionScores, ID, version, bson.M{"$set": ms})
 }
 
 // DeleteScore ...
@@ -68,13 +65,13 @@ func (m *MongoDAL) DeleteScore(id string) error {
 	if err != nil {
 		return err
 	}
-	_, err = m.C(CollectionScores).DeleteOne(context.Background(), bson.M{"_id": ID})
+	_, err = m.C(CollectionScores).DeleteOne(m.context(), bson.M{"_id": ID})
 	return err
 }
 
 // DeleteScores ...
 func (m *MongoDAL) DeleteScores(query persistence.Query) (int64, error) {
-	result, err := m.C(CollectionScores).DeleteMany(context.Background(), query.GetConditions())
+	result, err := m.C(CollectionScores).DeleteMany(m.context(), query.GetConditions())
 	if err != nil {
 		return 0, err
 	}
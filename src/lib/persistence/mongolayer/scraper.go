@@ -1,8 +1,6 @@
 package mongolayer
 
 import (
-	"context"
-
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,14 +9,14 @@ import (
 
 // InsertScraper ...
 func (m *MongoDAL) InsertScraper(scraper models.Scraper) error {
-	_, err := m.C(CollectionScrapers).InsertOne(context.Background(), scraper)
+	_, err := m.C(CollectionScrapers).InsertOne(m.context(), scraper)
 	return err
 }
 
 // FindScraper ...
 func (m *MongoDAL) FindScraper(query persistence.Query) (*models.Scraper, error) {
 	var result models.Scraper
-	err := m.C(CollectionScrapers).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionScrapers).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +36,7 @@ func (m *MongoDAL) GetScraper(id string, query persistence.Query) (*models.Scrap
 func (m *MongoDAL) GetScrapers(query persistence.Query) ([]models.Scraper, error) {
 	// TODO: Implement aggregate if we have any include queries
 	var result []models.Scraper
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionScrapers).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -54,7 +52,7 @@ func (m *MongoDAL) UpdateScraper(id string, ms models.Scraper) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	result, err := m.C(CollectionScrapers).UpdateOne(context.Background(), bson.M{"_id": ID}, bson.M{"$set": ms})
+	result, err := m.C(CollectionScrapers).UpdateOne(m.context(), bson.M{"_id": ID}, bson.M{"$set": ms})
 	if err != nil {
 		return 0, err
 	}
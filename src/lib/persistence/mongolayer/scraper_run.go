@@ -1,8 +1,6 @@
 package mongolayer
 
 import (
-	"context"
-
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -25,7 +23,7 @@ func (m *MongoDAL) InsertScraperRun(scraperRun models.ScraperRun) error {
 // FindScraperRun ...
 func (m *MongoDAL) FindScraperRun(query persistence.Query) (*models.ScraperRun, error) {
 	var result models.ScraperRun
-	err := m.C(CollectionScraperRuns).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionScraperRuns).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +43,7 @@ func (m *MongoDAL) GetScraperRun(id string, query persistence.Query) (*models.Sc
 func (m *MongoDAL) GetScraperRuns(query persistence.Query) ([]models.ScraperRun, error) {
 	// TODO: Implement aggregate if we have any include queries
 	var result []models.ScraperRun
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionScraperRuns).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -54,3 +52,12 @@ func (m *MongoDAL) GetScraperRuns(query persistence.Query) ([]models.ScraperRun,
 	cursor.All(ctx, &result)
 	return result, err
 }
+
+// DeleteScraperRuns ...
+func (m *MongoDAL) DeleteScraperRuns(query persistence.Query) (int64, error) {
+	result, err := m.C(CollectionScraperRuns).DeleteMany(m.context(), query.GetConditions())
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, err
+}
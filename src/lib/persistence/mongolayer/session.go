@@ -1,10 +1,12 @@
 package mongolayer
 
 import (
-	"context"
+	"strconv"
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/bsonutil"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,7 +15,7 @@ import (
 
 // InsertSession ...
 func (m *MongoDAL) InsertSession(session models.Session) error {
-	_, err := m.C(CollectionSessions).InsertOne(context.Background(), session)
+	_, err := m.C(CollectionSessions).InsertOne(m.context(), session)
 	return err
 }
 
@@ -23,14 +25,14 @@ func (m *MongoDAL) InsertSessions(sessions ...models.Session) error {
 	for i, p := range sessions {
 		arr[i] = p
 	}
-	_, err := m.C(CollectionSessions).InsertMany(context.Background(), arr)
+	_, err := m.C(CollectionSessions).InsertMany(m.context(), arr)
 	return err
 }
 
 // FindSession ...
 func (m *MongoDAL) FindSession(query persistence.Query) (*models.Session, error) {
 	var result models.Session
-	err := m.C(CollectionSessions).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionSessions).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +51,7 @@ func (m *MongoDAL) GetSession(id string, query persistence.Query) (*models.Sessi
 // GetSessions ...
 func (m *MongoDAL) GetSessions(query persistence.Query) ([]models.Session, error) {
 	var result []models.Session
-	var ctx = context.Background()
+	var ctx = m.context()
 	var cursor *mongo.Cursor
 	var err error
 
@@ -76,22 +78,102 @@ func (m *MongoDAL) DeleteSession(id string) error {
 	if err != nil {
 		return err
 	}
-	_, err = m.C(CollectionSessions).DeleteOne(context.Background(), bson.M{"_id": ID})
+	_, err = m.C(CollectionSessions).DeleteOne(m.context(), bson.M{"_id": ID})
+	return err
+}
+
+// SyncSessions ...
+func (m *MongoDAL) SyncSessions(theaterID primitive.ObjectID, window scheduleutil.Period, sessions []models.Session) (*persistence.SyncSummary, error) {
+	var summary *persistence.SyncSummary
+	err := m.WithTransaction(func(tx persistence.DataAccessLayer) error {
+		t := tx.(*MongoDAL)
+
+		existing, err := t.GetSessions(t.DefaultQuery().
+			Where(
+				persistence.Eq("theaterId", theaterID),
+				persistence.Range("startTime", window.Start, window.End),
+			).
+			SetLimit(-1))
+		if err != nil {
+			return err
+		}
+
+		diff := persistence.DiffSessions(existing, sessions)
+		if len(diff.Insert) > 0 {
+			now := getCurrentTime()
+			for i := range diff.Insert {
+				diff.Insert[i].TheaterID = theaterID
+				diff.Insert[i].Hidden = false
+				if diff.Insert[i].CreatedAt == nil {
+					diff.Insert[i].CreatedAt = now
+				}
+			}
+			err = t.InsertSessions(diff.Insert...)
+			if err != nil {
+				return err
+			}
+		}
+		err = t.setSessionsHidden(diff.Hide, true)
+		if err != nil {
+			return err
+		}
+		err = t.setSessionsHidden(diff.Show, false)
+		if err != nil {
+			return err
+		}
+
+		summary = diff.Summary()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (m *MongoDAL) setSessionsHidden(IDs []primitive.ObjectID, hidden bool) error {
+	if len(IDs) == 0 {
+		return nil
+	}
+	_, err := m.C(CollectionSessions).UpdateMany(m.context(),
+		bson.M{"_id": bson.M{"$in": IDs}},
+		bson.M{"$set": bson.M{"hidden": hidden}},
+	)
 	return err
 }
 
 // DeleteSessions ...
 func (m *MongoDAL) DeleteSessions(query persistence.Query) (int64, error) {
-	result, err := m.C(CollectionSessions).DeleteMany(context.Background(), query.GetConditions())
+	result, err := m.C(CollectionSessions).DeleteMany(m.context(), query.GetConditions())
 	if err != nil {
 		return 0, err
 	}
 	return result.DeletedCount, err
 }
 
+// UpdateSessions ...
+func (m *MongoDAL) UpdateSessions(query persistence.Query, update persistence.Update) (int64, error) {
+	result, err := m.C(CollectionSessions).UpdateMany(m.context(), query.GetConditions(), bsonutil.UpdateToBSON(update))
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, err
+}
+
 // BuildSessionQuery ...
 func (m *MongoDAL) BuildSessionQuery(q map[string]string) persistence.Query {
 	query := BuildQuery("", q)
+
+	// Sessions removed by scrapers are hidden unless requested otherwise
+	hidden := false
+	if v, ok := q["hidden"]; ok {
+		value, err := strconv.ParseBool(v)
+		if err == nil {
+			hidden = value
+		}
+	}
+	query.AddCondition("hidden", hidden)
+
 	if len(q) > 0 {
 
 		if theater, ok := q["theaterId"]; ok {
@@ -0,0 +1,42 @@
+package mongolayer
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetSessionHistories ...
+func (m *MongoDAL) GetSessionHistories(query persistence.Query) ([]models.SessionHistory, error) {
+	var result []models.SessionHistory
+	var ctx = m.context()
+	cursor, err := m.C(CollectionSessionHistory).Find(ctx, query.GetConditions(), getFindOptions(query))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &result)
+	return result, err
+}
+
+// SaveSessionHistories ...
+func (m *MongoDAL) SaveSessionHistories(histories ...models.SessionHistory) error {
+	if len(histories) == 0 {
+		return nil
+	}
+	now := getCurrentTime()
+	writes := make([]mongo.WriteModel, len(histories))
+	for i, h := range histories {
+		if h.CreatedAt == nil {
+			h.CreatedAt = now
+		}
+		h.UpdatedAt = now
+		writes[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": h.ID}).
+			SetReplacement(h).
+			SetUpsert(true)
+	}
+	_, err := m.C(CollectionSessionHistory).BulkWrite(m.context(), writes)
+	return err
+}
@@ -0,0 +1,94 @@
+package mongolayer
+
+import (
+	"strings"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// InsertState ...
+func (m *MongoDAL) InsertState(state models.State) error {
+	state.ID = strings.ToUpper(state.ID)
+	_, err := m.C(CollectionStates).InsertOne(m.context(), state)
+	return err
+}
+
+// FindState ...
+func (m *MongoDAL) FindState(query persistence.Query) (*models.State, error) {
+	var result models.State
+	err := decodeOne(m.C(CollectionStates).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, err
+}
+
+// GetState ...
+func (m *MongoDAL) GetState(id string, query persistence.Query) (*models.State, error) {
+	return m.FindState(query.AddCondition("_id", strings.ToUpper(id)))
+}
+
+// GetStates ...
+func (m *MongoDAL) GetStates(query persistence.Query) ([]models.State, error) {
+	var result []models.State
+	var ctx = m.context()
+	cursor, err := m.C(CollectionStates).Find(ctx, query.GetConditions(), getFindOptions(query))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &result)
+	return result, err
+}
+
+// UpdateState ...
+func (m *MongoDAL) UpdateState(id string, s models.State) (int64, error) {
+	s.ID = strings.ToUpper(id)
+	s.UpdatedAt = getCurrentTime()
+	result, err := m.C(CollectionStates).UpdateOne(m.context(), bson.M{"_id": s.ID}, bson.M{"$set": s})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, err
+}
+
+// DeleteState ...
+func (m *MongoDAL) DeleteState(id string) error {
+	_, err := m.C(CollectionStates).DeleteOne(m.context(), bson.M{"_id": strings.ToUpper(id)})
+	return err
+}
+
+// DeleteStates ...
+func (m *MongoDAL) DeleteStates(query persistence.Query) (int64, error) {
+	result, err := m.C(CollectionStates).DeleteMany(m.context(), query.GetConditions())
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, err
+}
+
+// BuildStateQuery converts a map of query string to mongolayer syntax for State model
+func (m *MongoDAL) BuildStateQuery(q map[string]string) persistence.Query {
+	query := BuildQuery("", q)
+	if _, ok := q["limit"]; !ok {
+		// There are only a few states, so all of them are returned by default.
+		query.SetLimit(-1)
+	}
+	if len(q) > 0 {
+		ID, ok := q["id"]
+		if ok {
+			query.AddCondition("_id", strings.ToUpper(ID))
+		}
+		name, ok := q["name"]
+		if ok {
+			query.AddCondition("name", name)
+		}
+		timeZone, ok := q["timeZone"]
+		if ok {
+			query.AddCondition("timeZone", timeZone)
+		}
+	}
+	return query
+}
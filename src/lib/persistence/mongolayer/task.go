@@ -1,24 +1,21 @@
 package mongolayer
 
 import (
-	"context"
-
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // InsertTask ...
 func (m *MongoDAL) InsertTask(task models.Task) error {
-	_, err := m.C(CollectionTasks).InsertOne(context.Background(), task)
+	_, err := m.C(CollectionTasks).InsertOne(m.context(), task)
 	return err
 }
 
 // FindTask ...
 func (m *MongoDAL) FindTask(query persistence.Query) (*models.Task, error) {
 	var result models.Task
-	err := m.C(CollectionTasks).FindOne(context.Background(), query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+	err := decodeOne(m.C(CollectionTasks).FindOne(m.context(), query.GetConditions(), getFindOneOptions(query)), &result)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +31,7 @@ func (m *MongoDAL) GetTask(id string, query persistence.Query) (*models.Task, er
 func (m *MongoDAL) GetTasks(query persistence.Query) ([]models.Task, error) {
 	// TODO: Implement aggregate if we have any include queries
 	var result []models.Task
-	var ctx = context.Background()
+	var ctx = m.context()
 	cursor, err := m.C(CollectionTasks).Find(ctx, query.GetConditions(), getFindOptions(query))
 	if err != nil {
 		return nil, err
@@ -46,7 +43,7 @@ func (m *MongoDAL) GetTasks(query persistence.Query) ([]models.Task, error) {
 
 // UpdateTask ...
 func (m *MongoDAL) UpdateTask(id string, mt models.Task) (int64, error) {
-	result, err := m.C(CollectionTheaters).UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": mt})
+	result, err := m.C(CollectionTheaters).UpdateOne(m.context(), bson.M{"_id": id}, bson.M{"$set": mt})
 	if err != nil {
 		return 0, err
 	}
@@ -55,13 +52,13 @@ func (m *MongoDAL) UpdateTask(id string, mt models.Task) (int64, error) {
 
 // DeleteTask ...
 func (m *MongoDAL) DeleteTask(id string) error {
-	_, err := m.C(CollectionTasks).DeleteOne(context.Background(), bson.M{"_id": id})
+	_, err := m.C(CollectionTasks).DeleteOne(m.context(), bson.M{"_id": id})
 	return err
 }
 
 // DeleteTasks ...
 func (m *MongoDAL) DeleteTasks(query persistence.Query) (int64, error) {
-	result, err := m.C(CollectionTasks).DeleteMany(context.Background(), query.GetConditions())
+	result, err := m.C(CollectionTasks).DeleteMany(m.context(), query.GetConditions())
 	if err != nil {
 		return 0, err
 	}
@@ -82,7 +79,7 @@ func (m *MongoDAL) EnsureTasksExists(mp map[string]models.Task) {
 
 		if ID != "" {
 			_, err := m.GetTask(ID, m.DefaultQuery())
-			if err != mongo.ErrNoDocuments {
+			if err == persistence.ErrNotFound {
 				m.InsertTask(v)
 			}
 		}
@@ -1,11 +1,11 @@
 package mongolayer
 
 import (
-	"context"
 	"strconv"
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/searchutil"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -14,12 +14,15 @@ import (
 
 // CountTheaters ...
 func (m *MongoDAL) CountTheaters(query persistence.Query) (int64, error) {
-	return m.C(CollectionTheaters).CountDocuments(context.Background(), query.GetConditions())
+	return m.C(CollectionTheaters).CountDocuments(m.context(), query.GetConditions())
 }
 
 // InsertTheater ...
 func (m *MongoDAL) InsertTheater(theater models.Theater) error {
-	_, err := m.C(CollectionTheaters).InsertOne(context.Background(), theater)
+	theater.UpdateSearchKeys()
+	theater.Distance = 0 // Only set in query results
+	theater.Version = 1
+	_, err := m.C(CollectionTheaters).InsertOne(m.context(), theater)
 	return err
 }
 
@@ -27,21 +30,24 @@ func (m *MongoDAL) InsertTheater(theater models.Theater) error {
 func (m *MongoDAL) FindTheater(query persistence.Query) (*models.Theater, error) {
 	var result models.Theater
 
-	var ctx = context.Background()
+	var ctx = m.context()
 	var cursor *mongo.Cursor
 	var err error
 
+	// Distances are only set by the $geoNear aggregation stage.
 	var C = m.C(CollectionTheaters)
-	if query.HasInclude() {
+	if query.HasInclude() || geoNearStage(query.GetConditions()) != nil {
 		cursor, err = C.Aggregate(ctx, buildPipeline(CollectionTheaters, query.(*QueryOptions)))
 		if cursor != nil {
 			defer cursor.Close(ctx)
 			if cursor.Next(ctx) {
 				err = cursor.Decode(&result)
+			} else if err = cursor.Err(); err == nil {
+				err = persistence.ErrNotFound
 			}
 		}
 	} else {
-		err = C.FindOne(ctx, query.GetConditions(), getFindOneOptions(query)).Decode(&result)
+		err = decodeOne(C.FindOne(ctx, query.GetConditions(), getFindOneOptions(query)), &result)
 	}
 
 	if err != nil {
@@ -64,12 +70,13 @@ func (m *MongoDAL) GetTheater(id string, query persistence.Query) (*models.Theat
 func (m *MongoDAL) GetTheaters(query persistence.Query) ([]models.Theater, error) {
 	var result []models.Theater
 
-	var ctx = context.Background()
+	var ctx = m.context()
 	var cursor *mongo.Cursor
 	var err error
 
+	// Distances are only set by the $geoNear aggregation stage.
 	var C = m.C(CollectionTheaters)
-	if query.HasInclude() {
+	if query.HasInclude() || geoNearStage(query.GetConditions()) != nil {
 		opts := options.Aggregate()
 		cursor, err = C.Aggregate(ctx, buildPipeline(CollectionTheaters, query.(*QueryOptions)), opts)
 	} else {
@@ -92,13 +99,13 @@ func (m *MongoDAL) DeleteTheater(id string) error {
 	if err != nil {
 		return err
 	}
-	_, err = m.C(CollectionTheaters).DeleteOne(context.Background(), bson.M{"_id": ID})
+	_, err = m.C(CollectionTheaters).DeleteOne(m.context(), bson.M{"_id": ID})
 	return err
 }
 
 // DeleteTheaters ...
 func (m *MongoDAL) DeleteTheaters(query persistence.Query) (int64, error) {
-	result, err := m.C(CollectionTheaters).DeleteMany(context.Background(), query.GetConditions())
+	result, err := m.C(CollectionTheaters).DeleteMany(m.context(), query.GetConditions())
 	if err != nil {
 		return 0, err
 	}
@@ -111,12 +118,13 @@ func (m *MongoDAL) UpdateTheater(id string, mt models.Theater) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	mt.ID = ID // _id isn't omitted when empty and can't be modified
+	mt.UpdateSearchKeys()
+	mt.Distance = 0 // Only set in query results
 	mt.UpdatedAt = getCurrentTime()
-	result, err := m.C(CollectionTheaters).UpdateOne(context.Background(), bson.M{"_id": ID}, bson.M{"$set": mt})
-	if err != nil {
-		return 0, err
-	}
-	return result.ModifiedCount, err
+	version := mt.Version
+	mt.Version = 0
+	return m.updateVersioned(CollectionTheaters, ID, version, bson.M{"$set": mt})
 }
 
 // BuildTheaterQuery converts a map of query string to mongolayer syntax for Theater model
@@ -146,10 +154,25 @@ func (m *MongoDAL) BuildTheaterQuery(q map[string]string) persistence.Query {
 
 		search, ok := q["search"]
 		if ok {
-			query.AddCondition("$or", []bson.M{
-				bson.M{"name": bson.M{"$regex": search, "$options": "ig"}},
-				bson.M{"shortName": bson.M{"$regex": search, "$options": "ig"}},
-			})
+			query.Where(searchutil.Conditions(search)...)
+		}
+
+		// Near, e.g.: near=-19.9191,-43.9386&radius=10000. The radius is in
+		// meters and results are sorted by distance unless sorted otherwise.
+		near, ok := q["near"]
+		if ok {
+			point, err := models.ParseGeoPoint(near)
+			if err == nil {
+				radius := float64(persistence.DefaultNearRadius)
+				value, err := strconv.ParseFloat(q["radius"], 64)
+				if err == nil && value > 0 {
+					radius = value
+				}
+				query.Where(persistence.Near("location", point, radius))
+				if _, ok := q["sort"]; !ok {
+					query.SetSort(persistence.SortDistance)
+				}
+			}
 		}
 	}
 	return query
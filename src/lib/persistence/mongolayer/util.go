@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -32,10 +33,21 @@ type LookupInfo struct {
 func buildPipeline(collectionName string, opts *QueryOptions) mongo.Pipeline {
 	p := make(mongo.Pipeline, 0)
 
+	// $geoNear must be the first stage
+	near := geoNearStage(opts.Conditions)
+	if near != nil {
+		p = append(p, near)
+	}
+
 	// Build $match
 	if len(opts.Conditions) > 0 {
 		and := make([]bson.D, 0)
 		for f, v := range opts.Conditions {
+			// Operators such as $and, $or and $text are kept as they are.
+			if strings.HasPrefix(f, "$") {
+				and = append(and, bson.D{{Key: f, Value: v}})
+				continue
+			}
 
 			switch v.(type) {
 			case bson.D:
@@ -99,6 +111,9 @@ func buildPipeline(collectionName string, opts *QueryOptions) mongo.Pipeline {
 	for _, included := range opts.Includes {
 		project[included.Field] = 1
 	}
+	if len(project) > 0 && near != nil {
+		project[distanceField] = 1
+	}
 	if len(project) > 0 {
 		p = append(p, bson.D{
 			{Key: "$project", Value: project},
@@ -130,6 +145,10 @@ func getCollection(s string) (*Collection, error) {
 		name = CollectionImages
 		collt = Images
 
+	case "integrity_reports", "integrity_report":
+		name = CollectionIntegrityReports
+		collt = IntegrityReports
+
 	case "movies", "movie":
 		name = CollectionMovies
 		collt = Movies
@@ -158,6 +177,14 @@ func getCollection(s string) (*Collection, error) {
 		name = CollectionSessions
 		collt = Sessions
 
+	case "session_history":
+		name = CollectionSessionHistory
+		collt = SessionHistory
+
+	case "states", "state":
+		name = CollectionStates
+		collt = States
+
 	case "theaters", "theater":
 		name = CollectionTheaters
 		collt = Theaters
@@ -272,6 +299,11 @@ func calculateLookupInfo(collection string, include QueryInclude) (*LookupInfo,
 				Key:   "$gte",
 				Value: []interface{}{"$startTime", period.Start},
 			},
+		}, bson.D{
+			{
+				Key:   "$eq",
+				Value: []interface{}{"$hidden", false},
+			},
 		})
 		result.sort = bson.D{
 			{
@@ -352,6 +384,12 @@ func calculateLookupInfo(collection string, include QueryInclude) (*LookupInfo,
 		}
 
 	case Cities:
+		if fType == States {
+			result.localField = "stateId"
+			result.foreignField = "_id"
+			result.as = "state"
+			result.unwind = true
+		}
 	}
 
 	if result.fromCollection.Type == None {
@@ -361,7 +399,9 @@ func calculateLookupInfo(collection string, include QueryInclude) (*LookupInfo,
 	return &result, nil
 }
 
-// SortToBSON converts a sort array to a bson object
+// SortToBSON converts a sort array to a bson object. persistence.SortTextScore
+// sorts by the textScore field, see withTextScore, and persistence.SortDistance
+// by the distance field, see geoNearStage.
 func SortToBSON(prefix string, sort ...string) bson.D {
 	result := bson.D{}
 
@@ -370,6 +410,15 @@ func SortToBSON(prefix string, sort ...string) bson.D {
 			continue
 		}
 
+		if f == persistence.SortTextScore {
+			result = append(result, bson.E{Key: textScoreField, Value: bson.M{"$meta": "textScore"}})
+			continue
+		}
+		if f == persistence.SortDistance {
+			result = append(result, bson.E{Key: distanceField, Value: 1})
+			continue
+		}
+
 		v := 0
 		if f[0] == '-' {
 			v = -1
@@ -442,3 +491,35 @@ func DefaultOptions(collectionName string) *QueryOptions {
 
 	return &result
 }
+
+const (
+	// textScoreField is the field holding the relevance of text search
+	// results.
+	textScoreField = "textScore"
+
+	// distanceField is the field holding the distance in meters of near
+	// query results.
+	distanceField = "distance"
+)
+
+// withTextScore adds the text search relevance to the projection if results
+// are sorted by it. The relevance must be projected to be used in a sort.
+func withTextScore(fields interface{}, sort []string) interface{} {
+	sorted := false
+	for _, f := range sort {
+		if f == persistence.SortTextScore {
+			sorted = true
+		}
+	}
+	if !sorted {
+		return fields
+	}
+
+	result := bson.M{textScoreField: bson.M{"$meta": "textScore"}}
+	if m, ok := fields.(bson.M); ok {
+		for k, v := range m {
+			result[k] = v
+		}
+	}
+	return result
+}
@@ -2,15 +2,24 @@ package persistence
 
 import (
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // DataAccessLayer is used to communicate with the database.
 type DataAccessLayer interface {
-	Setup()
+	// Setup prepares the database to be used, running pending migrations
+	// when supported.
+	Setup() error
 	Close()
 
 	DefaultQuery() Query
 
+	// WithTransaction runs fn in a transaction. All operations made through tx
+	// are committed if fn returns nil, otherwise they are discarded.
+	// @param	fn{func(tx DataAccessLayer) error} - Operations to run atomically
+	WithTransaction(fn func(tx DataAccessLayer) error) error
+
 	BuildCityQuery(q map[string]string) Query
 	BuildMovieQuery(q map[string]string) Query
 	BuildNotificationQuery(q map[string]string) Query
@@ -19,6 +28,7 @@ type DataAccessLayer interface {
 	BuildSessionQuery(q map[string]string) Query
 	BuildTheaterQuery(q map[string]string) Query
 	BuildScraperQuery(q map[string]string) Query
+	BuildStateQuery(q map[string]string) Query
 	BuildImageQuery(q map[string]string) Query
 
 	// ------ Admin ------
@@ -134,6 +144,16 @@ type DataAccessLayer interface {
 	// TODO:
 	UpdateImage(id string, m models.Image) (int64, error)
 
+	// ------ Integrity Report ------
+
+	// InsertIntegrityReport inserts a single IntegrityReport resource
+	// @param report{models.IntegrityReport} - An IntegrityReport resource to be inserted
+	InsertIntegrityReport(report models.IntegrityReport) error
+
+	// GetIntegrityReports retrieves all IntegrityReport resources matching the given Query
+	// @param	query{Query} - Options used to retrieve data
+	GetIntegrityReports(query Query) ([]models.IntegrityReport, error)
+
 	// ------ Movie ------
 	CountMovies(query Query) (int64, error)
 
@@ -148,8 +168,8 @@ type DataAccessLayer interface {
 	// FindMovieAndUpdate finds a single Movie matching the given query
 	// and updates it, returning either the original or the updated.
 	// @param	query{Query}  				- Options used to find movie
-	// @param	update{interface{}}   - Update data
-	FindMovieAndUpdate(query Query, update interface{}) (*models.Movie, error)
+	// @param	update{Update}   			- Update data
+	FindMovieAndUpdate(query Query, update Update) (*models.Movie, error)
 
 	// GetMovie retrieves a Movie resource by ID
 	// @param	id{string} 								- Movie identifier
@@ -182,6 +202,14 @@ type DataAccessLayer interface {
 	// TODO:
 	UpdateMovie(id string, m models.Movie) (int64, error)
 
+	// PatchMovie sets and unsets only the fields in update of the Movie with
+	// the given id. If version isn't zero it fails with a *ConflictError when
+	// the movie was modified since that version.
+	// @param	id{string} 		- Movie identifier
+	// @param	version{int64} 	- Version being patched
+	// @param	update{Update} 	- Update data
+	PatchMovie(id string, version int64, update Update) (int64, error)
+
 	// ------ Notification ------
 
 	// InsertNotification inserts a single Notification resource
@@ -303,6 +331,10 @@ type DataAccessLayer interface {
 	// @param	query{Query}  - Options used to retrieve data
 	GetScraperRun(id string, query Query) (*models.ScraperRun, error)
 
+	// DeleteScraperRuns removes all ScraperRuns matching the given Query
+	// @param	query{Query} - Options used to retrieve data
+	DeleteScraperRuns(query Query) (int64, error)
+
 	// ------ Session ------
 
 	// InsertSession inserts a single Session resource
@@ -326,10 +358,35 @@ type DataAccessLayer interface {
 	// @param	id{string} - Session identifier
 	DeleteSession(id string) error
 
+	// SyncSessions makes the visible sessions of a theater in the given window
+	// match the given list. Sessions are matched by SessionKey so unchanged
+	// sessions keep their IDs, new ones are inserted and missing ones hidden.
+	// @param	theaterID{primitive.ObjectID}	- Theater identifier
+	// @param	window{scheduleutil.Period}		- Period to synchronize
+	// @param	sessions{[]models.Session}		- Scraped sessions
+	SyncSessions(theaterID primitive.ObjectID, window scheduleutil.Period, sessions []models.Session) (*SyncSummary, error)
+
 	// DeleteSessions removes all Sessions matching the given Query
 	// @param	query{Query} - Options used to retrieve data
 	DeleteSessions(query Query) (int64, error)
 
+	// UpdateSessions applies the given Update to all Sessions matching the
+	// given Query and returns how many were modified
+	// @param	query{Query}	- Options used to retrieve data
+	// @param	update{Update}	- Changes to apply
+	UpdateSessions(query Query, update Update) (int64, error)
+
+	// ------ Session History ------
+
+	// GetSessionHistories retrieves all SessionHistory resources matching the given Query
+	// @param	query{Query} - Options used to retrieve data
+	GetSessionHistories(query Query) ([]models.SessionHistory, error)
+
+	// SaveSessionHistories inserts the given SessionHistory resources or
+	// replaces the stored ones with the same ID
+	// @param	histories{[]models.SessionHistory} - SessionHistory resources to save
+	SaveSessionHistories(histories ...models.SessionHistory) error
+
 	// ------ Task ------
 
 	// InsertTask inserts a single Task resource
@@ -356,6 +413,10 @@ type DataAccessLayer interface {
 	// TODO:
 	UpdateTask(id string, t models.Task) (int64, error)
 
+	// EnsureTasksExists inserts the given tasks that are not stored yet
+	// @param	mp{map[string]models.Task} - Tasks by name
+	EnsureTasksExists(mp map[string]models.Task)
+
 	// ------ City ------
 
 	// InsertCity inserts a single City resource
@@ -382,41 +443,79 @@ type DataAccessLayer interface {
 	// @param	query{Query} - Options used to retrieve data
 	DeleteCities(query Query) (int64, error)
 
+	// ------ Outbox Event ------
+
+	// InsertOutboxEvent inserts a single OutboxEvent resource
+	// @param event{models.OutboxEvent} - An OutboxEvent resource to be inserted
+	InsertOutboxEvent(event models.OutboxEvent) error
+
+	// GetPendingOutboxEvents retrieves up to limit OutboxEvents emitted by source not sent nor failed yet, the oldest first
+	// @param	source{string} - Service that emitted the events
+	// @param	limit{int64} - Maximum number of events
+	GetPendingOutboxEvents(source string, limit int64) ([]models.OutboxEvent, error)
+
+	// UpdateOutboxEvent applies the update to the OutboxEvent matching the given id
+	// @param	id{string} 				- OutboxEvent identifier
+	// @param	update{Update}  - Fields to set or unset
+	UpdateOutboxEvent(id string, update Update) (int64, error)
+
+	// ------ Processed Event ------
+
+	// SaveProcessedEvent inserts a ProcessedEvent resource or replaces the one with the same ID
+	// @param event{models.ProcessedEvent} - A ProcessedEvent resource to be saved
+	SaveProcessedEvent(event models.ProcessedEvent) error
+
+	// GetProcessedEvent retrieves a ProcessedEvent resource by ID, expired ones are never found
+	// @param	id{string} - ProcessedEvent identifier, see models.ProcessedEventID
+	GetProcessedEvent(id string) (*models.ProcessedEvent, error)
+
 	// ------ State ------
 
 	// InsertState inserts a single State resource
-	// @param task{models.State} - A State resource to be inserted
-	// InsertState(task models.State) error
+	// @param state{models.State} - A State resource to be inserted
+	InsertState(state models.State) error
+
+	// FindState retrieves a State resource matching the given Query
+	// @param	query{Query}  - Options used to retrieve data
+	FindState(query Query) (*models.State, error)
 
 	// GetState retrieves a State resource by ID
-	// @param	id{string} 		- State identifier
+	// @param	id{string} 		- State code, e.g. MG
 	// @param	query{Query}  - Options used to retrieve data
-	// GetState(id string, query Query) (*models.State, error)
+	GetState(id string, query Query) (*models.State, error)
 
 	// GetStates retrieves all State resources matching the given Query
 	// @param	query{Query} - Options used to retrieve data
-	// GetStates(query Query) ([]models.State, error)
+	GetStates(query Query) ([]models.State, error)
 
-	// TODO:
-	// UpdateState(id string, s models.State) (int64, error)
+	// UpdateState updates the State matching the given id
+	// @param	id{string} 		- State code, e.g. MG
+	// @param	s{models.State} - State data to be updated
+	UpdateState(id string, s models.State) (int64, error)
 
 	// DeleteState removes a single State matching the given id
-	// @param	id{string} - State identifier
-	// DeleteState(id string) error
+	// @param	id{string} - State code, e.g. MG
+	DeleteState(id string) error
 
-	// DeleteStates removes all Cities matching the given Query
+	// DeleteStates removes all States matching the given Query
 	// @param	query{Query} - Options used to retrieve data
-	// DeleteStates(query Query) (int64, error)
+	DeleteStates(query Query) (int64, error)
 }
 
 // Query ...
 type Query interface {
 	AddCondition(name string, value interface{}) Query
+	Where(conditions ...Condition) Query
 	GetConditions() interface{}
 	GetCondition(name string) interface{}
 
+	// AddField adds a field to the ones returned by the Query.
 	AddField(string) Query
-	SetFields(interface{}) Query
+	// SetFields limits results to the given fields, the ID is always returned.
+	SetFields(fields ...string) Query
+	// Exclude leaves the given fields out of results. It can't be combined
+	// with SetFields, except to exclude the ID.
+	Exclude(fields ...string) Query
 	GetFields() interface{}
 
 	SetSort(...string) Query
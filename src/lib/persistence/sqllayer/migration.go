@@ -0,0 +1,134 @@
+package sqllayer
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+)
+
+// TableMigrations is the table with the applied migrations.
+const TableMigrations = "migrations"
+
+type (
+	// Migration is a versioned change to the database schema.
+	Migration struct {
+		Version     int
+		Description string
+
+		// Up applies the migration.
+		Up func(tx *sql.Tx) error
+		// Down reverts the migration. It is nil for irreversible migrations.
+		Down func(tx *sql.Tx) error
+	}
+)
+
+// Migrate applies all pending migrations.
+func (s *SQLDAL) Migrate() error {
+	return s.MigrateTo(persistence.LatestMigration)
+}
+
+// MigrateTo applies or reverts migrations until the schema is at the given
+// version. Everything runs in a single transaction, which also keeps other
+// processes from migrating at the same time.
+func (s *SQLDAL) MigrateTo(version int) error {
+	defer s.store.resetColumns()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	err = migrateTo(tx, version)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func migrateTo(tx *sql.Tx, version int) error {
+	applied, err := appliedMigrations(tx)
+	if err != nil {
+		return err
+	}
+	return persistence.RunMigrations(migrationSteps(tx, migrations), applied, version)
+}
+
+// MigrationStatus lists every known migration and when it was applied.
+func (s *SQLDAL) MigrationStatus() ([]persistence.MigrationStatus, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	applied, err := appliedMigrations(tx)
+	if err != nil {
+		return nil, err
+	}
+	return persistence.MigrationStatuses(migrationSteps(tx, migrations), applied), nil
+}
+
+// migrationSteps binds list to tx. Steps record the migration in the
+// migrations table once it's applied or reverted.
+func migrationSteps(tx *sql.Tx, list []Migration) []persistence.MigrationStep {
+	result := make([]persistence.MigrationStep, len(list))
+	for i, mm := range list {
+		mm := mm
+		result[i] = persistence.MigrationStep{
+			Version:     mm.Version,
+			Description: mm.Description,
+		}
+		if mm.Up != nil {
+			result[i].Up = func() error {
+				if err := mm.Up(tx); err != nil {
+					return err
+				}
+				_, err := tx.Exec(fmt.Sprintf(`INSERT INTO "%s" (version, description, applied_at) VALUES (?, ?, ?)`, TableMigrations),
+					mm.Version, mm.Description, time.Now().UTC())
+				return err
+			}
+		}
+		if mm.Down != nil {
+			result[i].Down = func() error {
+				if err := mm.Down(tx); err != nil {
+					return err
+				}
+				_, err := tx.Exec(fmt.Sprintf(`DELETE FROM "%s" WHERE version = ?`, TableMigrations), mm.Version)
+				return err
+			}
+		}
+	}
+	return result
+}
+
+// appliedMigrations returns when each applied migration was applied. It
+// creates the migrations table if needed.
+func appliedMigrations(tx *sql.Tx) (map[int]time.Time, error) {
+	_, err := tx.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (
+		version INTEGER NOT NULL PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`, TableMigrations))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(fmt.Sprintf(`SELECT version, applied_at FROM "%s"`, TableMigrations))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		result[version] = appliedAt
+	}
+	return result, rows.Err()
+}
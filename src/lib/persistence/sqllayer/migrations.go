@@ -0,0 +1,331 @@
+package sqllayer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// migrations is the ordered list of schema migrations. New migrations must be
+// appended with a higher version and applied migrations must never change.
+//
+// Columns are only created for fields holding single values, documents with
+// other values in these fields are still found but can't use the index.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create collections",
+		Up: func(tx *sql.Tx) error {
+			return steps(
+				func() error { return createTable(tx, memlayer.CollectionAdmins) },
+				func() error { return createTable(tx, memlayer.CollectionAPIKeys, "key", "owner", "user_type") },
+				func() error { return createIndex(tx, memlayer.CollectionAPIKeys, true, "key") },
+				func() error { return createIndex(tx, memlayer.CollectionAPIKeys, false, "owner", "user_type") },
+				func() error { return createTable(tx, memlayer.CollectionCities, "name", "stateId", "timeZone") },
+				func() error { return createIndex(tx, memlayer.CollectionCities, false, "name", "stateId", "timeZone") },
+				func() error { return createTable(tx, memlayer.CollectionImages, "movieId") },
+				func() error { return createIndex(tx, memlayer.CollectionImages, false, "movieId") },
+				func() error {
+					return createTable(tx, memlayer.CollectionMovies,
+						"tmdbId", "imdbId", "claqueteId", "slug", "title", "originalTitle", "hidden", "releaseDate")
+				},
+				func() error {
+					return createIndex(tx, memlayer.CollectionMovies, false,
+						"tmdbId", "imdbId", "claqueteId", "slug", "title", "originalTitle", "hidden", "releaseDate")
+				},
+				func() error { return createTable(tx, memlayer.CollectionNotifications) },
+				func() error { return createTable(tx, memlayer.CollectionPrices, "theaterId") },
+				func() error { return createIndex(tx, memlayer.CollectionPrices, false, "theaterId") },
+				func() error { return createTable(tx, memlayer.CollectionScores, "movieId") },
+				func() error { return createIndex(tx, memlayer.CollectionScores, false, "movieId") },
+				func() error { return createTable(tx, memlayer.CollectionScrapers, "theaterId", "type", "provider") },
+				func() error {
+					return createIndex(tx, memlayer.CollectionScrapers, false, "theaterId", "type", "provider")
+				},
+				func() error { return createTable(tx, memlayer.CollectionScraperRuns, "scraper_id") },
+				func() error { return createIndex(tx, memlayer.CollectionScraperRuns, false, "scraper_id") },
+				func() error {
+					return createTable(tx, memlayer.CollectionSessions,
+						"movieSlug", "theaterId", "movieId", "startTime", "hidden", "room", "version", "format")
+				},
+				func() error {
+					return createIndex(tx, memlayer.CollectionSessions, false,
+						"movieSlug", "theaterId", "movieId", "startTime", "hidden", "room", "version", "format")
+				},
+				func() error { return createTable(tx, memlayer.CollectionTasks) },
+				func() error { return createTable(tx, memlayer.CollectionTheaters, "cityId", "internalId", "hidden") },
+				func() error {
+					return createIndex(tx, memlayer.CollectionTheaters, false, "cityId", "internalId", "hidden")
+				},
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropTables(tx,
+				memlayer.CollectionAdmins,
+				memlayer.CollectionAPIKeys,
+				memlayer.CollectionCities,
+				memlayer.CollectionImages,
+				memlayer.CollectionMovies,
+				memlayer.CollectionNotifications,
+				memlayer.CollectionPrices,
+				memlayer.CollectionScores,
+				memlayer.CollectionScrapers,
+				memlayer.CollectionScraperRuns,
+				memlayer.CollectionSessions,
+				memlayer.CollectionTasks,
+				memlayer.CollectionTheaters,
+			)
+		},
+	},
+	{
+		Version:     2,
+		Description: "create states",
+		Up: func(tx *sql.Tx) error {
+			now := time.Now()
+			states := models.DefaultStates()
+			docs := make([]interface{}, len(states))
+			for i, state := range states {
+				state.CreatedAt = &now
+				docs[i] = state
+			}
+			return steps(
+				func() error { return createTable(tx, memlayer.CollectionStates, "name") },
+				func() error { return createIndex(tx, memlayer.CollectionStates, false, "name") },
+				func() error { return insertDocuments(tx, memlayer.CollectionStates, docs...) },
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropTables(tx, memlayer.CollectionStates)
+		},
+	},
+	{
+		Version:     3,
+		Description: "create session history",
+		Up: func(tx *sql.Tx) error {
+			return steps(
+				func() error {
+					return createTable(tx, memlayer.CollectionSessionHistory, "movieId", "theaterId", "date")
+				},
+				func() error {
+					return createIndex(tx, memlayer.CollectionSessionHistory, false, "movieId", "theaterId", "date")
+				},
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropTables(tx, memlayer.CollectionSessionHistory)
+		},
+	},
+	{
+		// Search keys hold many values, so they have no column.
+		Version:     4,
+		Description: "create search keys",
+		Up: func(tx *sql.Tx) error {
+			return steps(
+				func() error { return updateSearchKeys(tx, memlayer.CollectionMovies, "title", "originalTitle") },
+				func() error { return updateSearchKeys(tx, memlayer.CollectionTheaters, "name", "shortName") },
+				func() error { return updateSearchKeys(tx, memlayer.CollectionCities, "name") },
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return steps(
+				func() error { return unsetField(tx, memlayer.CollectionMovies, "searchKeys") },
+				func() error { return unsetField(tx, memlayer.CollectionTheaters, "searchKeys") },
+				func() error { return unsetField(tx, memlayer.CollectionCities, "searchKeys") },
+			)
+		},
+	},
+	{
+		Version:     5,
+		Description: "create integrity reports",
+		Up: func(tx *sql.Tx) error {
+			return steps(
+				func() error { return createTable(tx, memlayer.CollectionIntegrityReports, "startTime") },
+				func() error { return createIndex(tx, memlayer.CollectionIntegrityReports, false, "startTime") },
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropTables(tx, memlayer.CollectionIntegrityReports)
+		},
+	},
+	{
+		Version:     6,
+		Description: "create processed events",
+		Up: func(tx *sql.Tx) error {
+			return steps(
+				func() error { return createTable(tx, memlayer.CollectionProcessedEvents, "expiresAt") },
+				func() error { return createIndex(tx, memlayer.CollectionProcessedEvents, false, "expiresAt") },
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropTables(tx, memlayer.CollectionProcessedEvents)
+		},
+	},
+	{
+		Version:     7,
+		Description: "create outbox events",
+		Up: func(tx *sql.Tx) error {
+			return steps(
+				func() error { return createTable(tx, memlayer.CollectionOutboxEvents, "timestamp", "expiresAt") },
+				func() error { return createIndex(tx, memlayer.CollectionOutboxEvents, false, "timestamp") },
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropTables(tx, memlayer.CollectionOutboxEvents)
+		},
+	},
+	{
+		// Documents stored before they were versioned can't be updated
+		// conditionally by clients until they have a version.
+		Version:     8,
+		Description: "set the version of unversioned documents",
+		Up: func(tx *sql.Tx) error {
+			return steps(
+				func() error { return setMissingVersion(tx, memlayer.CollectionMovies) },
+				func() error { return setMissingVersion(tx, memlayer.CollectionTheaters) },
+				func() error { return setMissingVersion(tx, memlayer.CollectionScores) },
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			// Versions are valid either way.
+			return nil
+		},
+	},
+}
+
+// createTable creates the table of a collection with a column for each of
+// the given fields.
+func createTable(tx *sql.Tx, collectionName string, fields ...string) error {
+	columns := []string{"id TEXT NOT NULL PRIMARY KEY", "doc BLOB NOT NULL"}
+	for _, f := range fields {
+		// Columns have no type so values keep the type they are stored with.
+		columns = append(columns, fmt.Sprintf(`"%s"`, f))
+	}
+	_, err := tx.Exec(fmt.Sprintf(`CREATE TABLE "%s" (%s)`, collectionName, strings.Join(columns, ", ")))
+	return err
+}
+
+// createIndex creates an index for each of the given field columns.
+func createIndex(tx *sql.Tx, collectionName string, unique bool, fields ...string) error {
+	statement := "CREATE INDEX"
+	if unique {
+		statement = "CREATE UNIQUE INDEX"
+	}
+	for _, f := range fields {
+		_, err := tx.Exec(fmt.Sprintf(`%s "%s_%s" ON "%s" ("%s")`, statement, collectionName, f, collectionName, f))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropTables(tx *sql.Tx, collectionNames ...string) error {
+	for _, name := range collectionNames {
+		_, err := tx.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertDocuments inserts the given values in the collection.
+func insertDocuments(tx *sql.Tx, collectionName string, values ...interface{}) error {
+	docs := make([]bson.M, len(values))
+	for i, v := range values {
+		raw, err := bson.Marshal(v)
+		if err != nil {
+			return err
+		}
+		docs[i], err = memlayer.UnmarshalDocument(raw)
+		if err != nil {
+			return err
+		}
+	}
+	store := &sqlStore{tx: tx}
+	return store.Insert(collectionName, docs)
+}
+
+// updateSearchKeys sets the searchKeys of every document to the folded words
+// of the given fields, the same as the UpdateSearchKeys method of models.
+func updateSearchKeys(tx *sql.Tx, collectionName string, fields ...string) error {
+	store := &sqlStore{tx: tx}
+	docs, err := store.Find(collectionName, bson.M{})
+	if err != nil || len(docs) == 0 {
+		return err
+	}
+	for _, doc := range docs {
+		texts := make([]string, len(fields))
+		for i, f := range fields {
+			texts[i], _ = doc[f].(string)
+		}
+		keys := stringutil.SearchKeys(texts...)
+		values := make(bson.A, len(keys))
+		for i, k := range keys {
+			values[i] = k
+		}
+		doc["searchKeys"] = values
+	}
+	return store.Replace(collectionName, docs)
+}
+
+// setMissingVersion sets the version of documents without one to 1, the
+// version of inserted documents.
+func setMissingVersion(tx *sql.Tx, collectionName string) error {
+	store := &sqlStore{tx: tx}
+	docs, err := store.Find(collectionName, bson.M{})
+	if err != nil {
+		return err
+	}
+	unversioned := make([]bson.M, 0)
+	for _, doc := range docs {
+		switch v := doc["version"].(type) {
+		case nil:
+		case int32:
+			if v != 0 {
+				continue
+			}
+		case int64:
+			if v != 0 {
+				continue
+			}
+		default:
+			continue
+		}
+		doc["version"] = int64(1)
+		unversioned = append(unversioned, doc)
+	}
+	if len(unversioned) == 0 {
+		return nil
+	}
+	return store.Replace(collectionName, unversioned)
+}
+
+func unsetField(tx *sql.Tx, collectionName, name string) error {
+	store := &sqlStore{tx: tx}
+	docs, err := store.Find(collectionName, bson.M{})
+	if err != nil || len(docs) == 0 {
+		return err
+	}
+	for _, doc := range docs {
+		delete(doc, name)
+	}
+	return store.Replace(collectionName, docs)
+}
+
+// steps runs each step of a migration in order, stopping at the first one
+// that fails.
+func steps(fns ...func() error) error {
+	for _, fn := range fns {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
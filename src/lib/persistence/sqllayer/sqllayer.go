@@ -0,0 +1,82 @@
+package sqllayer
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
+
+	// Registers the sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type (
+	// SQLDAL is an implementation of persistence.DataAccessLayer on an
+	// embedded SQLite database, meant for small deployments and CI.
+	//
+	// Documents are stored as BSON in a table for each collection and queries
+	// are evaluated by memlayer, so they behave as in the other
+	// implementations. Fields indexed by migrations are also kept in their own
+	// columns and used to narrow the rows read by each query.
+	//
+	// NOTE: The SQLite driver requires cgo. Binaries built with
+	// CGO_ENABLED=0 fail to open the database.
+	SQLDAL struct {
+		*memlayer.MemDAL
+
+		db    *sql.DB
+		store *sqlStore
+	}
+)
+
+// NewSQLDAL opens the SQLite database at the given path, e.g.: amenic.db or
+// sqlite://amenic.db. Parameters supported by go-sqlite3 may be appended
+// after a question mark.
+func NewSQLDAL(connection string) (persistence.DataAccessLayer, error) {
+	dsn := strings.TrimPrefix(connection, "sqlite://")
+	inMemory := strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+
+	// Transactions take the write lock when they start, so concurrent writers
+	// wait instead of failing when committing.
+	params := "_busy_timeout=10000&_txlock=immediate"
+	if !inMemory {
+		params += "&_journal_mode=WAL"
+	}
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + params
+	} else {
+		dsn += "?" + params
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if inMemory {
+		// Each connection would have its own database otherwise.
+		db.SetMaxOpenConns(1)
+	}
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store := newSQLStore(db)
+	return &SQLDAL{
+		MemDAL: memlayer.NewMemDALWithStore(store),
+		db:     db,
+		store:  store,
+	}, nil
+}
+
+// Setup applies all pending migrations.
+func (s *SQLDAL) Setup() error {
+	return s.Migrate()
+}
+
+// Close ...
+func (s *SQLDAL) Close() {
+	s.db.Close()
+}
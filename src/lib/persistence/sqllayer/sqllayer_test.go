@@ -0,0 +1,170 @@
+package sqllayer
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getTestingSQLDAL(t *testing.T) *SQLDAL {
+	data, err := NewSQLDAL(filepath.Join(t.TempDir(), "amenic.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(data.Close)
+	if err := data.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	return data.(*SQLDAL)
+}
+
+func TestMigrations(t *testing.T) {
+	data := getTestingSQLDAL(t)
+
+	status, err := data.MigrationStatus()
+	assert.NoError(t, err)
+	if assert.Len(t, status, len(migrations)) {
+		for _, s := range status {
+			assert.NotNil(t, s.AppliedAt)
+		}
+	}
+
+	// Running again does nothing
+	assert.NoError(t, data.Setup())
+
+	// Revert everything and apply again
+	assert.NoError(t, data.MigrateTo(0))
+	status, err = data.MigrationStatus()
+	assert.NoError(t, err)
+	for _, s := range status {
+		assert.Nil(t, s.AppliedAt)
+	}
+	_, err = data.GetStates(data.DefaultQuery())
+	assert.Error(t, err)
+
+	assert.NoError(t, data.Migrate())
+	states, err := data.GetStates(data.BuildStateQuery(map[string]string{}))
+	assert.NoError(t, err)
+	assert.Len(t, states, len(models.DefaultStates()))
+}
+
+func TestValidateMigrations(t *testing.T) {
+	assert.NoError(t, persistence.ValidateMigrations(migrationSteps(nil, migrations)))
+	assert.Error(t, persistence.ValidateMigrations(migrationSteps(nil, []Migration{{Version: 1}})))
+}
+
+func TestSQLDAL(t *testing.T) {
+	data := getTestingSQLDAL(t)
+
+	cities := []models.City{
+		{ID: primitive.NewObjectID(), Name: "Montes Claros", StateID: models.MG},
+		{ID: primitive.NewObjectID(), Name: "Belo Horizonte", StateID: models.MG},
+		{ID: primitive.NewObjectID(), Name: "São Paulo", StateID: models.SP},
+	}
+	for _, c := range cities {
+		assert.NoError(t, data.InsertCity(c))
+	}
+
+	// Duplicated _id
+	err := data.InsertCity(cities[0])
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), memlayer.ErrDuplicateKey.Error())
+	}
+
+	// Time zone comes from the state and the state is included
+	city, err := data.GetCity(cities[0].ID.Hex(), data.DefaultQuery().AddInclude("state"))
+	assert.NoError(t, err)
+	assert.Equal(t, "America/Sao_Paulo", city.TimeZone)
+	if assert.NotNil(t, city.State) {
+		assert.Equal(t, "Minas Gerais", city.State.Name)
+	}
+
+	result, err := data.GetCities(data.BuildCityQuery(map[string]string{"state": "mg", "sort": "name"}))
+	assert.NoError(t, err)
+	if assert.Len(t, result, 2) {
+		assert.Equal(t, "Belo Horizonte", result[0].Name)
+	}
+
+	// Update
+	_, err = data.UpdateCity(cities[2].ID.Hex(), models.City{ID: cities[2].ID, Name: "Campinas", StateID: models.SP})
+	assert.NoError(t, err)
+	city, err = data.GetCity(cities[2].ID.Hex(), data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, "Campinas", city.Name)
+
+	// Delete
+	assert.NoError(t, data.DeleteCity(cities[2].ID.Hex()))
+	_, err = data.GetCity(cities[2].ID.Hex(), data.DefaultQuery())
+	assert.Equal(t, persistence.ErrNotFound, err)
+
+	// Changes are discarded when the transaction fails
+	errRollback := errors.New("rollback")
+	err = data.WithTransaction(func(tx persistence.DataAccessLayer) error {
+		if err := tx.DeleteCity(cities[0].ID.Hex()); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.Equal(t, errRollback, err)
+	_, err = data.GetCity(cities[0].ID.Hex(), data.DefaultQuery())
+	assert.NoError(t, err)
+}
+
+func TestQueryColumns(t *testing.T) {
+	data := getTestingSQLDAL(t)
+
+	theaters := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}
+	start := time.Date(2019, 8, 1, 14, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		st := start.Add(time.Duration(i) * time.Hour)
+		assert.NoError(t, data.InsertSession(models.Session{
+			ID:        primitive.NewObjectID(),
+			TheaterID: theaters[i%2],
+			MovieID:   primitive.NewObjectID(),
+			Room:      uint(i),
+			Hidden:    i == 4,
+			StartTime: &st,
+		}))
+	}
+
+	count := func(conditions bson.M) int {
+		query := data.DefaultQuery().SetLimit(-1)
+		for k, v := range conditions {
+			query.AddCondition(k, v)
+		}
+		sessions, err := data.GetSessions(query)
+		assert.NoError(t, err)
+		return len(sessions)
+	}
+
+	assert.Equal(t, 3, count(bson.M{"theaterId": theaters[0]}))
+	assert.Equal(t, 2, count(bson.M{"theaterId": theaters[0], "hidden": false}))
+	assert.Equal(t, 6, count(bson.M{"theaterId": bson.M{"$in": theaters}}))
+	assert.Equal(t, 3, count(bson.M{"startTime": bson.M{"$gte": start.Add(3 * time.Hour)}}))
+	assert.Equal(t, 2, count(bson.M{"room": bson.M{"$in": []interface{}{1, 5}}}))
+	assert.Equal(t, 5, count(bson.M{"hidden": bson.M{"$ne": true}}))
+	assert.Equal(t, 0, count(bson.M{"_id": bson.M{"$in": []primitive.ObjectID{}}}))
+}
+
+func TestWhereClause(t *testing.T) {
+	columns := []string{"theaterId", "hidden"}
+
+	where, args := whereClause(columns, bson.M{"theaterId": "a", "hidden": bson.M{"$ne": true}, "title": "b"})
+	assert.Equal(t, ` WHERE ("theaterId" IS NULL OR "theaterId" = ?)`, where)
+	assert.Equal(t, []interface{}{"a"}, args)
+
+	where, args = whereClause(columns, bson.M{"_id": bson.M{"$in": []string{"a", "b"}}, "hidden": false})
+	assert.Equal(t, ` WHERE id IN (?, ?) AND ("hidden" IS NULL OR "hidden" = ?)`, where)
+	assert.Equal(t, []interface{}{"a", "b", 0}, args)
+
+	where, _ = whereClause(columns, bson.M{"$or": []bson.M{{"hidden": true}}})
+	assert.Empty(t, where)
+}
@@ -0,0 +1,452 @@
+package sqllayer
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
+	"github.com/mattn/go-sqlite3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxVariables is the number of values bound in a single statement, below the
+// SQLite default limit of 999.
+const maxVariables = 500
+
+type (
+	// querier is implemented by *sql.DB and *sql.Tx.
+	querier interface {
+		Exec(query string, args ...interface{}) (sql.Result, error)
+		Query(query string, args ...interface{}) (*sql.Rows, error)
+	}
+
+	// sqlStore implements memlayer.Store. Each collection is a table with the
+	// BSON document and its _id. Any other column holds the value of the
+	// document field with the same name, so migrations can index fields by
+	// adding columns.
+	sqlStore struct {
+		db *sql.DB
+		tx *sql.Tx
+
+		mu      *sync.Mutex
+		columns map[string][]string
+	}
+)
+
+func newSQLStore(db *sql.DB) *sqlStore {
+	return &sqlStore{
+		db:      db,
+		mu:      &sync.Mutex{},
+		columns: make(map[string][]string),
+	}
+}
+
+// withTx returns a store running its statements in the given transaction.
+// Columns are not cached since the transaction may change tables.
+func (s *sqlStore) withTx(tx *sql.Tx) *sqlStore {
+	return &sqlStore{db: s.db, tx: tx, mu: s.mu}
+}
+
+func (s *sqlStore) q() querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// Find ...
+func (s *sqlStore) Find(collectionName string, filter bson.M) ([]bson.M, error) {
+	columns, err := s.tableColumns(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := whereClause(columns, filter)
+	rows, err := s.q().Query(fmt.Sprintf(`SELECT doc FROM "%s"%s ORDER BY rowid`, collectionName, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]bson.M, 0)
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		doc, err := memlayer.UnmarshalDocument(raw)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, doc)
+	}
+	return result, rows.Err()
+}
+
+// Insert ...
+func (s *sqlStore) Insert(collectionName string, docs []bson.M) error {
+	return s.Atomic(func(store memlayer.Store) error {
+		s := store.(*sqlStore)
+		columns, err := s.tableColumns(collectionName)
+		if err != nil {
+			return err
+		}
+
+		names := append([]string{"id", "doc"}, columns...)
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+		statement := fmt.Sprintf(`INSERT INTO "%s" (%s) VALUES (%s)`, collectionName, quoteColumns(names), placeholders)
+		for _, doc := range docs {
+			args, err := rowValues(columns, doc)
+			if err != nil {
+				return err
+			}
+			_, err = s.q().Exec(statement, args...)
+			if isConstraintError(err) {
+				return fmt.Errorf("%s: collection %s _id %v: %v", memlayer.ErrDuplicateKey, collectionName, doc["_id"], err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Replace ...
+func (s *sqlStore) Replace(collectionName string, docs []bson.M) error {
+	return s.Atomic(func(store memlayer.Store) error {
+		s := store.(*sqlStore)
+		columns, err := s.tableColumns(collectionName)
+		if err != nil {
+			return err
+		}
+
+		assignments := []string{"doc = ?"}
+		for _, c := range columns {
+			assignments = append(assignments, fmt.Sprintf(`"%s" = ?`, c))
+		}
+		statement := fmt.Sprintf(`UPDATE "%s" SET %s WHERE id = ?`, collectionName, strings.Join(assignments, ", "))
+		for _, doc := range docs {
+			args, err := rowValues(columns, doc)
+			if err != nil {
+				return err
+			}
+			// Move id to the WHERE clause
+			args = append(args[1:], args[0])
+			_, err = s.q().Exec(statement, args...)
+			if isConstraintError(err) {
+				return fmt.Errorf("%s: collection %s _id %v: %v", memlayer.ErrDuplicateKey, collectionName, doc["_id"], err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete ...
+func (s *sqlStore) Delete(collectionName string, ids []interface{}) error {
+	return s.Atomic(func(store memlayer.Store) error {
+		s := store.(*sqlStore)
+		for len(ids) > 0 {
+			n := len(ids)
+			if n > maxVariables {
+				n = maxVariables
+			}
+			args := make([]interface{}, n)
+			for i, id := range ids[:n] {
+				args[i] = idKey(id)
+			}
+			ids = ids[n:]
+
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+			_, err := s.q().Exec(fmt.Sprintf(`DELETE FROM "%s" WHERE id IN (%s)`, collectionName, placeholders), args...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Atomic runs fn in a transaction. Transactions are started with BEGIN
+// IMMEDIATE, so they also serialize writes made by other processes.
+func (s *sqlStore) Atomic(fn func(s memlayer.Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	err = fn(s.withTx(tx))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// tableColumns returns the field columns of the table, which are all columns
+// except id and doc.
+func (s *sqlStore) tableColumns(collectionName string) ([]string, error) {
+	cached := s.tx == nil
+	if cached {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if columns, ok := s.columns[collectionName]; ok {
+			return columns, nil
+		}
+	}
+
+	rows, err := s.q().Query(fmt.Sprintf(`PRAGMA table_info("%s")`, collectionName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	found := false
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			ctype      string
+			notnull    int
+			defaultVal interface{}
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &defaultVal, &pk); err != nil {
+			return nil, err
+		}
+		found = true
+		if name != "id" && name != "doc" {
+			columns = append(columns, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no such table: %s", collectionName)
+	}
+
+	if cached {
+		s.columns[collectionName] = columns
+	}
+	return columns, nil
+}
+
+// resetColumns clears the cached columns after tables changed.
+func (s *sqlStore) resetColumns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name := range s.columns {
+		delete(s.columns, name)
+	}
+}
+
+// rowValues returns the values of id, doc and the field columns.
+func rowValues(columns []string, doc bson.M) ([]interface{}, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	result := []interface{}{idKey(doc["_id"]), raw}
+	for _, c := range columns {
+		result = append(result, columnValue(doc[c]))
+	}
+	return result, nil
+}
+
+// whereClause narrows a query using the conditions on _id and field columns.
+// Conditions which can't be expressed are left out, so the result may have
+// documents that don't match, but never misses any that do.
+func whereClause(columns []string, filter bson.M) (string, []interface{}) {
+	indexed := map[string]bool{"_id": true}
+	for _, c := range columns {
+		indexed[c] = true
+	}
+
+	fields := make([]string, 0, len(filter))
+	for f := range filter {
+		if indexed[f] {
+			fields = append(fields, f)
+		}
+	}
+	sort.Strings(fields)
+
+	var clauses []string
+	var args []interface{}
+	for _, f := range fields {
+		for _, e := range operators(filter[f]) {
+			clause, values, ok := columnClause(f, e.Key, e.Value)
+			if ok {
+				clauses = append(clauses, clause)
+				args = append(args, values...)
+			}
+		}
+	}
+
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// operators returns the operators of a field condition. Values which are not
+// an operator document are equality conditions.
+func operators(condition interface{}) []bson.E {
+	var elements []bson.E
+	switch c := condition.(type) {
+	case bson.M:
+		for k, v := range c {
+			elements = append(elements, bson.E{Key: k, Value: v})
+		}
+	case bson.D:
+		elements = c
+	}
+	if len(elements) == 0 || !strings.HasPrefix(elements[0].Key, "$") {
+		return []bson.E{{Key: "$eq", Value: condition}}
+	}
+	return elements
+}
+
+// columnClause builds the SQL condition for a single operator. Documents
+// without a column value, e.g.: arrays, are always kept.
+func columnClause(field, op string, value interface{}) (string, []interface{}, bool) {
+	if field == "_id" {
+		switch op {
+		case "$eq":
+			if id := idKey(value); id != nil {
+				return "id = ?", []interface{}{id}, true
+			}
+		case "$in":
+			list, ok := toList(value)
+			if !ok || len(list) > maxVariables {
+				return "", nil, false
+			}
+			if len(list) == 0 {
+				return "0", nil, true
+			}
+			args := make([]interface{}, len(list))
+			for i, v := range list {
+				if args[i] = idKey(v); args[i] == nil {
+					return "", nil, false
+				}
+			}
+			return fmt.Sprintf("id IN (%s)", strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")), args, true
+		}
+		return "", nil, false
+	}
+
+	column := fmt.Sprintf(`"%s"`, field)
+	switch op {
+	case "$eq", "$gt", "$gte", "$lt", "$lte":
+		v := columnValue(value)
+		if v == nil {
+			return "", nil, false
+		}
+		operator := map[string]string{"$eq": "=", "$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<="}[op]
+		return fmt.Sprintf("(%s IS NULL OR %s %s ?)", column, column, operator), []interface{}{v}, true
+	case "$in":
+		list, ok := toList(value)
+		if !ok || len(list) == 0 || len(list) > maxVariables {
+			return "", nil, false
+		}
+		args := make([]interface{}, len(list))
+		for i, v := range list {
+			if args[i] = columnValue(v); args[i] == nil {
+				return "", nil, false
+			}
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+		return fmt.Sprintf("(%s IS NULL OR %s IN (%s))", column, column, placeholders), args, true
+	}
+	return "", nil, false
+}
+
+func toList(value interface{}) ([]interface{}, bool) {
+	switch l := value.(type) {
+	case []interface{}:
+		return l, true
+	case primitive.A:
+		return l, true
+	case []primitive.ObjectID:
+		result := make([]interface{}, len(l))
+		for i, v := range l {
+			result[i] = v
+		}
+		return result, true
+	case []string:
+		result := make([]interface{}, len(l))
+		for i, v := range l {
+			result[i] = v
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// columnValue converts a field value to the value stored in its column.
+// Values that can't be compared in SQL, e.g.: documents and arrays, are nil.
+func columnValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string, int32, int64, float64:
+		return t
+	case int:
+		return int64(t)
+	case bool:
+		if t {
+			return 1
+		}
+		return 0
+	case primitive.ObjectID:
+		return t.Hex()
+	case primitive.DateTime:
+		return int64(t)
+	case time.Time:
+		return t.Unix()*1e3 + int64(t.Nanosecond()/1e6)
+	case *time.Time:
+		if t != nil {
+			return columnValue(*t)
+		}
+	}
+	return nil
+}
+
+// idKey converts an _id value to the value stored in the id column.
+func idKey(id interface{}) interface{} {
+	switch t := id.(type) {
+	case primitive.ObjectID:
+		return t.Hex()
+	case string:
+		return t
+	case int32, int64, float64, int:
+		return fmt.Sprintf("%T:%v", t, t)
+	}
+	return nil
+}
+
+func quoteColumns(columns []string) string {
+	result := make([]string, len(columns))
+	for i, c := range columns {
+		result[i] = fmt.Sprintf(`"%s"`, c)
+	}
+	return strings.Join(result, ", ")
+}
+
+func isConstraintError(err error) bool {
+	e, ok := err.(sqlite3.Error)
+	if !ok {
+		return false
+	}
+	return e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || e.ExtendedCode == sqlite3.ErrConstraintUnique
+}
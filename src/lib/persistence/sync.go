@@ -0,0 +1,91 @@
+package persistence
+
+import (
+	"fmt"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SyncSummary reports the changes made by SyncSessions.
+type SyncSummary struct {
+	Added     int `json:"added" bson:"added"`         // New sessions or hidden sessions shown again
+	Removed   int `json:"removed" bson:"removed"`     // Sessions hidden because they weren't found anymore
+	Unchanged int `json:"unchanged" bson:"unchanged"` // Sessions kept as they were
+}
+
+// SessionDiff is the result of DiffSessions.
+type SessionDiff struct {
+	Insert    []models.Session     // Sessions that don't exist yet
+	Hide      []primitive.ObjectID // Visible sessions missing in the new list
+	Show      []primitive.ObjectID // Hidden sessions present again in the new list
+	Unchanged int
+}
+
+// Summary returns the SyncSummary for the diff.
+func (d *SessionDiff) Summary() *SyncSummary {
+	return &SyncSummary{
+		Added:     len(d.Insert) + len(d.Show),
+		Removed:   len(d.Hide),
+		Unchanged: d.Unchanged,
+	}
+}
+
+// SessionKey returns the natural key of a session. Two sessions with the same
+// key represent the same screening.
+func SessionKey(s models.Session) string {
+	movie := s.MovieSlug
+	if !s.MovieID.IsZero() {
+		movie = s.MovieID.Hex()
+	}
+	var start int64
+	if s.StartTime != nil {
+		start = s.StartTime.Unix()
+	}
+	return fmt.Sprintf("%s|%d|%d|%s|%s", movie, s.Room, start, s.Format, s.Version)
+}
+
+// DiffSessions compares the stored sessions with the new ones using their
+// natural keys. Stored sessions with a repeated key are hidden.
+func DiffSessions(existing, sessions []models.Session) *SessionDiff {
+	result := &SessionDiff{}
+
+	stored := make(map[string]models.Session, len(existing))
+	for _, s := range existing {
+		key := SessionKey(s)
+		if _, ok := stored[key]; ok {
+			if !s.Hidden {
+				result.Hide = append(result.Hide, s.ID)
+			}
+			continue
+		}
+		stored[key] = s
+	}
+
+	seen := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		key := SessionKey(s)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		current, ok := stored[key]
+		switch {
+		case !ok:
+			result.Insert = append(result.Insert, s)
+		case current.Hidden:
+			result.Show = append(result.Show, current.ID)
+		default:
+			result.Unchanged++
+		}
+	}
+
+	for key, s := range stored {
+		if !seen[key] && !s.Hidden {
+			result.Hide = append(result.Hide, s.ID)
+		}
+	}
+
+	return result
+}
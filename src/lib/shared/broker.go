@@ -0,0 +1,55 @@
+package shared
+
+import (
+	"fmt"
+
+	"github.com/dsbezerra/amenic/src/lib/config"
+	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+)
+
+// EventsExchange is the AMQP exchange, or NATS stream, every event is
+// published to.
+const EventsExchange = "events"
+
+// OpenBroker connects to the message broker of the given type, see
+// config.ServiceConfig.MessageBrokerType. With NATS, the embedded server is
+// started first if enabled, and stopped once the broker is closed.
+func OpenBroker(settings *config.ServiceConfig) (messagequeue.Broker, error) {
+	switch settings.MessageBrokerType {
+	case messagequeue.BrokerAMQP:
+		conn, err := messagequeue.Dial(settings.AMQPMessageBroker)
+		if err != nil {
+			return nil, err
+		}
+		return messagequeue.NewAMQPBroker(conn, EventsExchange), nil
+
+	case messagequeue.BrokerNATS:
+		shutdown := func() {}
+		if settings.NATSEmbedded {
+			var err error
+			shutdown, err = messagequeue.RunEmbeddedNATS(settings.NATSMessageBroker, settings.NATSStoreDir)
+			if err != nil {
+				return nil, err
+			}
+		}
+		nc, err := messagequeue.DialNATS(settings.NATSMessageBroker)
+		if err != nil {
+			shutdown()
+			return nil, err
+		}
+		return &embeddedBroker{messagequeue.NewNATSBroker(nc, EventsExchange), shutdown}, nil
+	}
+	return nil, fmt.Errorf("unknown message broker type %s", settings.MessageBrokerType)
+}
+
+// embeddedBroker stops the embedded server, if any, once closed.
+type embeddedBroker struct {
+	messagequeue.Broker
+	shutdown func()
+}
+
+func (b *embeddedBroker) Close() error {
+	err := b.Broker.Close()
+	b.shutdown()
+	return err
+}
@@ -0,0 +1,49 @@
+package shared
+
+import (
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// ProcessedEventStore is a messagequeue.ProcessedEventStore keeping the
+// events handled by a consumer in the processed events collection.
+type ProcessedEventStore struct {
+	Data     persistence.DataAccessLayer
+	Consumer string        // Name of the consuming service
+	TTL      time.Duration // How long events are remembered
+}
+
+// NewProcessedEventStore ...
+func NewProcessedEventStore(data persistence.DataAccessLayer, consumer string) *ProcessedEventStore {
+	return &ProcessedEventStore{
+		Data:     data,
+		Consumer: consumer,
+		TTL:      models.DefaultProcessedEventTTL,
+	}
+}
+
+// IsProcessed ...
+func (s *ProcessedEventStore) IsProcessed(e messagequeue.Envelope) (bool, error) {
+	_, err := s.Data.GetProcessedEvent(models.ProcessedEventID(s.Consumer, e.ID))
+	if err == persistence.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MarkProcessed ...
+func (s *ProcessedEventStore) MarkProcessed(e messagequeue.Envelope) error {
+	now := time.Now().UTC()
+	return s.Data.SaveProcessedEvent(models.ProcessedEvent{
+		ID:            models.ProcessedEventID(s.Consumer, e.ID),
+		Consumer:      s.Consumer,
+		EventID:       e.ID,
+		EventName:     e.Name,
+		CorrelationID: e.CorrelationID,
+		ProcessedAt:   now,
+		ExpiresAt:     now.Add(s.TTL),
+	})
+}
@@ -9,6 +9,10 @@ import (
 
 // UpdateTaskStatus ...
 func UpdateTaskStatus(id string, data persistence.DataAccessLayer, log *logrus.Entry, runErr error) {
+	if id == "" {
+		// Run by an admin command, not by a task.
+		return
+	}
 	task, err := data.GetTask(id, data.DefaultQuery())
 	if err != nil {
 		log.Errorf("couldn't retrieve task with ID: %s", id)
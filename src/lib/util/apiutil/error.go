@@ -5,8 +5,8 @@ import (
 	"strconv"
 
 	jwt_lib "github.com/dgrijalva/jwt-go/request"
+	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -24,6 +24,7 @@ var (
 	apiInternalServerError     = NewAPIError("internal_server_error", "An internal server error occurred")
 	apiErrorProtectedResource  = NewAPIError("protected", "This resource is protected and cannot be deleted or modified")
 	apiErrorInvalidCredentials = NewAPIError("invalid_credentials", "Invalid credentials")
+	apiErrorConflict           = NewAPIError("conflict", "Resource was modified by another request, fetch it again and retry")
 )
 
 // HandleError main handler for errors in the API.
@@ -31,12 +32,9 @@ func HandleError(c *gin.Context, err error) {
 	res := &APIResponse{Status: 200}
 
 	switch err {
-	case mongo.ErrNoDocuments:
+	case persistence.ErrNotFound:
 		res.Status = http.StatusNotFound
 		res.Error = apiErrorNotFound
-	case err.(*strconv.NumError):
-		res.Status = http.StatusBadRequest
-		res.Error = apiErrorBadRequest
 	case jwt_lib.ErrNoTokenInRequest:
 		res.Status = http.StatusUnauthorized
 		res.Error = apiErrorUnauthorized
@@ -44,8 +42,16 @@ func HandleError(c *gin.Context, err error) {
 		res.Status = http.StatusUnauthorized
 		res.Error = apiErrorInvalidCredentials
 	default:
-		res.Status = 500
-		res.Error = NewAPIError("unknown", err.Error()) // @Temporary
+		if _, ok := err.(*strconv.NumError); ok {
+			res.Status = http.StatusBadRequest
+			res.Error = apiErrorBadRequest
+		} else if persistence.IsConflict(err) {
+			res.Status = http.StatusConflict
+			res.Error = apiErrorConflict
+		} else {
+			res.Status = 500
+			res.Error = NewAPIError("unknown", err.Error()) // @Temporary
+		}
 	}
 
 	c.SecureJSON(res.Status, res)
@@ -3,6 +3,8 @@ package apiutil
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -76,3 +78,23 @@ func SendProtectedResource(c *gin.Context) {
 	})
 	c.Abort()
 }
+
+// SetETag sets the ETag header of the response to the given document version.
+func SetETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// IfMatch returns the document version in the If-Match header of the request,
+// zero if it's missing. It's not ok if the header holds anything but a
+// version, since every stored document has one.
+func IfMatch(c *gin.Context) (int64, bool) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" {
+		return 0, true
+	}
+	version, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
@@ -3,28 +3,35 @@ package movieutil
 import (
 	"strings"
 
+	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
 )
 
-// ShouldUpdate ...
+// ShouldUpdate checks whether test has new or changed data for the movie src
+// and returns src with it applied. Fields locked in src, see models.MovieLocks,
+// are never changed.
 func ShouldUpdate(src, test *models.Movie) (bool, models.Movie) {
 	should := false
 	result := *src
 
+	unlocked := func(flag uint64) bool {
+		return models.FlagIsNotSet(src.LockFlags, flag)
+	}
+
 	// NOTE: If these IDs change we will need to implement some routine
 	// that checks if the new ID match the movie.
-	if src.TmdbID == 0 && test.TmdbID != 0 {
+	if unlocked(models.MovieLockTmdbID) && src.TmdbID == 0 && test.TmdbID != 0 {
 		result.TmdbID = test.TmdbID
 		should = true
 	}
 
-	if src.ImdbID == "" && test.ImdbID != "" {
+	if unlocked(models.MovieLockImdbID) && src.ImdbID == "" && test.ImdbID != "" {
 		result.ImdbID = test.ImdbID
 		should = true
 	}
 
-	if src.ClaqueteID == 0 && test.ClaqueteID != 0 {
+	if unlocked(models.MovieLockClaqueteID) && src.ClaqueteID == 0 && test.ClaqueteID != 0 {
 		result.ClaqueteID = test.ClaqueteID
 		should = true
 	}
@@ -32,85 +39,81 @@ func ShouldUpdate(src, test *models.Movie) (bool, models.Movie) {
 	// NOTE: These two can always change because poster and backdrop images will never contain the same
 	// address due to it being uploaded in Cloudinary whenever the admin system starts or we implement
 	// the images service
-	if src.BackdropURL == "" && test.BackdropURL != "" {
+	if unlocked(models.MovieLockBackdrop) && src.BackdropURL == "" && test.BackdropURL != "" {
 		result.BackdropURL = test.BackdropURL
 		should = true
 	}
 
-	if src.PosterURL == "" && test.PosterURL != "" {
+	if unlocked(models.MovieLockPoster) && src.PosterURL == "" && test.PosterURL != "" {
 		result.PosterURL = test.PosterURL
 		should = true
 	}
 
 	// Update slug if we don't have one or it changed.
-	if StringIsNewOrChanged(src.Slug, test.Slug) {
+	if unlocked(models.MovieLockSlug) && StringIsNewOrChanged(src.Slug, test.Slug) {
 		result.Slug = test.Slug
 		should = true
 	}
 
 	// Update release date if we don't have one or it changed.
-	if src.ReleaseDate == nil && test.ReleaseDate != nil ||
-		(test.ReleaseDate != nil && !test.ReleaseDate.IsZero() && test.ReleaseDate.Unix() != src.ReleaseDate.Unix()) {
-		result.ReleaseDate = test.ReleaseDate
-		should = true
+	if unlocked(models.MovieLockReleaseDate) {
+		if src.ReleaseDate == nil && test.ReleaseDate != nil ||
+			(test.ReleaseDate != nil && !test.ReleaseDate.IsZero() && test.ReleaseDate.Unix() != src.ReleaseDate.Unix()) {
+			result.ReleaseDate = test.ReleaseDate
+			should = true
+		}
 	}
 
 	// Update cast if we don't have one or it changed.
-	if SliceCountDifferent(len(src.Cast), len(test.Cast)) {
+	if unlocked(models.MovieLockCast) && SliceCountDifferent(len(src.Cast), len(test.Cast)) {
 		result.Cast = test.Cast
 		should = true
 	}
 
 	// Update genres if we don't have one or it changed.
-	if SliceCountDifferent(len(src.Genres), len(test.Genres)) {
+	if unlocked(models.MovieLockGenres) && SliceCountDifferent(len(src.Genres), len(test.Genres)) {
 		result.Genres = test.Genres
 		should = true
 	}
 
 	// Update original title if we don't have one or it changed.
-	if models.FlagIsNotSet(src.LockFlags, models.MovieLockOriginalTitle) {
-		if StringIsNewOrChanged(src.OriginalTitle, test.OriginalTitle) {
-			result.OriginalTitle = test.OriginalTitle
-			should = true
-		}
+	if unlocked(models.MovieLockOriginalTitle) && StringIsNewOrChanged(src.OriginalTitle, test.OriginalTitle) {
+		result.OriginalTitle = test.OriginalTitle
+		should = true
 	}
 
 	// Update title if we don't have one or it changed.
-	if models.FlagIsNotSet(src.LockFlags, models.MovieLockTitle) {
-		if StringIsNewOrChanged(src.Title, test.Title) {
-			result.Title = test.Title
-			should = true
-		}
+	if unlocked(models.MovieLockTitle) && StringIsNewOrChanged(src.Title, test.Title) {
+		result.Title = test.Title
+		should = true
 	}
 
 	// Update synopsis if we don't have one or it changed.
-	if models.FlagIsNotSet(src.LockFlags, models.MovieLockSynopsis) {
-		if StringIsNewOrChanged(src.Synopsis, test.Synopsis) {
-			result.Synopsis = test.Synopsis
-			should = true
-		}
+	if unlocked(models.MovieLockSynopsis) && StringIsNewOrChanged(src.Synopsis, test.Synopsis) {
+		result.Synopsis = test.Synopsis
+		should = true
 	}
 
 	// Update trailer if we don't have one or it changed.
-	if StringIsNewOrChanged(src.Trailer, test.Trailer) {
+	if unlocked(models.MovieLockTrailer) && StringIsNewOrChanged(src.Trailer, test.Trailer) {
 		result.Trailer = test.Trailer
 		should = true
 	}
 
 	// Update distributor if we don't have one or it changed.
-	if StringIsNewOrChanged(src.Distributor, test.Distributor) {
+	if unlocked(models.MovieLockDistributor) && StringIsNewOrChanged(src.Distributor, test.Distributor) {
 		result.Distributor = test.Distributor
 		should = true
 	}
 
 	// Update runtime if we don't have one or it changed.
-	if IntIsNewOrChanged(src.Runtime, test.Runtime) {
+	if unlocked(models.MovieLockRuntime) && IntIsNewOrChanged(src.Runtime, test.Runtime) {
 		result.Runtime = test.Runtime
 		should = true
 	}
 
 	// Update rating if we don't have one or it changed.
-	if IntIsNewOrChanged(src.Rating, test.Rating) {
+	if unlocked(models.MovieLockRating) && IntIsNewOrChanged(src.Rating, test.Rating) {
 		result.Rating = test.Rating
 		should = true
 	}
@@ -255,3 +258,24 @@ func CapTitle(str string) string {
 
 	return result.String()
 }
+
+// SearchKeys returns the search keys of movie after update is applied to its
+// titles.
+func SearchKeys(movie models.Movie, update persistence.Update) []string {
+	for _, field := range update.Unset {
+		switch field {
+		case "title":
+			movie.Title = ""
+		case "originalTitle":
+			movie.OriginalTitle = ""
+		}
+	}
+	if title, ok := update.Set["title"].(string); ok {
+		movie.Title = title
+	}
+	if title, ok := update.Set["originalTitle"].(string); ok {
+		movie.OriginalTitle = title
+	}
+	movie.UpdateSearchKeys()
+	return movie.SearchKeys
+}
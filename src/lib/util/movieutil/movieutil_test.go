@@ -0,0 +1,37 @@
+package movieutil
+
+import (
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldUpdateLockedFields(t *testing.T) {
+	src := models.Movie{
+		Title:     "Bacurau",
+		Synopsis:  "Edited by an admin",
+		LockFlags: models.MovieLockSynopsis | models.MovieLockPoster,
+	}
+	scraped := models.Movie{
+		Title:     "Bacurau",
+		Synopsis:  "Scraped synopsis",
+		PosterURL: "https://image.tmdb.org/poster.jpg",
+		Trailer:   "trailer-key",
+		Runtime:   131,
+	}
+
+	should, result := ShouldUpdate(&src, &scraped)
+	assert.True(t, should)
+	assert.Equal(t, "Edited by an admin", result.Synopsis)
+	assert.Empty(t, result.PosterURL)
+	assert.Equal(t, "trailer-key", result.Trailer)
+	assert.Equal(t, 131, result.Runtime)
+	assert.Equal(t, src.LockFlags, result.LockFlags)
+
+	// Nothing unlocked has changed
+	src.Trailer = scraped.Trailer
+	src.Runtime = scraped.Runtime
+	should, _ = ShouldUpdate(&src, &scraped)
+	assert.False(t, should)
+}
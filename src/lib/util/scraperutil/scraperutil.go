@@ -29,6 +29,9 @@ const (
 	// RunResultNotFound indicates the run encountered zero items in its execution.
 	RunResultNotFound = "not_found"
 
+	// RunResultFailed indicates the run couldn't persist the extracted data.
+	RunResultFailed = "failed"
+
 	// RunResultTimeout indicates the run encountered a timeout error during its execution.
 	// TODO: detect this!
 	RunResultTimeout = "server_timeout"
@@ -0,0 +1,22 @@
+package searchutil
+
+import (
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
+)
+
+// KeysField is the field holding the search keys of models that support
+// prefix search, e.g.: Movie.SearchKeys.
+const KeysField = "searchKeys"
+
+// Conditions creates the conditions that match documents whose search keys
+// start with every folded word of search, e.g.: "acao vinga" matches "Ação
+// Vingadora". An empty search creates no conditions.
+func Conditions(search string) []persistence.Condition {
+	words := stringutil.SearchKeys(search)
+	result := make([]persistence.Condition, len(words))
+	for i, w := range words {
+		result[i] = persistence.Prefix(KeysField, w)
+	}
+	return result
+}
@@ -380,3 +380,33 @@ func ConsumeNextLine(reader *bufio.Reader) (string, bool) {
 		return lineStr, true
 	}
 }
+
+// Fold returns s in lower case and without diacritics, e.g.: "Ação" becomes
+// "acao".
+func Fold(s string) string {
+	t := transform.Chain(norm.NFD, transform.RemoveFunc(isMn), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+// SearchKeys returns the distinct folded words of the given texts, in the
+// order they appear. Anything that is not a letter or number separates words.
+func SearchKeys(texts ...string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		words := strings.FieldsFunc(Fold(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		for _, w := range words {
+			if !seen[w] {
+				seen[w] = true
+				result = append(result, w)
+			}
+		}
+	}
+	return result
+}
@@ -1,6 +1,7 @@
 package listener
 
 import (
+	"context"
 	"log"
 
 	"github.com/dsbezerra/amenic/src/contracts"
@@ -13,6 +14,11 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// commands are the types of the commands run by this service.
+var commands = map[string]bool{
+	models.TaskCheckOpeningMovies: true,
+}
+
 // EventProcessor ...
 type EventProcessor struct {
 	EventListener messagequeue.EventListener
@@ -24,40 +30,41 @@ type EventProcessor struct {
 func (p *EventProcessor) ProcessEvents() error {
 	log.Println("Listening to events...")
 
-	var eventsList = []string{
-		"commandDispatched",
-	}
-
-	received, errors, err := p.EventListener.Listen(eventsList...)
+	errors, err := p.routes().Listen(p.EventListener)
 	if err != nil {
 		return err
 	}
 
-	for {
-		select {
-		case event := <-received:
-			p.handle(event)
-		case err = <-errors:
-			log.Printf("received error while processing message: %s", err)
-		}
+	for err = range errors {
+		log.Printf("received error while processing message: %s", err)
 	}
+	return nil
 }
 
-func (p *EventProcessor) handle(event messagequeue.Event) {
-	switch event.(type) {
-	case *contracts.EventCommandDispatched:
-		p.handleCommandDispatched(event.(*contracts.EventCommandDispatched))
-
-	default:
-		log.Printf("unknown event: %t", event)
-	}
+// routes returns a router calling the handler of each event.
+func (p *EventProcessor) routes() *messagequeue.Router {
+	router := messagequeue.NewRouter()
+	messagequeue.Handle(router, p.handleCommandDispatched)
+	return router
 }
 
-func (p *EventProcessor) handleCommandDispatched(e *contracts.EventCommandDispatched) {
+// handleCommandDispatched never fails once notifications may have been sent,
+// a retry could send them twice.
+func (p *EventProcessor) handleCommandDispatched(ctx context.Context, e *contracts.EventCommandDispatched) error {
+	// Tag every log entry with the event and the correlation it belongs to.
+	scoped := *p
+	scoped.Log = p.Log.WithFields(messagequeue.LogFields(ctx))
+	p = &scoped
+
 	abort := messagequeue.CheckAbort(e.DispatchTime, e.ExecutionTimeout)
 	if abort {
+		if !commands[e.Type] {
+			// Meant for another service, which replies the failure.
+			messagequeue.Ignore(ctx)
+			return nil
+		}
 		p.Log.Warnf("event %s aborted. reason: timeout reached", e.Name)
-		return
+		return messagequeue.Permanent(messagequeue.ErrTimeout)
 	}
 
 	p.Log.Infof("handling event %s. dispatched at: %s", e.Name, e.DispatchTime)
@@ -77,6 +84,8 @@ func (p *EventProcessor) handleCommandDispatched(e *contracts.EventCommandDispat
 			}
 		}
 	default:
+		// Meant for another service.
+		messagequeue.Ignore(ctx)
 		p.Log.Infof("handler for event %s was not found", e.Name)
 		ran = false
 	}
@@ -84,4 +93,5 @@ func (p *EventProcessor) handleCommandDispatched(e *contracts.EventCommandDispat
 	if ran {
 		shared.UpdateTaskStatus(e.TaskID, p.Data, p.Log, runError)
 	}
+	return nil
 }
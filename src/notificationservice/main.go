@@ -10,12 +10,12 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/config"
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/dblayer"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
+	"github.com/dsbezerra/amenic/src/lib/shared"
 	"github.com/dsbezerra/amenic/src/notificationservice/listener"
 	"github.com/robfig/cron"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
 )
 
 const (
@@ -45,41 +45,47 @@ func main() {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Config = settings
+	// Catch events that don't match their contract during development.
+	messagequeue.ValidatePayloads = !settings.IsProduction
 
 	tasks, err := config.LoadTasks()
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
 
-	conn, err := amqp.Dial(settings.AMQPMessageBroker)
+	broker, err := shared.OpenBroker(settings)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
+	defer broker.Close()
 
-	eventEmitter, err := messagequeue.NewAMQPEventEmitter(conn, "events")
+	eventEmitter, err := broker.Emitter(ServiceName)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Emitter = eventEmitter
 
-	eventListener, err := messagequeue.NewAMQPEventListener(conn, "events", ServiceName)
+	eventListener, err := broker.Listener(ServiceName)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Listener = eventListener
 
-	data, err := mongolayer.NewMongoDAL(settings.DBConnection)
+	data, err := dblayer.NewPersistenceLayer(settings.DBType, settings.DBConnection)
+	if err != nil {
+		ctx.Log.Fatal(err)
+	}
+	err = data.Setup()
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
-	data.Setup()
 	defer data.Close()
 
 	ctx.Data = data
 	ctx.Log.Info("Database setup completed!")
 
 	// Ensure our tasks are saved in database.
-	(data.(*mongolayer.MongoDAL)).EnsureTasksExists(tasks)
+	data.EnsureTasksExists(tasks)
 
 	// Start event processor.
 	p := listener.EventProcessor{
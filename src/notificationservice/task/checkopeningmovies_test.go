@@ -6,73 +6,63 @@ import (
 	"time"
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
 	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
-	"go.mongodb.org/mongo-driver/bson"
-)
-
-const (
-	testConnection = "mongodb://localhost/amenic-test"
+	"github.com/dsbezerra/amenic/src/notificationservice/premiere"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestCheckOpeningMovies(t *testing.T) {
 	data := newMockDataAccessLayer()
 
-	mockTheater := &models.Cinema{
-		ID:   "mock-theater",
+	mockTheater := models.Theater{
+		ID:   primitive.NewObjectID(),
 		Name: "Test Theater",
 	}
 
-	err := data.InsertCinema(mockTheater)
+	err := data.InsertTheater(mockTheater)
 	exitIfErr(err)
 
 	tt := time.Now().UTC()
-	mockMovie := &models.Movie{
-		ID:          bson.NewObjectId(),
+	mockMovie := models.Movie{
+		ID:          primitive.NewObjectID(),
 		Title:       "Test Movie",
 		ReleaseDate: &tt,
 	}
 
 	period := scheduleutil.GetWeekPeriod(nil)
-	mockShowtimes := []models.Showtime{
-		models.Showtime{
-			ID:       bson.NewObjectId(),
-			MovieID:  mockMovie.ID,
-			CinemaID: mockTheater.ID,
-			Period: struct {
-				Start time.Time `json:"start,omitempty" bson:"start,omitempty"`
-				End   time.Time `json:"end,omitempty" bson:"end,omitempty"`
-			}{
-				Start: period.Start,
-				End:   period.End,
-			},
+	mockSessions := []models.Session{
+		models.Session{
+			ID:        primitive.NewObjectID(),
+			MovieID:   mockMovie.ID,
+			TheaterID: mockTheater.ID,
+			StartTime: &period.Start,
 		},
 	}
 
 	err = data.InsertMovie(mockMovie)
 	exitIfErr(err)
 
-	for _, s := range mockShowtimes {
-		err = data.InsertShowtime(&s)
-		exitIfErr(err)
-	}
-
-	CheckOpeningMovies(data)
-
-	err = data.RemoveAllShowtimes(data.DefaultQuery().
-		AddCondition("movieId", mockMovie.ID))
+	err = data.InsertSessions(mockSessions...)
 	exitIfErr(err)
 
-	err = data.RemoveCinema(mockTheater.ID)
-	exitIfErr(err)
+	releases, err := GetWeekReleases(data)
+	assert.NoError(t, err)
+	if assert.Len(t, releases, 1) {
+		assert.Equal(t, mockMovie.ID, releases[0].ID)
+	}
 
-	err = data.RemoveMovie(mockMovie.ID.Hex())
-	exitIfErr(err)
+	notification := premiere.PrepareNotification(releases)
+	if assert.NotNil(t, notification) {
+		assert.True(t, notification.Single)
+		assert.Equal(t, mockMovie.ID.Hex(), notification.ItemID)
+	}
 }
 
 func newMockDataAccessLayer() persistence.DataAccessLayer {
-	data, err := mongolayer.NewMongoDAL(testConnection)
+	data, err := memlayer.NewMemDAL()
 	exitIfErr(err)
 	return data
 }
@@ -1,6 +1,7 @@
 package listener
 
 import (
+	"context"
 	"log"
 
 	"github.com/dsbezerra/amenic/src/contracts"
@@ -12,51 +13,72 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// commands are the types of the commands run by this service.
+var commands = map[string]bool{
+	models.TaskSyncScores: true,
+}
+
 // EventProcessor ...
 type EventProcessor struct {
 	EventListener messagequeue.EventListener
 	Data          persistence.DataAccessLayer
 	Log           *logrus.Entry
+	// Processed, if set, makes the effects of some events happen only once
+	// even if they are redelivered.
+	Processed messagequeue.ProcessedEventStore
 }
 
 // ProcessEvents ...
 func (p *EventProcessor) ProcessEvents() error {
 	log.Println("Listening to events...")
 
-	var eventsList = []string{
-		"commandDispatched",
-	}
-
-	received, errors, err := p.EventListener.Listen(eventsList...)
+	errors, err := p.routes().Listen(p.EventListener)
 	if err != nil {
 		return err
 	}
 
-	for {
-		select {
-		case event := <-received:
-			p.handle(event)
-		case err = <-errors:
-			log.Printf("received error while processing message: %s", err)
-		}
+	for err = range errors {
+		log.Printf("received error while processing message: %s", err)
 	}
+	return nil
 }
 
-func (p *EventProcessor) handle(event messagequeue.Event) {
-	switch event.(type) {
-	case *contracts.EventCommandDispatched:
-		p.handleCommandDispatched(event.(*contracts.EventCommandDispatched))
+// routes returns a router calling the handler of each event.
+func (p *EventProcessor) routes() *messagequeue.Router {
+	router := messagequeue.NewRouter()
+	messagequeue.Handle(router, p.handleCommandDispatched)
 
-	default:
-		log.Printf("unknown event: %t", event)
+	if p.Processed != nil {
+		// Syncing scores twice for the same command is wasted work.
+		router.Use(func(next messagequeue.EventHandler) messagequeue.EventHandler {
+			return messagequeue.Idempotent(p.Processed, next, "commandDispatched")
+		})
 	}
+	return router
+}
+
+// scoped returns a copy of p tagging every log entry with the event and the
+// correlation it belongs to.
+func (p *EventProcessor) scoped(ctx context.Context) *EventProcessor {
+	scoped := *p
+	scoped.Log = p.Log.WithFields(messagequeue.LogFields(ctx))
+	return &scoped
 }
 
-func (p *EventProcessor) handleCommandDispatched(e *contracts.EventCommandDispatched) {
-	abort := messagequeue.CheckAbort(e.DispatchTime, e.ExecutionTimeout)
+func (p *EventProcessor) handleCommandDispatched(ctx context.Context, e *contracts.EventCommandDispatched) error {
+	p = p.scoped(ctx)
+
+	// Retried commands were received in time, only the first delivery may be
+	// too late.
+	abort := messagequeue.RetryCount(ctx) == 0 && messagequeue.CheckAbort(e.DispatchTime, e.ExecutionTimeout)
 	if abort {
+		if !commands[e.Type] {
+			// Meant for another service, which replies the failure.
+			messagequeue.Ignore(ctx)
+			return nil
+		}
 		p.Log.Warnf("event %s aborted. reason: timeout reached", e.Name)
-		return
+		return messagequeue.Permanent(messagequeue.ErrTimeout)
 	}
 
 	p.Log.Infof("handling event %s. dispatched at: %s", e.Name, e.DispatchTime)
@@ -66,8 +88,14 @@ func (p *EventProcessor) handleCommandDispatched(e *contracts.EventCommandDispat
 
 	switch e.Type {
 	case models.TaskSyncScores:
-		runError = task.SyncScores(p.Data)
+		runError = task.SyncScoresWithProgress(p.Data, func(done float64, step string) {
+			if err := messagequeue.ReportProgress(ctx, done, step); err != nil {
+				p.Log.Warn(err)
+			}
+		})
 	default:
+		// Meant for another service.
+		messagequeue.Ignore(ctx)
 		p.Log.Infof("handler for event %s was not found", e.Name)
 		ran = false
 	}
@@ -75,4 +103,5 @@ func (p *EventProcessor) handleCommandDispatched(e *contracts.EventCommandDispat
 	if ran {
 		shared.UpdateTaskStatus(e.TaskID, p.Data, p.Log, runError)
 	}
+	return runError
 }
@@ -28,17 +28,30 @@ var (
 //
 // Then it finally calls Sync to actually sync the scores and updates the database.
 func SyncScores(data persistence.DataAccessLayer) error {
+	return SyncScoresWithProgress(data, nil)
+}
+
+// SyncScoresWithProgress is like SyncScores but calls progress, if not nil,
+// before each step with the fraction of steps done.
+func SyncScoresWithProgress(data persistence.DataAccessLayer, progress func(done float64, step string)) error {
+	if progress == nil {
+		progress = func(float64, string) {}
+	}
+
+	progress(0, "ensuring only now playing movies are synced")
 	_, err := EnsureSyncOnlyNowPlayingMovies(data)
 	if err != nil {
 		return err
 	}
 
+	progress(1.0/3, "finding scores for now playing movies")
 	err = FindScoresForNowPlayingMovies(data)
 	if err != nil {
 		return err
 	}
 
 	// Now actually sync.
+	progress(2.0/3, "syncing scores")
 	return Sync(data, 10, 0, true)
 }
 
@@ -137,7 +150,10 @@ func EnsureSyncOnlyNowPlayingMovies(data persistence.DataAccessLayer) ([]models.
 		if !keepSynced {
 			score.KeepSynced = false
 			_, err := data.UpdateScore(score.ID.Hex(), score)
-			if err != nil {
+			if persistence.IsConflict(err) {
+				// Modified by someone else, it will be checked again in the next run.
+				log.Printf("Error: %s", err.Error())
+			} else if err != nil {
 				log.Fatal(err)
 			}
 		}
@@ -200,6 +216,7 @@ func FindScoresForNowPlayingMovies(data persistence.DataAccessLayer) error {
 				if !isNew {
 					item := scores[0]
 					score.ID = item.ID
+					score.Version = item.Version
 					score.Imdb = item.Imdb
 					score.Rotten = item.Rotten
 					score.CreatedAt = item.CreatedAt
@@ -1,31 +1,54 @@
 package task
 
 import (
-	"log"
 	"testing"
+	"time"
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
-)
-
-const (
-	testConnection = "mongodb://localhost/amenic-test"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestSyncScores(t *testing.T) {
-	data := newMockDataAccessLayer()
-	err := SyncScores(data)
-	exitIfErr(err)
-}
+	data := newMockDataAccessLayer(t)
+	assert.Equal(t, ErrNowPlayingMoviesNotFound, SyncScores(data))
 
-func newMockDataAccessLayer() persistence.DataAccessLayer {
-	data, err := mongolayer.NewMongoDAL(testConnection)
-	exitIfErr(err)
-	return data
+	// Movies without original title and scores without IDs are skipped, so
+	// nothing is fetched from the providers. Only scores of now playing
+	// movies are kept synced.
+	now := time.Now()
+	playing := models.Movie{ID: primitive.NewObjectID(), Title: "Em Cartaz"}
+	ended := models.Movie{ID: primitive.NewObjectID(), Title: "Fora de Cartaz"}
+	playingScore := models.Score{ID: primitive.NewObjectID(), MovieID: playing.ID, KeepSynced: true}
+	endedScore := models.Score{ID: primitive.NewObjectID(), MovieID: ended.ID, KeepSynced: true}
+	assert.NoError(t, data.InsertMovie(playing))
+	assert.NoError(t, data.InsertMovie(ended))
+	assert.NoError(t, data.InsertSessions(models.Session{
+		ID:        primitive.NewObjectID(),
+		TheaterID: primitive.NewObjectID(),
+		MovieID:   playing.ID,
+		StartTime: &now,
+	}))
+	assert.NoError(t, data.InsertScore(playingScore))
+	assert.NoError(t, data.InsertScore(endedScore))
+
+	assert.NoError(t, SyncScores(data))
+
+	score, err := data.GetScore(playingScore.ID.Hex(), data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.True(t, score.KeepSynced)
+
+	score, err = data.GetScore(endedScore.ID.Hex(), data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.False(t, score.KeepSynced)
 }
 
-func exitIfErr(err error) {
+func newMockDataAccessLayer(t *testing.T) persistence.DataAccessLayer {
+	data, err := memlayer.NewMemDAL()
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
+	return data
 }
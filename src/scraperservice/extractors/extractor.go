@@ -19,8 +19,8 @@ type Extractor interface {
 	// TODO: DOC
 	Execute() error
 
-	// TODO: DOC
-	Complete()
+	// Complete persists the extracted data.
+	Complete() error
 }
 
 // NewExtractor creates a brand new extractor instance.
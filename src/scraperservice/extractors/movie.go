@@ -16,10 +16,10 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
 	"github.com/dsbezerra/amenic/src/lib/util/movieutil"
 	"github.com/dsbezerra/amenic/src/lib/util/scraperutil"
+	"github.com/dsbezerra/amenic/src/lib/util/stringutil"
 	"github.com/dsbezerra/amenic/src/scraperservice/provider"
 	tmdb "github.com/ryanbradynd05/go-tmdb"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -78,7 +78,7 @@ func (e *MovieExtractor) Execute() error {
 }
 
 // Complete ...
-func (e *MovieExtractor) Complete() {
+func (e *MovieExtractor) Complete() error {
 	var wg sync.WaitGroup
 	for index := range e.Movies {
 		wg.Add(1)
@@ -93,6 +93,7 @@ func (e *MovieExtractor) Complete() {
 	wg.Wait()
 
 	e.Run.Movies = e.Movies
+	return nil
 }
 
 // ExtractedHash TODO
@@ -294,13 +295,21 @@ func (e *MovieExtractor) UpsertMovie(movie *models.Movie) {
 		// IBICINEMAS outdated.
 		// TODO: Elaborate this logic.
 		e.Logger.Infof("Movie '%s' is in database. Checking for update...", movie.Title)
+		if result.LockFlags != 0 {
+			// Locked fields were edited by an admin and are kept by ShouldUpdate.
+			e.Logger.Debugf("Movie '%s' has locked fields (%b).", movie.Title, result.LockFlags)
+		}
 		update, u := movieutil.ShouldUpdate(result, movie)
 		if update && e.Run.Scraper.Provider != provider.ProviderIbicinemas {
 			e.Logger.Debugf("Updating movie: %s (%s)...", u.ID.Hex(), movie.Title)
 			updatedAt := time.Now()
 			u.UpdatedAt = &updatedAt
 			_, err := e.Data.UpdateMovie(u.ID.Hex(), u)
-			if err != nil {
+			if persistence.IsConflict(err) {
+				// Someone else, probably an admin, changed it after we found it. Their
+				// changes win and we try again in the next run.
+				e.Logger.Warnf("Movie '%s' was modified during the update. Skipped.", movie.Title)
+			} else if err != nil {
 				e.Logger.Error(err.Error())
 			} else {
 				e.Logger.Info("Updated.")
@@ -361,13 +370,10 @@ func FindMovieMatch(data persistence.DataAccessLayer, movie *models.Movie) (bool
 		return true, result
 	}
 
-	// Query the films that has the closest title sorted by matching score
+	// Query the films that has the closest title, most relevant first
 	query := data.DefaultQuery().
-		AddCondition("$text", bson.M{"$search": movie.Title}).
-		SetFields(bson.M{
-			"score": bson.M{"$meta": "textScore"},
-		}).
-		SetSort("$testScore:score")
+		Where(persistence.Text(movie.Title)).
+		SetSort(persistence.SortTextScore)
 	possible, err := data.GetMovies(query)
 	if err != nil {
 		fmt.Println(err)
@@ -375,7 +381,7 @@ func FindMovieMatch(data persistence.DataAccessLayer, movie *models.Movie) (bool
 	}
 	// TODO: Improve the match algorithm here if necessary
 	for _, m := range possible {
-		distance := levenshtein.ComputeDistance(m.Title, movie.Title)
+		distance := levenshtein.ComputeDistance(stringutil.Fold(m.Title), stringutil.Fold(movie.Title))
 		if distance <= 2 {
 			result = &m
 			break
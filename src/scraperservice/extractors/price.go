@@ -3,6 +3,7 @@ package extractors
 import (
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/scraperutil"
 	"github.com/dsbezerra/amenic/src/scraperservice/provider"
 	"github.com/sirupsen/logrus"
 )
@@ -38,18 +39,25 @@ func (e *PriceExtractor) Execute() error {
 	return nil
 }
 
-// Complete TODO
-func (e *PriceExtractor) Complete() {
-	query := e.Data.DefaultQuery().
-		AddCondition("theaterId", e.Run.Scraper.TheaterID)
-	_, err := e.Data.DeletePrices(query)
-	if err != nil {
-		e.Logger.Error(err)
-	}
-	err = e.Data.InsertPrices(e.Prices...)
-	if err != nil {
-		e.Logger.Error(err)
+// Complete replaces the theater prices with the extracted ones.
+func (e *PriceExtractor) Complete() error {
+	switch e.Run.ResultCode {
+	case scraperutil.RunResultSuccess:
+		return e.Data.WithTransaction(func(tx persistence.DataAccessLayer) error {
+			query := tx.DefaultQuery().
+				AddCondition("theaterId", e.Run.Scraper.TheaterID)
+			_, err := tx.DeletePrices(query)
+			if err != nil {
+				return err
+			}
+			return tx.InsertPrices(e.Prices...)
+		})
+	case scraperutil.RunResultNotModified:
+		fallthrough
+	default:
+		// Do nothing.
 	}
+	return nil
 }
 
 // ExtractedHash TODO
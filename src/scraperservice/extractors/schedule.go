@@ -1,21 +1,22 @@
 package extractors
 
 import (
-	"log"
 	"time"
 
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/util/scheduleutil"
 	"github.com/dsbezerra/amenic/src/lib/util/scraperutil"
 	"github.com/dsbezerra/amenic/src/lib/util/timeutil"
 	"github.com/dsbezerra/amenic/src/scraperservice/provider"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type (
 	ScheduleExtractor struct {
 		Data     persistence.DataAccessLayer
+		Logger   *logrus.Entry
 		Provider provider.Provider
 		Run      *models.ScraperRun
 		Sessions []models.Session
@@ -26,6 +27,7 @@ type (
 func NewScheduleExtractor(data persistence.DataAccessLayer, p provider.Provider, s *models.ScraperRun) *ScheduleExtractor {
 	result := &ScheduleExtractor{
 		Data:     data,
+		Logger:   logrus.WithFields(logrus.Fields{"extractor": "Schedule"}),
 		Run:      s,
 		Provider: p,
 	}
@@ -56,8 +58,9 @@ func (e *ScheduleExtractor) Execute() error {
 	return nil
 }
 
-// Complete TODO
-func (e *ScheduleExtractor) Complete() {
+// Complete synchronizes the theater sessions in the extracted period with the
+// extracted ones. Sessions that didn't change keep their IDs.
+func (e *ScheduleExtractor) Complete() error {
 
 	switch e.Run.ResultCode {
 	case scraperutil.RunResultSuccess:
@@ -70,29 +73,22 @@ func (e *ScheduleExtractor) Complete() {
 				end = *session.StartTime
 			}
 		}
-		query := e.Data.DefaultQuery().
-			AddCondition("$and", []bson.D{
-				bson.D{
-					{Key: "theaterId", Value: e.Run.Scraper.TheaterID},
-					{Key: "startTime", Value: bson.D{{Key: "$gte", Value: start}}},
-					{Key: "startTime", Value: bson.D{{Key: "$lte", Value: end}}},
-				},
-			})
-		_, err := e.Data.DeleteSessions(query)
+		window := scheduleutil.Period{Start: start, End: end}
+		summary, err := e.Data.SyncSessions(e.Run.Scraper.TheaterID, window, e.Sessions)
 		if err != nil {
-			// TODO: Handle
-			log.Fatal(err)
-		}
-		err = e.Data.InsertSessions(e.Sessions...)
-		if err != nil {
-			// TODO: Handle
-			log.Fatal(err)
+			return err
 		}
+		e.Logger.WithFields(logrus.Fields{
+			"added":     summary.Added,
+			"removed":   summary.Removed,
+			"unchanged": summary.Unchanged,
+		}).Info("Sessions synchronized")
 	case scraperutil.RunResultNotModified:
 		fallthrough
 	default:
 		// Do nothing.
 	}
+	return nil
 }
 
 // ExtractedHash TODO
@@ -109,8 +105,8 @@ func (e *ScheduleExtractor) ExtractedCount() int {
 func LookupMovies(data persistence.DataAccessLayer, sessions []models.Session) []models.Movie {
 	defer timeutil.TimeTrack(time.Now(), "LookupMovies")
 
-	claquete := []int{}
-	slugs := []string{}
+	claquete := []interface{}{}
+	slugs := []interface{}{}
 
 	movies := make([]models.Movie, 0)
 
@@ -128,18 +124,16 @@ func LookupMovies(data persistence.DataAccessLayer, sessions []models.Session) [
 	}
 
 	if len(claquete) > 0 {
-		// @Refactor support other databases.
 		m, err := data.GetMovies(data.DefaultQuery().
-			AddCondition("_id", bson.M{"$in": claquete}))
+			Where(persistence.In("claqueteId", claquete...)))
 		if err == nil {
 			movies = append(movies, m...)
 		}
 	}
 
 	if len(slugs) > 0 {
-		// @Refactor support other databases.
 		m, err := data.GetMovies(data.DefaultQuery().
-			AddCondition("slug", bson.M{"$in": slugs}))
+			Where(persistence.In("slug", slugs...)))
 		if err == nil {
 			movies = append(movies, m...)
 		}
@@ -1,6 +1,7 @@
 package listener
 
 import (
+	"context"
 	"log"
 	"strconv"
 
@@ -8,10 +9,30 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/dsbezerra/amenic/src/lib/shared"
 	"github.com/dsbezerra/amenic/src/scraperservice/queue"
+	"github.com/dsbezerra/amenic/src/scraperservice/task"
 	"github.com/sirupsen/logrus"
 )
 
+// StartScraperResult is the result of start_scraper sent to its requester.
+type StartScraperResult struct {
+	Queued int `json:"queued"` // Scrapers added to the work queue
+}
+
+// IntegrityResult is the result of check_integrity sent to its requester.
+type IntegrityResult struct {
+	ReportID string `json:"report_id"`
+	Issues   int    `json:"issues"`
+}
+
+// commands are the types of the commands run by this service.
+var commands = map[string]bool{
+	models.TaskStartScraper:   true,
+	models.TaskApplyRetention: true,
+	models.TaskCheckIntegrity: true,
+}
+
 // EventProcessor ...
 type EventProcessor struct {
 	EventListener messagequeue.EventListener
@@ -24,40 +45,46 @@ type EventProcessor struct {
 func (p *EventProcessor) ProcessEvents() error {
 	log.Println("Listening to events...")
 
-	var eventsList = []string{
-		"commandDispatched",
-		"scraperRun",
-	}
-
-	received, errors, err := p.EventListener.Listen(eventsList...)
+	errors, err := p.routes().Listen(p.EventListener)
 	if err != nil {
 		return err
 	}
 
-	for {
-		select {
-		case event := <-received:
-			p.handle(event)
-		case err = <-errors:
-			log.Printf("received error while processing message: %s", err)
-		}
+	for err = range errors {
+		log.Printf("received error while processing message: %s", err)
 	}
+	return nil
 }
 
-func (p *EventProcessor) handle(event messagequeue.Event) {
-	switch event.(type) {
-	case *contracts.EventCommandDispatched:
-		p.handleCommandDispatched(event.(*contracts.EventCommandDispatched))
-	default:
-		log.Printf("unknown event: %t", event)
-	}
+// routes returns a router calling the handler of each event.
+func (p *EventProcessor) routes() *messagequeue.Router {
+	router := messagequeue.NewRouter()
+	messagequeue.Handle(router, p.handleCommandDispatched)
+	return router
+}
+
+// scoped returns a copy of p tagging every log entry with the event and the
+// correlation it belongs to.
+func (p *EventProcessor) scoped(ctx context.Context) *EventProcessor {
+	scoped := *p
+	scoped.Log = p.Log.WithFields(messagequeue.LogFields(ctx))
+	return &scoped
 }
 
-func (p *EventProcessor) handleCommandDispatched(e *contracts.EventCommandDispatched) {
-	abort := messagequeue.CheckAbort(e.DispatchTime, e.ExecutionTimeout)
+func (p *EventProcessor) handleCommandDispatched(ctx context.Context, e *contracts.EventCommandDispatched) error {
+	p = p.scoped(ctx)
+
+	// Retried commands were received in time, only the first delivery may be
+	// too late.
+	abort := messagequeue.RetryCount(ctx) == 0 && messagequeue.CheckAbort(e.DispatchTime, e.ExecutionTimeout)
 	if abort {
+		if !commands[e.Type] {
+			// Meant for another service, which replies the failure.
+			messagequeue.Ignore(ctx)
+			return nil
+		}
 		p.Log.Warnf("event %s aborted. reason: timeout reached", e.Name)
-		return
+		return messagequeue.Permanent(messagequeue.ErrTimeout)
 	}
 
 	p.Log.Infof("handling event %s. dispatched at: %s", e.Name, e.DispatchTime)
@@ -75,8 +102,9 @@ func (p *EventProcessor) handleCommandDispatched(e *contracts.EventCommandDispat
 			queue.AddWork(queue.WorkRequest{
 				ScraperID:     args["scraper_id"],
 				IgnoreLastRun: ignoreLastRun,
+				Context:       ctx,
 			})
-			return
+			return messagequeue.SetResult(ctx, StartScraperResult{Queued: 1})
 		}
 
 		var query persistence.Query
@@ -101,18 +129,61 @@ func (p *EventProcessor) handleCommandDispatched(e *contracts.EventCommandDispat
 			scrapers, err := p.Data.GetScrapers(query)
 			if err != nil {
 				p.Log.Error(err.Error())
-				return
+				return err
 			}
 			for _, s := range scrapers {
 				queue.AddWork(queue.WorkRequest{
 					ScraperID:     s.ID.Hex(),
 					IgnoreLastRun: ignoreLastRun,
+					Context:       ctx,
 				})
 			}
+			return messagequeue.SetResult(ctx, StartScraperResult{Queued: len(scrapers)})
 		}
 
 		// Add to queue for each
+	case models.TaskApplyRetention:
+		opts, err := task.ParseRetentionOptions(e.Args)
+		if err != nil {
+			// Bad arguments won't get better by retrying.
+			err = messagequeue.Permanent(err)
+		} else {
+			var summary *task.RetentionSummary
+			summary, err = task.ApplyRetention(p.Data, opts)
+			if summary != nil {
+				p.Log.Infof("archived %d sessions in %d histories and deleted %d scraper runs",
+					summary.ArchivedSessions, summary.UpdatedHistories, summary.DeletedScraperRuns)
+				messagequeue.SetResult(ctx, summary)
+			}
+		}
+		if err != nil {
+			p.Log.Errorf("couldn't apply retention policy: %s", err)
+		}
+		shared.UpdateTaskStatus(e.TaskID, p.Data, p.Log, err)
+		return err
+
+	case models.TaskCheckIntegrity:
+		opts, err := task.ParseIntegrityOptions(e.Args)
+		if err != nil {
+			err = messagequeue.Permanent(err)
+		} else {
+			var report *models.IntegrityReport
+			report, err = task.CheckIntegrity(p.Data, opts)
+			if report != nil {
+				p.Log.Infof("found %d integrity issues, see report %s", report.IssueCount(), report.ID.Hex())
+				messagequeue.SetResult(ctx, IntegrityResult{ReportID: report.ID.Hex(), Issues: report.IssueCount()})
+			}
+		}
+		if err != nil {
+			p.Log.Errorf("couldn't check integrity: %s", err)
+		}
+		shared.UpdateTaskStatus(e.TaskID, p.Data, p.Log, err)
+		return err
+
 	default:
+		// Meant for another service.
+		messagequeue.Ignore(ctx)
 		p.Log.Infof("handler for event %s was not found", e.Name)
 	}
+	return nil
 }
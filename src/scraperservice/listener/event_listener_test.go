@@ -0,0 +1,109 @@
+package listener
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/contracts"
+	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandDispatched(t *testing.T) {
+	data, err := memlayer.NewMemDAL()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broker := messagequeue.NewMemoryBrokerWithRetry(messagequeue.RetryPolicy{MaxRetries: 1, InitialDelay: time.Millisecond})
+	defer broker.Close()
+
+	p := EventProcessor{
+		EventListener: broker.Listener("Scraper"),
+		EventEmitter:  broker.Emitter("Admin"),
+		Data:          data,
+		Log:           logrus.WithField("App", "Scraper"),
+	}
+	errors, err := p.routes().Listen(p.EventListener)
+	if !assert.NoError(t, err) {
+		return
+	}
+	go func() {
+		for range errors {
+		}
+	}()
+
+	tasks := []models.Task{
+		{ID: "check", Service: "Scraper", Type: models.TaskCheckIntegrity, Args: []string{"-repair", "true"}},
+		{ID: "bad", Service: "Scraper", Type: models.TaskCheckIntegrity, Args: []string{"-repair", "maybe"}},
+	}
+	for _, task := range tasks {
+		assert.NoError(t, data.InsertTask(task))
+		assert.NoError(t, p.EventEmitter.Emit(&contracts.EventCommandDispatched{
+			TaskID:           task.ID,
+			Name:             task.Name,
+			Type:             task.Type,
+			Args:             task.Args,
+			DispatchTime:     time.Now().UTC(),
+			ExecutionTimeout: time.Minute,
+		}))
+	}
+	broker.Wait()
+
+	reports, err := data.GetIntegrityReports(data.DefaultQuery())
+	assert.NoError(t, err)
+	if assert.Len(t, reports, 1) {
+		assert.True(t, reports[0].Repair)
+	}
+
+	task, err := data.GetTask("check", data.DefaultQuery())
+	if assert.NoError(t, err) {
+		assert.False(t, task.LastRun.IsZero())
+		assert.Empty(t, task.LastError)
+	}
+
+	// Bad arguments are not retried.
+	task, err = data.GetTask("bad", data.DefaultQuery())
+	if assert.NoError(t, err) {
+		assert.Equal(t, "invalid repair: maybe", task.LastError)
+	}
+	dead := broker.DeadLetters("Scraper")
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, 0, dead[0].Retries)
+	}
+
+	// Commands received too late fail, the ones of other services are left
+	// for them to answer.
+	requester := broker.Requester("Admin")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	late := time.Now().UTC().Add(-time.Minute)
+	call, err := requester.Request(ctx, &contracts.EventCommandDispatched{
+		Type:             models.TaskApplyRetention,
+		DispatchTime:     late,
+		ExecutionTimeout: time.Second,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	reply, err := call.Wait(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, messagequeue.ReplyFailed, reply.Status)
+	assert.Contains(t, reply.Error, messagequeue.ErrTimeout.Error())
+
+	call, err = requester.Request(ctx, &contracts.EventCommandDispatched{
+		Type:             models.TaskSyncScores,
+		DispatchTime:     late,
+		ExecutionTimeout: time.Second,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	broker.Wait()
+	_, ok := call.Last()
+	assert.False(t, ok)
+}
@@ -12,9 +12,11 @@ import (
 	"github.com/dsbezerra/amenic/src/lib/config"
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
 	restm "github.com/dsbezerra/amenic/src/lib/middlewares/rest"
+	"github.com/dsbezerra/amenic/src/lib/outbox"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/dblayer"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
+	"github.com/dsbezerra/amenic/src/lib/shared"
 	"github.com/dsbezerra/amenic/src/scraperservice/listener"
 	"github.com/dsbezerra/amenic/src/scraperservice/queue"
 	"github.com/dsbezerra/amenic/src/scraperservice/rest"
@@ -23,7 +25,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/robfig/cron"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
 )
 
 const (
@@ -53,41 +54,47 @@ func main() {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Config = settings
+	// Catch events that don't match their contract during development.
+	messagequeue.ValidatePayloads = !settings.IsProduction
 
 	tasks, err := config.LoadTasks()
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
 
-	conn, err := amqp.Dial(settings.AMQPMessageBroker)
+	broker, err := shared.OpenBroker(settings)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
+	defer broker.Close()
 
-	eventEmitter, err := messagequeue.NewAMQPEventEmitter(conn, "events")
+	eventEmitter, err := broker.Emitter(ServiceName)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Emitter = eventEmitter
 
-	eventListener, err := messagequeue.NewAMQPEventListener(conn, "events", ServiceName)
+	eventListener, err := broker.Listener(ServiceName)
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
 	ctx.Listener = eventListener
 
-	data, err := mongolayer.NewMongoDAL(settings.DBConnection)
+	data, err := dblayer.NewPersistenceLayer(settings.DBType, settings.DBConnection)
+	if err != nil {
+		ctx.Log.Fatal(err)
+	}
+	err = data.Setup()
 	if err != nil {
 		ctx.Log.Fatal(err)
 	}
-	data.Setup()
 	defer data.Close()
 
 	ctx.Data = data
 	ctx.Log.Info("Database setup completed!")
 
 	// Ensure our tasks are saved in database.
-	(data.(*mongolayer.MongoDAL)).EnsureTasksExists(tasks)
+	data.EnsureTasksExists(tasks)
 
 	// Start event processor.
 	p := listener.EventProcessor{
@@ -118,7 +125,7 @@ func main() {
 					ctx.Log.Infof("Spec %s", spec)
 					c.AddFunc(spec, func() {
 						switch t.Type {
-						case models.TaskStartScraper:
+						case models.TaskStartScraper, models.TaskApplyRetention, models.TaskCheckIntegrity:
 							// NOTE: We emit here because our event processor do extra things to keep our tasks collection synced.
 							ctx.Emitter.Emit(&contracts.EventCommandDispatched{
 								TaskID:           t.ID,
@@ -147,8 +154,16 @@ func main() {
 		router.Run(settings.RESTEndpoint)
 	}()
 
+	// Scraper runs and their events are stored together and the events are
+	// published by the relay.
+	publisher, err := broker.Publisher()
+	if err != nil {
+		ctx.Log.Fatal(err)
+	}
+	go outbox.NewRelay(data, publisher, ServiceName, ctx.Log).Run()
+
 	// TODO: Get the number of workers from .env
-	queue.SetupWorkerQueue(data, eventEmitter, 4)
+	queue.SetupWorkerQueue(data, outbox.NewEmitter(data, ServiceName), 4)
 
 	// Wait for a signal
 	sig := <-sigCh
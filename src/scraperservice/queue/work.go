@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"context"
 	"errors"
 )
 
@@ -9,6 +10,9 @@ var WorkQueue = make(chan WorkRequest, 100)
 type WorkRequest struct {
 	ScraperID     string
 	IgnoreLastRun bool
+	// Context of the event that requested the work, events emitted when it's
+	// done are correlated to it.
+	Context context.Context
 }
 
 func AddWork(wr WorkRequest) error {
@@ -1,8 +1,12 @@
 package queue
 
 import (
+	"context"
+	"log"
+
 	"github.com/dsbezerra/amenic/src/contracts"
 	"github.com/dsbezerra/amenic/src/lib/messagequeue"
+	"github.com/dsbezerra/amenic/src/lib/outbox"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
 	"github.com/dsbezerra/amenic/src/scraperservice/task"
 )
@@ -38,15 +42,29 @@ func (w *Worker) Start() {
 					ScraperID:     work.ScraperID,
 					IgnoreLastRun: work.IgnoreLastRun,
 				}
-				run, err := task.StartScraper(w.Data, opts)
+				run, err := task.RunScraper(w.Data, opts)
 				if err != nil {
 					// p.Log.Errorln(err.Error())
 					return
 				}
-				w.EventEmitter.Emit(&contracts.EventScraperFinished{
-					Type:      run.Scraper.Type,
-					ScraperID: opts.ScraperID,
+				ctx := work.Context
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				// The event is only emitted if the run is stored.
+				err = w.Data.WithTransaction(func(tx persistence.DataAccessLayer) error {
+					err := tx.InsertScraperRun(*run)
+					if err != nil {
+						return err
+					}
+					return outbox.InTx(w.EventEmitter, tx).EmitContext(ctx, &contracts.EventScraperFinished{
+						Type:      run.Scraper.Type,
+						ScraperID: opts.ScraperID,
+					})
 				})
+				if err != nil {
+					log.Printf("couldn't store run of scraper %s: %s", opts.ScraperID, err)
+				}
 			case <-w.QuitChan:
 				return
 			}
@@ -0,0 +1,214 @@
+package task
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+const (
+	// DefaultSessionAge is how long sessions are kept after they start.
+	DefaultSessionAge = 7 * 24 * time.Hour
+
+	// DefaultScraperRunAge is how long scraper runs are kept after they start.
+	DefaultScraperRunAge = 30 * 24 * time.Hour
+
+	// DefaultRetentionBatchSize is how many sessions are archived at once.
+	DefaultRetentionBatchSize = 500
+
+	// defaultTimeZone is used for sessions without time zone.
+	defaultTimeZone = "America/Sao_Paulo"
+)
+
+// RetentionOptions ...
+type RetentionOptions struct {
+	SessionAge    time.Duration `json:"session_age"`
+	ScraperRunAge time.Duration `json:"scraper_run_age"`
+	BatchSize     int           `json:"batch_size"`
+}
+
+// RetentionSummary describes what was done by ApplyRetention.
+type RetentionSummary struct {
+	ArchivedSessions   int   `json:"archived_sessions"`
+	UpdatedHistories   int   `json:"updated_histories"`
+	DeletedScraperRuns int64 `json:"deleted_scraper_runs"`
+}
+
+// ParseRetentionOptions parses the task arguments, e.g.:
+// -session_age 168h -scraper_run_age 720h -batch_size 500. Missing arguments
+// use their default values.
+func ParseRetentionOptions(args []string) (RetentionOptions, error) {
+	opts := RetentionOptions{
+		SessionAge:    DefaultSessionAge,
+		ScraperRunAge: DefaultScraperRunAge,
+		BatchSize:     DefaultRetentionBatchSize,
+	}
+
+	parsed := models.ParseArgs(args)
+	var err error
+	if value, ok := parsed["session_age"]; ok {
+		opts.SessionAge, err = time.ParseDuration(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid session_age: %s", err)
+		}
+	}
+	if value, ok := parsed["scraper_run_age"]; ok {
+		opts.ScraperRunAge, err = time.ParseDuration(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid scraper_run_age: %s", err)
+		}
+	}
+	if value, ok := parsed["batch_size"]; ok {
+		_, err = fmt.Sscanf(value, "%d", &opts.BatchSize)
+		if err != nil || opts.BatchSize <= 0 {
+			return opts, fmt.Errorf("invalid batch_size: %s", value)
+		}
+	}
+	return opts, nil
+}
+
+// ApplyRetention moves sessions older than SessionAge to the session history
+// and removes scraper runs older than ScraperRunAge. The last run of each
+// scraper is always kept since it's used to detect changes.
+func ApplyRetention(data persistence.DataAccessLayer, opts RetentionOptions) (*RetentionSummary, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultRetentionBatchSize
+	}
+
+	now := time.Now().UTC()
+	summary := &RetentionSummary{}
+	if opts.SessionAge > 0 {
+		err := archiveSessions(data, now.Add(-opts.SessionAge), opts.BatchSize, summary)
+		if err != nil {
+			return summary, err
+		}
+	}
+	if opts.ScraperRunAge > 0 {
+		deleted, err := pruneScraperRuns(data, now.Add(-opts.ScraperRunAge))
+		summary.DeletedScraperRuns = deleted
+		if err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// archiveSessions archives sessions that started before the given time in
+// batches. Each batch is added to the history and deleted in a single
+// transaction so sessions are never counted twice.
+func archiveSessions(data persistence.DataAccessLayer, before time.Time, batchSize int, summary *RetentionSummary) error {
+	for {
+		var archived, updated int
+		err := data.WithTransaction(func(tx persistence.DataAccessLayer) error {
+			sessions, err := tx.GetSessions(tx.DefaultQuery().
+				Where(persistence.Range("startTime", nil, before)).
+				SetSort("_id").
+				SetLimit(int64(batchSize)))
+			if err != nil {
+				return err
+			}
+			archived, updated = len(sessions), 0
+			if archived == 0 {
+				return nil
+			}
+
+			histories, err := addToHistory(tx, sessions)
+			if err != nil {
+				return err
+			}
+			err = tx.SaveSessionHistories(histories...)
+			if err != nil {
+				return err
+			}
+
+			ids := make([]interface{}, len(sessions))
+			for i, s := range sessions {
+				ids[i] = s.ID
+			}
+			_, err = tx.DeleteSessions(tx.DefaultQuery().Where(persistence.In("_id", ids...)))
+			updated = len(histories)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		summary.ArchivedSessions += archived
+		summary.UpdatedHistories += updated
+		if archived < batchSize {
+			return nil
+		}
+	}
+}
+
+// addToHistory returns the histories of the given sessions with them added.
+func addToHistory(data persistence.DataAccessLayer, sessions []models.Session) ([]models.SessionHistory, error) {
+	added := make(map[string]*models.SessionHistory)
+	var ids []interface{}
+	for _, s := range sessions {
+		// Hidden sessions were removed from the theater schedule, so they
+		// didn't play.
+		if s.Hidden || s.StartTime == nil {
+			continue
+		}
+		date := s.StartTime.In(sessionLocation(s)).Format("2006-01-02")
+		ID := models.SessionHistoryID(s, date)
+		if _, ok := added[ID]; !ok {
+			added[ID] = &models.SessionHistory{ID: ID, Date: date}
+			ids = append(ids, ID)
+		}
+		added[ID].AddSession(s)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	stored, err := data.GetSessionHistories(data.DefaultQuery().
+		Where(persistence.In("_id", ids...)).
+		SetLimit(int64(len(ids))))
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range stored {
+		h := h
+		h.Merge(*added[h.ID])
+		added[h.ID] = &h
+	}
+
+	result := make([]models.SessionHistory, len(ids))
+	for i, ID := range ids {
+		result[i] = *added[ID.(string)]
+	}
+	return result, nil
+}
+
+func sessionLocation(s models.Session) *time.Location {
+	name := s.TimeZone
+	if name == "" {
+		name = defaultTimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// pruneScraperRuns removes scraper runs started before the given time that
+// are not the last run of their scrapers.
+func pruneScraperRuns(data persistence.DataAccessLayer, before time.Time) (int64, error) {
+	scrapers, err := data.GetScrapers(data.DefaultQuery().SetLimit(-1))
+	if err != nil {
+		return 0, err
+	}
+	keep := make([]interface{}, 0, len(scrapers))
+	for _, s := range scrapers {
+		if !s.LastRun.IsZero() {
+			keep = append(keep, s.LastRun)
+		}
+	}
+	return data.DeleteScraperRuns(data.DefaultQuery().
+		Where(persistence.Range("start_time", nil, before)).
+		Where(persistence.NotIn("_id", keep...)))
+}
@@ -0,0 +1,106 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestApplyRetention(t *testing.T) {
+	data := newMockDataAccessLayer()
+
+	theaterID := primitive.NewObjectID()
+	movieID := primitive.NewObjectID()
+	now := time.Now().UTC()
+	old := time.Date(2019, 8, 1, 20, 0, 0, 0, time.UTC) // 17h in São Paulo
+
+	session := func(start time.Time, room uint, format string, hidden bool) models.Session {
+		return models.Session{
+			ID:        primitive.NewObjectID(),
+			MovieID:   movieID,
+			TheaterID: theaterID,
+			Room:      room,
+			Format:    format,
+			Version:   models.VersionDubbed,
+			Hidden:    hidden,
+			StartTime: &start,
+		}
+	}
+	assert.NoError(t, data.InsertSessions(
+		session(old, 1, models.Format2D, false),
+		session(old.Add(2*time.Hour), 2, models.Format3D, false),
+		session(old.Add(4*time.Hour), 1, models.Format2D, false), // Next day in UTC, same day in São Paulo
+		session(old.Add(24*time.Hour), 1, models.Format2D, false),
+		session(old.Add(26*time.Hour), 1, models.Format2D, true),
+		session(now.Add(time.Hour), 1, models.Format2D, false),
+	))
+
+	// Runs referenced by scrapers are kept
+	runs := []models.ScraperRun{
+		{ID: primitive.NewObjectID(), StartTime: &old},
+		{ID: primitive.NewObjectID(), StartTime: &old},
+		{ID: primitive.NewObjectID(), StartTime: &now},
+	}
+	for _, r := range runs {
+		assert.NoError(t, data.InsertScraperRun(r))
+	}
+	assert.NoError(t, data.InsertScraper(models.Scraper{ID: primitive.NewObjectID(), LastRun: runs[1].ID}))
+
+	summary, err := ApplyRetention(data, RetentionOptions{
+		SessionAge:    24 * time.Hour,
+		ScraperRunAge: 24 * time.Hour,
+		BatchSize:     2,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &RetentionSummary{ArchivedSessions: 5, UpdatedHistories: 3, DeletedScraperRuns: 1}, summary)
+
+	sessions, err := data.GetSessions(data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	histories, err := data.GetSessionHistories(data.DefaultQuery().SetSort("date"))
+	assert.NoError(t, err)
+	if assert.Len(t, histories, 2) {
+		h := histories[0]
+		assert.Equal(t, models.SessionHistoryID(sessions[0], "2019-08-01"), h.ID)
+		assert.Equal(t, "2019-08-01", h.Date)
+		assert.Equal(t, 3, h.Sessions)
+		assert.Equal(t, map[string]int{models.Format2D: 2, models.Format3D: 1}, h.Formats)
+		assert.Equal(t, map[string]int{models.VersionDubbed: 3}, h.Versions)
+		assert.Equal(t, []uint{1, 2}, h.Rooms)
+		assert.True(t, old.Equal(*h.FirstStartTime))
+		assert.True(t, old.Add(4*time.Hour).Equal(*h.LastStartTime))
+
+		// Hidden sessions are not counted
+		assert.Equal(t, "2019-08-02", histories[1].Date)
+		assert.Equal(t, 1, histories[1].Sessions)
+	}
+
+	_, err = data.GetScraperRun(runs[0].ID.Hex(), data.DefaultQuery())
+	assert.Error(t, err)
+	_, err = data.GetScraperRun(runs[1].ID.Hex(), data.DefaultQuery())
+	assert.NoError(t, err)
+
+	// Running again changes nothing
+	summary, err = ApplyRetention(data, RetentionOptions{SessionAge: 24 * time.Hour, ScraperRunAge: 24 * time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, &RetentionSummary{}, summary)
+}
+
+func TestParseRetentionOptions(t *testing.T) {
+	opts, err := ParseRetentionOptions(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, RetentionOptions{DefaultSessionAge, DefaultScraperRunAge, DefaultRetentionBatchSize}, opts)
+
+	opts, err = ParseRetentionOptions([]string{"-session_age", "48h", "-batch_size", "10"})
+	assert.NoError(t, err)
+	assert.Equal(t, RetentionOptions{48 * time.Hour, DefaultScraperRunAge, 10}, opts)
+
+	_, err = ParseRetentionOptions([]string{"-scraper_run_age", "a month"})
+	assert.Error(t, err)
+	_, err = ParseRetentionOptions([]string{"-batch_size", "0"})
+	assert.Error(t, err)
+}
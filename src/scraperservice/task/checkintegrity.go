@@ -0,0 +1,254 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+)
+
+// integrityBatchSize is how many sessions are repaired at once.
+const integrityBatchSize = 500
+
+// IntegrityOptions ...
+type IntegrityOptions struct {
+	Repair bool `json:"repair"`
+}
+
+// ParseIntegrityOptions parses the task arguments, e.g.: -repair true.
+// Issues are only reported unless repair is given, which is left to the
+// check_integrity admin command after the report is reviewed.
+func ParseIntegrityOptions(args []string) (IntegrityOptions, error) {
+	opts := IntegrityOptions{}
+
+	parsed := models.ParseArgs(args)
+	if value, ok := parsed["repair"]; ok {
+		var err error
+		opts.Repair, err = strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid repair: %s", value)
+		}
+	}
+	return opts, nil
+}
+
+// CheckIntegrity looks for references to movies and theaters that don't
+// exist and for visible sessions whose movie was never found, then stores a
+// report with them.
+//
+// When Repair is set, sessions with a bad movie reference are matched again
+// by their movie slug and the ones that can't be matched, or whose theater
+// doesn't exist, are hidden. Prices, scores, images and scrapers are only
+// reported. Slugs must match exactly, so a movie renamed by the scraper
+// hides its sessions instead of being rematched and repairs shouldn't run
+// unattended.
+func CheckIntegrity(data persistence.DataAccessLayer, opts IntegrityOptions) (*models.IntegrityReport, error) {
+	start := time.Now().UTC()
+	report := models.NewIntegrityReport(opts.Repair, start)
+
+	movies, slugs, err := movieReferences(data)
+	if err != nil {
+		return nil, err
+	}
+	theaters, err := theaterReferences(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkSessions(data, report, movies, slugs, theaters, opts.Repair)
+	if err != nil {
+		return nil, err
+	}
+	err = checkReferences(data, report, movies, theaters)
+	if err != nil {
+		return nil, err
+	}
+
+	end := time.Now().UTC()
+	report.EndTime = &end
+	err = data.InsertIntegrityReport(*report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// movieReferences returns the hex IDs of every movie and the movie of each
+// slug.
+func movieReferences(data persistence.DataAccessLayer) (map[string]bool, map[string]models.Movie, error) {
+	movies, err := data.GetMovies(data.DefaultQuery().
+		SetFields("slug").
+		SetLimit(-1))
+	if err != nil {
+		return nil, nil, err
+	}
+	IDs := make(map[string]bool, len(movies))
+	slugs := make(map[string]models.Movie, len(movies))
+	for _, m := range movies {
+		IDs[m.ID.Hex()] = true
+		if m.Slug != "" {
+			slugs[m.Slug] = m
+		}
+	}
+	return IDs, slugs, nil
+}
+
+// theaterReferences returns the hex IDs of every theater.
+func theaterReferences(data persistence.DataAccessLayer) (map[string]bool, error) {
+	theaters, err := data.GetTheaters(data.DefaultQuery().
+		SetFields("_id").
+		SetLimit(-1))
+	if err != nil {
+		return nil, err
+	}
+	IDs := make(map[string]bool, len(theaters))
+	for _, t := range theaters {
+		IDs[t.ID.Hex()] = true
+	}
+	return IDs, nil
+}
+
+// checkSessions adds the issues of visible sessions to the report and
+// repairs them if requested. Hidden sessions are skipped since they're not
+// served and hiding is how sessions are repaired when nothing else works.
+func checkSessions(data persistence.DataAccessLayer, report *models.IntegrityReport,
+	movies map[string]bool, slugs map[string]models.Movie,
+	theaters map[string]bool, repair bool) error {
+
+	sessions, err := data.GetSessions(data.DefaultQuery().
+		Where(persistence.Eq("hidden", false)).
+		SetFields("movieId", "movieSlug", "theaterId").
+		SetLimit(-1))
+	if err != nil {
+		return err
+	}
+	report.Checked["sessions"] = len(sessions)
+
+	// Sessions to rematch by the slug of their movie.
+	rematch := make(map[string][]interface{})
+	var hide []interface{}
+	for _, s := range sessions {
+		var issues []models.IntegrityIssue
+		theaterFound := theaters[s.TheaterID.Hex()]
+		if !theaterFound {
+			issues = append(issues, models.OrphanedIssue("sessions", s.ID, "theaterId", s.TheaterID))
+		}
+		if s.MovieID.IsZero() {
+			issues = append(issues, models.IntegrityIssue{
+				Collection: "sessions",
+				DocumentID: s.ID,
+				Field:      "movieId",
+				Kind:       models.IntegrityUnresolved,
+			})
+		} else if !movies[s.MovieID.Hex()] {
+			issues = append(issues, models.OrphanedIssue("sessions", s.ID, "movieId", s.MovieID))
+		}
+		if len(issues) == 0 {
+			continue
+		}
+
+		var fix string
+		if repair {
+			_, ok := slugs[s.MovieSlug]
+			if ok && theaterFound {
+				rematch[s.MovieSlug] = append(rematch[s.MovieSlug], s.ID)
+				fix = models.IntegrityRematched
+			} else {
+				hide = append(hide, s.ID)
+				fix = models.IntegrityHidden
+			}
+		}
+		for _, issue := range issues {
+			issue.Repair = fix
+			report.AddIssue(issue)
+		}
+	}
+
+	for slug, IDs := range rematch {
+		err = updateSessions(data, IDs, persistence.Update{Set: map[string]interface{}{"movieId": slugs[slug].ID}})
+		if err != nil {
+			return err
+		}
+	}
+	return updateSessions(data, hide, persistence.Update{Set: map[string]interface{}{"hidden": true}})
+}
+
+// checkReferences adds the issues of prices, scores, images and scrapers to
+// the report.
+func checkReferences(data persistence.DataAccessLayer, report *models.IntegrityReport,
+	movies, theaters map[string]bool) error {
+
+	prices, err := data.GetPrices(data.DefaultQuery().
+		SetFields("theaterId").
+		SetLimit(-1))
+	if err != nil {
+		return err
+	}
+	report.Checked["prices"] = len(prices)
+	for _, p := range prices {
+		if !theaters[p.TheaterID.Hex()] {
+			report.AddIssue(models.OrphanedIssue("prices", p.ID, "theaterId", p.TheaterID))
+		}
+	}
+
+	scrapers, err := data.GetScrapers(data.DefaultQuery().
+		SetFields("theaterId").
+		SetLimit(-1))
+	if err != nil {
+		return err
+	}
+	report.Checked["scrapers"] = len(scrapers)
+	for _, s := range scrapers {
+		if !theaters[s.TheaterID.Hex()] {
+			report.AddIssue(models.OrphanedIssue("scrapers", s.ID, "theaterId", s.TheaterID))
+		}
+	}
+
+	scores, err := data.GetScores(data.DefaultQuery().
+		SetFields("movieId").
+		SetLimit(-1))
+	if err != nil {
+		return err
+	}
+	report.Checked["scores"] = len(scores)
+	for _, s := range scores {
+		if !movies[s.MovieID.Hex()] {
+			report.AddIssue(models.OrphanedIssue("scores", s.ID, "movieId", s.MovieID))
+		}
+	}
+
+	images, err := data.GetImages(data.DefaultQuery().
+		SetFields("movieId").
+		SetLimit(-1))
+	if err != nil {
+		return err
+	}
+	report.Checked["images"] = len(images)
+	for _, i := range images {
+		// Images without movie belong to something else.
+		if !i.MovieID.IsZero() && !movies[i.MovieID.Hex()] {
+			report.AddIssue(models.OrphanedIssue("images", i.ID, "movieId", i.MovieID))
+		}
+	}
+	return nil
+}
+
+// updateSessions applies update to the sessions with the given IDs in
+// batches.
+func updateSessions(data persistence.DataAccessLayer, IDs []interface{}, update persistence.Update) error {
+	for start := 0; start < len(IDs); start += integrityBatchSize {
+		end := start + integrityBatchSize
+		if end > len(IDs) {
+			end = len(IDs)
+		}
+		_, err := data.UpdateSessions(data.DefaultQuery().
+			Where(persistence.In("_id", IDs[start:end]...)).
+			SetLimit(-1), update)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
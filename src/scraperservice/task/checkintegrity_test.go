@@ -0,0 +1,93 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/dsbezerra/amenic/src/lib/persistence/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	data := newMockDataAccessLayer()
+
+	theater := models.Theater{ID: primitive.NewObjectID(), Name: "Cine Araújo"}
+	movie := models.Movie{ID: primitive.NewObjectID(), Slug: "bacurau", Title: "Bacurau"}
+	assert.NoError(t, data.InsertTheater(theater))
+	assert.NoError(t, data.InsertMovie(movie))
+
+	missing := primitive.NewObjectID()
+	sessions := []models.Session{
+		{ID: primitive.NewObjectID(), MovieID: movie.ID, TheaterID: theater.ID, MovieSlug: movie.Slug},
+		{ID: primitive.NewObjectID(), TheaterID: theater.ID, MovieSlug: movie.Slug},                   // Unresolved, rematched
+		{ID: primitive.NewObjectID(), MovieID: missing, TheaterID: theater.ID, MovieSlug: "parasita"}, // Orphaned, hidden
+		{ID: primitive.NewObjectID(), MovieID: movie.ID, TheaterID: missing, MovieSlug: movie.Slug},   // Orphaned, hidden
+		{ID: primitive.NewObjectID(), TheaterID: theater.ID, MovieSlug: "parasita", Hidden: true},     // Skipped
+	}
+	assert.NoError(t, data.InsertSessions(sessions...))
+	assert.NoError(t, data.InsertPrices(
+		models.Price{ID: primitive.NewObjectID(), TheaterID: theater.ID},
+		models.Price{ID: primitive.NewObjectID(), TheaterID: missing},
+	))
+	assert.NoError(t, data.InsertScore(models.Score{ID: primitive.NewObjectID(), MovieID: missing}))
+	assert.NoError(t, data.InsertImage(models.Image{ID: primitive.NewObjectID(), MovieID: movie.ID}))
+	assert.NoError(t, data.InsertScraper(models.Scraper{ID: primitive.NewObjectID(), TheaterID: theater.ID}))
+
+	// Only reports
+	report, err := CheckIntegrity(data, IntegrityOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]int{"sessions": 4, "prices": 2, "scrapers": 1, "scores": 1, "images": 1}, report.Checked)
+	assert.Equal(t, models.IntegrityCount{Orphaned: 2, Unresolved: 1}, report.Counts["sessions"])
+	assert.Equal(t, models.IntegrityCount{Orphaned: 1}, report.Counts["prices"])
+	assert.Equal(t, models.IntegrityCount{Orphaned: 1}, report.Counts["scores"])
+	assert.Equal(t, 5, report.IssueCount())
+	assert.Len(t, report.Issues, 5)
+
+	stored, err := data.GetSessions(data.DefaultQuery().AddCondition("hidden", false).SetLimit(-1))
+	assert.NoError(t, err)
+	assert.Len(t, stored, 4)
+
+	// Repairs sessions
+	report, err = CheckIntegrity(data, IntegrityOptions{Repair: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, models.IntegrityCount{Orphaned: 2, Unresolved: 1, Rematched: 1, Hidden: 2}, report.Counts["sessions"])
+
+	rematched, err := data.GetSession(sessions[1].ID.Hex(), data.DefaultQuery())
+	if assert.NoError(t, err) {
+		assert.Equal(t, movie.ID, rematched.MovieID)
+		assert.False(t, rematched.Hidden)
+	}
+	for _, s := range sessions[2:4] {
+		hidden, err := data.GetSession(s.ID.Hex(), data.DefaultQuery())
+		if assert.NoError(t, err) {
+			assert.True(t, hidden.Hidden)
+		}
+	}
+
+	// Sessions are fine now, other issues remain
+	report, err = CheckIntegrity(data, IntegrityOptions{Repair: true})
+	assert.NoError(t, err)
+	assert.Equal(t, models.IntegrityCount{}, report.Counts["sessions"])
+	assert.Equal(t, 2, report.IssueCount())
+
+	reports, err := data.GetIntegrityReports(data.DefaultQuery())
+	assert.NoError(t, err)
+	assert.Len(t, reports, 3)
+}
+
+func TestParseIntegrityOptions(t *testing.T) {
+	opts, err := ParseIntegrityOptions(nil)
+	assert.NoError(t, err)
+	assert.False(t, opts.Repair)
+
+	opts, err = ParseIntegrityOptions([]string{"-repair", "true"})
+	assert.NoError(t, err)
+	assert.True(t, opts.Repair)
+
+	_, err = ParseIntegrityOptions([]string{"-repair", "maybe"})
+	assert.Error(t, err)
+}
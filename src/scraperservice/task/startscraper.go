@@ -20,8 +20,18 @@ type ScraperOptions struct {
 	IgnoreLastRun bool   `json:"ignore_last_run"`
 }
 
-// StartScraper ...
+// StartScraper runs the scraper and stores its run.
 func StartScraper(data persistence.DataAccessLayer, opts ScraperOptions) (*models.ScraperRun, error) {
+	run, err := RunScraper(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return run, data.InsertScraperRun(*run)
+}
+
+// RunScraper runs the scraper like StartScraper but leaves storing the run to
+// the caller, e.g.: to store it in a transaction.
+func RunScraper(data persistence.DataAccessLayer, opts ScraperOptions) (*models.ScraperRun, error) {
 	run, err := InitScraper(data, opts)
 	if err != nil {
 		return nil, err
@@ -54,10 +64,14 @@ func StartScraper(data persistence.DataAccessLayer, opts ScraperOptions) (*model
 		} else {
 			run.ResultCode = scraperutil.RunResultSuccess
 		}
-		e.Complete()
+		err = e.Complete()
+		if err != nil {
+			run.ResultCode = scraperutil.RunResultFailed
+			run.Error = err.Error()
+		}
 	}
 
-	return run, data.InsertScraperRun(*run)
+	return run, nil
 }
 
 // InitScraper ...
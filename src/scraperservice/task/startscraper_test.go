@@ -8,17 +8,12 @@ import (
 
 	"github.com/dsbezerra/amenic/src/lib/config"
 	"github.com/dsbezerra/amenic/src/lib/persistence"
+	"github.com/dsbezerra/amenic/src/lib/persistence/memlayer"
 	"github.com/dsbezerra/amenic/src/lib/persistence/models"
-	"github.com/dsbezerra/amenic/src/lib/persistence/mongolayer"
 	"github.com/dsbezerra/amenic/src/lib/util/scraperutil"
 	"github.com/dsbezerra/amenic/src/scraperservice/provider"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-)
-
-const (
-	testConnection = "mongodb://localhost/amenic-test"
 )
 
 func TestStartScraperCinemais(t *testing.T) {
@@ -73,7 +68,7 @@ func TestStartScraperIbicinemas(t *testing.T) {
 
 func ensureCinemaisTestDataExists(data persistence.DataAccessLayer) (*models.Theater, *models.Scraper, error) {
 	theater, err := data.GetTheater("5d4e00db1b3e2d231434d147", data.DefaultQuery())
-	if err != nil && err == mongo.ErrNoDocuments {
+	if err != nil && err == persistence.ErrNotFound {
 		ID, _ := primitive.ObjectIDFromHex("5d4e00db1b3e2d231434d147") // Ignoring error since it's a valid hex
 		testTheater := models.Theater{
 			ID:         ID,
@@ -94,7 +89,7 @@ func ensureCinemaisTestDataExists(data persistence.DataAccessLayer) (*models.The
 		AddCondition("theaterId", theater.ID).
 		AddCondition("type", scraperutil.TypeSchedule).
 		AddCondition("provider", provider.ProviderCinemais))
-	if err != nil && err == mongo.ErrNoDocuments {
+	if err != nil && err == persistence.ErrNotFound {
 		scraper = &models.Scraper{
 			ID:        primitive.NewObjectID(),
 			TheaterID: theater.ID,
@@ -109,7 +104,7 @@ func ensureCinemaisTestDataExists(data persistence.DataAccessLayer) (*models.The
 
 func ensureIbicinemaisTestDataExists(data persistence.DataAccessLayer) (*models.Theater, *models.Scraper, error) {
 	theater, err := data.GetTheater("5d4e01661b3e2d231434d148", data.DefaultQuery())
-	if err != nil && err == mongo.ErrNoDocuments {
+	if err != nil && err == persistence.ErrNotFound {
 		ID, _ := primitive.ObjectIDFromHex("5d4e01661b3e2d231434d148") // Ignoring error since it's a valid hex
 		testTheater := models.Theater{
 			ID:         ID,
@@ -130,7 +125,7 @@ func ensureIbicinemaisTestDataExists(data persistence.DataAccessLayer) (*models.
 		AddCondition("theaterId", theater.ID).
 		AddCondition("type", scraperutil.TypeSchedule).
 		AddCondition("provider", provider.ProviderIbicinemas))
-	if err != nil && err == mongo.ErrNoDocuments {
+	if err != nil && err == persistence.ErrNotFound {
 		scraper = &models.Scraper{
 			ID:        primitive.NewObjectID(),
 			TheaterID: theater.ID,
@@ -144,7 +139,7 @@ func ensureIbicinemaisTestDataExists(data persistence.DataAccessLayer) (*models.
 }
 
 func newMockDataAccessLayer() persistence.DataAccessLayer {
-	data, err := mongolayer.NewMongoDAL(testConnection)
+	data, err := memlayer.NewMemDAL()
 	exitIfErr(err)
 	return data
 }